@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/fakeexchange"
+	"github.com/alik/TestForWork/pkg/logger"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	configPath := flag.String("config", "", "YAML file with markets, faults and scenarios; a single usdtrub random walk if empty")
+	logLevel := flag.String("log-level", "info", "log level")
+	flag.Parse()
+
+	log, err := logger.New(*logLevel, "json")
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Close()
+
+	cfg := fakeexchange.DefaultConfig()
+	if *configPath != "" {
+		cfg, err = fakeexchange.LoadConfig(*configPath)
+		if err != nil {
+			log.Error("Failed to load config", zap.String("path", *configPath), zap.Error(err))
+			os.Exit(1)
+		}
+	}
+
+	exchange, err := fakeexchange.New(cfg, log.Logger)
+	if err != nil {
+		log.Error("Failed to create fake exchange", zap.Error(err))
+		os.Exit(1)
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           exchange,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info("Starting fake exchange", zap.String("addr", *addr), zap.Int("markets", len(cfg.Markets)))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		log.Error("Server error", zap.Error(err))
+	case sig := <-sigChan:
+		log.Info("Received signal, shutting down", zap.String("signal", sig.String()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error("Failed to stop server gracefully", zap.Error(err))
+	}
+}
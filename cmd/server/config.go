@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/alik/TestForWork/internal/config"
+)
+
+// runConfig runs a config subcommand and returns the exit code
+func runConfig() int {
+	command := ""
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	switch command {
+	case "check":
+		return runConfigCheck()
+	default:
+		fmt.Println("Usage: app config check [--config FILE] [--print] [flags]")
+		return 2
+	}
+}
+
+// runConfigCheck loads and validates the configuration the server would run
+// with, including flags and environment variables, without starting it
+func runConfigCheck() int {
+	printConfig := flag.Bool("print", false, "Print the effective configuration with secrets redacted")
+
+	cfg, err := config.Load()
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			fmt.Println(invalid.Error())
+			return 1
+		}
+		fmt.Printf("Failed to load config: %v\n", err)
+		return 1
+	}
+
+	if *printConfig {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Dump(cfg)); err != nil {
+			fmt.Printf("Failed to print config: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Println("Configuration is valid")
+	return 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/export"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/alik/TestForWork/pkg/logger"
+)
+
+// exportTimeLayouts are the accepted --from and --to formats; a date without
+// a time is midnight UTC
+var exportTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// runExport writes the rates history of a market to a file or standard
+// output and returns the exit code. Data goes to standard output only, so
+// messages are printed to standard error.
+func runExport() int {
+	market := flag.String("market", "", "Exported market (default grinex.market)")
+	from := flag.String("from", "", "Start of the range, inclusive: RFC 3339 time or date; the whole history if empty")
+	to := flag.String("to", "", "End of the range, exclusive: RFC 3339 time or date; now if empty")
+	format := flag.String("format", "", "Output format: csv, jsonl or parquet; detected from --output, csv for standard output")
+	resample := flag.Duration("resample", 0, "Emit the last known rate at this interval instead of every stored rate")
+	output := flag.String("output", "-", "Output file, - for standard output")
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	req := export.Request{Market: *market, Format: *format, Resample: *resample}
+	if req.Market == "" {
+		req.Market = cfg.Grinex.Market
+	}
+	if req.From, err = parseExportTime(*from); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --from: %v\n", err)
+		return 2
+	}
+	if req.To, err = parseExportTime(*to); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --to: %v\n", err)
+		return 2
+	}
+	if req.Format == "" {
+		req.Format = export.FormatCSV
+		if *output != "-" {
+			if req.Format, err = export.DetectFormat(*output); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 2
+			}
+		}
+	}
+
+	log, err := logger.New(cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		return 1
+	}
+	defer log.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.OpenPool(cfg.Database.DatabaseDSN(), poolConfig(cfg.Database, "primary"), log.Logger)
+	if err != nil {
+		log.Error("Failed to initialize database", zap.Error(err))
+		return 1
+	}
+	defer db.Close()
+
+	exporter := export.New(postgres.NewRepository(db, log.Logger), log.Logger)
+
+	if *output == "-" {
+		if err := exportTo(ctx, exporter, os.Stdout, req); err != nil {
+			log.Error("Export failed", zap.Error(err))
+			return 1
+		}
+		return 0
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		log.Error("Failed to create output file", zap.Error(err))
+		return 1
+	}
+	err = exportTo(ctx, exporter, file, req)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error("Export failed", zap.String("output", *output), zap.Error(err))
+		// A partial file is not a valid export
+		os.Remove(*output)
+		return 1
+	}
+
+	return 0
+}
+
+// exportTo runs the export through a buffer
+func exportTo(ctx context.Context, exporter *export.Exporter, w io.Writer, req export.Request) error {
+	buf := bufio.NewWriterSize(w, 256*1024)
+	if _, err := exporter.Export(ctx, buf, req); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// parseExportTime parses a time flag, returning the zero time for an empty value
+func parseExportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range exportTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a date", value)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	flag "github.com/spf13/pflag"
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/importer"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/alik/TestForWork/pkg/logger"
+)
+
+// runImport loads historical rates from the files given as arguments and
+// returns the exit code
+func runImport() int {
+	dryRun := flag.Bool("dry-run", false, "Validate and count new rows without writing them")
+	format := flag.String("format", "", "Input format: csv or jsonl; detected from the file extension if empty")
+	source := flag.String("source", "import", "Source recorded for rows without one")
+	batchSize := flag.Int("batch-size", 5000, "Rows copied per transaction")
+	maxErrors := flag.Int("max-errors", 100, "Abort after more invalid rows than this, -1 never aborts")
+	progress := flag.Duration("progress-interval", 0, "How often progress is logged (default 5s)")
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		return 1
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		fmt.Println("Usage: app import [flags] FILE... (use - for standard input)")
+		return 2
+	}
+
+	log, err := logger.New(cfg.Logging.Level, cfg.Logging.Format)
+	if err != nil {
+		fmt.Printf("Failed to initialize logger: %v\n", err)
+		return 1
+	}
+	defer log.Close()
+
+	// An interrupted import keeps its checkpoint and resumes on the next run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.OpenPool(cfg.Database.DatabaseDSN(), poolConfig(cfg.Database, "primary"), log.Logger)
+	if err != nil {
+		log.Error("Failed to initialize database", zap.Error(err))
+		return 1
+	}
+	defer db.Close()
+
+	if err := runMigrations(db, log.Logger); err != nil {
+		log.Error("Failed to run migrations", zap.Error(err))
+		return 1
+	}
+
+	opts := []importer.Option{
+		importer.WithBatchSize(*batchSize),
+		importer.WithDefaultSource(*source),
+		importer.WithMaxErrors(*maxErrors),
+		importer.WithProgressInterval(*progress),
+	}
+	if *dryRun {
+		opts = append(opts, importer.WithDryRun())
+	}
+	imp := importer.New(postgres.NewRepository(db, log.Logger), log.Logger, opts...)
+
+	code := 0
+	for _, file := range files {
+		stats, err := imp.ImportFile(ctx, file, *format)
+		fields := []zap.Field{
+			zap.String("file", file),
+			zap.Bool("dry_run", *dryRun),
+			zap.Int64("read", stats.Read),
+			zap.Int64("inserted", stats.Inserted),
+			zap.Int64("duplicates", stats.Duplicates),
+			zap.Int64("invalid", stats.Invalid),
+		}
+		if err != nil {
+			log.Error("Import failed", append(fields, zap.Error(err))...)
+			code = 1
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		log.Info("Import completed", fields...)
+	}
+
+	return code
+}
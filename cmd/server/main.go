@@ -2,38 +2,65 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
-	migrate_postgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	migrate_pgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.uber.org/zap"
 
+	"github.com/alik/TestForWork/internal/admin"
+	"github.com/alik/TestForWork/internal/alerting"
 	"github.com/alik/TestForWork/internal/api/grpc"
 	"github.com/alik/TestForWork/internal/client"
 	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/export"
+	"github.com/alik/TestForWork/internal/outbox"
+	"github.com/alik/TestForWork/internal/pricing"
 	"github.com/alik/TestForWork/internal/service"
 	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/alik/TestForWork/internal/tracing"
 	"github.com/alik/TestForWork/pkg/logger"
 )
 
 const version = "1.0.0"
 
 func main() {
+	// The first argument selects a subcommand, the server runs by default
+	command := "serve"
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	switch command {
+	case "serve":
+		serve()
+	case "import":
+		os.Exit(runImport())
+	case "export":
+		os.Exit(runExport())
+	case "config":
+		os.Exit(runConfig())
+	default:
+		fmt.Printf("Unknown command %q, expected serve, import, export or config\n", command)
+		os.Exit(2)
+	}
+}
+
+// serve runs the rates service until it receives a shutdown signal
+func serve() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -52,65 +79,217 @@ func main() {
 	log.Info("Starting USDT Rates Service", zap.String("version", version))
 
 	// Initialize tracing
-	var shutdown func()
 	if cfg.Tracing.Enabled {
-		var err error
-		shutdown, err = initTracing(cfg.Tracing.JaegerURL, cfg.Tracing.ServiceName)
+		shutdown, err := tracing.Setup(context.Background(), cfg.Tracing, version)
 		if err != nil {
 			log.Error("Failed to initialize tracing", zap.Error(err))
 		} else {
-			defer shutdown()
-			log.Info("Tracing initialized", zap.String("jaeger_url", cfg.Tracing.JaegerURL))
+			defer shutdownTracing(shutdown)
+			log.Info("Tracing initialized",
+				zap.String("exporter", cfg.Tracing.Exporter),
+				zap.String("endpoint", cfg.Tracing.Endpoint),
+				zap.Float64("sample_ratio", cfg.Tracing.SampleRatio))
 		}
 	}
 
+	// Background jobs run until the server shuts down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Initialize services
-	_, grpcServer, metricsServer, err := initializeServices(cfg, log)
+	reload := newReloader(cfg, log)
+	stop, grpcServer, metricsServer, err := initializeServices(ctx, cfg, log, reload)
 	if err != nil {
 		log.Error("Failed to initialize services", zap.Error(err))
 		os.Exit(1)
 	}
 
+	// Apply config file changes and SIGHUP reloads to the running services
+	reload.watch(ctx)
+
 	// Run the server
-	runServer(grpcServer, metricsServer, log)
+	runServer(grpcServer, metricsServer, cfg.Server.GracefulTimeout, log)
+
+	// Requests have finished; stop the background jobs before closing
+	// what they write to
+	cancel()
+	stop()
 }
 
-// initializeServices initializes all application services
-func initializeServices(cfg *config.Config, log *logger.Logger) (*service.RatesService, *grpc.Server, *http.Server, error) {
-	// Initialize database
-	db, err := postgres.NewDB(
-		cfg.Database.DatabaseDSN(),
-		cfg.Database.MaxOpenConns,
-		cfg.Database.MaxIdleConns,
-		cfg.Database.ConnMaxLifetime,
-		log.Logger,
-	)
+// initializeServices initializes all application services and registers
+// the reloadable ones with the reloader. The returned stop function closes
+// what must outlive the servers and background jobs, such as the recording.
+func initializeServices(ctx context.Context, cfg *config.Config, log *logger.Logger, reload *reloader) (func(), *grpc.Server, *http.Server, error) {
+	stop := func() {}
+
+	// Initialize database; rotated credentials are applied to the pool
+	db, err := postgres.OpenPool(cfg.Database.DatabaseDSN(), poolConfig(cfg.Database, "primary"), log.Logger)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	reload.db = db
 
 	// Run migrations
-	if err := runMigrations(db, cfg.Database.DatabaseDSN(), log.Logger); err != nil {
+	if err := runMigrations(db, log.Logger); err != nil {
 		db.Close()
 		return nil, nil, nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	// Initialize repository
-	repo := postgres.NewRepository(db, log.Logger)
+	repoOpts := []postgres.Option{postgres.WithStatementTimeouts(statementTimeouts(cfg.Database.StatementTimeouts))}
+	if cfg.Outbox.Enabled {
+		repoOpts = append(repoOpts, postgres.WithOutbox())
+	}
+	if cfg.Database.ReadYourWrites {
+		repoOpts = append(repoOpts, postgres.WithReadYourWrites())
+	}
+	// Rates saved by this instance are tagged, so it skips its own notifications
+	origin := instanceID()
+	if cfg.Database.Notifications {
+		repoOpts = append(repoOpts, postgres.WithNotifications(origin))
+	}
+	if len(cfg.Database.Replicas) > 0 {
+		replicas, err := initReplicas(ctx, cfg.Database, log.Logger, reload)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("failed to initialize read replicas: %w", err)
+		}
+		repoOpts = append(repoOpts, postgres.WithReplicas(replicas))
+	}
+	repo := postgres.NewRepository(db, log.Logger, repoOpts...)
+	reload.repo = repo
+
+	// Start outbox relay
+	if cfg.Outbox.Enabled {
+		relay, err := initOutbox(ctx, cfg.Outbox, repo, log.Logger)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("failed to initialize outbox: %w", err)
+		}
+		reload.relay = relay
+	}
 
 	// Initialize Grinex client
+	var clientOpts []client.GrinexOption
+	if cfg.Grinex.RecordPath != "" {
+		// Every run records to its own file, so restarts keep earlier recordings
+		path := client.RecordingPath(cfg.Grinex.RecordPath, time.Now())
+		recorder, err := client.CreateRecording(path)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("failed to start recording: %w", err)
+		}
+		stop = func() {
+			if err := recorder.Close(); err != nil {
+				log.Error("Failed to close recording", zap.Error(err))
+			}
+		}
+		clientOpts = append(clientOpts, client.WithRecorder(recorder))
+		log.Info("Recording depth responses", zap.String("path", path))
+	}
 	grinexClient := client.NewGrinexClient(
 		cfg.Grinex.BaseURL,
 		cfg.Grinex.Market,
 		cfg.Grinex.Timeout,
 		log.Logger,
+		clientOpts...,
 	)
+	reload.grinex = grinexClient
+
+	// Initialize market catalog
+	catalog := initCatalog(ctx, cfg.Catalog, grinexClient, log.Logger)
+	reload.catalog = catalog
 
 	// Initialize service
-	ratesService := service.NewRatesService(grinexClient, repo, log.Logger)
+	serviceOpts := []service.Option{
+		service.WithBatchConcurrency(cfg.Grinex.BatchConcurrency),
+		service.WithMarketCatalog(catalog),
+	}
+	if cfg.CrossRates.Enabled {
+		serviceOpts = append(serviceOpts, service.WithCrossRates(service.NewCrossRateEngine(catalog, cfg.CrossRates.MaxHops)))
+	}
+	if cfg.Pricing.Enabled {
+		engine, err := initPricing(ctx, cfg.Pricing, repo, catalog, log.Logger)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("failed to initialize pricing: %w", err)
+		}
+		serviceOpts = append(serviceOpts, service.WithPricing(engine))
+		reload.pricing = engine
+	}
+
+	// Initialize alerting
+	var handlerOpts []grpc.HandlerOption
+	if cfg.Alerting.Enabled {
+		alertManager, evaluator, dispatcher := initAlerting(ctx, cfg.Alerting, repo, log.Logger)
+		serviceOpts = append(serviceOpts, service.WithRateObservers(evaluator))
+		handlerOpts = append(handlerOpts, grpc.WithAlertService(alertManager))
+		reload.alerts = alertManager
+		reload.dispatcher = dispatcher
+	}
+
+	// Select the market data transport
+	var upstream service.GrinexClient = grinexClient
+	switch cfg.Grinex.Transport {
+	case "websocket":
+		streamClient := client.NewStreamClient(cfg.Grinex.WSURL, cfg.Grinex.SnapshotTimeout, log.Logger)
+		streamClient.Subscribe(cfg.Grinex.Market)
+		streamClient.Start(ctx)
+		upstream = streamClient
+	case "replay":
+		replayOpts := []client.ReplayOption{client.WithReplaySpeed(cfg.Grinex.ReplaySpeed)}
+		if cfg.Grinex.ReplayLoop {
+			replayOpts = append(replayOpts, client.WithReplayLoop())
+		}
+		replayClient, err := client.OpenReplay(cfg.Grinex.ReplayPath, log.Logger, replayOpts...)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("failed to open replay: %w", err)
+		}
+		log.Info("Replaying depth responses",
+			zap.String("path", cfg.Grinex.ReplayPath),
+			zap.Strings("markets", replayClient.Markets()),
+			zap.Float64("speed", cfg.Grinex.ReplaySpeed))
+		upstream = replayClient
+	}
+
+	// Health checks probe the exchange directly; a replay has nothing to probe
+	if cfg.Grinex.Transport != "replay" {
+		serviceOpts = append(serviceOpts, service.WithUpstreamProbe(grinexClient))
+	}
+
+	// Validate upstream quotes before they are stored or served
+	if cfg.Guard.Enabled {
+		upstream = service.NewSanityGuard(upstream, repo, service.GuardConfig{
+			MaxStaleness:        cfg.Guard.MaxStaleness,
+			JumpSigma:           cfg.Guard.JumpSigma,
+			JumpWindow:          cfg.Guard.JumpWindow,
+			JumpMinSamples:      cfg.Guard.JumpMinSamples,
+			JumpMinPercent:      cfg.Guard.JumpMinPercent,
+			MaxConsecutiveJumps: cfg.Guard.MaxConsecutiveJumps,
+		}, log.Logger)
+	}
+
+	ratesService := service.NewRatesService(upstream, repo, log.Logger, serviceOpts...)
+	reload.rates = ratesService
+
+	// Apply the rates other instances save
+	if cfg.Database.Notifications {
+		listener := postgres.NewListener(db.ListenConn, origin, ratesService.ObserveRemoteRate, log.Logger,
+			postgres.WithResync(ratesService.SyncLatestRates))
+		go listener.Run(ctx)
+	}
 
 	// Initialize gRPC handler
-	ratesHandler := grpc.NewRatesHandler(ratesService, log.Logger, version)
+	if cfg.Quotes.Enabled {
+		signer := service.NewQuoteSigner([]byte(cfg.Quotes.SigningKey))
+		reload.signer = signer
+		quoteService := service.NewQuoteService(ratesService, repo, signer, cfg.Quotes.TTL, log.Logger)
+		quoteService.StartCleanup(ctx, cfg.Quotes.CleanupInterval, cfg.Quotes.Retention)
+		handlerOpts = append(handlerOpts, grpc.WithQuoteService(quoteService))
+	}
+	handlerOpts = append(handlerOpts, grpc.WithExporter(export.New(repo, log.Logger)))
+	ratesHandler := grpc.NewRatesHandler(ratesService, log.Logger, version, handlerOpts...)
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
@@ -120,19 +299,234 @@ func initializeServices(cfg *config.Config, log *logger.Logger) (*service.RatesS
 		cfg.Server.MaxConnectionIdle,
 		cfg.Metrics.Enabled,
 		cfg.Tracing.Enabled,
+		grpc.WithConnectionTimeout(cfg.Server.ReadTimeout),
+		grpc.WithRequestTimeout(cfg.Server.WriteTimeout),
 	)
 
 	// Start metrics server if enabled
 	var metricsServer *http.Server
 	if cfg.Metrics.Enabled {
-		metricsServer = startMetricsServer(cfg.Metrics.Port, cfg.Metrics.Path, log.Logger)
+		var adminHandler http.Handler
+		if h := initAdmin(cfg, log, reload.Config); h != nil {
+			reload.admin = h
+			adminHandler = h
+		}
+		metricsServer = startMetricsServer(cfg.Metrics.Port, cfg.Metrics.Path, cfg.Server.ReadTimeout, adminHandler, log.Logger)
+	}
+
+	return stop, grpcServer, metricsServer, nil
+}
+
+// initCatalog creates the market catalog and starts its periodic refresh
+func initCatalog(ctx context.Context, cfg config.CatalogConfig, source service.MarketSource, logger *zap.Logger) *service.MarketCatalog {
+	if cfg.Source == "config" {
+		source = nil
 	}
 
-	return ratesService, grpcServer, metricsServer, nil
+	catalog := service.NewMarketCatalog(source, staticMarkets(cfg.Markets), logger)
+
+	refreshCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := catalog.Refresh(refreshCtx); err != nil {
+		logger.Warn("Using configured markets until the exchange catalog is available", zap.Error(err))
+	}
+
+	catalog.Start(ctx, cfg.RefreshInterval)
+
+	return catalog
+}
+
+// staticMarkets converts the configured markets
+func staticMarkets(markets []config.MarketConfig) []service.Market {
+	static := make([]service.Market, 0, len(markets))
+	for _, m := range markets {
+		static = append(static, service.Market{
+			ID:              m.ID,
+			Base:            m.Base,
+			Quote:           m.Quote,
+			PricePrecision:  m.PricePrecision,
+			AmountPrecision: m.AmountPrecision,
+			Status:          m.Status,
+		})
+	}
+	return static
+}
+
+// instanceID returns a random identifier of this process
+func instanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// initReplicas opens the read replicas and starts their health checks
+func initReplicas(ctx context.Context, cfg config.DatabaseConfig, logger *zap.Logger, reload *reloader) (*postgres.ReplicaSet, error) {
+	var replicas []postgres.Replica
+	for _, addr := range cfg.Replicas {
+		pool, err := postgres.NewPool(cfg.ReplicaDSN(addr), poolConfig(cfg, addr))
+		if err != nil {
+			for _, r := range replicas {
+				r.DB.Close()
+			}
+			return nil, fmt.Errorf("replica %s: %w", addr, err)
+		}
+		replicas = append(replicas, postgres.Replica{Name: addr, DB: pool})
+		reload.replicaPools = append(reload.replicaPools, replicaPool{addr: addr, pool: pool})
+	}
+
+	set := postgres.NewReplicaSet(cfg.ReplicaMaxLag, cfg.ReplicaCheckInterval, logger, replicas...)
+	reload.replicas = set
+
+	// Check once before serving, so reads use the replicas from the start
+	set.Check(ctx)
+	logger.Info("Read replicas configured",
+		zap.Strings("replicas", cfg.Replicas),
+		zap.Strings("healthy", set.Healthy()))
+	go set.Run(ctx)
+
+	return set, nil
+}
+
+// poolConfig sizes the connection pool of the primary or of a replica
+func poolConfig(cfg config.DatabaseConfig, name string) postgres.PoolConfig {
+	return postgres.PoolConfig{
+		Name:            name,
+		MaxConns:        cfg.MaxOpenConns,
+		MinConns:        cfg.MinConns,
+		MaxConnLifetime: cfg.ConnMaxLifetime,
+		MaxConnIdleTime: cfg.ConnMaxIdleTime,
+	}
+}
+
+func statementTimeouts(cfg config.StatementTimeoutsConfig) postgres.StatementTimeouts {
+	return postgres.StatementTimeouts{
+		Read:        cfg.Read,
+		Write:       cfg.Write,
+		Bulk:        cfg.Bulk,
+		Maintenance: cfg.Maintenance,
+	}
+}
+
+// initOutbox creates the configured publisher and starts the outbox relay
+func initOutbox(ctx context.Context, cfg config.OutboxConfig, repo *postgres.Repository, logger *zap.Logger) (*outbox.Relay, error) {
+	var publisher outbox.Publisher
+	switch cfg.Publisher {
+	case "stdout":
+		publisher = outbox.NewWriterPublisher(os.Stdout)
+	case "file":
+		p, err := outbox.NewFilePublisher(cfg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		publisher = p
+	case "nats":
+		p, err := outbox.NewNATSPublisher(cfg.NATS.URL, cfg.NATS.SubjectPrefix, logger)
+		if err != nil {
+			return nil, err
+		}
+		publisher = p
+	case "kafka":
+		publisher = outbox.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.Topic)
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
+	}
+
+	relay := outbox.NewRelay(repo, publisher, cfg.BatchSize, logger)
+	relay.Start(ctx, cfg.PollInterval)
+	relay.StartCleanup(ctx, cfg.CleanupInterval, cfg.Retention)
+
+	go func() {
+		<-ctx.Done()
+		if err := publisher.Close(); err != nil {
+			logger.Error("Failed to close outbox publisher", zap.Error(err))
+		}
+	}()
+
+	logger.Info("Outbox relay started", zap.String("publisher", cfg.Publisher))
+
+	return relay, nil
+}
+
+// initAlerting starts webhook delivery and loads the alert rules
+func initAlerting(
+	ctx context.Context,
+	cfg config.AlertingConfig,
+	repo *postgres.Repository,
+	logger *zap.Logger,
+) (*alerting.Manager, *alerting.Evaluator, *alerting.Dispatcher) {
+	dispatcher := alerting.NewDispatcher(alerting.DispatcherConfig{
+		Secret:       cfg.WebhookSecret,
+		Timeout:      cfg.WebhookTimeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		RetryBackoff: cfg.RetryBackoff,
+		Workers:      cfg.Workers,
+	}, repo, logger)
+	dispatcher.Start(ctx)
+
+	evaluator := alerting.NewEvaluator(dispatcher, logger)
+	manager := alerting.NewManager(repo, evaluator, logger)
+
+	// A failed initial load is retried by the periodic reload
+	_ = manager.Reload(ctx)
+	manager.Start(ctx, cfg.RefreshInterval)
+
+	return manager, evaluator, dispatcher
+}
+
+// initPricing creates the pricing engine and loads the initial rules
+func initPricing(
+	ctx context.Context,
+	cfg config.PricingConfig,
+	repo *postgres.Repository,
+	catalog *service.MarketCatalog,
+	logger *zap.Logger,
+) (*pricing.Engine, error) {
+	var source pricing.RuleSource
+	if cfg.Source == "database" {
+		source = pricing.NewRepositorySource(repo)
+	} else {
+		source = pricing.StaticSource(pricingRules(cfg.Rules))
+	}
+
+	precision := func(market string) (int, bool) {
+		m, ok := catalog.Lookup(market)
+		return m.PricePrecision, ok
+	}
+
+	engine := pricing.NewEngine(source, precision, logger)
+	if err := engine.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	if cfg.Source == "database" {
+		engine.Start(ctx, cfg.RefreshInterval)
+	}
+
+	return engine, nil
+}
+
+// pricingRules converts the configured pricing rules
+func pricingRules(rules []config.PricingRuleConfig) []pricing.Rule {
+	out := make([]pricing.Rule, 0, len(rules))
+	for _, r := range rules {
+		out = append(out, pricing.Rule{
+			ID:            r.ID,
+			Version:       r.Version,
+			Market:        r.Market,
+			ClientTier:    r.ClientTier,
+			SpreadPercent: r.SpreadPercent,
+			FixedFee:      r.FixedFee,
+			MinPrice:      r.MinPrice,
+			MaxPrice:      r.MaxPrice,
+		})
+	}
+	return out
 }
 
 // runServer runs the gRPC server and handles graceful shutdown
-func runServer(grpcServer *grpc.Server, metricsServer *http.Server, log *logger.Logger) {
+func runServer(grpcServer *grpc.Server, metricsServer *http.Server, gracefulTimeout time.Duration, log *logger.Logger) {
 	// Start gRPC server in a goroutine
 	serverErr := make(chan error, 1)
 	go func() {
@@ -151,7 +545,7 @@ func runServer(grpcServer *grpc.Server, metricsServer *http.Server, log *logger.
 	}
 
 	// Graceful shutdown
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracefulTimeout)
 	defer cancel()
 
 	// Stop gRPC server
@@ -169,45 +563,33 @@ func runServer(grpcServer *grpc.Server, metricsServer *http.Server, log *logger.
 	log.Info("Service stopped")
 }
 
-// initTracing initializes OpenTelemetry tracing
-func initTracing(jaegerURL, serviceName string) (func(), error) {
-	// Convert Jaeger URL to OTLP endpoint
-	otlpEndpoint := jaegerURL
-	if jaegerURL == "http://jaeger:14268/api/traces" {
-		otlpEndpoint = "http://jaeger:4318"
+// shutdownTracing flushes the remaining spans
+func shutdownTracing(shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		// Logger might be closed at this point, use fmt
+		fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
 	}
+}
 
-	exp, err := otlptracehttp.New(context.Background(),
-		otlptracehttp.WithEndpoint(otlpEndpoint),
-		otlptracehttp.WithInsecure(),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+// initAdmin creates the admin handler, nil when the admin endpoints are
+// disabled; current returns the configuration in effect
+func initAdmin(cfg *config.Config, log *logger.Logger, current func() *config.Config) *admin.Handler {
+	if !cfg.Admin.Enabled {
+		return nil
 	}
 
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exp),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(version),
-		)),
+	return admin.New(cfg.Admin.Token, log.Logger,
+		admin.WithLogLevel(log.Level),
+		admin.WithConfig(current),
+		admin.WithVersion(version),
 	)
-
-	otel.SetTracerProvider(tp)
-
-	return func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := tp.Shutdown(ctx); err != nil {
-			// Logger might be closed at this point, use fmt
-			fmt.Printf("Failed to shutdown tracer provider: %v\n", err)
-		}
-	}, nil
 }
 
-// startMetricsServer starts the Prometheus metrics server
-func startMetricsServer(port int, path string, logger *zap.Logger) *http.Server {
+// startMetricsServer starts the Prometheus metrics server, with the admin
+// endpoints under /admin/ when adminHandler is set
+func startMetricsServer(port int, path string, readTimeout time.Duration, adminHandler http.Handler, logger *zap.Logger) *http.Server {
 	mux := http.NewServeMux()
 	mux.Handle(path, promhttp.HandlerFor(
 		prometheus.DefaultGatherer,
@@ -215,11 +597,15 @@ func startMetricsServer(port int, path string, logger *zap.Logger) *http.Server
 			EnableOpenMetrics: true,
 		},
 	))
+	if adminHandler != nil {
+		mux.Handle(admin.Prefix+"/", adminHandler)
+	}
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", port),
 		Handler:           mux,
-		ReadHeaderTimeout: 10 * time.Second,
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
 	}
 
 	go func() {
@@ -233,15 +619,18 @@ func startMetricsServer(port int, path string, logger *zap.Logger) *http.Server
 }
 
 // runMigrations runs database migrations
-func runMigrations(db *sql.DB, _ string, logger *zap.Logger) error {
-	driver, err := migrate_postgres.WithInstance(db, &migrate_postgres.Config{})
+func runMigrations(pool *postgres.Pool, logger *zap.Logger) error {
+	db := stdlib.OpenDBFromPool(pool.Pool)
+	defer db.Close()
+
+	driver, err := migrate_pgx.WithInstance(db, &migrate_pgx.Config{})
 	if err != nil {
 		return fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(
 		"file://internal/storage/migrations",
-		"postgres", driver)
+		"pgx5", driver)
 	if err != nil {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
 	}
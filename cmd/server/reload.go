@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/alik/TestForWork/internal/admin"
+	"github.com/alik/TestForWork/internal/alerting"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/outbox"
+	"github.com/alik/TestForWork/internal/pricing"
+	"github.com/alik/TestForWork/internal/schedule"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/alik/TestForWork/pkg/logger"
+)
+
+// reloader applies the reloadable part of a changed configuration to the
+// running components. Components are nil when disabled.
+type reloader struct {
+	mu      sync.Mutex
+	current atomic.Pointer[config.Config]
+	log     *logger.Logger
+	secrets *schedule.Interval
+
+	db           *postgres.Pool
+	repo         *postgres.Repository
+	replicas     *postgres.ReplicaSet
+	replicaPools []replicaPool
+	admin        *admin.Handler
+	grinex       *client.GrinexClient
+	rates        *service.RatesService
+	catalog      *service.MarketCatalog
+	pricing      *pricing.Engine
+	alerts       *alerting.Manager
+	dispatcher   *alerting.Dispatcher
+	signer       *service.QuoteSigner
+	relay        *outbox.Relay
+}
+
+// replicaPool is the connection pool of a read replica
+type replicaPool struct {
+	addr string
+	pool *postgres.Pool
+}
+
+func newReloader(cfg *config.Config, log *logger.Logger) *reloader {
+	r := &reloader{log: log, secrets: schedule.NewInterval(cfg.Secrets.RefreshInterval)}
+	r.current.Store(cfg)
+	return r
+}
+
+// Config returns the configuration currently in effect
+func (r *reloader) Config() *config.Config {
+	return r.current.Load()
+}
+
+// watch reloads the configuration on SIGHUP, when the config file changes
+// and when a secret is rotated
+func (r *reloader) watch(ctx context.Context) {
+	go r.secrets.Run(ctx, r.checkSecrets)
+
+	watching, err := config.Watch(ctx,
+		func() { r.reload("file") },
+		func(err error) { r.log.Warn("Config watcher error", zap.Error(err)) })
+	if err != nil {
+		r.log.Error("Failed to watch config file", zap.Error(err))
+	} else if watching {
+		r.log.Info("Watching config file for changes")
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				r.reload("SIGHUP")
+			}
+		}
+	}()
+}
+
+// checkSecrets reloads the configuration when a secret file or reference
+// resolves to a new value
+func (r *reloader) checkSecrets(ctx context.Context) {
+	rotated, err := r.Config().RotatedSecrets(ctx)
+	if err != nil {
+		r.log.Warn("Failed to check secrets for rotation", zap.Error(err))
+		return
+	}
+	if len(rotated) > 0 {
+		r.log.Info("Secrets rotated", zap.Strings("keys", rotated))
+		r.reload("secrets")
+	}
+}
+
+// reload reads and validates the configuration and applies the changes
+// that are reloadable. Changes that need a restart are logged and keep
+// their running values; when applying fails, nothing is changed.
+func (r *reloader) reload(trigger string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log := r.log.With(zap.String("trigger", trigger))
+
+	next, err := config.Reload()
+	if err != nil {
+		log.Error("Config reload rejected", zap.Error(err))
+		return
+	}
+
+	changes := config.Diff(r.Config(), next)
+	if len(changes) == 0 {
+		log.Info("Config reloaded without changes")
+		return
+	}
+
+	var reloadable, fixed []config.Change
+	for _, c := range changes {
+		if config.IsReloadable(c.Key) {
+			reloadable = append(reloadable, c)
+		} else {
+			fixed = append(fixed, c)
+		}
+	}
+	if len(fixed) > 0 {
+		log.Warn("Config changes require a restart", zap.Strings("changes", formatChanges(fixed)))
+		next = config.KeepFixed(r.Config(), next, fixed)
+	}
+	if len(reloadable) == 0 {
+		r.current.Store(next)
+		return
+	}
+
+	if err := r.apply(next, reloadable); err != nil {
+		log.Error("Config reload rejected", zap.Error(err))
+		return
+	}
+	r.current.Store(next)
+
+	log.Info("Config reloaded", zap.Strings("changes", formatChanges(reloadable)))
+}
+
+// apply updates the components affected by the changes. The new values
+// are validated first and the credential rotation is the last step that can
+// fail, so a failed reload leaves everything unchanged.
+func (r *reloader) apply(cfg *config.Config, changes []config.Change) error {
+	changed := func(key string) bool {
+		for _, c := range changes {
+			if c.Key == key || strings.HasPrefix(c.Key, key+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var rules []pricing.Rule
+	setRules := r.pricing != nil && changed("pricing.rules") && cfg.Pricing.Source != "database"
+	if setRules {
+		rules = pricingRules(cfg.Pricing.Rules)
+		if err := pricing.ValidateRules(rules); err != nil {
+			return fmt.Errorf("failed to apply pricing rules: %w", err)
+		}
+	}
+	level, err := zapcore.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		return fmt.Errorf("failed to apply log level: %w", err)
+	}
+
+	if r.db != nil && (changed("database.user") || changed("database.password")) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := r.db.RotateCredentials(ctx, cfg.Database.DatabaseDSN()); err != nil {
+			return fmt.Errorf("failed to rotate database credentials: %w", err)
+		}
+		// A replica that is down keeps its old credentials and is not used
+		// once they stop working, so it does not block the rotation
+		for _, rp := range r.replicaPools {
+			if err := rp.pool.RotateCredentials(ctx, cfg.Database.ReplicaDSN(rp.addr)); err != nil {
+				r.log.Warn("Failed to rotate read replica credentials", zap.String("replica", rp.addr), zap.Error(err))
+			}
+		}
+	}
+
+	// The rules were validated above
+	if setRules {
+		if err := r.pricing.SetRules(rules); err != nil {
+			r.log.Error("Failed to apply pricing rules", zap.Error(err))
+		}
+	}
+	if changed("logging.level") {
+		r.log.Level.SetLevel(level)
+	}
+
+	if r.pricing != nil && changed("pricing.refresh_interval") && cfg.Pricing.Source == "database" {
+		r.pricing.SetRefreshInterval(cfg.Pricing.RefreshInterval)
+	}
+	if r.grinex != nil && changed("grinex.timeout") {
+		r.grinex.SetTimeout(cfg.Grinex.Timeout)
+	}
+	if r.rates != nil && changed("grinex.batch_concurrency") {
+		r.rates.SetBatchConcurrency(cfg.Grinex.BatchConcurrency)
+	}
+	if r.catalog != nil && changed("catalog.refresh_interval") {
+		r.catalog.SetRefreshInterval(cfg.Catalog.RefreshInterval)
+	}
+	if r.catalog != nil && changed("catalog.markets") {
+		r.catalog.SetStaticMarkets(staticMarkets(cfg.Catalog.Markets))
+	}
+	if r.alerts != nil && changed("alerting.refresh_interval") {
+		r.alerts.SetRefreshInterval(cfg.Alerting.RefreshInterval)
+	}
+	if r.relay != nil && changed("outbox.poll_interval") {
+		r.relay.SetPollInterval(cfg.Outbox.PollInterval)
+	}
+	if r.repo != nil && changed("database.statement_timeouts") {
+		r.repo.SetStatementTimeouts(statementTimeouts(cfg.Database.StatementTimeouts))
+	}
+	if r.replicas != nil && changed("database.replica_max_lag") {
+		r.replicas.SetMaxLag(cfg.Database.ReplicaMaxLag)
+	}
+	if r.replicas != nil && changed("database.replica_check_interval") {
+		r.replicas.SetCheckInterval(cfg.Database.ReplicaCheckInterval)
+	}
+	if r.admin != nil && changed("admin.token") {
+		r.admin.SetToken(cfg.Admin.Token)
+	}
+	if r.signer != nil && changed("quotes.signing_key") {
+		r.signer.SetKey([]byte(cfg.Quotes.SigningKey))
+	}
+	if r.dispatcher != nil && changed("alerting.webhook_secret") {
+		r.dispatcher.SetSecret(cfg.Alerting.WebhookSecret)
+	}
+	if changed("secrets.refresh_interval") {
+		r.secrets.Set(cfg.Secrets.RefreshInterval)
+	}
+
+	return nil
+}
+
+func formatChanges(changes []config.Change) []string {
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		out[i] = c.String()
+	}
+	return out
+}
@@ -0,0 +1,176 @@
+// Package admin serves runtime administration endpoints: the log level,
+// pprof profiles, build info and the effective configuration.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alik/TestForWork/internal/config"
+	"go.uber.org/zap"
+)
+
+// Prefix is the path the admin handler is mounted at
+const Prefix = "/admin"
+
+// Handler serves the admin endpoints behind a bearer token
+type Handler struct {
+	token   atomic.Pointer[string]
+	logger  *zap.Logger
+	level   *zap.AtomicLevel
+	config  func() *config.Config
+	version string
+	mux     *http.ServeMux
+}
+
+// Option configures the admin handler
+type Option func(*Handler)
+
+// WithLogLevel serves the level at /admin/log/level
+func WithLogLevel(level zap.AtomicLevel) Option {
+	return func(h *Handler) {
+		h.level = &level
+	}
+}
+
+// WithConfig serves the config returned by fn, with secrets redacted, at /admin/config
+func WithConfig(fn func() *config.Config) Option {
+	return func(h *Handler) {
+		h.config = fn
+	}
+}
+
+// WithVersion sets the service version reported at /admin/buildinfo
+func WithVersion(version string) Option {
+	return func(h *Handler) {
+		h.version = version
+	}
+}
+
+// New creates the admin handler; every request must carry the token as
+// "Authorization: Bearer <token>"
+func New(token string, logger *zap.Logger, opts ...Option) *Handler {
+	h := &Handler{
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+	h.SetToken(token)
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	// pprof.Index serves named profiles only under /debug/pprof/
+	h.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	h.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	h.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	h.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	h.mux.HandleFunc("/buildinfo", h.buildInfo)
+	if h.level != nil {
+		h.mux.HandleFunc("/log/level", h.logLevel)
+	}
+	if h.config != nil {
+		h.mux.HandleFunc("/config", h.dumpConfig)
+	}
+
+	return h
+}
+
+// SetToken replaces the token, e.g. when it is rotated
+func (h *Handler) SetToken(token string) {
+	h.token.Store(&token)
+}
+
+// ServeHTTP authenticates the request and serves it with the prefix stripped
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		h.logger.Warn("Unauthorized admin request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr))
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	h.logger.Info("Admin request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	http.StripPrefix(Prefix, h.mux).ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	expected := *h.token.Load()
+	return ok && expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// logLevel reports the level on GET and changes it on PUT with a body such as {"level":"debug"}
+func (h *Handler) logLevel(w http.ResponseWriter, r *http.Request) {
+	before := h.level.Level()
+	h.level.ServeHTTP(w, r)
+
+	if after := h.level.Level(); after != before {
+		h.logger.Warn("Log level changed",
+			zap.Stringer("from", before),
+			zap.Stringer("to", after),
+			zap.String("remote_addr", r.RemoteAddr))
+	}
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version    string `json:"version"`
+	GoVersion  string `json:"go_version"`
+	Path       string `json:"path,omitempty"`
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	Modified   bool   `json:"modified"`
+}
+
+func (h *Handler) buildInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := BuildInfo{Version: h.version}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+		info.Path = bi.Path
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.time":
+				info.CommitTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	writeJSON(w, info)
+}
+
+func (h *Handler) dumpConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, config.Dump(h.config()))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,290 @@
+package alerting
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSamplesPerMarket bounds the rolling window memory per market
+	maxSamplesPerMarket = 10000
+
+	// defaultDedupWindow groups firings of a rule without cooldown for de-duplication
+	defaultDedupWindow = time.Minute
+)
+
+// Sample is a single price observation
+type Sample struct {
+	Market    string
+	Ask       float64
+	Bid       float64
+	Timestamp time.Time
+}
+
+// Mid returns the mid price of the sample
+func (s Sample) Mid() float64 {
+	return (s.Ask + s.Bid) / 2
+}
+
+// Alert is a fired alert ready for delivery
+type Alert struct {
+	RuleID    int64     `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Type      string    `json:"type"`
+	Market    string    `json:"market"`
+	Field     string    `json:"field"`
+	Threshold float64   `json:"threshold"`
+	Observed  float64   `json:"observed"`
+	Ask       float64   `json:"ask"`
+	Bid       float64   `json:"bid"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   string    `json:"message"`
+
+	// DedupKey identifies the alert episode across replicas
+	DedupKey string `json:"dedup_key"`
+
+	// WebhookURL and WebhookSecret are taken from the rule at firing time
+	WebhookURL    string `json:"-"`
+	WebhookSecret string `json:"-"`
+}
+
+// Sink receives fired alerts
+type Sink interface {
+	Enqueue(alert Alert)
+}
+
+// ruleState tracks whether a rule is currently firing
+type ruleState struct {
+	firing    bool
+	lastFired time.Time
+}
+
+// compiledRule is a rule with its value parsed
+type compiledRule struct {
+	postgres.AlertRule
+	value float64
+}
+
+// Evaluator checks incoming samples against alert rules.
+// Rules fire when their condition becomes true and stay silent until it
+// clears, so a sustained condition produces a single alert.
+type Evaluator struct {
+	mu        sync.Mutex
+	rules     map[string][]compiledRule
+	samples   map[string][]Sample
+	states    map[int64]*ruleState
+	maxWindow time.Duration
+	sink      Sink
+	logger    *zap.Logger
+}
+
+// NewEvaluator creates a new alert evaluator
+func NewEvaluator(sink Sink, logger *zap.Logger) *Evaluator {
+	return &Evaluator{
+		rules:   make(map[string][]compiledRule),
+		samples: make(map[string][]Sample),
+		states:  make(map[int64]*ruleState),
+		sink:    sink,
+		logger:  logger,
+	}
+}
+
+// SetRules replaces the rules being evaluated; disabled rules are ignored
+func (e *Evaluator) SetRules(rules []postgres.AlertRule) {
+	byMarket := make(map[string][]compiledRule)
+	var maxWindow time.Duration
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		value, err := strconv.ParseFloat(rule.Value, 64)
+		if err != nil {
+			e.logger.Warn("Skipping alert rule with invalid value", zap.Int64("rule_id", rule.ID))
+			continue
+		}
+		byMarket[rule.Market] = append(byMarket[rule.Market], compiledRule{AlertRule: rule, value: value})
+		if rule.Window > maxWindow {
+			maxWindow = rule.Window
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = byMarket
+	e.maxWindow = maxWindow
+
+	// Drop state of rules that no longer exist
+	active := make(map[int64]*ruleState)
+	for _, rules := range byMarket {
+		for _, rule := range rules {
+			if state, ok := e.states[rule.ID]; ok {
+				active[rule.ID] = state
+			}
+		}
+	}
+	e.states = active
+}
+
+// ObserveRate converts exchange rates into a sample and evaluates it.
+// Rates without both prices are ignored.
+func (e *Evaluator) ObserveRate(rate *client.RateData) {
+	ask, errAsk := strconv.ParseFloat(rate.Ask, 64)
+	bid, errBid := strconv.ParseFloat(rate.Bid, 64)
+	if errAsk != nil || errBid != nil {
+		return
+	}
+
+	timestamp := rate.Time()
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	e.Observe(Sample{Market: rate.Market, Ask: ask, Bid: bid, Timestamp: timestamp})
+}
+
+// Observe evaluates a sample against the rules of its market
+func (e *Evaluator) Observe(sample Sample) {
+	e.mu.Lock()
+	window := e.appendSample(sample)
+	rules := e.rules[sample.Market]
+
+	var fired []Alert
+	for _, rule := range rules {
+		observed, active := evaluate(rule, sample, window)
+
+		state, ok := e.states[rule.ID]
+		if !ok {
+			state = &ruleState{}
+			e.states[rule.ID] = state
+		}
+
+		if !active {
+			state.firing = false
+			continue
+		}
+		if state.firing {
+			continue
+		}
+		// A condition that holds through the cooldown fires once it ends
+		if rule.Cooldown > 0 && sample.Timestamp.Sub(state.lastFired) < rule.Cooldown {
+			continue
+		}
+		state.firing = true
+		state.lastFired = sample.Timestamp
+
+		fired = append(fired, newAlert(rule, sample, observed))
+	}
+	e.mu.Unlock()
+
+	for _, alert := range fired {
+		e.logger.Info("Alert fired",
+			zap.Int64("rule_id", alert.RuleID),
+			zap.String("market", alert.Market),
+			zap.String("message", alert.Message))
+		e.sink.Enqueue(alert)
+	}
+}
+
+// appendSample stores the sample and returns the market's samples within the longest window
+func (e *Evaluator) appendSample(sample Sample) []Sample {
+	samples := append(e.samples[sample.Market], sample)
+
+	cutoff := sample.Timestamp.Add(-e.maxWindow)
+	start := 0
+	for start < len(samples)-1 && samples[start].Timestamp.Before(cutoff) {
+		start++
+	}
+	if len(samples)-start > maxSamplesPerMarket {
+		start = len(samples) - maxSamplesPerMarket
+	}
+
+	samples = samples[start:]
+	e.samples[sample.Market] = samples
+	return samples
+}
+
+// evaluate returns the observed value and whether the rule condition holds
+func evaluate(rule compiledRule, sample Sample, window []Sample) (float64, bool) {
+	switch rule.Type {
+	case RuleThresholdAbove:
+		price := field(sample, rule.Field)
+		return price, price > rule.value
+	case RuleThresholdBelow:
+		price := field(sample, rule.Field)
+		return price, price < rule.value
+	case RuleSpreadAbove:
+		spread := sample.Ask - sample.Bid
+		return spread, spread > rule.value
+	case RulePercentChange:
+		cutoff := sample.Timestamp.Add(-rule.Window)
+		for _, ref := range window {
+			if ref.Timestamp.Before(cutoff) {
+				continue
+			}
+			base := field(ref, rule.Field)
+			if base == 0 {
+				return 0, false
+			}
+			change := (field(sample, rule.Field) - base) / base * 100
+			return change, math.Abs(change) >= rule.value
+		}
+	}
+	return 0, false
+}
+
+// field returns the watched price of a sample
+func field(sample Sample, name string) float64 {
+	switch name {
+	case FieldAsk:
+		return sample.Ask
+	case FieldBid:
+		return sample.Bid
+	default:
+		return sample.Mid()
+	}
+}
+
+// newAlert builds the alert for a fired rule
+func newAlert(rule compiledRule, sample Sample, observed float64) Alert {
+	dedupWindow := rule.Cooldown
+	if dedupWindow <= 0 {
+		dedupWindow = defaultDedupWindow
+	}
+
+	var message string
+	switch rule.Type {
+	case RuleThresholdAbove:
+		message = fmt.Sprintf("%s %s %.8g is above %.8g", sample.Market, rule.Field, observed, rule.value)
+	case RuleThresholdBelow:
+		message = fmt.Sprintf("%s %s %.8g is below %.8g", sample.Market, rule.Field, observed, rule.value)
+	case RuleSpreadAbove:
+		message = fmt.Sprintf("%s spread %.8g is wider than %.8g", sample.Market, observed, rule.value)
+	case RulePercentChange:
+		message = fmt.Sprintf("%s %s moved %.4g%% within %s", sample.Market, rule.Field, observed, rule.Window)
+	}
+
+	return Alert{
+		RuleID:        rule.ID,
+		RuleName:      rule.Name,
+		Type:          rule.Type,
+		Market:        sample.Market,
+		Field:         rule.Field,
+		Threshold:     rule.value,
+		Observed:      observed,
+		Ask:           sample.Ask,
+		Bid:           sample.Bid,
+		Timestamp:     sample.Timestamp,
+		Message:       message,
+		DedupKey:      fmt.Sprintf("%d:%d", rule.ID, sample.Timestamp.Truncate(dedupWindow).Unix()),
+		WebhookURL:    rule.WebhookURL,
+		WebhookSecret: rule.WebhookSecret,
+	}
+}
@@ -0,0 +1,142 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alik/TestForWork/internal/schedule"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+// RuleStore stores alert rules
+type RuleStore interface {
+	CreateAlertRule(ctx context.Context, rule *postgres.AlertRule) error
+	UpdateAlertRule(ctx context.Context, rule *postgres.AlertRule) (bool, error)
+	DeleteAlertRule(ctx context.Context, id int64) (bool, error)
+	GetAlertRule(ctx context.Context, id int64) (*postgres.AlertRule, error)
+	ListAlertRules(ctx context.Context) ([]postgres.AlertRule, error)
+}
+
+// Manager manages alert rules and keeps the evaluator in sync with the store
+type Manager struct {
+	store     RuleStore
+	evaluator *Evaluator
+	interval  *schedule.Interval
+	logger    *zap.Logger
+}
+
+// NewManager creates a new alert rule manager
+func NewManager(store RuleStore, evaluator *Evaluator, logger *zap.Logger) *Manager {
+	return &Manager{
+		store:     store,
+		evaluator: evaluator,
+		interval:  schedule.NewInterval(0),
+		logger:    logger,
+	}
+}
+
+// CreateRule validates and stores a new rule
+func (m *Manager) CreateRule(ctx context.Context, rule *postgres.AlertRule) error {
+	if err := Validate(rule); err != nil {
+		return err
+	}
+
+	if err := m.store.CreateAlertRule(ctx, rule); err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+
+	m.logger.Info("Alert rule created", zap.Int64("id", rule.ID), zap.String("name", rule.Name))
+
+	// A failed reload is logged and retried by the periodic refresh
+	_ = m.Reload(ctx)
+
+	return nil
+}
+
+// UpdateRule validates and replaces an existing rule
+func (m *Manager) UpdateRule(ctx context.Context, rule *postgres.AlertRule) error {
+	if err := Validate(rule); err != nil {
+		return err
+	}
+
+	found, err := m.store.UpdateAlertRule(ctx, rule)
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrRuleNotFound, rule.ID)
+	}
+
+	m.logger.Info("Alert rule updated", zap.Int64("id", rule.ID))
+
+	_ = m.Reload(ctx)
+
+	return nil
+}
+
+// DeleteRule deletes a rule
+func (m *Manager) DeleteRule(ctx context.Context, id int64) error {
+	found, err := m.store.DeleteAlertRule(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+
+	m.logger.Info("Alert rule deleted", zap.Int64("id", id))
+
+	_ = m.Reload(ctx)
+
+	return nil
+}
+
+// GetRule returns a rule by id
+func (m *Manager) GetRule(ctx context.Context, id int64) (*postgres.AlertRule, error) {
+	rule, err := m.store.GetAlertRule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
+	}
+	if rule == nil {
+		return nil, fmt.Errorf("%w: %d", ErrRuleNotFound, id)
+	}
+	return rule, nil
+}
+
+// ListRules returns all rules
+func (m *Manager) ListRules(ctx context.Context) ([]postgres.AlertRule, error) {
+	rules, err := m.store.ListAlertRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Reload loads all rules into the evaluator
+func (m *Manager) Reload(ctx context.Context) error {
+	rules, err := m.store.ListAlertRules(ctx)
+	if err != nil {
+		m.logger.Error("Failed to load alert rules", zap.Error(err))
+		return fmt.Errorf("failed to load alert rules: %w", err)
+	}
+
+	m.evaluator.SetRules(rules)
+
+	return nil
+}
+
+// Start reloads rules every interval so that changes made through other
+// replicas are picked up
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	m.interval.Set(interval)
+	go m.interval.Run(ctx, func(ctx context.Context) {
+		_ = m.Reload(ctx)
+	})
+}
+
+// SetRefreshInterval changes the reload interval of a started manager
+func (m *Manager) SetRefreshInterval(interval time.Duration) {
+	m.interval.Set(interval)
+}
@@ -0,0 +1,84 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+// Rule types
+const (
+	// RuleThresholdAbove fires when the price rises above the value
+	RuleThresholdAbove = "threshold_above"
+	// RuleThresholdBelow fires when the price falls below the value
+	RuleThresholdBelow = "threshold_below"
+	// RulePercentChange fires when the price moves by at least value percent within the window
+	RulePercentChange = "percent_change"
+	// RuleSpreadAbove fires when ask minus bid is wider than the value
+	RuleSpreadAbove = "spread_above"
+)
+
+// Price fields a rule can watch
+const (
+	FieldAsk = "ask"
+	FieldBid = "bid"
+	FieldMid = "mid"
+)
+
+var (
+	// ErrInvalidRule is returned when an alert rule fails validation
+	ErrInvalidRule = errors.New("invalid alert rule")
+
+	// ErrRuleNotFound is returned when an alert rule does not exist
+	ErrRuleNotFound = errors.New("alert rule not found")
+)
+
+// Validate checks an alert rule and fills in defaults
+func Validate(rule *postgres.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if rule.Market == "" {
+		return fmt.Errorf("%w: market is required", ErrInvalidRule)
+	}
+
+	switch rule.Type {
+	case RuleThresholdAbove, RuleThresholdBelow, RuleSpreadAbove:
+	case RulePercentChange:
+		if rule.Window <= 0 {
+			return fmt.Errorf("%w: window is required for %s rules", ErrInvalidRule, RulePercentChange)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, rule.Type)
+	}
+
+	if rule.Field == "" {
+		rule.Field = FieldMid
+	}
+	if rule.Field != FieldAsk && rule.Field != FieldBid && rule.Field != FieldMid {
+		return fmt.Errorf("%w: unknown field %q", ErrInvalidRule, rule.Field)
+	}
+
+	value, err := strconv.ParseFloat(rule.Value, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: value must be a number", ErrInvalidRule)
+	}
+	if value < 0 {
+		return fmt.Errorf("%w: value must not be negative", ErrInvalidRule)
+	}
+
+	if rule.Window < 0 || rule.Cooldown < 0 {
+		return fmt.Errorf("%w: window and cooldown must not be negative", ErrInvalidRule)
+	}
+
+	u, err := url.Parse(rule.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: webhook_url must be an http(s) URL", ErrInvalidRule)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+// Delivery statuses recorded in the delivery log
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook headers
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+	DeliveryHeader  = "X-Delivery-Id"
+)
+
+// DeliveryLog records webhook deliveries
+type DeliveryLog interface {
+	CreateAlertDelivery(ctx context.Context, delivery *postgres.AlertDelivery) (bool, error)
+	UpdateAlertDelivery(ctx context.Context, delivery *postgres.AlertDelivery) error
+}
+
+// DispatcherConfig holds webhook delivery settings
+type DispatcherConfig struct {
+	Secret       string
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	Workers      int
+	QueueSize    int
+}
+
+// Dispatcher delivers alerts as signed HTTP webhooks with retries
+type Dispatcher struct {
+	cfg        DispatcherConfig
+	secret     atomic.Pointer[string]
+	log        DeliveryLog
+	httpClient *http.Client
+	queue      chan Alert
+	logger     *zap.Logger
+	wg         sync.WaitGroup
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(cfg DispatcherConfig, log DeliveryLog, logger *zap.Logger) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+
+	d := &Dispatcher{
+		cfg:        cfg,
+		log:        log,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		queue:      make(chan Alert, cfg.QueueSize),
+		logger:     logger,
+	}
+	d.SetSecret(cfg.Secret)
+	return d
+}
+
+// SetSecret replaces the secret used for rules without their own, e.g.
+// when it is rotated. Deliveries already being retried keep the old secret.
+func (d *Dispatcher) SetSecret(secret string) {
+	d.secret.Store(&secret)
+}
+
+// Enqueue schedules an alert for delivery without blocking.
+// Alerts are dropped with an error log when the queue is full.
+func (d *Dispatcher) Enqueue(alert Alert) {
+	select {
+	case d.queue <- alert:
+	default:
+		d.logger.Error("Alert delivery queue is full, dropping alert",
+			zap.Int64("rule_id", alert.RuleID),
+			zap.String("market", alert.Market))
+	}
+}
+
+// Start runs the delivery workers until ctx is done
+func (d *Dispatcher) Start(ctx context.Context) {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case alert := <-d.queue:
+					d.deliver(ctx, alert)
+				}
+			}
+		}()
+	}
+}
+
+// Wait blocks until all workers have stopped
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// deliver records the alert in the delivery log and posts it to the webhook
+func (d *Dispatcher) deliver(ctx context.Context, alert Alert) {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		d.logger.Error("Failed to encode alert", zap.Error(err))
+		return
+	}
+
+	delivery := &postgres.AlertDelivery{
+		RuleID:   alert.RuleID,
+		DedupKey: alert.DedupKey,
+		Payload:  payload,
+		Status:   DeliveryPending,
+	}
+
+	// The unique dedup key prevents duplicate deliveries across replicas
+	created, err := d.log.CreateAlertDelivery(ctx, delivery)
+	if err != nil {
+		d.logger.Error("Failed to record alert delivery", zap.Error(err))
+		return
+	}
+	if !created {
+		return
+	}
+
+	secret := alert.WebhookSecret
+	if secret == "" {
+		secret = *d.secret.Load()
+	}
+
+	backoff := d.cfg.RetryBackoff
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		delivery.Attempts = attempt
+
+		code, err := d.post(ctx, alert.WebhookURL, secret, delivery.ID, payload)
+		if code != 0 {
+			delivery.ResponseCode = sql.NullInt32{Int32: int32(code), Valid: true}
+		}
+		if err == nil {
+			delivery.Status = DeliveryDelivered
+			delivery.LastError = ""
+			delivery.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+			break
+		}
+
+		delivery.Status = DeliveryFailed
+		delivery.LastError = err.Error()
+		d.logger.Warn("Alert webhook delivery failed",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+
+		if attempt == d.cfg.MaxAttempts || !sleepContext(ctx, backoff) {
+			break
+		}
+		backoff *= 2
+	}
+
+	if err := d.log.UpdateAlertDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		d.logger.Error("Failed to update alert delivery", zap.Error(err))
+	}
+
+	d.logger.Info("Alert delivery finished",
+		zap.Int64("delivery_id", delivery.ID),
+		zap.String("status", delivery.Status),
+		zap.Int("attempts", delivery.Attempts))
+}
+
+// post sends the payload and returns the response status code
+func (d *Dispatcher) post(ctx context.Context, url, secret string, deliveryID int64, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryID, 10))
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, payload))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// sleepContext waits for the duration and reports false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Sign computes the webhook signature over the timestamp and payload.
+// Receivers should recompute it and reject stale timestamps.
+func Sign(secret, timestamp string, payload []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(payload)
+	return "sha256=" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alik/TestForWork/internal/alerting"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateAlertRule handles the CreateAlertRule gRPC request
+func (h *RatesHandler) CreateAlertRule(ctx context.Context, req *pb.CreateAlertRuleRequest) (*pb.AlertRule, error) {
+	h.log(ctx).Info("CreateAlertRule request received")
+
+	if h.alertService == nil {
+		return nil, status.Error(codes.Unimplemented, "alerting is disabled")
+	}
+	if req.Rule == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	rule := fromAlertRule(req.Rule)
+	rule.ID = 0
+	if err := h.alertService.CreateRule(ctx, rule); err != nil {
+		h.log(ctx).Error("Failed to create alert rule", zap.Error(err))
+		return nil, alertError(err)
+	}
+
+	return toAlertRule(rule), nil
+}
+
+// GetAlertRule handles the GetAlertRule gRPC request
+func (h *RatesHandler) GetAlertRule(ctx context.Context, req *pb.GetAlertRuleRequest) (*pb.AlertRule, error) {
+	h.log(ctx).Debug("GetAlertRule request received", zap.Int64("id", req.Id))
+
+	if h.alertService == nil {
+		return nil, status.Error(codes.Unimplemented, "alerting is disabled")
+	}
+
+	rule, err := h.alertService.GetRule(ctx, req.Id)
+	if err != nil {
+		h.log(ctx).Error("Failed to get alert rule", zap.Error(err))
+		return nil, alertError(err)
+	}
+
+	return toAlertRule(rule), nil
+}
+
+// ListAlertRules handles the ListAlertRules gRPC request
+func (h *RatesHandler) ListAlertRules(ctx context.Context, _ *pb.ListAlertRulesRequest) (*pb.ListAlertRulesResponse, error) {
+	h.log(ctx).Debug("ListAlertRules request received")
+
+	if h.alertService == nil {
+		return nil, status.Error(codes.Unimplemented, "alerting is disabled")
+	}
+
+	rules, err := h.alertService.ListRules(ctx)
+	if err != nil {
+		h.log(ctx).Error("Failed to list alert rules", zap.Error(err))
+		return nil, alertError(err)
+	}
+
+	response := &pb.ListAlertRulesResponse{Rules: make([]*pb.AlertRule, 0, len(rules))}
+	for i := range rules {
+		response.Rules = append(response.Rules, toAlertRule(&rules[i]))
+	}
+
+	return response, nil
+}
+
+// UpdateAlertRule handles the UpdateAlertRule gRPC request
+func (h *RatesHandler) UpdateAlertRule(ctx context.Context, req *pb.UpdateAlertRuleRequest) (*pb.AlertRule, error) {
+	if h.alertService == nil {
+		return nil, status.Error(codes.Unimplemented, "alerting is disabled")
+	}
+	if req.Rule == nil || req.Rule.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "rule with id is required")
+	}
+
+	h.log(ctx).Info("UpdateAlertRule request received", zap.Int64("id", req.Rule.Id))
+
+	rule := fromAlertRule(req.Rule)
+	if err := h.alertService.UpdateRule(ctx, rule); err != nil {
+		h.log(ctx).Error("Failed to update alert rule", zap.Error(err))
+		return nil, alertError(err)
+	}
+
+	return toAlertRule(rule), nil
+}
+
+// DeleteAlertRule handles the DeleteAlertRule gRPC request
+func (h *RatesHandler) DeleteAlertRule(ctx context.Context, req *pb.DeleteAlertRuleRequest) (*emptypb.Empty, error) {
+	h.log(ctx).Info("DeleteAlertRule request received", zap.Int64("id", req.Id))
+
+	if h.alertService == nil {
+		return nil, status.Error(codes.Unimplemented, "alerting is disabled")
+	}
+
+	if err := h.alertService.DeleteRule(ctx, req.Id); err != nil {
+		h.log(ctx).Error("Failed to delete alert rule", zap.Error(err))
+		return nil, alertError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// fromAlertRule converts a protobuf alert rule
+func fromAlertRule(rule *pb.AlertRule) *postgres.AlertRule {
+	return &postgres.AlertRule{
+		ID:            rule.Id,
+		Name:          rule.Name,
+		Market:        rule.Market,
+		Type:          rule.Type,
+		Field:         rule.Field,
+		Value:         rule.Value,
+		Window:        rule.Window.AsDuration(),
+		Cooldown:      rule.Cooldown.AsDuration(),
+		WebhookURL:    rule.WebhookUrl,
+		WebhookSecret: rule.WebhookSecret,
+		Enabled:       rule.Enabled,
+	}
+}
+
+// toAlertRule converts an alert rule to protobuf, omitting the webhook secret
+func toAlertRule(rule *postgres.AlertRule) *pb.AlertRule {
+	return &pb.AlertRule{
+		Id:         rule.ID,
+		Name:       rule.Name,
+		Market:     rule.Market,
+		Type:       rule.Type,
+		Field:      rule.Field,
+		Value:      rule.Value,
+		Window:     durationpb.New(rule.Window),
+		Cooldown:   durationpb.New(rule.Cooldown),
+		WebhookUrl: rule.WebhookURL,
+		Enabled:    rule.Enabled,
+		CreatedAt:  timestamppb.New(rule.CreatedAt),
+		UpdatedAt:  timestamppb.New(rule.UpdatedAt),
+	}
+}
+
+// alertError maps alerting errors to gRPC status errors
+func alertError(err error) error {
+	switch {
+	case errors.Is(err, alerting.ErrInvalidRule):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, alerting.ErrRuleNotFound):
+		return status.Error(codes.NotFound, "alert rule not found")
+	default:
+		return status.Error(codes.Internal, "failed to process alert rule")
+	}
+}
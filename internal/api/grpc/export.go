@@ -0,0 +1,126 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alik/TestForWork/internal/export"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// exportChunkSize is the largest amount of file data sent in one message
+const exportChunkSize = 64 * 1024
+
+// ExportRates handles the ExportRates gRPC request
+func (h *RatesHandler) ExportRates(req *pb.ExportRatesRequest, stream pb.RatesService_ExportRatesServer) error {
+	log := h.log(stream.Context())
+	log.Info("ExportRates request received",
+		zap.String("market", req.Market),
+		zap.String("format", req.Format.String()))
+
+	if h.exporter == nil {
+		return status.Error(codes.Unimplemented, "export is disabled")
+	}
+
+	exportReq := export.Request{
+		Market: req.Market,
+		Format: exportFormat(req.Format),
+	}
+	if req.From != nil {
+		if err := req.From.CheckValid(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid from: %v", err)
+		}
+		exportReq.From = req.From.AsTime()
+	}
+	if req.To != nil {
+		if err := req.To.CheckValid(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid to: %v", err)
+		}
+		exportReq.To = req.To.AsTime()
+	}
+	if req.ResampleInterval != nil {
+		if err := req.ResampleInterval.CheckValid(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid resample_interval: %v", err)
+		}
+		exportReq.Resample = req.ResampleInterval.AsDuration()
+	}
+
+	w := &chunkWriter{stream: stream, buf: make([]byte, 0, exportChunkSize)}
+	rows, err := h.exporter.Export(stream.Context(), w, exportReq)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		log.Error("Failed to export rates", zap.Error(err), zap.String("market", req.Market))
+		return exportError(stream.Context(), err)
+	}
+
+	log.Info("ExportRates request completed",
+		zap.String("market", req.Market),
+		zap.Int64("rows", rows))
+
+	return nil
+}
+
+// exportFormat converts the protobuf format to an export format
+func exportFormat(format pb.ExportFormat) string {
+	switch format {
+	case pb.ExportFormat_EXPORT_FORMAT_JSONL:
+		return export.FormatJSONL
+	case pb.ExportFormat_EXPORT_FORMAT_PARQUET:
+		return export.FormatParquet
+	default:
+		return export.FormatCSV
+	}
+}
+
+// exportError maps export errors to gRPC status errors
+func exportError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, export.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	default:
+		return status.Error(codes.Internal, "failed to export rates")
+	}
+}
+
+// chunkWriter sends the written data as ExportRatesChunk messages of at most
+// exportChunkSize bytes
+type chunkWriter struct {
+	stream pb.RatesService_ExportRatesServer
+	buf    []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.Flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush sends the buffered data
+func (w *chunkWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.stream.Send(&pb.ExportRatesChunk{Data: w.buf}); err != nil {
+		return err
+	}
+	// The sent message may still reference the buffer
+	w.buf = make([]byte, 0, exportChunkSize)
+	return nil
+}
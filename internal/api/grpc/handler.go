@@ -2,58 +2,85 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/pkg/logger"
 	pb "github.com/alik/TestForWork/proto/rates"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// maxBatchSize limits the number of markets in a single BatchGetRates request
+const maxBatchSize = 100
+
 // RatesHandler implements the gRPC RatesService
 type RatesHandler struct {
 	pb.UnimplementedRatesServiceServer
 	ratesService RatesService
+	quoteService QuoteService
+	alertService AlertService
+	exporter     RateExporter
 	logger       *zap.Logger
 	version      string
+
+	// stopping is closed when the server stops, to end open subscriptions
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 // NewRatesHandler creates a new gRPC rates handler
-func NewRatesHandler(ratesService RatesService, logger *zap.Logger, version string) *RatesHandler {
-	return &RatesHandler{
+func NewRatesHandler(ratesService RatesService, logger *zap.Logger, version string, opts ...HandlerOption) *RatesHandler {
+	h := &RatesHandler{
 		ratesService: ratesService,
 		logger:       logger,
 		version:      version,
+		stopping:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	return h
+}
+
+// log returns the handler logger with the trace of the request
+func (h *RatesHandler) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, h.logger)
 }
 
 // GetRates handles the GetRates gRPC request
 func (h *RatesHandler) GetRates(ctx context.Context, req *pb.GetRatesRequest) (*pb.GetRatesResponse, error) {
-	h.logger.Info("GetRates request received", zap.String("market", req.Market))
+	h.log(ctx).Info("GetRates request received", zap.String("market", req.Market))
 
 	// Validate request
 	if req.Market == "" {
-		h.logger.Warn("Empty market in request")
+		h.log(ctx).Warn("Empty market in request")
 		return nil, status.Error(codes.InvalidArgument, "market is required")
 	}
+	maxAge, err := maxAgeOption(req.MaxAge)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get rates from service
-	rateData, err := h.ratesService.GetRates(ctx, req.Market)
+	rateData, err := h.ratesService.GetRates(ctx, req.Market, service.WithClientTier(req.ClientTier), maxAge)
 	if err != nil {
-		h.logger.Error("Failed to get rates", zap.Error(err))
-		return nil, status.Error(codes.Internal, "failed to get rates")
+		h.log(ctx).Error("Failed to get rates", zap.Error(err))
+		return nil, ratesError(err)
 	}
 
 	// Convert to protobuf response
-	response := &pb.GetRatesResponse{
-		Ask:       rateData.Ask,
-		Bid:       rateData.Bid,
-		Timestamp: timestamppb.New(rateData.Timestamp),
-		Market:    rateData.Market,
-	}
+	response := toRatesResponse(rateData)
 
-	h.logger.Info("GetRates request completed successfully",
+	h.log(ctx).Info("GetRates request completed successfully",
 		zap.String("market", req.Market),
 		zap.String("ask", rateData.Ask),
 		zap.String("bid", rateData.Bid))
@@ -61,15 +88,93 @@ func (h *RatesHandler) GetRates(ctx context.Context, req *pb.GetRatesRequest) (*
 	return response, nil
 }
 
+// BatchGetRates handles the BatchGetRates gRPC request
+func (h *RatesHandler) BatchGetRates(ctx context.Context, req *pb.BatchGetRatesRequest) (*pb.BatchGetRatesResponse, error) {
+	h.log(ctx).Info("BatchGetRates request received", zap.Strings("markets", req.Markets))
+
+	// Validate request
+	if len(req.Markets) == 0 {
+		h.log(ctx).Warn("Empty markets in batch request")
+		return nil, status.Error(codes.InvalidArgument, "markets are required")
+	}
+	if len(req.Markets) > maxBatchSize {
+		h.log(ctx).Warn("Too many markets in batch request", zap.Int("count", len(req.Markets)))
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d markets are allowed", maxBatchSize)
+	}
+	maxAge, err := maxAgeOption(req.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty markets are reported per market instead of failing the batch
+	results := make([]*pb.MarketRatesResult, len(req.Markets))
+	var markets []string
+	var positions []int
+	for i, market := range req.Markets {
+		if market == "" {
+			results[i] = marketErrorResult(market, codes.InvalidArgument, "market is required")
+			continue
+		}
+		markets = append(markets, market)
+		positions = append(positions, i)
+	}
+
+	if len(markets) > 0 {
+		for i, result := range h.ratesService.BatchGetRates(ctx, markets, service.WithClientTier(req.ClientTier), maxAge) {
+			if result.Err != nil {
+				h.log(ctx).Error("Failed to get rates in batch",
+					zap.String("market", result.Market),
+					zap.Error(result.Err))
+				st, _ := status.FromError(ratesError(result.Err))
+				results[positions[i]] = marketErrorResult(result.Market, st.Code(), st.Message())
+				continue
+			}
+			results[positions[i]] = &pb.MarketRatesResult{
+				Market: result.Market,
+				Result: &pb.MarketRatesResult_Rates{Rates: toRatesResponse(result.Rate)},
+			}
+		}
+	}
+
+	h.log(ctx).Info("BatchGetRates request completed", zap.Int("count", len(results)))
+
+	return &pb.BatchGetRatesResponse{Results: results}, nil
+}
+
+// ListMarkets handles the ListMarkets gRPC request
+func (h *RatesHandler) ListMarkets(ctx context.Context, _ *pb.ListMarketsRequest) (*pb.ListMarketsResponse, error) {
+	h.log(ctx).Debug("ListMarkets request received")
+
+	markets := h.ratesService.ListMarkets(ctx)
+
+	response := &pb.ListMarketsResponse{
+		Markets: make([]*pb.Market, 0, len(markets)),
+	}
+	for _, market := range markets {
+		response.Markets = append(response.Markets, &pb.Market{
+			Id:              market.ID,
+			Base:            market.Base,
+			Quote:           market.Quote,
+			PricePrecision:  int32(market.PricePrecision),
+			AmountPrecision: int32(market.AmountPrecision),
+			Status:          market.Status,
+		})
+	}
+
+	h.log(ctx).Debug("ListMarkets request completed", zap.Int("count", len(markets)))
+
+	return response, nil
+}
+
 // Healthcheck handles the Healthcheck gRPC request
 func (h *RatesHandler) Healthcheck(ctx context.Context, req *pb.HealthcheckRequest) (*pb.HealthcheckResponse, error) {
-	h.logger.Debug("Healthcheck request received")
+	h.log(ctx).Debug("Healthcheck request received")
 
 	// Perform health check
 	err := h.ratesService.HealthCheck(ctx)
 	serviceStatus := "healthy"
 	if err != nil {
-		h.logger.Warn("Health check failed", zap.Error(err))
+		h.log(ctx).Warn("Health check failed", zap.Error(err))
 		serviceStatus = "unhealthy"
 	}
 
@@ -79,7 +184,80 @@ func (h *RatesHandler) Healthcheck(ctx context.Context, req *pb.HealthcheckReque
 		Timestamp: timestamppb.New(time.Now()),
 	}
 
-	h.logger.Debug("Healthcheck request completed", zap.String("status", serviceStatus))
+	h.log(ctx).Debug("Healthcheck request completed", zap.String("status", serviceStatus))
 
 	return response, nil
 }
+
+// toRatesResponse converts rate data to the protobuf response
+func toRatesResponse(rateData *client.RateData) *pb.GetRatesResponse {
+	response := &pb.GetRatesResponse{
+		Ask:       rateData.Ask,
+		Bid:       rateData.Bid,
+		Timestamp: timestamppb.New(rateData.Time()),
+		Market:    rateData.Market,
+		Derived:   rateData.Derived,
+		Age:       durationpb.New(rateData.Age(time.Now())),
+
+		CustomerAsk:        rateData.CustomerAsk,
+		CustomerBid:        rateData.CustomerBid,
+		PricingRuleId:      rateData.PricingRuleID,
+		PricingRuleVersion: int32(rateData.PricingRuleVersion),
+	}
+
+	if !rateData.Timestamp.IsZero() {
+		response.ExchangeTimestamp = timestamppb.New(rateData.Timestamp)
+	}
+	if !rateData.ReceivedAt.IsZero() {
+		response.ReceivedAt = timestamppb.New(rateData.ReceivedAt)
+	}
+
+	for _, leg := range rateData.Path {
+		response.Path = append(response.Path, &pb.CrossRateLeg{
+			Market:   leg.Market,
+			From:     leg.From,
+			To:       leg.To,
+			Inverted: leg.Inverted,
+		})
+	}
+
+	return response
+}
+
+// maxAgeOption validates the requested max quote age
+func maxAgeOption(maxAge *durationpb.Duration) (service.RequestOption, error) {
+	if maxAge == nil {
+		return service.WithMaxAge(0), nil
+	}
+	if err := maxAge.CheckValid(); err != nil || maxAge.AsDuration() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max_age must be a non-negative duration")
+	}
+	return service.WithMaxAge(maxAge.AsDuration()), nil
+}
+
+// marketErrorResult builds a failed per-market batch result
+func marketErrorResult(market string, code codes.Code, message string) *pb.MarketRatesResult {
+	return &pb.MarketRatesResult{
+		Market: market,
+		Result: &pb.MarketRatesResult_Error{Error: &pb.MarketError{
+			Code:    int32(code),
+			Message: message,
+		}},
+	}
+}
+
+// ratesError maps service errors to gRPC status errors
+func ratesError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrUnknownMarket):
+		return status.Error(codes.NotFound, "market not found")
+	case errors.Is(err, service.ErrMarketInactive):
+		return status.Error(codes.FailedPrecondition, "market is not active")
+	case errors.Is(err, service.ErrRejectedQuote):
+		return status.Error(codes.Unavailable, "upstream quote rejected")
+	case errors.Is(err, service.ErrStaleQuote):
+		return status.Error(codes.Unavailable, "no quote within max_age")
+	default:
+		return status.Error(codes.Internal, "failed to get rates")
+	}
+}
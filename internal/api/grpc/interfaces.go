@@ -2,12 +2,40 @@ package grpc
 
 import (
 	"context"
+	"io"
 
 	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/export"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/storage/postgres"
 )
 
 // RatesService interface for the service layer
 type RatesService interface {
-	GetRates(ctx context.Context, market string) (*client.RateData, error)
+	GetRates(ctx context.Context, market string, opts ...service.RequestOption) (*client.RateData, error)
+	BatchGetRates(ctx context.Context, markets []string, opts ...service.RequestOption) []service.MarketResult
+	ListMarkets(ctx context.Context) []service.Market
+	SubscribeRates(markets []string) *service.Subscription
+	Unsubscribe(sub *service.Subscription)
 	HealthCheck(ctx context.Context) error
 }
+
+// QuoteService interface for locked quotes
+type QuoteService interface {
+	CreateQuote(ctx context.Context, market, side, amount, tier string) (*service.Quote, error)
+	RedeemQuote(ctx context.Context, id, token string) (*service.Quote, service.QuoteStatus, error)
+}
+
+// AlertService interface for alert rule management
+type AlertService interface {
+	CreateRule(ctx context.Context, rule *postgres.AlertRule) error
+	GetRule(ctx context.Context, id int64) (*postgres.AlertRule, error)
+	ListRules(ctx context.Context) ([]postgres.AlertRule, error)
+	UpdateRule(ctx context.Context, rule *postgres.AlertRule) error
+	DeleteRule(ctx context.Context, id int64) error
+}
+
+// RateExporter interface for rates history exports
+type RateExporter interface {
+	Export(ctx context.Context, w io.Writer, req export.Request) (int64, error)
+}
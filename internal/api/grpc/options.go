@@ -0,0 +1,25 @@
+package grpc
+
+// HandlerOption configures optional RatesHandler dependencies
+type HandlerOption func(*RatesHandler)
+
+// WithQuoteService enables the locked quote RPCs
+func WithQuoteService(quoteService QuoteService) HandlerOption {
+	return func(h *RatesHandler) {
+		h.quoteService = quoteService
+	}
+}
+
+// WithAlertService enables the alert rule RPCs
+func WithAlertService(alertService AlertService) HandlerOption {
+	return func(h *RatesHandler) {
+		h.alertService = alertService
+	}
+}
+
+// WithExporter enables the ExportRates RPC
+func WithExporter(exporter RateExporter) HandlerOption {
+	return func(h *RatesHandler) {
+		h.exporter = exporter
+	}
+}
@@ -0,0 +1,118 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alik/TestForWork/internal/service"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateLockedQuote handles the CreateLockedQuote gRPC request
+func (h *RatesHandler) CreateLockedQuote(ctx context.Context, req *pb.CreateLockedQuoteRequest) (*pb.LockedQuote, error) {
+	h.log(ctx).Info("CreateLockedQuote request received",
+		zap.String("market", req.Market),
+		zap.String("side", req.Side),
+		zap.String("amount", req.Amount))
+
+	if h.quoteService == nil {
+		return nil, status.Error(codes.Unimplemented, "locked quotes are disabled")
+	}
+
+	// Validate request
+	if req.Market == "" {
+		h.log(ctx).Warn("Empty market in request")
+		return nil, status.Error(codes.InvalidArgument, "market is required")
+	}
+
+	quote, err := h.quoteService.CreateQuote(ctx, req.Market, req.Side, req.Amount, req.ClientTier)
+	if err != nil {
+		h.log(ctx).Error("Failed to create locked quote", zap.Error(err))
+		return nil, quoteError(err)
+	}
+
+	h.log(ctx).Info("CreateLockedQuote request completed", zap.String("quote_id", quote.ID))
+
+	return toLockedQuote(quote), nil
+}
+
+// RedeemQuote handles the RedeemQuote gRPC request
+func (h *RatesHandler) RedeemQuote(ctx context.Context, req *pb.RedeemQuoteRequest) (*pb.RedeemQuoteResponse, error) {
+	h.log(ctx).Info("RedeemQuote request received", zap.String("quote_id", req.QuoteId))
+
+	if h.quoteService == nil {
+		return nil, status.Error(codes.Unimplemented, "locked quotes are disabled")
+	}
+
+	// Validate request
+	if req.QuoteId == "" || req.Token == "" {
+		h.log(ctx).Warn("Empty quote id or token in request")
+		return nil, status.Error(codes.InvalidArgument, "quote_id and token are required")
+	}
+
+	quote, quoteStatus, err := h.quoteService.RedeemQuote(ctx, req.QuoteId, req.Token)
+	if err != nil {
+		h.log(ctx).Error("Failed to redeem locked quote", zap.Error(err))
+		return nil, quoteError(err)
+	}
+
+	h.log(ctx).Info("RedeemQuote request completed",
+		zap.String("quote_id", req.QuoteId),
+		zap.String("status", string(quoteStatus)))
+
+	return &pb.RedeemQuoteResponse{
+		Status: toQuoteStatus(quoteStatus),
+		Quote:  toLockedQuote(quote),
+	}, nil
+}
+
+// toLockedQuote converts a quote to the protobuf message
+func toLockedQuote(quote *service.Quote) *pb.LockedQuote {
+	return &pb.LockedQuote{
+		QuoteId:       quote.ID,
+		Market:        quote.Market,
+		Side:          quote.Side,
+		Amount:        quote.Amount,
+		Price:         quote.Price,
+		Total:         quote.Total,
+		ExpiresAt:     timestamppb.New(quote.ExpiresAt),
+		Token:         quote.Token,
+		PricingRuleId: quote.PricingRuleID,
+	}
+}
+
+// toQuoteStatus converts a quote status to the protobuf enum
+func toQuoteStatus(quoteStatus service.QuoteStatus) pb.QuoteStatus {
+	switch quoteStatus {
+	case service.QuoteStatusValid:
+		return pb.QuoteStatus_QUOTE_STATUS_VALID
+	case service.QuoteStatusExpired:
+		return pb.QuoteStatus_QUOTE_STATUS_EXPIRED
+	case service.QuoteStatusUsed:
+		return pb.QuoteStatus_QUOTE_STATUS_USED
+	case service.QuoteStatusInvalid:
+		return pb.QuoteStatus_QUOTE_STATUS_INVALID
+	default:
+		return pb.QuoteStatus_QUOTE_STATUS_UNSPECIFIED
+	}
+}
+
+// quoteError maps quote service errors to gRPC status errors
+func quoteError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidQuoteRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrQuoteNotFound):
+		return status.Error(codes.NotFound, "quote not found")
+	case errors.Is(err, service.ErrNoLiquidity):
+		return status.Error(codes.FailedPrecondition, "no price available for the requested side")
+	case errors.Is(err, service.ErrUnknownMarket), errors.Is(err, service.ErrMarketInactive):
+		return ratesError(err)
+	default:
+		return status.Error(codes.Internal, "failed to process quote")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -28,6 +29,30 @@ type Server struct {
 	ratesHandler *RatesHandler
 }
 
+// ServerOption configures the gRPC server
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	connectionTimeout time.Duration
+	requestTimeout    time.Duration
+}
+
+// WithConnectionTimeout limits how long a new connection may take to
+// complete its handshake
+func WithConnectionTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.connectionTimeout = timeout
+	}
+}
+
+// WithRequestTimeout sets the deadline for handling a unary RPC; a shorter
+// deadline set by the client is kept. Streams are not limited.
+func WithRequestTimeout(timeout time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.requestTimeout = timeout
+	}
+}
+
 // NewServer creates a new gRPC server
 func NewServer(
 	ratesHandler *RatesHandler,
@@ -36,9 +61,18 @@ func NewServer(
 	maxConnectionIdle time.Duration,
 	enableMetrics bool,
 	enableTracing bool,
+	serverOpts ...ServerOption,
 ) *Server {
+	var o serverOptions
+	for _, opt := range serverOpts {
+		opt(&o)
+	}
+
 	// Server options
 	var opts []grpc.ServerOption
+	if o.connectionTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(o.connectionTimeout))
+	}
 
 	// Keepalive settings
 	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
@@ -55,6 +89,17 @@ func NewServer(
 	unaryInterceptors = append(unaryInterceptors, grpc_ctxtags.UnaryServerInterceptor())
 	streamInterceptors = append(streamInterceptors, grpc_ctxtags.StreamServerInterceptor())
 
+	// Trace IDs in the request log
+	if enableTracing {
+		unaryInterceptors = append(unaryInterceptors, traceTagsUnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, traceTagsStreamInterceptor)
+	}
+
+	// Request deadline, applied before logging so timeouts are logged
+	if o.requestTimeout > 0 {
+		unaryInterceptors = append(unaryInterceptors, timeoutUnaryInterceptor(o.requestTimeout))
+	}
+
 	// Logging
 	unaryInterceptors = append(unaryInterceptors, grpc_zap.UnaryServerInterceptor(logger))
 	streamInterceptors = append(streamInterceptors, grpc_zap.StreamServerInterceptor(logger))
@@ -130,6 +175,9 @@ func (s *Server) Start() error {
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping gRPC server")
 
+	// Subscriptions never end on their own and would hold up GracefulStop
+	s.ratesHandler.StopStreams()
+
 	stopped := make(chan struct{})
 	go func() {
 		s.server.GracefulStop()
@@ -146,3 +194,39 @@ func (s *Server) Stop(ctx context.Context) error {
 		return nil
 	}
 }
+
+// timeoutUnaryInterceptor applies the request timeout unless the client set
+// a shorter deadline
+func timeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > timeout {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// traceTagsUnaryInterceptor adds the trace and span IDs to the request tags,
+// so the request log line carries them
+func traceTagsUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	setTraceTags(ctx)
+	return handler(ctx, req)
+}
+
+// traceTagsStreamInterceptor adds the trace and span IDs to the stream tags
+func traceTagsStreamInterceptor(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	setTraceTags(stream.Context())
+	return handler(srv, stream)
+}
+
+func setTraceTags(ctx context.Context) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	grpc_ctxtags.Extract(ctx).
+		Set("trace_id", sc.TraceID().String()).
+		Set("span_id", sc.SpanID().String())
+}
@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	pb "github.com/alik/TestForWork/proto/rates"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SubscribeRates handles the SubscribeRates gRPC request
+func (h *RatesHandler) SubscribeRates(req *pb.SubscribeRatesRequest, stream pb.RatesService_SubscribeRatesServer) error {
+	log := h.log(stream.Context())
+	log.Info("SubscribeRates request received", zap.Strings("markets", req.Markets))
+
+	if len(req.Markets) > maxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "at most %d markets are allowed", maxBatchSize)
+	}
+	for _, market := range req.Markets {
+		if market == "" {
+			return status.Error(codes.InvalidArgument, "market is required")
+		}
+	}
+
+	sub := h.ratesService.SubscribeRates(req.Markets)
+	defer h.ratesService.Unsubscribe(sub)
+
+	sent := 0
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Info("SubscribeRates stream closed by client", zap.Int("sent", sent))
+			return nil
+		case <-h.stopping:
+			log.Info("SubscribeRates stream closed on shutdown", zap.Int("sent", sent))
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-sub.Ready():
+			for _, rate := range sub.Next() {
+				if err := stream.Send(toRatesResponse(&rate)); err != nil {
+					log.Warn("Failed to send rate to subscriber", zap.Error(err))
+					return err
+				}
+				sent++
+			}
+		}
+	}
+}
+
+// StopStreams ends the open subscriptions with codes.Unavailable, so their
+// clients reconnect to another instance
+func (h *RatesHandler) StopStreams() {
+	h.stopOnce.Do(func() { close(h.stopping) })
+}
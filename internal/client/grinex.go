@@ -4,18 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sync/atomic"
 	"time"
 
+	"github.com/alik/TestForWork/pkg/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
 // GrinexClient represents the Grinex API client
 type GrinexClient struct {
 	httpClient *http.Client
+	timeout    atomic.Int64
 	baseURL    string
 	market     string
 	logger     *zap.Logger
+	recorder   *Recorder
 }
 
 // OrderBook represents a single order in the order book
@@ -36,63 +43,139 @@ type DepthResponse struct {
 
 // RateData represents exchange rate information
 type RateData struct {
-	Ask       string
-	Bid       string
+	Ask    string
+	Bid    string
+	Market string
+
+	// Timestamp is the exchange timestamp of the quote, zero if the exchange did not report one
 	Timestamp time.Time
-	Market    string
+	// ReceivedAt is when the quote was received from the exchange
+	ReceivedAt time.Time
+
+	// Derived is set for synthetic quotes built from other markets
+	Derived bool
+	// Path lists the markets used to derive a synthetic quote
+	Path []RateLeg
+
+	// Customer prices after applying pricing rules
+	CustomerAsk        string
+	CustomerBid        string
+	PricingRuleID      string
+	PricingRuleVersion int
+}
+
+// Time returns the exchange timestamp, or the receive time if the exchange did not report one
+func (r *RateData) Time() time.Time {
+	if r.Timestamp.IsZero() {
+		return r.ReceivedAt
+	}
+	return r.Timestamp
+}
+
+// Age returns how old the quote is at now
+func (r *RateData) Age(now time.Time) time.Duration {
+	return now.Sub(r.Time())
+}
+
+// RateLeg is one step of a cross-rate path
+type RateLeg struct {
+	Market string
+	From   string
+	To     string
+	// Inverted is set when the market is traversed from quote to base
+	Inverted bool
+}
+
+// MarketInfo represents a market entry from the markets API
+type MarketInfo struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	BaseUnit        string `json:"base_unit"`
+	QuoteUnit       string `json:"quote_unit"`
+	PricePrecision  int    `json:"price_precision"`
+	AmountPrecision int    `json:"amount_precision"`
+	State           string `json:"state"`
+}
+
+// Grinex API endpoints, also used as metric labels
+const (
+	depthEndpoint   = "/api/v2/depth"
+	marketsEndpoint = "/api/v2/markets"
+)
+
+// GrinexOption configures a GrinexClient
+type GrinexOption func(*GrinexClient)
+
+// WithRecorder records every depth response to rec
+func WithRecorder(rec *Recorder) GrinexOption {
+	return func(c *GrinexClient) {
+		c.recorder = rec
+	}
 }
 
 // NewGrinexClient creates a new Grinex API client
-func NewGrinexClient(baseURL, market string, timeout time.Duration, logger *zap.Logger) *GrinexClient {
-	return &GrinexClient{
+func NewGrinexClient(baseURL, market string, timeout time.Duration, logger *zap.Logger, opts ...GrinexOption) *GrinexClient {
+	c := &GrinexClient{
+		// The timeout is applied through the request context, since
+		// http.Client.Timeout cancels traced transports without a deadline error
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport,
+				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+					return r.Method + " " + r.URL.Path
+				})),
 		},
 		baseURL: baseURL,
 		market:  market,
 		logger:  logger,
 	}
+	c.timeout.Store(int64(timeout))
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SetTimeout changes the timeout of requests started after the call
+func (c *GrinexClient) SetTimeout(timeout time.Duration) {
+	c.timeout.Store(int64(timeout))
+}
+
+// log returns the client logger with the trace of the request
+func (c *GrinexClient) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, c.logger)
 }
 
 // GetRates retrieves exchange rates from Grinex API
 func (c *GrinexClient) GetRates(ctx context.Context, market string) (*RateData, error) {
-	url := fmt.Sprintf("%s/api/v2/depth?market=%s", c.baseURL, market)
-
-	c.logger.Debug("Making request to Grinex API",
-		zap.String("url", url),
-		zap.String("market", market))
+	start := time.Now()
+	status, body, err := c.get(ctx, depthEndpoint, url.Values{"market": {market}})
+	receivedAt := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		c.logger.Error("Failed to create request", zap.Error(err))
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if c.recorder != nil {
+		if recErr := c.recorder.Record(market, status, body, err, receivedAt, receivedAt.Sub(start)); recErr != nil {
+			c.log(ctx).Warn("Failed to record depth response", zap.String("market", market), zap.Error(recErr))
+		}
 	}
-
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		c.logger.Error("Failed to make request", zap.Error(err))
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		c.logger.Error("Unexpected status code",
-			zap.Int("status_code", resp.StatusCode),
-			zap.String("status", resp.Status))
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	rateData, err := parseDepth(market, status, body, receivedAt, c.log(ctx))
+	countError(depthEndpoint, err)
+	return rateData, err
+}
 
+// parseDepth builds rate data from a depth response
+func parseDepth(market string, status int, body []byte, receivedAt time.Time, logger *zap.Logger) (*RateData, error) {
 	var depthResp DepthResponse
-	if err := json.NewDecoder(resp.Body).Decode(&depthResp); err != nil {
-		c.logger.Error("Failed to decode response", zap.Error(err))
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := decodeResponse(status, body, &depthResp, logger); err != nil {
+		return nil, err
 	}
 
 	// Validate response structure - allow empty asks/bids but log warning
 	if len(depthResp.Asks) == 0 && len(depthResp.Bids) == 0 {
-		c.logger.Warn("Empty asks and bids in response")
+		logger.Warn("Empty asks and bids in response")
 	}
 
 	// Get first ask and bid prices
@@ -110,23 +193,134 @@ func (c *GrinexClient) GetRates(ctx context.Context, market string) (*RateData,
 		bid = "N/A" // No bid orders available
 	}
 
-	timestamp := time.Now()
-	if depthResp.Timestamp > 0 {
-		timestamp = time.Unix(depthResp.Timestamp/1000, 0)
-	}
-
 	rateData := &RateData{
-		Ask:       ask,
-		Bid:       bid,
-		Timestamp: timestamp,
-		Market:    market,
+		Ask:        ask,
+		Bid:        bid,
+		Timestamp:  parseExchangeTimestamp(depthResp.Timestamp),
+		ReceivedAt: receivedAt,
+		Market:     market,
 	}
 
-	c.logger.Info("Successfully retrieved rates",
+	logger.Info("Successfully retrieved rates",
 		zap.String("ask", ask),
 		zap.String("bid", bid),
 		zap.String("market", market),
-		zap.Time("timestamp", timestamp))
+		zap.Time("timestamp", rateData.Timestamp),
+		zap.Time("received_at", receivedAt))
 
 	return rateData, nil
 }
+
+// millisecondTimestampThreshold separates second and millisecond Unix timestamps;
+// values below it are seconds until the year 33658
+const millisecondTimestampThreshold = 1e12
+
+// parseExchangeTimestamp converts a Unix timestamp in seconds or milliseconds,
+// returning the zero time when the exchange did not report one
+func parseExchangeTimestamp(ts int64) time.Time {
+	switch {
+	case ts <= 0:
+		return time.Time{}
+	case ts < millisecondTimestampThreshold:
+		return time.Unix(ts, 0)
+	default:
+		return time.UnixMilli(ts)
+	}
+}
+
+// GetMarkets retrieves the list of markets available on Grinex
+func (c *GrinexClient) GetMarkets(ctx context.Context) ([]MarketInfo, error) {
+	var markets []MarketInfo
+	if err := c.getJSON(ctx, marketsEndpoint, nil, &markets); err != nil {
+		return nil, err
+	}
+
+	c.log(ctx).Info("Successfully retrieved markets", zap.Int("count", len(markets)))
+
+	return markets, nil
+}
+
+// Ping checks that the Grinex API answers. Nothing is recorded or decoded.
+func (c *GrinexClient) Ping(ctx context.Context) error {
+	status, _, err := c.get(ctx, marketsEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, status)
+	}
+	return nil
+}
+
+// getJSON performs a GET request to the Grinex API and decodes the JSON response
+func (c *GrinexClient) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	status, body, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	err = decodeResponse(status, body, out, c.log(ctx))
+	countError(path, err)
+	return err
+}
+
+// get performs a GET request to the Grinex API and returns the status code and body
+func (c *GrinexClient) get(ctx context.Context, path string, query url.Values) (status int, body []byte, err error) {
+	start := time.Now()
+	defer func() {
+		observeRequest(path, status, time.Since(start))
+		countError(path, err)
+	}()
+
+	if timeout := time.Duration(c.timeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	reqURL := c.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	c.log(ctx).Debug("Making request to Grinex API", zap.String("url", reqURL))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, http.NoBody)
+	if err != nil {
+		c.log(ctx).Error("Failed to create request", zap.Error(err))
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.log(ctx).Error("Failed to make request", zap.Error(err))
+		return 0, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		c.log(ctx).Error("Failed to read response", zap.Error(err))
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// decodeResponse checks the status code and decodes the JSON body
+func decodeResponse(status int, body []byte, out interface{}, logger *zap.Logger) error {
+	if status != http.StatusOK {
+		logger.Error("Unexpected status code",
+			zap.Int("status_code", status),
+			zap.String("status", http.StatusText(status)))
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, status)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		logger.Error("Failed to decode response", zap.Error(err))
+		return fmt.Errorf("%w: %w", errDecode, err)
+	}
+
+	return nil
+}
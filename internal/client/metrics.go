@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Error classes of failed upstream requests
+const (
+	ErrorClassTimeout = "timeout"
+	ErrorClassNetwork = "network"
+	ErrorClassStatus  = "non_200"
+	ErrorClassDecode  = "decode"
+)
+
+var (
+	errUnexpectedStatus = errors.New("unexpected status code")
+	errDecode           = errors.New("failed to decode response")
+)
+
+var (
+	// upstreamRequestDuration measures Grinex API requests; status is the
+	// HTTP status code or "error" when no response was received
+	upstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "rates_upstream_request_duration_seconds",
+		Help:    "Latency of Grinex API requests by endpoint and HTTP status.",
+		Buckets: []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"endpoint", "status"})
+
+	// upstreamErrors counts failed Grinex API requests
+	upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "rates_upstream_errors_total",
+		Help: "Failed Grinex API requests by endpoint and error class.",
+	}, []string{"endpoint", "class"})
+)
+
+// observeRequest records the latency of a request
+func observeRequest(endpoint string, status int, duration time.Duration) {
+	label := "error"
+	if status > 0 {
+		label = strconv.Itoa(status)
+	}
+	upstreamRequestDuration.WithLabelValues(endpoint, label).Observe(duration.Seconds())
+}
+
+// countError records a failed request. Requests canceled by the caller are
+// not upstream failures and are not counted.
+func countError(endpoint string, err error) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	upstreamErrors.WithLabelValues(endpoint, errorClass(err)).Inc()
+}
+
+// errorClass classifies a request error
+func errorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errUnexpectedStatus):
+		return ErrorClassStatus
+	case errors.Is(err, errDecode):
+		return ErrorClassDecode
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	default:
+		return ErrorClassNetwork
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordingVersion is the version of the recording file format
+const recordingVersion = 1
+
+// A recording is a JSON lines file, gzip-compressed when the name ends in .gz.
+// The first line is a header with the format version and the start time,
+// every following line is one depth response:
+//
+//	{"version":1,"started_at":"2026-10-18T12:00:00.123Z"}
+//	{"t":1520,"m":"usdtrub","s":200,"d":84,"b":{"timestamp":1792353600,"asks":[...],"bids":[...]}}
+//	{"t":3011,"m":"usdtrub","s":429,"d":12,"b":{"error":"rate limited"}}
+//	{"t":4530,"m":"usdtrub","s":200,"d":95,"r":"{\"asks\":[{\"pri"}
+//	{"t":9002,"m":"usdtrub","d":10000,"e":"failed to make request: context deadline exceeded"}
+//
+// t is the receive time in milliseconds since started_at and d the request
+// duration in milliseconds. The body is stored as JSON in b, or as a string
+// in r when it is not valid JSON. Failed requests have no status and carry
+// the error in e.
+
+// recordingHeader is the first line of a recording
+type recordingHeader struct {
+	Version   int       `json:"version"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// recordedResponse is a depth response line of a recording
+type recordedResponse struct {
+	Offset   int64           `json:"t"`
+	Market   string          `json:"m"`
+	Status   int             `json:"s,omitempty"`
+	Duration int64           `json:"d,omitempty"`
+	Body     json.RawMessage `json:"b,omitempty"`
+	Raw      string          `json:"r,omitempty"`
+	Error    string          `json:"e,omitempty"`
+}
+
+// body returns the response body as received
+func (r *recordedResponse) body() []byte {
+	if len(r.Body) > 0 {
+		return r.Body
+	}
+	return []byte(r.Raw)
+}
+
+// Recorder writes depth responses to a recording. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closers []io.Closer
+	start   time.Time
+	closed  bool
+}
+
+// NewRecorder starts a recording on w
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	r := &Recorder{w: bufio.NewWriter(w), start: time.Now()}
+	if err := r.writeLine(recordingHeader{Version: recordingVersion, StartedAt: r.start}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RecordingPath returns path with the time t inserted before the extension,
+// e.g. depth.jsonl.gz becomes depth-20261018T120000Z.jsonl.gz, so every run
+// records to its own file
+func RecordingPath(path string, t time.Time) string {
+	base, gz := strings.CutSuffix(path, ".gz")
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext) + "-" + t.UTC().Format("20060102T150405Z") + ext
+	if gz {
+		name += ".gz"
+	}
+	return name
+}
+
+// CreateRecording starts a recording in a new file, compressed if the name ends in .gz.
+// An existing file is never overwritten. A compressed recording is complete only after Close.
+func CreateRecording(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	var w io.Writer = file
+	var closers []io.Closer
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(file)
+		w = gz
+		closers = append(closers, gz)
+	}
+	closers = append(closers, file)
+
+	r, err := NewRecorder(w)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	r.closers = closers
+
+	return r, nil
+}
+
+// Record writes a depth response, or the error of a failed request
+func (r *Recorder) Record(market string, status int, body []byte, reqErr error, receivedAt time.Time, duration time.Duration) error {
+	line := recordedResponse{
+		Offset:   receivedAt.Sub(r.start).Milliseconds(),
+		Market:   market,
+		Status:   status,
+		Duration: duration.Milliseconds(),
+	}
+
+	switch {
+	case reqErr != nil && status == 0:
+		line.Error = reqErr.Error()
+	case json.Valid(body):
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err != nil {
+			return fmt.Errorf("failed to compact response: %w", err)
+		}
+		line.Body = compact.Bytes()
+	default:
+		line.Raw = string(body)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errors.New("recording is closed")
+	}
+	return r.writeLine(line)
+}
+
+// Close flushes the recording and closes the file it was created with
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	err := r.w.Flush()
+	for _, c := range r.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to close recording: %w", err)
+	}
+	return nil
+}
+
+// writeLine writes v as a JSON line and flushes it; r.mu must be held after construction
+func (r *Recorder) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode recording line: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := r.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrReplayExhausted is returned when a replay has no more responses for a market
+var ErrReplayExhausted = errors.New("replay exhausted")
+
+// ReplayOption configures a ReplayClient
+type ReplayOption func(*ReplayClient)
+
+// WithReplaySpeed replays speed times faster than recorded; 0 replays without waiting
+func WithReplaySpeed(speed float64) ReplayOption {
+	return func(c *ReplayClient) {
+		c.speed = speed
+	}
+}
+
+// WithReplayLoop starts over from the beginning when a market's responses run out
+func WithReplayLoop() ReplayOption {
+	return func(c *ReplayClient) {
+		c.loop = true
+	}
+}
+
+// WithOriginalTimes keeps the recorded receive and exchange times instead of
+// shifting them to the time of the replay, for deterministic output
+func WithOriginalTimes() ReplayOption {
+	return func(c *ReplayClient) {
+		c.originalTimes = true
+	}
+}
+
+// ReplayClient serves recorded depth responses through the same GetRates
+// method as GrinexClient. Each call returns the next response recorded for
+// the market, after waiting until it is due relative to the first call.
+type ReplayClient struct {
+	startedAt time.Time
+	responses map[string][]recordedResponse
+	// span is the recorded time between the first and the last response
+	span   time.Duration
+	first  int64
+	logger *zap.Logger
+
+	speed         float64
+	loop          bool
+	originalTimes bool
+
+	mu        sync.Mutex
+	positions map[string]int
+	start     time.Time
+}
+
+// NewReplayClient reads a recording, compressed or not, from r
+func NewReplayClient(r io.Reader, logger *zap.Logger, opts ...ReplayOption) (*ReplayClient, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open compressed recording: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	decoder := json.NewDecoder(br)
+
+	var header recordingHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+	if header.Version != recordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d", header.Version)
+	}
+
+	c := &ReplayClient{
+		startedAt: header.StartedAt,
+		responses: make(map[string][]recordedResponse),
+		logger:    logger,
+		speed:     1,
+		positions: make(map[string]int),
+	}
+
+	count := 0
+	var last int64
+	for {
+		var line recordedResponse
+		if err := decoder.Decode(&line); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read recording line %d: %w", count+2, err)
+		}
+		if count == 0 || line.Offset < c.first {
+			c.first = line.Offset
+		}
+		last = max(last, line.Offset)
+		c.responses[line.Market] = append(c.responses[line.Market], line)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("recording has no responses")
+	}
+	c.span = time.Duration(last-c.first) * time.Millisecond
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// OpenReplay reads a recording file
+func OpenReplay(path string, logger *zap.Logger, opts ...ReplayOption) (*ReplayClient, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	return NewReplayClient(file, logger, opts...)
+}
+
+// GetRates returns the next recorded response for the market
+func (c *ReplayClient) GetRates(ctx context.Context, market string) (*RateData, error) {
+	line, due, err := c.next(market)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait := time.Until(due); wait > 0 {
+		if !sleepContext(ctx, wait) {
+			return nil, fmt.Errorf("failed to replay response: %w", ctx.Err())
+		}
+	}
+
+	if line.Error != "" {
+		return nil, errors.New(line.Error)
+	}
+
+	recordedAt := c.startedAt.Add(time.Duration(line.Offset) * time.Millisecond)
+	receivedAt := recordedAt
+	if !c.originalTimes {
+		receivedAt = time.Now()
+	}
+
+	rate, err := parseDepth(market, line.Status, line.body(), receivedAt, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the recorded lag between the exchange and the receive time
+	if !c.originalTimes && !rate.Timestamp.IsZero() {
+		rate.Timestamp = rate.Timestamp.Add(receivedAt.Sub(recordedAt))
+	}
+
+	return rate, nil
+}
+
+// Markets returns the markets present in the recording
+func (c *ReplayClient) Markets() []string {
+	markets := make([]string, 0, len(c.responses))
+	for market := range c.responses {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+// next advances the market's position and returns the response with the time it is due
+func (c *ReplayClient) next(market string) (recordedResponse, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	responses, ok := c.responses[market]
+	if !ok {
+		return recordedResponse{}, time.Time{}, fmt.Errorf("no recorded responses for market %s", market)
+	}
+
+	pos := c.positions[market]
+	if pos >= len(responses) && !c.loop {
+		return recordedResponse{}, time.Time{}, fmt.Errorf("%w for market %s", ErrReplayExhausted, market)
+	}
+	c.positions[market] = pos + 1
+
+	if c.start.IsZero() {
+		c.start = time.Now()
+	}
+
+	line := responses[pos%len(responses)]
+	if c.speed <= 0 {
+		return line, time.Time{}, nil
+	}
+
+	// Every loop continues after the end of the recording
+	offset := time.Duration(line.Offset-c.first)*time.Millisecond + time.Duration(pos/len(responses))*c.span
+	return line, c.start.Add(time.Duration(float64(offset) / c.speed)), nil
+}
@@ -0,0 +1,454 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// streamMinBackoff and streamMaxBackoff bound the reconnect delay
+	streamMinBackoff = 500 * time.Millisecond
+	streamMaxBackoff = 30 * time.Second
+
+	// streamPingInterval keeps the connection alive; streamReadTimeout
+	// drops a connection that stopped delivering messages and pongs
+	streamPingInterval = 20 * time.Second
+	streamReadTimeout  = 60 * time.Second
+
+	// defaultSnapshotTimeout is how long GetRates waits for a book snapshot
+	defaultSnapshotTimeout = 5 * time.Second
+)
+
+// streamRequest is a subscription request of the ranger protocol
+type streamRequest struct {
+	Event   string   `json:"event"`
+	Streams []string `json:"streams"`
+}
+
+// bookMessage is an order book snapshot or increment.
+// Increments carry a single [price, amount] level per side,
+// snapshots a list of levels.
+type bookMessage struct {
+	Asks     json.RawMessage `json:"asks"`
+	Bids     json.RawMessage `json:"bids"`
+	Sequence int64           `json:"sequence"`
+}
+
+// orderBook is the local copy of a market's order book.
+// Each side maps the numeric price of a level to the price as last sent by
+// the exchange, so "100.0" and "100.00" are the same level.
+type orderBook struct {
+	asks     map[float64]string
+	bids     map[float64]string
+	sequence int64
+	ready    bool
+	// updatedAt is when the last snapshot or increment was applied
+	updatedAt time.Time
+}
+
+// StreamClient maintains order books from the Grinex WebSocket market-data
+// stream and serves rates from them. It offers the same GetRates method as
+// GrinexClient, so the service can use either.
+type StreamClient struct {
+	url             string
+	dialer          *websocket.Dialer
+	snapshotTimeout time.Duration
+	logger          *zap.Logger
+
+	mu      sync.Mutex
+	books   map[string]*orderBook
+	markets map[string]bool
+	conn    *websocket.Conn
+	updated chan struct{}
+
+	writeMu sync.Mutex
+}
+
+// NewStreamClient creates a new WebSocket market-data client
+func NewStreamClient(wsURL string, snapshotTimeout time.Duration, logger *zap.Logger) *StreamClient {
+	if snapshotTimeout <= 0 {
+		snapshotTimeout = defaultSnapshotTimeout
+	}
+
+	return &StreamClient{
+		url:             wsURL,
+		dialer:          websocket.DefaultDialer,
+		snapshotTimeout: snapshotTimeout,
+		logger:          logger,
+		books:           make(map[string]*orderBook),
+		markets:         make(map[string]bool),
+		updated:         make(chan struct{}),
+	}
+}
+
+// Start keeps the connection open until ctx is done, reconnecting with
+// backoff and resubscribing to all markets after every reconnect
+func (c *StreamClient) Start(ctx context.Context) {
+	go func() {
+		backoff := streamMinBackoff
+		for ctx.Err() == nil {
+			conn, _, err := c.dialer.DialContext(ctx, c.url, nil)
+			if err != nil {
+				c.logger.Warn("Failed to connect to market-data stream",
+					zap.String("url", c.url),
+					zap.Duration("retry_in", backoff),
+					zap.Error(err))
+				if !sleepContext(ctx, backoff) {
+					return
+				}
+				backoff = min(backoff*2, streamMaxBackoff)
+				continue
+			}
+			backoff = streamMinBackoff
+
+			c.logger.Info("Connected to market-data stream", zap.String("url", c.url))
+			err = c.serve(ctx, conn)
+			c.disconnect()
+
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn("Market-data stream disconnected", zap.Error(err))
+			if !sleepContext(ctx, streamMinBackoff) {
+				return
+			}
+		}
+	}()
+}
+
+// Subscribe starts maintaining order books for the markets
+func (c *StreamClient) Subscribe(markets ...string) {
+	var added []string
+
+	c.mu.Lock()
+	for _, market := range markets {
+		if !c.markets[market] {
+			c.markets[market] = true
+			added = append(added, market)
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn != nil && len(added) > 0 {
+		if err := c.send(conn, "subscribe", added...); err != nil {
+			c.logger.Warn("Failed to subscribe", zap.Strings("markets", added), zap.Error(err))
+		}
+	}
+}
+
+// GetRates returns the best ask and bid of the local order book.
+// The first call for a market subscribes to it and waits for the snapshot.
+// Stream quotes carry no exchange timestamp; ReceivedAt is when the last
+// snapshot or increment was applied, so a book that stopped receiving
+// updates while the connection stayed up ages like any other quote.
+func (c *StreamClient) GetRates(ctx context.Context, market string) (*RateData, error) {
+	c.Subscribe(market)
+
+	timer := time.NewTimer(c.snapshotTimeout)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		book := c.books[market]
+		if book != nil && book.ready {
+			ask, bid := book.best()
+			updatedAt := book.updatedAt
+			c.mu.Unlock()
+
+			return &RateData{
+				Ask:        ask,
+				Bid:        bid,
+				Market:     market,
+				ReceivedAt: updatedAt,
+			}, nil
+		}
+		updated := c.updated
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to get order book: %w", ctx.Err())
+		case <-timer.C:
+			return nil, fmt.Errorf("no order book snapshot for %s within %s", market, c.snapshotTimeout)
+		case <-updated:
+		}
+	}
+}
+
+// serve subscribes to all markets and applies messages until the connection fails
+func (c *StreamClient) serve(ctx context.Context, conn *websocket.Conn) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamReadTimeout))
+	})
+
+	c.mu.Lock()
+	c.conn = conn
+	markets := make([]string, 0, len(c.markets))
+	for market := range c.markets {
+		markets = append(markets, market)
+	}
+	c.mu.Unlock()
+
+	if len(markets) > 0 {
+		if err := c.send(conn, "subscribe", markets...); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.ping(conn, done)
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(streamReadTimeout)); err != nil {
+			return err
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+		c.handleMessage(conn, data)
+	}
+}
+
+// ping sends keepalive pings until done is closed
+func (c *StreamClient) ping(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(streamPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamPingInterval))
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// disconnect forgets the connection and invalidates all books,
+// since updates may be missed until the next snapshot
+func (c *StreamClient) disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn = nil
+	for _, book := range c.books {
+		book.ready = false
+	}
+	c.notifyLocked()
+}
+
+// handleMessage applies a stream message to the order books
+func (c *StreamClient) handleMessage(conn *websocket.Conn, data []byte) {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.logger.Warn("Failed to decode stream message", zap.Error(err))
+		return
+	}
+
+	for stream, payload := range msg {
+		switch stream {
+		case "success":
+			c.logger.Debug("Stream request succeeded", zap.ByteString("response", payload))
+			continue
+		case "error":
+			c.logger.Warn("Stream request failed", zap.ByteString("response", payload))
+			continue
+		}
+
+		market, kind, ok := strings.Cut(stream, ".")
+		if !ok {
+			continue
+		}
+
+		var update bookMessage
+		if err := json.Unmarshal(payload, &update); err != nil {
+			c.logger.Warn("Failed to decode order book update", zap.String("stream", stream), zap.Error(err))
+			continue
+		}
+
+		switch kind {
+		case "ob-snap":
+			c.applySnapshot(market, update)
+		case "ob-inc":
+			if !c.applyIncrement(market, update) {
+				c.resubscribe(conn, market)
+			}
+		}
+	}
+}
+
+// applySnapshot replaces the market's book
+func (c *StreamClient) applySnapshot(market string, snap bookMessage) {
+	book := &orderBook{
+		asks:      make(map[float64]string),
+		bids:      make(map[float64]string),
+		sequence:  snap.Sequence,
+		ready:     true,
+		updatedAt: time.Now(),
+	}
+	applyLevels(book.asks, snap.Asks)
+	applyLevels(book.bids, snap.Bids)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.books[market] = book
+	c.notifyLocked()
+}
+
+// applyIncrement applies a delta and reports false on a sequence gap
+func (c *StreamClient) applyIncrement(market string, inc bookMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	book := c.books[market]
+	if book == nil || !book.ready {
+		// Waiting for a snapshot
+		return true
+	}
+
+	if inc.Sequence != 0 && book.sequence != 0 && inc.Sequence != book.sequence+1 {
+		c.logger.Warn("Order book sequence gap",
+			zap.String("market", market),
+			zap.Int64("expected", book.sequence+1),
+			zap.Int64("received", inc.Sequence))
+		book.ready = false
+		c.notifyLocked()
+		return false
+	}
+
+	applyLevels(book.asks, inc.Asks)
+	applyLevels(book.bids, inc.Bids)
+	book.sequence = inc.Sequence
+	book.updatedAt = time.Now()
+	c.notifyLocked()
+
+	return true
+}
+
+// resubscribe requests a fresh snapshot for the market
+func (c *StreamClient) resubscribe(conn *websocket.Conn, market string) {
+	if err := c.send(conn, "unsubscribe", market); err != nil {
+		c.logger.Warn("Failed to unsubscribe", zap.String("market", market), zap.Error(err))
+		return
+	}
+	if err := c.send(conn, "subscribe", market); err != nil {
+		c.logger.Warn("Failed to resubscribe", zap.String("market", market), zap.Error(err))
+	}
+}
+
+// send writes a subscription request for the markets' order book streams
+func (c *StreamClient) send(conn *websocket.Conn, event string, markets ...string) error {
+	streams := make([]string, len(markets))
+	for i, market := range markets {
+		streams[i] = market + ".ob-inc"
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := conn.WriteJSON(streamRequest{Event: event, Streams: streams}); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", event, err)
+	}
+	return nil
+}
+
+// notifyLocked wakes up callers waiting for book changes; c.mu must be held
+func (c *StreamClient) notifyLocked() {
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+// best returns the lowest ask and highest bid, or "N/A" for an empty side
+func (b *orderBook) best() (string, string) {
+	ask, bid := "N/A", "N/A"
+
+	bestAsk := 0.0
+	for value, price := range b.asks {
+		if ask == "N/A" || value < bestAsk {
+			ask, bestAsk = price, value
+		}
+	}
+
+	bestBid := 0.0
+	for value, price := range b.bids {
+		if bid == "N/A" || value > bestBid {
+			bid, bestBid = price, value
+		}
+	}
+
+	return ask, bid
+}
+
+// applyLevels updates one side of a book from a [price, amount] level or a
+// list of levels; an empty or zero amount removes the price level
+func applyLevels(side map[float64]string, raw json.RawMessage) {
+	if len(raw) == 0 {
+		return
+	}
+
+	var levels [][]string
+	if err := json.Unmarshal(raw, &levels); err != nil {
+		var level []string
+		if err := json.Unmarshal(raw, &level); err != nil {
+			return
+		}
+		levels = [][]string{level}
+	}
+
+	for _, level := range levels {
+		if len(level) == 0 {
+			continue
+		}
+		price := level[0]
+		value, err := strconv.ParseFloat(price, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		amount := 0.0
+		if len(level) > 1 && level[1] != "" {
+			amount, _ = strconv.ParseFloat(level[1], 64)
+		}
+
+		if amount <= 0 {
+			delete(side, value)
+			continue
+		}
+		side[value] = price
+	}
+}
+
+// sleepContext waits for the duration and reports false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -1,22 +1,40 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alik/TestForWork/internal/secrets"
+	"github.com/mitchellh/mapstructure"
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // Config holds application configuration
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Grinex   GrinexConfig   `mapstructure:"grinex"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Tracing  TracingConfig  `mapstructure:"tracing"`
-	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Grinex     GrinexConfig     `mapstructure:"grinex"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Metrics    MetricsConfig    `mapstructure:"metrics"`
+	Catalog    CatalogConfig    `mapstructure:"catalog"`
+	CrossRates CrossRatesConfig `mapstructure:"cross_rates"`
+	Pricing    PricingConfig    `mapstructure:"pricing"`
+	Quotes     QuotesConfig     `mapstructure:"quotes"`
+	Alerting   AlertingConfig   `mapstructure:"alerting"`
+	Outbox     OutboxConfig     `mapstructure:"outbox"`
+	Guard      GuardConfig      `mapstructure:"guard"`
+	Admin      AdminConfig      `mapstructure:"admin"`
+	Secrets    SecretsConfig    `mapstructure:"secrets"`
+
+	// secrets remembers the references secrets were resolved from
+	secrets map[string]secret
 }
 
 // ServerConfig holds server configuration
@@ -33,19 +51,147 @@ type DatabaseConfig struct {
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	User            string        `mapstructure:"user"`
-	Password        string        `mapstructure:"password"`
+	Password        string        `mapstructure:"password" redact:"true"`
 	Database        string        `mapstructure:"database"`
 	SSLMode         string        `mapstructure:"ssl_mode"`
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
-	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	MinConns        int           `mapstructure:"min_conns"`
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+	// StatementTimeouts bound the statements of each query class
+	StatementTimeouts StatementTimeoutsConfig `mapstructure:"statement_timeouts"`
+	// Replicas are host or host:port addresses of read replicas, which
+	// share the credentials, database name and SSL mode of the primary
+	Replicas             []string      `mapstructure:"replicas"`
+	ReplicaMaxLag        time.Duration `mapstructure:"replica_max_lag"`
+	ReplicaCheckInterval time.Duration `mapstructure:"replica_check_interval"`
+	ReadYourWrites       bool          `mapstructure:"read_your_writes"`
+	// Notifications share saved rates with the other instances through
+	// LISTEN/NOTIFY on the primary
+	Notifications bool `mapstructure:"notifications"`
+}
+
+// StatementTimeoutsConfig holds the statement timeouts per query class;
+// zero leaves a class unbounded
+type StatementTimeoutsConfig struct {
+	Read        time.Duration `mapstructure:"read"`
+	Write       time.Duration `mapstructure:"write"`
+	Bulk        time.Duration `mapstructure:"bulk"`
+	Maintenance time.Duration `mapstructure:"maintenance"`
 }
 
 // GrinexConfig holds Grinex API configuration
 type GrinexConfig struct {
-	BaseURL string        `mapstructure:"base_url"`
-	Timeout time.Duration `mapstructure:"timeout"`
-	Market  string        `mapstructure:"market"`
+	BaseURL          string        `mapstructure:"base_url"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	Market           string        `mapstructure:"market"`
+	BatchConcurrency int           `mapstructure:"batch_concurrency"`
+	Transport        string        `mapstructure:"transport"`
+	WSURL            string        `mapstructure:"ws_url"`
+	SnapshotTimeout  time.Duration `mapstructure:"snapshot_timeout"`
+	RecordPath       string        `mapstructure:"record_path"`
+	ReplayPath       string        `mapstructure:"replay_path"`
+	ReplaySpeed      float64       `mapstructure:"replay_speed"`
+	ReplayLoop       bool          `mapstructure:"replay_loop"`
+}
+
+// CatalogConfig holds market catalog configuration
+type CatalogConfig struct {
+	Source          string         `mapstructure:"source"`
+	RefreshInterval time.Duration  `mapstructure:"refresh_interval"`
+	Markets         []MarketConfig `mapstructure:"markets"`
+}
+
+// CrossRatesConfig holds cross-rate derivation configuration
+type CrossRatesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	MaxHops int  `mapstructure:"max_hops"`
+}
+
+// PricingConfig holds customer pricing configuration
+type PricingConfig struct {
+	Enabled         bool                `mapstructure:"enabled"`
+	Source          string              `mapstructure:"source"`
+	RefreshInterval time.Duration       `mapstructure:"refresh_interval"`
+	Rules           []PricingRuleConfig `mapstructure:"rules"`
+}
+
+// PricingRuleConfig describes a pricing rule defined in the configuration
+type PricingRuleConfig struct {
+	ID            string `mapstructure:"id"`
+	Version       int    `mapstructure:"version"`
+	Market        string `mapstructure:"market"`
+	ClientTier    string `mapstructure:"client_tier"`
+	SpreadPercent string `mapstructure:"spread_percent"`
+	FixedFee      string `mapstructure:"fixed_fee"`
+	MinPrice      string `mapstructure:"min_price"`
+	MaxPrice      string `mapstructure:"max_price"`
+}
+
+// QuotesConfig holds locked quotes configuration
+type QuotesConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	TTL             time.Duration `mapstructure:"ttl"`
+	SigningKey      string        `mapstructure:"signing_key" redact:"true"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	Retention       time.Duration `mapstructure:"retention"`
+}
+
+// AlertingConfig holds alert rules and webhook delivery configuration
+type AlertingConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	WebhookSecret   string        `mapstructure:"webhook_secret" redact:"true"`
+	WebhookTimeout  time.Duration `mapstructure:"webhook_timeout"`
+	MaxAttempts     int           `mapstructure:"max_attempts"`
+	RetryBackoff    time.Duration `mapstructure:"retry_backoff"`
+	Workers         int           `mapstructure:"workers"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+// OutboxConfig holds rate-change event publishing configuration
+type OutboxConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Publisher       string        `mapstructure:"publisher"`
+	PollInterval    time.Duration `mapstructure:"poll_interval"`
+	BatchSize       int           `mapstructure:"batch_size"`
+	CleanupInterval time.Duration `mapstructure:"cleanup_interval"`
+	Retention       time.Duration `mapstructure:"retention"`
+	FilePath        string        `mapstructure:"file_path"`
+	NATS            NATSConfig    `mapstructure:"nats"`
+	Kafka           KafkaConfig   `mapstructure:"kafka"`
+}
+
+// NATSConfig holds NATS publisher configuration
+type NATSConfig struct {
+	URL           string `mapstructure:"url" redact:"url"`
+	SubjectPrefix string `mapstructure:"subject_prefix"`
+}
+
+// KafkaConfig holds Kafka publisher configuration
+type KafkaConfig struct {
+	Brokers []string `mapstructure:"brokers"`
+	Topic   string   `mapstructure:"topic"`
+}
+
+// GuardConfig holds upstream quote sanity check configuration
+type GuardConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	MaxStaleness        time.Duration `mapstructure:"max_staleness"`
+	JumpSigma           float64       `mapstructure:"jump_sigma"`
+	JumpWindow          int           `mapstructure:"jump_window"`
+	JumpMinSamples      int           `mapstructure:"jump_min_samples"`
+	JumpMinPercent      float64       `mapstructure:"jump_min_percent"`
+	MaxConsecutiveJumps int           `mapstructure:"max_consecutive_jumps"`
+}
+
+// MarketConfig describes a statically configured market
+type MarketConfig struct {
+	ID              string `mapstructure:"id"`
+	Base            string `mapstructure:"base"`
+	Quote           string `mapstructure:"quote"`
+	PricePrecision  int    `mapstructure:"price_precision"`
+	AmountPrecision int    `mapstructure:"amount_precision"`
+	Status          string `mapstructure:"status"`
 }
 
 // LoggingConfig holds logging configuration
@@ -56,9 +202,40 @@ type LoggingConfig struct {
 
 // TracingConfig holds tracing configuration
 type TracingConfig struct {
-	Enabled     bool   `mapstructure:"enabled"`
-	JaegerURL   string `mapstructure:"jaeger_url"`
-	ServiceName string `mapstructure:"service_name"`
+	Enabled bool `mapstructure:"enabled"`
+	// Exporter is otlp-grpc, otlp-http, stdout or none
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint is host:port or a URL such as http://jaeger:4318; empty uses the OTEL_EXPORTER_OTLP_* variables
+	Endpoint string            `mapstructure:"endpoint"`
+	Headers  map[string]string `mapstructure:"headers" redact:"true"`
+	// Insecure disables TLS for the OTLP exporters
+	Insecure    bool             `mapstructure:"insecure"`
+	TLS         TracingTLSConfig `mapstructure:"tls"`
+	SampleRatio float64          `mapstructure:"sample_ratio"`
+	ServiceName string           `mapstructure:"service_name"`
+	// ResourceAttributes are added to every span, e.g. deployment.environment
+	ResourceAttributes map[string]string `mapstructure:"resource_attributes"`
+}
+
+// TracingTLSConfig holds TLS settings of the tracing exporter
+type TracingTLSConfig struct {
+	CAFile             string `mapstructure:"ca_file"`
+	CertFile           string `mapstructure:"cert_file"`
+	KeyFile            string `mapstructure:"key_file"`
+	ServerName         string `mapstructure:"server_name"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+}
+
+// AdminConfig holds the admin endpoints served next to the metrics
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token" redact:"true"`
+}
+
+// SecretsConfig holds secret rotation configuration
+type SecretsConfig struct {
+	// RefreshInterval is how often secret references are resolved again to pick up rotated secrets; 0 disables
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
 }
 
 // MetricsConfig holds metrics configuration
@@ -68,47 +245,128 @@ type MetricsConfig struct {
 	Port    int    `mapstructure:"port"`
 }
 
-// Load loads configuration from flags and environment variables
+// Load loads configuration from flags, environment variables and the config
+// file, and validates it
 func Load() (*Config, error) {
+	// Config keys use underscores, accept dashes as well: --grinex.base-url
+	flag.CommandLine.SetNormalizeFunc(normalizeFlagName)
+
 	// Define command line flags
+	flag.String("config", "", "Config file (default ./config.yaml or ./configs/config.yaml)")
 	flag.Int("server.port", 8080, "Server port")
-	flag.Duration("server.graceful-timeout", 30*time.Second, "Graceful shutdown timeout")
-	flag.Duration("server.read-timeout", 10*time.Second, "Server read timeout")
-	flag.Duration("server.write-timeout", 10*time.Second, "Server write timeout")
-	flag.Duration("server.max-connection-idle", 2*time.Minute, "Max connection idle time")
+	flag.Duration("server.graceful_timeout", 30*time.Second, "Graceful shutdown timeout")
+	flag.Duration("server.read_timeout", 10*time.Second, "Connection handshake and metrics request read timeout")
+	flag.Duration("server.write_timeout", 10*time.Second, "Deadline for handling a unary RPC")
+	flag.Duration("server.max_connection_idle", 2*time.Minute, "Max connection idle time")
 
 	flag.String("database.host", "localhost", "Database host")
 	flag.Int("database.port", 5432, "Database port")
 	flag.String("database.user", "postgres", "Database user")
-	flag.String("database.password", "postgres", "Database password")
+	flag.String("database.password", "", "Database password, or a reference such as ${file:/run/secrets/db_password}")
 	flag.String("database.database", "usdt_rates", "Database name")
-	flag.String("database.ssl-mode", "disable", "Database SSL mode")
-	flag.Int("database.max-open-conns", 25, "Database max open connections")
-	flag.Int("database.max-idle-conns", 25, "Database max idle connections")
-	flag.Duration("database.conn-max-lifetime", 5*time.Minute, "Database connection max lifetime")
+	flag.String("database.ssl_mode", "disable", "Database SSL mode: disable, allow, prefer, require, verify-ca or verify-full")
+	flag.Int("database.max_open_conns", 25, "Database max open connections")
+	flag.Int("database.min_conns", 0, "Database connections kept open when idle")
+	flag.Duration("database.conn_max_lifetime", 5*time.Minute, "Database connection max lifetime")
+	flag.Duration("database.conn_max_idle_time", 5*time.Minute, "Idle time after which database connections above database.min_conns are closed")
+	flag.Duration("database.statement_timeouts.read", 5*time.Second, "Timeout of point and history reads, 0 for none")
+	flag.Duration("database.statement_timeouts.write", 5*time.Second, "Timeout of writes, 0 for none")
+	flag.Duration("database.statement_timeouts.bulk", time.Minute, "Timeout of each statement of imports and exports, 0 for none")
+	flag.Duration("database.statement_timeouts.maintenance", time.Minute, "Timeout of cleanup of expired rows, 0 for none")
+	flag.StringSlice("database.replicas", nil, "Read replica addresses as host or host:port for history and export queries")
+	flag.Duration("database.replica_max_lag", 10*time.Second, "Replication lag above which a replica is not used")
+	flag.Duration("database.replica_check_interval", 5*time.Second, "Interval of replica health and lag checks")
+	flag.Bool("database.read_your_writes", true, "Read the latest rate from the primary even when replicas are configured")
+	flag.Bool("database.notifications", false, "Notify other instances of saved rates and apply the rates they save")
 
 	flag.String("grinex.base_url", "https://grinex.io", "Grinex API base URL")
 	flag.Duration("grinex.timeout", 10*time.Second, "Grinex API timeout")
 	flag.String("grinex.market", "usdtrub", "Trading market pair")
+	flag.Int("grinex.batch_concurrency", 4, "Max concurrent Grinex requests per batch")
+	flag.String("grinex.transport", "http", "Market data transport: http (depth polling), websocket or replay")
+	flag.String("grinex.ws_url", "wss://grinex.io/api/v2/ranger/public/", "Grinex WebSocket market-data URL")
+	flag.Duration("grinex.snapshot_timeout", 5*time.Second, "How long to wait for an order book snapshot")
+	flag.String("grinex.record_path", "", "Record depth responses to this file, gzip-compressed if it ends in .gz")
+	flag.String("grinex.replay_path", "", "Recording served by the replay transport")
+	flag.Float64("grinex.replay_speed", 1, "Replay speed relative to the recording, 0 replays without waiting")
+	flag.Bool("grinex.replay_loop", false, "Start the replay over when the recording runs out")
+
+	flag.String("catalog.source", "exchange", "Market catalog source: exchange or config")
+	flag.Duration("catalog.refresh_interval", 10*time.Minute, "Market catalog refresh interval")
+
+	flag.Bool("cross_rates.enabled", true, "Derive quotes for markets not listed on the exchange")
+	flag.Int("cross_rates.max_hops", 2, "Max intermediate currencies in a cross-rate path")
+
+	flag.Bool("pricing.enabled", false, "Apply customer pricing rules")
+	flag.String("pricing.source", "config", "Pricing rules source: config or database")
+	flag.Duration("pricing.refresh_interval", time.Minute, "Pricing rules reload interval for the database source")
+
+	flag.Bool("quotes.enabled", false, "Enable locked quotes")
+	flag.Duration("quotes.ttl", 15*time.Second, "Locked quote lifetime")
+	flag.String("quotes.signing_key", "", "Secret key for signing quote tokens")
+	flag.Duration("quotes.cleanup_interval", time.Minute, "Expired quotes cleanup interval")
+	flag.Duration("quotes.retention", 24*time.Hour, "How long expired quotes are kept")
+
+	flag.Bool("alerting.enabled", false, "Enable alert rules and webhooks")
+	flag.String("alerting.webhook_secret", "", "Default secret for signing alert webhooks")
+	flag.Duration("alerting.webhook_timeout", 5*time.Second, "Alert webhook request timeout")
+	flag.Int("alerting.max_attempts", 5, "Maximum alert webhook delivery attempts")
+	flag.Duration("alerting.retry_backoff", time.Second, "Initial backoff between webhook retries")
+	flag.Int("alerting.workers", 2, "Number of webhook delivery workers")
+	flag.Duration("alerting.refresh_interval", 30*time.Second, "Alert rules reload interval")
+
+	flag.Bool("outbox.enabled", false, "Publish rate-change events through the outbox table")
+	flag.String("outbox.publisher", "stdout", "Outbox publisher: stdout, file, nats or kafka")
+	flag.Duration("outbox.poll_interval", time.Second, "Outbox relay poll interval")
+	flag.Int("outbox.batch_size", 100, "Max events published per relay run")
+	flag.Duration("outbox.cleanup_interval", time.Hour, "Published outbox events cleanup interval")
+	flag.Duration("outbox.retention", 24*time.Hour, "How long published outbox events are kept")
+	flag.String("outbox.file_path", "", "File for the file publisher")
+	flag.String("outbox.nats.url", "nats://localhost:4222", "NATS server URL")
+	flag.String("outbox.nats.subject_prefix", "rates", "NATS subject prefix")
+	flag.StringSlice("outbox.kafka.brokers", []string{"localhost:9092"}, "Kafka broker addresses")
+	flag.String("outbox.kafka.topic", "rates", "Kafka topic")
+
+	flag.Bool("guard.enabled", true, "Reject insane upstream quotes")
+	flag.Duration("guard.max_staleness", 5*time.Minute, "Max age of the upstream quote timestamp, 0 disables")
+	flag.Float64("guard.jump_sigma", 6, "Reject mid price moves beyond this many standard deviations, 0 disables")
+	flag.Int("guard.jump_window", 60, "Number of recent moves used for jump statistics")
+	flag.Int("guard.jump_min_samples", 20, "Moves needed before jumps are checked")
+	flag.Float64("guard.jump_min_percent", 1, "Moves below this percentage are never treated as jumps")
+	flag.Int("guard.max_consecutive_jumps", 3, "Accept a new price level after this many rejected jumps in a row")
 
 	flag.String("logging.level", "info", "Logging level")
 	flag.String("logging.format", "json", "Logging format")
 
 	flag.Bool("tracing.enabled", false, "Enable tracing")
-	flag.String("tracing.jaeger-url", "http://localhost:14268/api/traces", "Jaeger URL")
-	flag.String("tracing.service-name", "usdt-rates-service", "Service name for tracing")
+	flag.String("tracing.exporter", "otlp-http", "Trace exporter: otlp-grpc, otlp-http, stdout or none")
+	flag.String("tracing.endpoint", "", "OTLP collector host:port or URL (default from OTEL_EXPORTER_OTLP_ENDPOINT or localhost)")
+	flag.StringToString("tracing.headers", nil, "Headers sent to the OTLP collector, KEY=VALUE,...")
+	flag.Bool("tracing.insecure", false, "Send traces to the OTLP collector without TLS")
+	flag.String("tracing.tls.ca_file", "", "CA certificate for verifying the OTLP collector")
+	flag.String("tracing.tls.cert_file", "", "Client certificate for the OTLP collector")
+	flag.String("tracing.tls.key_file", "", "Client key for the OTLP collector")
+	flag.String("tracing.tls.server_name", "", "Server name checked against the collector certificate")
+	flag.Bool("tracing.tls.insecure_skip_verify", false, "Do not verify the collector certificate")
+	flag.Float64("tracing.sample_ratio", 1, "Share of new traces sampled, 0 to 1; requests with a parent follow its decision")
+	flag.String("tracing.service_name", "usdt-rates-service", "Service name for tracing")
+	flag.StringToString("tracing.resource_attributes", nil, "Extra resource attributes, e.g. deployment.environment=prod,service.instance.id=a1")
 
 	flag.Bool("metrics.enabled", true, "Enable metrics")
 	flag.String("metrics.path", "/metrics", "Metrics endpoint path")
 	flag.Int("metrics.port", 9090, "Metrics server port")
 
+	flag.Bool("admin.enabled", false, "Serve admin endpoints under /admin/ on the metrics server")
+	flag.String("admin.token", "", "Bearer token required by the admin endpoints")
+
+	flag.Duration("secrets.refresh_interval", time.Minute, "How often secret files and references are checked for rotation, 0 disables")
+
 	flag.Parse()
 
-	// Configure viper
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./configs")
+	// Markets used when the exchange catalog is unavailable
+	viper.SetDefault("catalog.markets", []map[string]interface{}{
+		{"id": "usdtrub", "base": "usdt", "quote": "rub", "price_precision": 2, "amount_precision": 2, "status": "active"},
+	})
 
 	// Enable environment variables
 	viper.AutomaticEnv()
@@ -120,7 +378,22 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to bind flags: %w", err)
 	}
 
-	// Read config file if it exists
+	// Configure viper, an explicitly given config file must exist
+	if path := viper.GetString("config"); path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("./configs")
+	}
+
+	return read()
+}
+
+// read reads the config file if it exists, then decodes and validates the
+// merged configuration
+func read() (*Config, error) {
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -129,15 +402,87 @@ func Load() (*Config, error) {
 
 	// Unmarshal config
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := viper.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToMapHook,
+	))); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.resolveSecrets(context.Background(), secrets.Default); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
 // DatabaseDSN returns the database connection string
 func (c *DatabaseConfig) DatabaseDSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable connect_timeout=10",
-		c.Host, c.Port, c.User, c.Password, c.Database)
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=10",
+		dsnValue(c.Host), c.Port, dsnValue(c.User), dsnValue(c.Password), dsnValue(c.Database), dsnValue(c.SSLMode))
+}
+
+// ReplicaDSN returns the connection string of the replica at addr, given as
+// host or host:port; the port defaults to the primary's
+func (c *DatabaseConfig) ReplicaDSN(addr string) string {
+	replica := *c
+	replica.Host, replica.Port = splitHostPort(addr, c.Port)
+	return replica.DatabaseDSN()
+}
+
+// splitHostPort splits an address into host and port, using defaultPort
+// when the address has no port or an invalid one
+func splitHostPort(addr string, defaultPort int) (string, int) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, defaultPort
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, defaultPort
+	}
+	return host, port
+}
+
+// dsnValue quotes a connection string value when it is empty or contains
+// spaces, quotes or backslashes
+func dsnValue(s string) string {
+	if s != "" && !strings.ContainsAny(s, ` '\`) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// normalizeFlagName maps dashes in config key flags to the underscores of the
+// config keys; other flags, such as those of the subcommands, keep their names
+func normalizeFlagName(_ *flag.FlagSet, name string) flag.NormalizedName {
+	if strings.Contains(name, ".") {
+		name = strings.ReplaceAll(name, "-", "_")
+	}
+	return flag.NormalizedName(name)
+}
+
+// stringToMapHook decodes KEY=VALUE,... strings from environment variables
+// into string maps, as used by the tracing headers and resource attributes
+func stringToMapHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(map[string]string{}) {
+		return data, nil
+	}
+
+	m := make(map[string]string)
+	for _, pair := range strings.Split(data.(string), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid pair %q, expected KEY=VALUE", pair)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return m, nil
 }
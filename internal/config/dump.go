@@ -0,0 +1,97 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// Redacted replaces the value of a secret in a config dump
+const Redacted = "[REDACTED]"
+
+// Dump returns the config as nested maps keyed like the config file, with
+// secrets redacted. Fields tagged redact:"true" are replaced when set, and
+// every value of such a map is replaced; fields tagged redact:"url" keep the
+// URL but lose its password.
+func Dump(cfg *Config) map[string]interface{} {
+	return dumpStruct(reflect.ValueOf(cfg).Elem(), true)
+}
+
+func dumpStruct(v reflect.Value, redact bool) map[string]interface{} {
+	out := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || !field.IsExported() {
+			continue
+		}
+		if !redact {
+			out[key] = dumpValue(v.Field(i), false)
+			continue
+		}
+		out[key] = dumpField(v.Field(i), field.Tag.Get("redact"))
+	}
+	return out
+}
+
+func dumpField(v reflect.Value, redact string) interface{} {
+	switch redact {
+	case "true":
+		return redactValue(v)
+	case "url":
+		return redactURL(v.String())
+	}
+	return dumpValue(v, true)
+}
+
+func dumpValue(v reflect.Value, redact bool) interface{} {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return dumpStruct(v, redact)
+	case reflect.Slice:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = dumpValue(v.Index(i), redact)
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			out[k.String()] = dumpValue(v.MapIndex(k), redact)
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// redactValue hides a secret but shows whether it is set
+func redactValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Map {
+		out := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			out[k.String()] = Redacted
+		}
+		return out
+	}
+	if v.IsZero() {
+		return v.Interface()
+	}
+	return Redacted
+}
+
+// redactURL hides the password of a URL; unparsable URLs are hidden entirely
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Redacted
+	}
+	return u.Redacted()
+}
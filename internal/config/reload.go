@@ -0,0 +1,215 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// watchDebounce groups the bursts of events editors produce on save
+const watchDebounce = 200 * time.Millisecond
+
+// reloadableKeys can change while the service runs, other keys need a restart
+var reloadableKeys = []string{
+	"logging.level",
+	"grinex.timeout",
+	"grinex.batch_concurrency",
+	"catalog.markets",
+	"catalog.refresh_interval",
+	"pricing.rules",
+	"pricing.refresh_interval",
+	"alerting.refresh_interval",
+	"outbox.poll_interval",
+	"secrets.refresh_interval",
+	"database.replica_max_lag",
+	"database.replica_check_interval",
+	"database.statement_timeouts",
+	// Rotated credentials apply to new connections and requests
+	"database.user",
+	"database.password",
+	"admin.token",
+	"quotes.signing_key",
+	"alerting.webhook_secret",
+}
+
+// IsReloadable reports whether the key, or the key it belongs to, can be
+// applied without a restart
+func IsReloadable(key string) bool {
+	for _, k := range reloadableKeys {
+		if key == k || strings.HasPrefix(key, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepFixed returns next with the keys that are not reloadable reset to
+// their values in current, so the rest of a reload can be applied while
+// those changes wait for a restart. The secrets of next are kept, so a
+// rotated secret that needs a restart is not reported as rotated again.
+func KeepFixed(current, next *Config, changes []Change) *Config {
+	kept := *next
+	for _, c := range changes {
+		if !IsReloadable(c.Key) {
+			keepField(reflect.ValueOf(&kept).Elem(), reflect.ValueOf(current).Elem(), strings.Split(c.Key, "."))
+		}
+	}
+	return &kept
+}
+
+// keepField copies the field at path, or the map or list it belongs to,
+// from src to dst
+func keepField(dst, src reflect.Value, path []string) {
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Type().Field(i).Tag.Get("mapstructure") != path[0] {
+			continue
+		}
+		if dst.Field(i).Kind() == reflect.Struct && len(path) > 1 {
+			keepField(dst.Field(i), src.Field(i), path[1:])
+			return
+		}
+		dst.Field(i).Set(src.Field(i))
+		return
+	}
+}
+
+// Reload reads the config file loaded by Load again and validates the
+// result. Flags and environment variables keep overriding the file.
+// Reload must not be called concurrently.
+func Reload() (*Config, error) {
+	return read()
+}
+
+// Watch calls onChange when the config file read by Load changes, until ctx
+// is done. The directory of the file is watched, so files replaced by
+// editors or by Kubernetes config map updates are noticed as well.
+// It returns false when the config does not come from a file.
+func Watch(ctx context.Context, onChange func(), onError func(error)) (bool, error) {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return false, nil
+	}
+	file = filepath.Clean(file)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return false, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return false, fmt.Errorf("failed to watch %s: %w", filepath.Dir(file), err)
+	}
+
+	// A config map update swaps the symlink target instead of writing the file
+	target, _ := filepath.EvalSymlinks(file)
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				current, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+				if written || current != target {
+					target = current
+					debounce = time.After(watchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onError(err)
+			case <-debounce:
+				debounce = nil
+				onChange()
+			}
+		}
+	}()
+
+	return true, nil
+}
+
+// Change is a config key whose value differs between two configs, with
+// secrets redacted as in Dump
+type Change struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Key, formatValue(c.Old), formatValue(c.New))
+}
+
+// Diff lists the keys that differ between old and new, sorted by key.
+// Lists such as catalog.markets are compared as a whole.
+func Diff(old, new *Config) []Change {
+	oldRaw := flatten(dumpStruct(reflect.ValueOf(old).Elem(), false))
+	newRaw := flatten(dumpStruct(reflect.ValueOf(new).Elem(), false))
+	oldShown := flatten(Dump(old))
+	newShown := flatten(Dump(new))
+
+	keys := make(map[string]bool, len(oldRaw))
+	for k := range oldRaw {
+		keys[k] = true
+	}
+	for k := range newRaw {
+		keys[k] = true
+	}
+
+	var changes []Change
+	for k := range keys {
+		if reflect.DeepEqual(oldRaw[k], newRaw[k]) {
+			continue
+		}
+		changes = append(changes, Change{Key: k, Old: oldShown[k], New: newShown[k]})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// flatten turns nested maps into dotted keys
+func flatten(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if nested, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+".", nested)
+				continue
+			}
+			out[prefix+k] = v
+		}
+	}
+	walk("", m)
+	return out
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<unset>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
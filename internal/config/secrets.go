@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/alik/TestForWork/internal/secrets"
+)
+
+// secret remembers where a secret came from, so rotation can be detected
+type secret struct {
+	ref   string
+	value string
+}
+
+// resolveSecrets replaces secret references with their values. Every string
+// field tagged redact may be a reference such as ${file:/run/secrets/db},
+// or be given as a file with the _FILE variant of its environment variable,
+// e.g. USDT_DATABASE_PASSWORD_FILE.
+func (c *Config) resolveSecrets(ctx context.Context, resolver *secrets.Resolver) error {
+	c.secrets = make(map[string]secret)
+
+	var problems []string
+	walkSecrets(reflect.ValueOf(c).Elem(), "", func(key string, field reflect.Value) {
+		ref := field.String()
+
+		env := "USDT_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if path, ok := os.LookupEnv(env + "_FILE"); ok && path != "" {
+			if _, ok := os.LookupEnv(env); ok {
+				problems = append(problems, fmt.Sprintf("%s: set either %s or %s_FILE", key, env, env))
+				return
+			}
+			ref = secrets.Reference(secrets.FileProvider{}.Scheme(), path)
+		}
+
+		if _, _, ok := secrets.ParseReference(ref); !ok {
+			return
+		}
+		value, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			return
+		}
+		field.SetString(value)
+		c.secrets[key] = secret{ref: ref, value: value}
+	})
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// RotatedSecrets resolves the secret references again and returns the keys
+// whose values changed since the config was loaded
+func (c *Config) RotatedSecrets(ctx context.Context) ([]string, error) {
+	var rotated []string
+	for key, s := range c.secrets {
+		value, err := secrets.Default.Resolve(ctx, s.ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if value != s.value {
+			rotated = append(rotated, key)
+		}
+	}
+	return rotated, nil
+}
+
+// walkSecrets calls fn for every string field tagged redact
+func walkSecrets(v reflect.Value, prefix string, fn func(key string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || !field.IsExported() {
+			continue
+		}
+
+		switch {
+		case v.Field(i).Kind() == reflect.Struct:
+			walkSecrets(v.Field(i), prefix+key+".", fn)
+		case field.Tag.Get("redact") != "" && v.Field(i).Kind() == reflect.String:
+			fn(prefix+key, v.Field(i))
+		}
+	}
+}
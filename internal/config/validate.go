@@ -0,0 +1,352 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every problem found in the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// validator collects problems keyed by the config key they refer to
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.addf(key, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	if d <= 0 {
+		v.addf(key, "must be positive, got %s", d)
+	}
+}
+
+func (v *validator) nonNegative(key string, d time.Duration) {
+	if d < 0 {
+		v.addf(key, "must not be negative, got %s", d)
+	}
+}
+
+func (v *validator) atLeast(key string, n, min int) {
+	if n < min {
+		v.addf(key, "must be at least %d, got %d", min, n)
+	}
+}
+
+func (v *validator) required(key, s string) {
+	if strings.TrimSpace(s) == "" {
+		v.addf(key, "is required")
+	}
+}
+
+func (v *validator) oneOf(key, s string, allowed ...string) {
+	for _, a := range allowed {
+		if s == a {
+			return
+		}
+	}
+	v.addf(key, "must be one of %s, got %q", strings.Join(allowed, ", "), s)
+}
+
+func (v *validator) url(key, raw string, schemes ...string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		v.addf(key, "must be an absolute URL, got %q", raw)
+		return
+	}
+	v.oneOf(key+" scheme", u.Scheme, schemes...)
+}
+
+func (v *validator) decimal(key, s string) *big.Rat {
+	if s == "" {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		v.addf(key, "must be a decimal number, got %q", s)
+	}
+	return r
+}
+
+// Validate checks ranges, enums, URLs and rules between keys, and reports
+// every problem at once as a *ValidationError
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.Server.validate(v)
+	c.Database.validate(v)
+	c.Grinex.validate(v)
+	c.Catalog.validate(v)
+	c.Pricing.validate(v)
+	c.Quotes.validate(v)
+	c.Alerting.validate(v)
+	c.Outbox.validate(v)
+	c.Guard.validate(v)
+	c.Logging.validate(v)
+	c.Tracing.validate(v)
+	c.Metrics.validate(v)
+
+	v.nonNegative("secrets.refresh_interval", c.Secrets.RefreshInterval)
+
+	if c.CrossRates.Enabled {
+		v.atLeast("cross_rates.max_hops", c.CrossRates.MaxHops, 1)
+	}
+
+	// Upstream calls made while serving a request must fit into its deadline
+	if c.Grinex.Timeout > c.Server.WriteTimeout && c.Server.WriteTimeout > 0 {
+		v.addf("grinex.timeout", "must not exceed server.write_timeout (%s), got %s", c.Server.WriteTimeout, c.Grinex.Timeout)
+	}
+	if c.Metrics.Enabled && c.Metrics.Port == c.Server.Port {
+		v.addf("metrics.port", "must differ from server.port (%d)", c.Server.Port)
+	}
+	if c.Admin.Enabled {
+		v.required("admin.token", c.Admin.Token)
+		if !c.Metrics.Enabled {
+			v.addf("admin.enabled", "requires metrics.enabled, the admin endpoints are served by the metrics server")
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (c *ServerConfig) validate(v *validator) {
+	v.port("server.port", c.Port)
+	v.positive("server.graceful_timeout", c.GracefulTimeout)
+	v.positive("server.read_timeout", c.ReadTimeout)
+	v.positive("server.write_timeout", c.WriteTimeout)
+	v.nonNegative("server.max_connection_idle", c.MaxConnectionIdle)
+}
+
+func (c *DatabaseConfig) validate(v *validator) {
+	v.required("database.host", c.Host)
+	v.port("database.port", c.Port)
+	v.required("database.user", c.User)
+	v.required("database.database", c.Database)
+	v.oneOf("database.ssl_mode", c.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.atLeast("database.max_open_conns", c.MaxOpenConns, 1)
+	v.atLeast("database.min_conns", c.MinConns, 0)
+	if c.MaxOpenConns > 0 && c.MinConns > c.MaxOpenConns {
+		v.addf("database.min_conns", "must not exceed database.max_open_conns (%d), got %d", c.MaxOpenConns, c.MinConns)
+	}
+	v.nonNegative("database.conn_max_lifetime", c.ConnMaxLifetime)
+	v.nonNegative("database.conn_max_idle_time", c.ConnMaxIdleTime)
+	v.nonNegative("database.statement_timeouts.read", c.StatementTimeouts.Read)
+	v.nonNegative("database.statement_timeouts.write", c.StatementTimeouts.Write)
+	v.nonNegative("database.statement_timeouts.bulk", c.StatementTimeouts.Bulk)
+	v.nonNegative("database.statement_timeouts.maintenance", c.StatementTimeouts.Maintenance)
+
+	if len(c.Replicas) == 0 {
+		return
+	}
+	v.positive("database.replica_max_lag", c.ReplicaMaxLag)
+	v.positive("database.replica_check_interval", c.ReplicaCheckInterval)
+	seen := make(map[string]bool, len(c.Replicas))
+	for i, addr := range c.Replicas {
+		key := fmt.Sprintf("database.replicas[%d]", i)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, ""
+		}
+		v.required(key, host)
+		if port != "" {
+			n, err := strconv.Atoi(port)
+			if err != nil {
+				n = -1
+			}
+			v.port(key+" port", n)
+		}
+		if seen[addr] {
+			v.addf(key, "duplicate replica %q", addr)
+		}
+		seen[addr] = true
+	}
+}
+
+func (c *GrinexConfig) validate(v *validator) {
+	v.url("grinex.base_url", c.BaseURL, "http", "https")
+	v.positive("grinex.timeout", c.Timeout)
+	v.required("grinex.market", c.Market)
+	v.atLeast("grinex.batch_concurrency", c.BatchConcurrency, 1)
+	v.oneOf("grinex.transport", c.Transport, "http", "websocket", "replay")
+
+	switch c.Transport {
+	case "websocket":
+		v.url("grinex.ws_url", c.WSURL, "ws", "wss")
+		v.positive("grinex.snapshot_timeout", c.SnapshotTimeout)
+	case "replay":
+		v.required("grinex.replay_path", c.ReplayPath)
+		if c.ReplaySpeed < 0 {
+			v.addf("grinex.replay_speed", "must not be negative, got %g", c.ReplaySpeed)
+		}
+	}
+}
+
+func (c *CatalogConfig) validate(v *validator) {
+	v.oneOf("catalog.source", c.Source, "exchange", "config")
+	if c.Source == "exchange" {
+		v.positive("catalog.refresh_interval", c.RefreshInterval)
+	}
+	if c.Source == "config" && len(c.Markets) == 0 {
+		v.addf("catalog.markets", "must not be empty when catalog.source is config")
+	}
+
+	seen := make(map[string]bool, len(c.Markets))
+	for i, m := range c.Markets {
+		key := fmt.Sprintf("catalog.markets[%d]", i)
+		v.required(key+".id", m.ID)
+		v.required(key+".base", m.Base)
+		v.required(key+".quote", m.Quote)
+		v.atLeast(key+".price_precision", m.PricePrecision, 0)
+		v.atLeast(key+".amount_precision", m.AmountPrecision, 0)
+		if seen[m.ID] && m.ID != "" {
+			v.addf(key+".id", "duplicate market %q", m.ID)
+		}
+		seen[m.ID] = true
+	}
+}
+
+func (c *PricingConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.oneOf("pricing.source", c.Source, "config", "database")
+	if c.Source == "database" {
+		v.positive("pricing.refresh_interval", c.RefreshInterval)
+		return
+	}
+
+	for i, r := range c.Rules {
+		key := fmt.Sprintf("pricing.rules[%d]", i)
+		v.required(key+".id", r.ID)
+		v.decimal(key+".spread_percent", r.SpreadPercent)
+		v.decimal(key+".fixed_fee", r.FixedFee)
+		minPrice := v.decimal(key+".min_price", r.MinPrice)
+		maxPrice := v.decimal(key+".max_price", r.MaxPrice)
+		if minPrice != nil && maxPrice != nil && minPrice.Cmp(maxPrice) > 0 {
+			v.addf(key+".min_price", "must not exceed max_price")
+		}
+	}
+}
+
+func (c *QuotesConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.positive("quotes.ttl", c.TTL)
+	v.required("quotes.signing_key", c.SigningKey)
+	v.positive("quotes.cleanup_interval", c.CleanupInterval)
+	v.nonNegative("quotes.retention", c.Retention)
+}
+
+func (c *AlertingConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.positive("alerting.webhook_timeout", c.WebhookTimeout)
+	v.atLeast("alerting.max_attempts", c.MaxAttempts, 1)
+	v.nonNegative("alerting.retry_backoff", c.RetryBackoff)
+	v.atLeast("alerting.workers", c.Workers, 1)
+	v.positive("alerting.refresh_interval", c.RefreshInterval)
+}
+
+func (c *OutboxConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.oneOf("outbox.publisher", c.Publisher, "stdout", "file", "nats", "kafka")
+	v.positive("outbox.poll_interval", c.PollInterval)
+	v.atLeast("outbox.batch_size", c.BatchSize, 1)
+	v.positive("outbox.cleanup_interval", c.CleanupInterval)
+	v.nonNegative("outbox.retention", c.Retention)
+
+	switch c.Publisher {
+	case "file":
+		v.required("outbox.file_path", c.FilePath)
+	case "nats":
+		v.url("outbox.nats.url", c.NATS.URL, "nats", "tls", "ws", "wss")
+		v.required("outbox.nats.subject_prefix", c.NATS.SubjectPrefix)
+	case "kafka":
+		if len(c.Kafka.Brokers) == 0 {
+			v.addf("outbox.kafka.brokers", "must not be empty")
+		}
+		v.required("outbox.kafka.topic", c.Kafka.Topic)
+	}
+}
+
+func (c *GuardConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.nonNegative("guard.max_staleness", c.MaxStaleness)
+	if c.JumpSigma < 0 {
+		v.addf("guard.jump_sigma", "must not be negative, got %g", c.JumpSigma)
+	}
+	if c.JumpMinPercent < 0 {
+		v.addf("guard.jump_min_percent", "must not be negative, got %g", c.JumpMinPercent)
+	}
+	v.atLeast("guard.max_consecutive_jumps", c.MaxConsecutiveJumps, 0)
+	if c.JumpSigma > 0 {
+		v.atLeast("guard.jump_window", c.JumpWindow, 2)
+		v.atLeast("guard.jump_min_samples", c.JumpMinSamples, 0)
+		if c.JumpMinSamples > c.JumpWindow {
+			v.addf("guard.jump_min_samples", "must not exceed guard.jump_window (%d), got %d", c.JumpWindow, c.JumpMinSamples)
+		}
+	}
+}
+
+func (c *LoggingConfig) validate(v *validator) {
+	v.oneOf("logging.level", c.Level, "debug", "info", "warn", "error")
+	v.oneOf("logging.format", c.Format, "json", "console")
+}
+
+func (c *TracingConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.oneOf("tracing.exporter", c.Exporter, "otlp-grpc", "otlp-http", "stdout", "none")
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio", "must be between 0 and 1, got %g", c.SampleRatio)
+	}
+	v.required("tracing.service_name", c.ServiceName)
+	if strings.Contains(c.Endpoint, "://") {
+		v.url("tracing.endpoint", c.Endpoint, "http", "https")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		v.addf("tracing.tls", "cert_file and key_file must be set together")
+	}
+}
+
+func (c *MetricsConfig) validate(v *validator) {
+	if !c.Enabled {
+		return
+	}
+	v.port("metrics.port", c.Port)
+	if !strings.HasPrefix(c.Path, "/") {
+		v.addf("metrics.path", "must start with /, got %q", c.Path)
+	}
+	if strings.HasPrefix(c.Path, "/admin/") || c.Path == "/admin" {
+		v.addf("metrics.path", "must not be under /admin, which is reserved for the admin endpoints")
+	}
+}
@@ -0,0 +1,132 @@
+// Package export writes the stored rates history as CSV, JSON Lines or Parquet.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidRequest is returned for export requests that cannot be served
+var ErrInvalidRequest = errors.New("invalid export request")
+
+// Store streams stored rates
+type Store interface {
+	StreamRates(ctx context.Context, market string, from, to time.Time, fn func(postgres.ExportedRate) error) error
+}
+
+// Request describes an export
+type Request struct {
+	// Market is the exported market
+	Market string
+	// From is the inclusive start of the range; zero exports from the first rate
+	From time.Time
+	// To is the exclusive end of the range; zero exports up to now
+	To time.Time
+	// Format is one of FormatCSV, FormatJSONL and FormatParquet
+	Format string
+	// Resample emits the last known rate at every interval when positive
+	Resample time.Duration
+}
+
+// Exporter streams rates from the store into a file format
+type Exporter struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// New creates an exporter
+func New(store Store, logger *zap.Logger) *Exporter {
+	return &Exporter{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Export writes the rates selected by the request to w and returns the
+// number of rows written
+func (e *Exporter) Export(ctx context.Context, w io.Writer, req Request) (int64, error) {
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if err := validateRequest(req); err != nil {
+		return 0, err
+	}
+
+	encoder, err := NewWriter(w, req.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	counter := &countingWriter{w: encoder}
+	var out Writer = counter
+	if req.Resample > 0 {
+		out = newResampler(counter, req.Resample, req.From, req.To)
+	}
+
+	e.logger.Info("Exporting rates",
+		zap.String("market", req.Market),
+		zap.Time("from", req.From),
+		zap.Time("to", req.To),
+		zap.String("format", req.Format),
+		zap.Duration("resample", req.Resample))
+	started := time.Now()
+
+	if err := e.store.StreamRates(ctx, req.Market, req.From, req.To, out.Write); err != nil {
+		return counter.n, fmt.Errorf("failed to export rates: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return counter.n, fmt.Errorf("failed to export rates: %w", err)
+	}
+
+	e.logger.Info("Export completed",
+		zap.String("market", req.Market),
+		zap.Int64("rows", counter.n),
+		zap.Duration("elapsed", time.Since(started).Truncate(time.Millisecond)))
+
+	return counter.n, nil
+}
+
+// validateRequest checks the request fields
+func validateRequest(req Request) error {
+	switch {
+	case req.Market == "":
+		return fmt.Errorf("%w: market is required", ErrInvalidRequest)
+	case !req.From.IsZero() && !req.To.After(req.From):
+		return fmt.Errorf("%w: the end of the range must be after its start", ErrInvalidRequest)
+	case req.Resample < 0:
+		return fmt.Errorf("%w: the resample interval must not be negative", ErrInvalidRequest)
+	case req.Resample > 0 && req.Resample < time.Second:
+		return fmt.Errorf("%w: the resample interval must be at least 1s", ErrInvalidRequest)
+	}
+
+	switch req.Format {
+	case FormatCSV, FormatJSONL, FormatParquet:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown format %q", ErrInvalidRequest, req.Format)
+	}
+}
+
+// countingWriter counts the rows written
+type countingWriter struct {
+	w Writer
+	n int64
+}
+
+func (c *countingWriter) Write(rate postgres.ExportedRate) error {
+	if err := c.w.Write(rate); err != nil {
+		return err
+	}
+	c.n++
+	return nil
+}
+
+func (c *countingWriter) Close() error {
+	return c.w.Close()
+}
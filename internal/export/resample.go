@@ -0,0 +1,71 @@
+package export
+
+import (
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+// resampler turns a time-ordered stream of rates into one rate per interval.
+// The rate at sample time T is the last rate with a timestamp at or before T,
+// so gaps are filled with the previous rate. Samples run from the start of
+// the range, or the first rate truncated to the interval if the range has no
+// start, up to but excluding the end of the range. Sample times before the
+// first rate are skipped.
+type resampler struct {
+	w        Writer
+	interval time.Duration
+	next     time.Time
+	to       time.Time
+
+	last    postgres.ExportedRate
+	hasLast bool
+}
+
+// newResampler wraps w. The rates written must be ordered by timestamp.
+func newResampler(w Writer, interval time.Duration, from, to time.Time) *resampler {
+	return &resampler{w: w, interval: interval, next: from, to: to}
+}
+
+func (r *resampler) Write(rate postgres.ExportedRate) error {
+	if r.next.IsZero() {
+		r.next = rate.Timestamp.Truncate(r.interval)
+	}
+	if err := r.emitBefore(rate.Timestamp); err != nil {
+		return err
+	}
+	r.last = rate
+	r.hasLast = true
+	return nil
+}
+
+// Close fills the samples up to the end of the range and closes the writer
+func (r *resampler) Close() error {
+	if r.hasLast {
+		if err := r.emitBefore(r.to); err != nil {
+			return err
+		}
+	}
+	return r.w.Close()
+}
+
+// emitBefore writes the samples taken before t
+func (r *resampler) emitBefore(t time.Time) error {
+	if !r.hasLast {
+		// Nothing to emit yet, skip to the last sample time before t
+		if gap := t.Sub(r.next); gap > r.interval {
+			r.next = r.next.Add((gap - 1) / r.interval * r.interval)
+		}
+	}
+	for r.next.Before(t) && r.next.Before(r.to) {
+		if r.hasLast {
+			sample := r.last
+			sample.Timestamp = r.next
+			if err := r.w.Write(sample); err != nil {
+				return err
+			}
+		}
+		r.next = r.next.Add(r.interval)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Supported export formats
+const (
+	FormatCSV     = "csv"
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+// parquetRowGroupSize is the number of rows buffered before a Parquet row
+// group is written out
+const parquetRowGroupSize = 50000
+
+// Writer encodes exported rates. Close flushes buffered rows and writes the
+// file trailer; it does not close the underlying writer.
+type Writer interface {
+	Write(rate postgres.ExportedRate) error
+	Close() error
+}
+
+// DetectFormat returns the format of a file from its extension
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".parquet":
+		return FormatParquet, nil
+	default:
+		return "", fmt.Errorf("cannot detect the format of %s, use .csv, .jsonl or .parquet or set the format", path)
+	}
+}
+
+// NewWriter returns a writer for the format
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[parquetRate](w,
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+			parquet.Compression(&parquet.Zstd),
+		)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// formatTime formats a timestamp in UTC
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// csvHeader names the CSV columns; the file can be loaded back with the import command
+var csvHeader = []string{"market", "ask", "bid", "timestamp", "received_at", "source"}
+
+// csvWriter writes CSV with a header row
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(rate postgres.ExportedRate) error {
+	return c.w.Write([]string{
+		rate.Market,
+		rate.Ask,
+		rate.Bid,
+		formatTime(rate.Timestamp),
+		formatTime(rate.ReceivedAt),
+		rate.Source,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlRate is a line of a JSONL export. Prices are strings to keep their
+// exact decimal value.
+type jsonlRate struct {
+	Market     string `json:"market"`
+	Ask        string `json:"ask"`
+	Bid        string `json:"bid"`
+	Timestamp  string `json:"timestamp"`
+	ReceivedAt string `json:"received_at"`
+	Source     string `json:"source"`
+}
+
+// jsonlWriter writes one JSON object per line
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(rate postgres.ExportedRate) error {
+	return j.enc.Encode(jsonlRate{
+		Market:     rate.Market,
+		Ask:        rate.Ask,
+		Bid:        rate.Bid,
+		Timestamp:  formatTime(rate.Timestamp),
+		ReceivedAt: formatTime(rate.ReceivedAt),
+		Source:     rate.Source,
+	})
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
+
+// parquetRate is a row of a Parquet export. Prices are strings to keep their
+// exact decimal value.
+type parquetRate struct {
+	Market     string    `parquet:"market,dict"`
+	Ask        string    `parquet:"ask"`
+	Bid        string    `parquet:"bid"`
+	Timestamp  time.Time `parquet:"timestamp,timestamp(microsecond)"`
+	ReceivedAt time.Time `parquet:"received_at,timestamp(microsecond)"`
+	Source     string    `parquet:"source,dict"`
+}
+
+// parquetWriter writes a Parquet file in row groups of parquetRowGroupSize rows
+type parquetWriter struct {
+	w   *parquet.GenericWriter[parquetRate]
+	row [1]parquetRate
+}
+
+func (p *parquetWriter) Write(rate postgres.ExportedRate) error {
+	p.row[0] = parquetRate{
+		Market:     rate.Market,
+		Ask:        rate.Ask,
+		Bid:        rate.Bid,
+		Timestamp:  rate.Timestamp.UTC(),
+		ReceivedAt: rate.ReceivedAt.UTC(),
+		Source:     rate.Source,
+	}
+	if _, err := p.w.Write(p.row[:]); err != nil {
+		return fmt.Errorf("failed to write Parquet row: %w", err)
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.w.Close(); err != nil {
+		return fmt.Errorf("failed to finish Parquet file: %w", err)
+	}
+	return nil
+}
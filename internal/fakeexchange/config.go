@@ -0,0 +1,156 @@
+package fakeexchange
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes the markets served by the fake exchange and how it misbehaves
+type Config struct {
+	// Seed makes random walks and fault injection reproducible; 0 uses the current time
+	Seed int64 `yaml:"seed"`
+	// Latency delays every response; Jitter adds a uniform random delay on top
+	Latency time.Duration `yaml:"latency"`
+	Jitter  time.Duration `yaml:"jitter"`
+	// Levels is the number of price levels on each side of the book
+	Levels int `yaml:"levels"`
+	// Faults are injected at random into responses that are not scripted
+	Faults  Faults   `yaml:"faults"`
+	Markets []Market `yaml:"markets"`
+}
+
+// Faults holds the probabilities, from 0 to 1, of each injected failure
+type Faults struct {
+	ErrorRate     float64 `yaml:"error_rate"`
+	RateLimitRate float64 `yaml:"rate_limit_rate"`
+	MalformedRate float64 `yaml:"malformed_rate"`
+	EmptyRate     float64 `yaml:"empty_rate"`
+}
+
+// Market is a market served by the fake exchange
+type Market struct {
+	ID        string `yaml:"id"`
+	BaseUnit  string `yaml:"base_unit"`
+	QuoteUnit string `yaml:"quote_unit"`
+	State     string `yaml:"state"`
+
+	// Price is the starting mid price of the random walk
+	Price float64 `yaml:"price"`
+	// Spread is the distance between ask and bid relative to the mid price
+	Spread float64 `yaml:"spread"`
+	// Volatility is the standard deviation of the relative mid price move per request
+	Volatility float64 `yaml:"volatility"`
+	// PricePrecision is the number of decimals in prices
+	PricePrecision int `yaml:"price_precision"`
+
+	// Scenario is served step by step, one step per depth request.
+	// When it is exhausted the market loops over it or falls back to the random walk.
+	Scenario []Step `yaml:"scenario"`
+	Loop     bool   `yaml:"loop"`
+}
+
+// Step is a scripted depth response
+type Step struct {
+	// Ask and Bid fix the best prices; when empty the random walk price is used
+	Ask string `yaml:"ask"`
+	Bid string `yaml:"bid"`
+	// Status returns an error response with this HTTP status code
+	Status int `yaml:"status"`
+	// Malformed returns a body that is not valid JSON
+	Malformed bool `yaml:"malformed"`
+	// Empty returns a book without asks and bids
+	Empty bool `yaml:"empty"`
+	// Latency delays this response in addition to the configured latency
+	Latency time.Duration `yaml:"latency"`
+	// TimestampOffset shifts the exchange timestamp, e.g. -10m for a stale quote
+	TimestampOffset time.Duration `yaml:"timestamp_offset"`
+	// NoTimestamp omits the exchange timestamp
+	NoTimestamp bool `yaml:"no_timestamp"`
+}
+
+// DefaultConfig returns a single usdtrub market with a gentle random walk
+func DefaultConfig() Config {
+	return Config{
+		Levels: 5,
+		Markets: []Market{{
+			ID:             "usdtrub",
+			BaseUnit:       "usdt",
+			QuoteUnit:      "rub",
+			Price:          95.5,
+			Spread:         0.002,
+			Volatility:     0.0005,
+			PricePrecision: 2,
+		}},
+	}
+}
+
+// LoadConfig reads a YAML config file. Unset fields keep their defaults.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := Config{Levels: 5}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// validate checks the config for values the exchange cannot serve
+func (c Config) validate() error {
+	if len(c.Markets) == 0 {
+		return fmt.Errorf("no markets configured")
+	}
+
+	for _, rate := range []float64{c.Faults.ErrorRate, c.Faults.RateLimitRate, c.Faults.MalformedRate, c.Faults.EmptyRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("fault rate %g is outside [0, 1]", rate)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range c.Markets {
+		if m.ID == "" {
+			return fmt.Errorf("market without id")
+		}
+		if seen[m.ID] {
+			return fmt.Errorf("market %s is configured twice", m.ID)
+		}
+		seen[m.ID] = true
+
+		if m.Price <= 0 && !(m.Loop && scriptedPrices(m.Scenario)) {
+			return fmt.Errorf("market %s: price must be positive", m.ID)
+		}
+		if m.Spread < 0 || m.Volatility < 0 || m.PricePrecision < 0 {
+			return fmt.Errorf("market %s: spread, volatility and price_precision must not be negative", m.ID)
+		}
+	}
+
+	return nil
+}
+
+// scriptedPrices reports whether every step either fixes its prices or returns no book,
+// so a looping scenario never needs a random walk price
+func scriptedPrices(steps []Step) bool {
+	if len(steps) == 0 {
+		return false
+	}
+	for _, step := range steps {
+		if step.Status != 0 || step.Malformed || step.Empty {
+			continue
+		}
+		if step.Ask == "" || step.Bid == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,317 @@
+// Package fakeexchange serves a scriptable imitation of the Grinex REST API
+// for local development and tests.
+package fakeexchange
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"go.uber.org/zap"
+)
+
+// malformedBody is a truncated depth response
+const malformedBody = `{"timestamp":1700000000,"asks":[{"price":"95.5","volume":`
+
+// marketState holds the random walk and scenario position of a market
+type marketState struct {
+	cfg      Market
+	mid      float64
+	step     int
+	requests int
+}
+
+// response is what a depth request will be answered with
+type response struct {
+	delay     time.Duration
+	status    int
+	malformed bool
+	empty     bool
+	ask       string
+	bid       string
+	// timestampOffset shifts the exchange timestamp taken when the response is written
+	timestampOffset time.Duration
+	noTimestamp     bool
+}
+
+// Exchange is an http.Handler serving /api/v2/depth and /api/v2/markets
+type Exchange struct {
+	cfg    Config
+	logger *zap.Logger
+	mux    *http.ServeMux
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	markets map[string]*marketState
+}
+
+// New creates a fake exchange from the config
+func New(cfg Config, logger *zap.Logger) (*Exchange, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Levels <= 0 {
+		cfg.Levels = 1
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	e := &Exchange{
+		cfg:     cfg,
+		logger:  logger,
+		mux:     http.NewServeMux(),
+		rng:     rand.New(rand.NewSource(seed)),
+		markets: make(map[string]*marketState, len(cfg.Markets)),
+	}
+	for _, m := range cfg.Markets {
+		e.markets[m.ID] = &marketState{cfg: m, mid: m.Price}
+	}
+
+	e.mux.HandleFunc("/api/v2/depth", e.handleDepth)
+	e.mux.HandleFunc("/api/v2/markets", e.handleMarkets)
+	e.mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return e, nil
+}
+
+// ServeHTTP implements http.Handler
+func (e *Exchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+// Requests returns the number of depth requests received for the market
+func (e *Exchange) Requests(market string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if state, ok := e.markets[market]; ok {
+		return state.requests
+	}
+	return 0
+}
+
+// handleDepth serves the order book of a market
+func (e *Exchange) handleDepth(w http.ResponseWriter, r *http.Request) {
+	market := r.URL.Query().Get("market")
+
+	e.mu.Lock()
+	state, ok := e.markets[market]
+	var resp response
+	if ok {
+		resp = e.next(state)
+	}
+	e.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	if !sleep(r.Context(), resp.delay) {
+		return
+	}
+
+	switch {
+	case resp.status != 0:
+		e.logger.Debug("Injecting error response", zap.String("market", market), zap.Int("status", resp.status))
+		if resp.status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeError(w, resp.status, http.StatusText(resp.status))
+	case resp.malformed:
+		e.logger.Debug("Injecting malformed response", zap.String("market", market))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(malformedBody))
+	default:
+		depth := client.DepthResponse{
+			Asks: []client.OrderBook{},
+			Bids: []client.OrderBook{},
+		}
+		if !resp.noTimestamp {
+			depth.Timestamp = time.Now().Add(resp.timestampOffset).Unix()
+		}
+		if !resp.empty {
+			depth.Asks = e.levels(resp.ask, state.cfg.PricePrecision, 1)
+			depth.Bids = e.levels(resp.bid, state.cfg.PricePrecision, -1)
+		}
+		writeJSON(w, depth)
+	}
+}
+
+// handleMarkets lists the configured markets
+func (e *Exchange) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	if !sleep(r.Context(), e.cfg.Latency) {
+		return
+	}
+
+	markets := make([]client.MarketInfo, 0, len(e.cfg.Markets))
+	for _, m := range e.cfg.Markets {
+		state := m.State
+		if state == "" {
+			state = "enabled"
+		}
+		markets = append(markets, client.MarketInfo{
+			ID:              m.ID,
+			Name:            strings.ToUpper(m.BaseUnit) + "/" + strings.ToUpper(m.QuoteUnit),
+			BaseUnit:        m.BaseUnit,
+			QuoteUnit:       m.QuoteUnit,
+			PricePrecision:  m.PricePrecision,
+			AmountPrecision: 4,
+			State:           state,
+		})
+	}
+
+	writeJSON(w, markets)
+}
+
+// next decides the response to the market's next depth request; e.mu must be held
+func (e *Exchange) next(state *marketState) response {
+	state.requests++
+
+	var step Step
+	if state.step < len(state.cfg.Scenario) {
+		step = state.cfg.Scenario[state.step]
+		state.step++
+		if state.cfg.Loop && state.step == len(state.cfg.Scenario) {
+			state.step = 0
+		}
+	} else {
+		step = e.randomFault()
+	}
+
+	resp := response{
+		delay:     e.cfg.Latency + step.Latency,
+		status:    step.Status,
+		malformed: step.Malformed,
+		empty:     step.Empty,
+		ask:       step.Ask,
+		bid:       step.Bid,
+
+		timestampOffset: step.TimestampOffset,
+		noTimestamp:     step.NoTimestamp,
+	}
+	if e.cfg.Jitter > 0 {
+		resp.delay += time.Duration(e.rng.Int63n(int64(e.cfg.Jitter)))
+	}
+
+	if resp.status == 0 && !resp.malformed && !resp.empty && (resp.ask == "" || resp.bid == "") {
+		ask, bid := e.walk(state)
+		if resp.ask == "" {
+			resp.ask = ask
+		}
+		if resp.bid == "" {
+			resp.bid = bid
+		}
+	}
+
+	return resp
+}
+
+// randomFault rolls the configured fault probabilities; e.mu must be held
+func (e *Exchange) randomFault() Step {
+	faults := e.cfg.Faults
+	roll := e.rng.Float64()
+
+	switch {
+	case roll < faults.RateLimitRate:
+		return Step{Status: http.StatusTooManyRequests}
+	case roll < faults.RateLimitRate+faults.ErrorRate:
+		return Step{Status: http.StatusInternalServerError}
+	case roll < faults.RateLimitRate+faults.ErrorRate+faults.MalformedRate:
+		return Step{Malformed: true}
+	case roll < faults.RateLimitRate+faults.ErrorRate+faults.MalformedRate+faults.EmptyRate:
+		return Step{Empty: true}
+	default:
+		return Step{}
+	}
+}
+
+// walk moves the mid price and returns the best ask and bid around it; e.mu must be held
+func (e *Exchange) walk(state *marketState) (string, string) {
+	m := state.cfg
+	state.mid *= math.Exp(m.Volatility * e.rng.NormFloat64())
+
+	ask := state.mid * (1 + m.Spread/2)
+	bid := state.mid * (1 - m.Spread/2)
+
+	// Keep at least one tick between the sides after rounding
+	tick := math.Pow10(-m.PricePrecision)
+	if ask-bid < tick {
+		ask = bid + tick
+	}
+
+	return strconv.FormatFloat(ask, 'f', m.PricePrecision, 64),
+		strconv.FormatFloat(bid, 'f', m.PricePrecision, 64)
+}
+
+// levels builds a side of the book starting at the best price and moving away
+// from it one tick per level; direction is 1 for asks and -1 for bids.
+// A best price that is not a number is served as a single level.
+func (e *Exchange) levels(best string, precision, direction int) []client.OrderBook {
+	price, err := strconv.ParseFloat(best, 64)
+	if err != nil {
+		return []client.OrderBook{{Price: best, Volume: "1", Amount: "1", Type: "limit"}}
+	}
+
+	tick := math.Pow10(-precision)
+	book := make([]client.OrderBook, 0, e.cfg.Levels)
+	for i := 0; i < e.cfg.Levels; i++ {
+		levelPrice := best
+		if i > 0 {
+			levelPrice = strconv.FormatFloat(price+float64(direction*i)*tick, 'f', precision, 64)
+		}
+		volume := float64(i+1) * 100
+		book = append(book, client.OrderBook{
+			Price:  levelPrice,
+			Volume: strconv.FormatFloat(volume, 'f', 4, 64),
+			Amount: strconv.FormatFloat(volume*price, 'f', 4, 64),
+			Type:   "limit",
+		})
+	}
+
+	return book
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format of the exchange API
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": status, "message": message},
+	})
+}
+
+// sleep waits for the duration and reports false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
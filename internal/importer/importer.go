@@ -0,0 +1,381 @@
+// Package importer loads historical rates from CSV and JSON Lines files.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize        = 5000
+	defaultMaxErrors        = 100
+	defaultProgressInterval = 5 * time.Second
+)
+
+// checkpointSuffix is appended to the input path to name its checkpoint file
+const checkpointSuffix = ".import-checkpoint"
+
+// Store loads rates into the database
+type Store interface {
+	ImportRates(ctx context.Context, rates []postgres.ImportedRate, dryRun bool) (int64, error)
+}
+
+// Stats counts the rows of an import
+type Stats struct {
+	// Read is the number of rows read, including invalid ones
+	Read int64 `json:"read"`
+	// Inserted is the number of new rates
+	Inserted int64 `json:"inserted"`
+	// Duplicates is the number of valid rows already present in the table or the batch
+	Duplicates int64 `json:"duplicates"`
+	// Invalid is the number of rows that failed to parse or validate
+	Invalid int64 `json:"invalid"`
+}
+
+// checkpoint records the progress of a file import. Rows up to Line are
+// committed; the file size and modification time detect a changed input.
+type checkpoint struct {
+	Line    int       `json:"line"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Stats   Stats     `json:"stats"`
+}
+
+// Option configures the importer
+type Option func(*Importer)
+
+// WithBatchSize sets the number of rows copied per transaction
+func WithBatchSize(n int) Option {
+	return func(i *Importer) {
+		if n > 0 {
+			i.batchSize = n
+		}
+	}
+}
+
+// WithDryRun validates and deduplicates rows without committing them. The
+// keys of all rows are kept in memory to find duplicates across batches.
+func WithDryRun() Option {
+	return func(i *Importer) {
+		i.dryRun = true
+	}
+}
+
+// WithDefaultSource sets the source of rows that do not name one
+func WithDefaultSource(source string) Option {
+	return func(i *Importer) {
+		i.defaultSource = source
+	}
+}
+
+// WithMaxErrors aborts the import after more than n invalid rows; a negative n never aborts
+func WithMaxErrors(n int) Option {
+	return func(i *Importer) {
+		i.maxErrors = n
+	}
+}
+
+// WithProgressInterval sets how often progress is logged
+func WithProgressInterval(d time.Duration) Option {
+	return func(i *Importer) {
+		if d > 0 {
+			i.progressInterval = d
+		}
+	}
+}
+
+// Importer streams rates from files into the store in batches
+type Importer struct {
+	store  Store
+	logger *zap.Logger
+
+	batchSize        int
+	dryRun           bool
+	defaultSource    string
+	maxErrors        int
+	progressInterval time.Duration
+}
+
+// New creates an importer
+func New(store Store, logger *zap.Logger, opts ...Option) *Importer {
+	i := &Importer{
+		store:            store,
+		logger:           logger,
+		batchSize:        defaultBatchSize,
+		maxErrors:        defaultMaxErrors,
+		progressInterval: defaultProgressInterval,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// ImportFile imports a file, or standard input for "-". An empty format is
+// detected from the file extension.
+//
+// After every committed batch the progress is saved next to the file, so an
+// interrupted import resumes after the last committed row. The checkpoint is
+// removed when the import completes. Dry runs and standard input are not
+// checkpointed.
+func (i *Importer) ImportFile(ctx context.Context, path, format string) (Stats, error) {
+	if format == "" {
+		if path == "-" {
+			return Stats{}, fmt.Errorf("the format of standard input must be set")
+		}
+		var err error
+		if format, err = DetectFormat(path); err != nil {
+			return Stats{}, err
+		}
+	}
+
+	if path == "-" {
+		return i.importReader(ctx, os.Stdin, format, "stdin", 0, nil)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if i.dryRun {
+		return i.importReader(ctx, file, format, path, info.Size(), nil)
+	}
+
+	checkpointPath := path + checkpointSuffix
+	cp, err := loadCheckpoint(checkpointPath)
+	if err != nil {
+		return Stats{}, err
+	}
+	if cp != nil {
+		if cp.Size != info.Size() || !cp.ModTime.Equal(info.ModTime()) {
+			return Stats{}, fmt.Errorf("%s changed since the interrupted import, remove %s to start over", path, checkpointPath)
+		}
+		i.logger.Info("Resuming import", zap.String("file", path), zap.Int("after_line", cp.Line))
+	} else {
+		cp = &checkpoint{Size: info.Size(), ModTime: info.ModTime()}
+	}
+
+	stats, err := i.importReader(ctx, file, format, path, info.Size(), cp)
+	if err != nil {
+		return stats, err
+	}
+
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return stats, fmt.Errorf("failed to remove checkpoint: %w", err)
+	}
+	return stats, nil
+}
+
+// importReader streams records from r into the store. With a checkpoint the
+// rows up to cp.Line are skipped and the checkpoint is saved after every batch.
+func (i *Importer) importReader(ctx context.Context, r io.Reader, format, name string, size int64, cp *checkpoint) (Stats, error) {
+	counter := &countingReader{r: r}
+	reader, err := NewReader(counter, format)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	var stats Stats
+	startLine := 0
+	if cp != nil {
+		stats = cp.Stats
+		startLine = cp.Line
+	}
+
+	progress := time.NewTicker(i.progressInterval)
+	defer progress.Stop()
+	started := time.Now()
+
+	batch := make([]postgres.ImportedRate, 0, i.batchSize)
+	lastLine := startLine
+
+	// Dry-run batches are rolled back, so rows repeated in a later batch are
+	// found here instead of by the store
+	var seen map[rateKey]struct{}
+	if i.dryRun {
+		seen = make(map[rateKey]struct{})
+	}
+
+	flush := func() error {
+		if len(batch) > 0 {
+			inserted, err := i.store.ImportRates(ctx, batch, i.dryRun)
+			if err != nil {
+				return err
+			}
+			stats.Inserted += inserted
+			stats.Duplicates += int64(len(batch)) - inserted
+			batch = batch[:0]
+		}
+
+		if cp != nil && lastLine > cp.Line {
+			cp.Line = lastLine
+			cp.Stats = stats
+			if err := saveCheckpoint(name+checkpointSuffix, cp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, fmt.Errorf("import interrupted: %w", err)
+		}
+
+		rec, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *RowError
+		switch {
+		case errors.As(err, &rowErr):
+			if rowErr.Line <= startLine {
+				continue
+			}
+			lastLine = rowErr.Line
+		case err != nil:
+			return stats, fmt.Errorf("failed to read %s: %w", name, err)
+		default:
+			if rec.Line <= startLine {
+				continue
+			}
+			lastLine = rec.Line
+
+			var rate postgres.ImportedRate
+			if rate, err = Validate(rec, i.defaultSource, time.Now()); err != nil {
+				rowErr = &RowError{Line: rec.Line, Err: err}
+			} else if i.seenBefore(seen, rate) {
+				stats.Duplicates++
+			} else {
+				batch = append(batch, rate)
+			}
+		}
+
+		stats.Read++
+		if rowErr != nil {
+			stats.Invalid++
+			i.logger.Warn("Skipping invalid row", zap.String("file", name), zap.Int("line", rowErr.Line), zap.Error(rowErr.Err))
+			if i.maxErrors >= 0 && stats.Invalid > int64(i.maxErrors) {
+				return stats, fmt.Errorf("too many invalid rows in %s: %d", name, stats.Invalid)
+			}
+		}
+
+		if len(batch) >= i.batchSize {
+			if err := flush(); err != nil {
+				return stats, fmt.Errorf("failed to import batch ending at line %d: %w", lastLine, err)
+			}
+		}
+
+		select {
+		case <-progress.C:
+			i.logProgress(name, stats, counter.n, size, started)
+		default:
+		}
+	}
+
+	if err := flush(); err != nil {
+		return stats, fmt.Errorf("failed to import batch ending at line %d: %w", lastLine, err)
+	}
+	i.logProgress(name, stats, counter.n, size, started)
+
+	return stats, nil
+}
+
+// rateKey identifies a rate the way the rates table deduplicates imports
+type rateKey struct {
+	market    string
+	timestamp int64 // microseconds, the precision of the column
+}
+
+// seenBefore reports whether a rate with the same key was read earlier,
+// remembering the rate otherwise; it is always false without seen
+func (i *Importer) seenBefore(seen map[rateKey]struct{}, rate postgres.ImportedRate) bool {
+	if seen == nil {
+		return false
+	}
+	key := rateKey{market: rate.Market, timestamp: rate.Timestamp.UnixMicro()}
+	if _, ok := seen[key]; ok {
+		return true
+	}
+	seen[key] = struct{}{}
+	return false
+}
+
+// logProgress reports the rows processed so far
+func (i *Importer) logProgress(name string, stats Stats, read, size int64, started time.Time) {
+	fields := []zap.Field{
+		zap.String("file", name),
+		zap.Bool("dry_run", i.dryRun),
+		zap.Int64("read", stats.Read),
+		zap.Int64("inserted", stats.Inserted),
+		zap.Int64("duplicates", stats.Duplicates),
+		zap.Int64("invalid", stats.Invalid),
+		zap.Duration("elapsed", time.Since(started).Truncate(time.Millisecond)),
+	}
+	if size > 0 {
+		fields = append(fields, zap.Float64("percent", float64(read)*100/float64(size)))
+	}
+	i.logger.Info("Import progress", fields...)
+}
+
+// loadCheckpoint reads a checkpoint, returning nil if there is none
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint atomically replaces the checkpoint file
+func saveCheckpoint(path string, cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read for progress reporting
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,200 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Supported import formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Record is a rate read from an import file. Fields hold the raw values
+// until the record is validated.
+type Record struct {
+	Line      int
+	Market    string
+	Ask       string
+	Bid       string
+	Timestamp string
+	Source    string
+}
+
+// RowError is a row that cannot be imported; reading continues after it
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads records from an import file. Read returns io.EOF at the end
+// of the input and a *RowError for rows that cannot be parsed.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// DetectFormat returns the format of a file from its extension
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("cannot detect the format of %s, use .csv or .jsonl or set the format", path)
+	}
+}
+
+// NewReader returns a reader for the format
+func NewReader(r io.Reader, format string) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r)
+	case FormatJSONL:
+		return NewJSONLReader(r), nil
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+// csvReader reads CSV with a header row naming the columns
+// market, ask, bid, timestamp and optionally source, in any order
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// NewCSVReader reads the header and returns a CSV record reader
+func NewCSVReader(r io.Reader) (Reader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"market", "ask", "bid", "timestamp"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %s column", required)
+		}
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (c *csvReader) Read() (Record, error) {
+	fields, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		}
+		return Record{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+	field := func(name string) string {
+		if i, ok := c.columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	return Record{
+		Line:      line,
+		Market:    field("market"),
+		Ask:       field("ask"),
+		Bid:       field("bid"),
+		Timestamp: field("timestamp"),
+		Source:    field("source"),
+	}, nil
+}
+
+// jsonlReader reads one JSON object per line. Prices and the timestamp may be
+// JSON strings or numbers; blank lines are skipped.
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// jsonlRecord is a line of a JSONL import file
+type jsonlRecord struct {
+	Market    string          `json:"market"`
+	Ask       json.RawMessage `json:"ask"`
+	Bid       json.RawMessage `json:"bid"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Source    string          `json:"source"`
+}
+
+// maxJSONLLine is the longest accepted JSONL line
+const maxJSONLLine = 1 << 20
+
+// NewJSONLReader returns a JSON Lines record reader
+func NewJSONLReader(r io.Reader) Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONLLine)
+	return &jsonlReader{scanner: scanner}
+}
+
+func (j *jsonlReader) Read() (Record, error) {
+	for j.scanner.Scan() {
+		j.line++
+		data := strings.TrimSpace(j.scanner.Text())
+		if data == "" {
+			continue
+		}
+
+		var rec jsonlRecord
+		if err := json.Unmarshal([]byte(data), &rec); err != nil {
+			return Record{}, &RowError{Line: j.line, Err: fmt.Errorf("invalid JSON: %w", err)}
+		}
+
+		return Record{
+			Line:      j.line,
+			Market:    strings.TrimSpace(rec.Market),
+			Ask:       rawValue(rec.Ask),
+			Bid:       rawValue(rec.Bid),
+			Timestamp: rawValue(rec.Timestamp),
+			Source:    strings.TrimSpace(rec.Source),
+		}, nil
+	}
+
+	if err := j.scanner.Err(); err != nil {
+		return Record{}, fmt.Errorf("failed to read line %d: %w", j.line+1, err)
+	}
+	return Record{}, io.EOF
+}
+
+// rawValue returns a JSON string's content or a number's literal text
+func rawValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return strings.TrimSpace(string(raw))
+}
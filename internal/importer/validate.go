@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+var (
+	// marketPattern matches market ids that fit the rates table
+	marketPattern = regexp.MustCompile(`^[a-z0-9]{2,20}$`)
+	// pricePattern matches positive decimals that fit DECIMAL(20, 8)
+	pricePattern = regexp.MustCompile(`^[0-9]{1,12}(\.[0-9]{1,8})?$`)
+)
+
+const (
+	// maxSourceLength is the size of the rates source column
+	maxSourceLength = 50
+	// maxClockSkew is how far in the future a timestamp may be
+	maxClockSkew = time.Minute
+	// millisecondTimestampThreshold separates second and millisecond Unix timestamps
+	millisecondTimestampThreshold = 1e12
+)
+
+// timestampLayouts are the accepted textual timestamp formats; layouts
+// without a zone are read as UTC
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// Validate checks a record and converts it to a rate. An empty source is
+// replaced with defaultSource.
+func Validate(rec Record, defaultSource string, now time.Time) (postgres.ImportedRate, error) {
+	market := strings.ToLower(rec.Market)
+	if !marketPattern.MatchString(market) {
+		return postgres.ImportedRate{}, fmt.Errorf("invalid market %q", rec.Market)
+	}
+
+	ask, err := parsePrice("ask", rec.Ask)
+	if err != nil {
+		return postgres.ImportedRate{}, err
+	}
+	bid, err := parsePrice("bid", rec.Bid)
+	if err != nil {
+		return postgres.ImportedRate{}, err
+	}
+	if bid > ask {
+		return postgres.ImportedRate{}, fmt.Errorf("bid %s is above ask %s", rec.Bid, rec.Ask)
+	}
+
+	timestamp, err := parseTimestamp(rec.Timestamp)
+	if err != nil {
+		return postgres.ImportedRate{}, err
+	}
+	if timestamp.After(now.Add(maxClockSkew)) {
+		return postgres.ImportedRate{}, fmt.Errorf("timestamp %s is in the future", timestamp.Format(time.RFC3339))
+	}
+
+	source := rec.Source
+	if source == "" {
+		source = defaultSource
+	}
+	if source == "" {
+		return postgres.ImportedRate{}, fmt.Errorf("missing source")
+	}
+	if len(source) > maxSourceLength {
+		return postgres.ImportedRate{}, fmt.Errorf("source is longer than %d characters", maxSourceLength)
+	}
+
+	return postgres.ImportedRate{
+		Market:    market,
+		Ask:       rec.Ask,
+		Bid:       rec.Bid,
+		Timestamp: timestamp,
+		Source:    source,
+	}, nil
+}
+
+// parsePrice checks that a price is a positive decimal
+func parsePrice(name, value string) (float64, error) {
+	if !pricePattern.MatchString(value) {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price <= 0 {
+		return 0, fmt.Errorf("%s %q must be positive", name, value)
+	}
+	return price, nil
+}
+
+// parseTimestamp accepts RFC 3339 and similar layouts, or a Unix timestamp in seconds or milliseconds
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("missing timestamp")
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		switch {
+		case unix <= 0:
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+		case unix < millisecondTimestampThreshold:
+			return time.Unix(unix, 0).UTC(), nil
+		default:
+			return time.UnixMilli(unix).UTC(), nil
+		}
+	}
+
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// NATSPublisher publishes messages to NATS subjects "<prefix>.<market>"
+type NATSPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher connects to NATS; the connection reconnects on its own
+func NewNATSPublisher(url, subjectPrefix string, logger *zap.Logger) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("usdt-rates-outbox"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("NATS disconnected", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info("NATS reconnected", zap.String("url", c.ConnectedUrl()))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	return &NATSPublisher{conn: conn, prefix: subjectPrefix}, nil
+}
+
+// Publish sends the message and waits for the server to acknowledge the flush.
+// The Nats-Msg-Id header lets JetStream streams drop redelivered duplicates.
+func (p *NATSPublisher) Publish(ctx context.Context, msg Message) error {
+	m := nats.NewMsg(p.prefix + "." + msg.Key)
+	m.Data = msg.Payload
+	m.Header.Set(nats.MsgIdHdr, strconv.FormatInt(msg.ID, 10))
+	m.Header.Set("Event-Type", msg.Type)
+
+	if err := p.conn.PublishMsg(m); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush NATS connection: %w", err)
+	}
+	return nil
+}
+
+// Close drains and closes the connection
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
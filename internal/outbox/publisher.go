@@ -0,0 +1,24 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Message is an event handed to a publisher
+type Message struct {
+	ID        int64           `json:"id"`
+	Key       string          `json:"key"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Publisher delivers messages to a broker.
+// Publish must return only after the broker has accepted the message;
+// messages with the same key must be delivered in the order they are published.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
@@ -0,0 +1,145 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alik/TestForWork/internal/schedule"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+// defaultBatchSize is the number of events published per relay run
+const defaultBatchSize = 100
+
+// Store interface for the outbox table
+type Store interface {
+	ProcessOutbox(ctx context.Context, limit int, handle func(ctx context.Context, events []postgres.OutboxEvent) []int64) (int, error)
+	DeletePublishedOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Relay publishes pending outbox events.
+// Events are marked published only after the publisher accepted them, so
+// delivery is at-least-once. When an event fails, later events of the same
+// market are held back until the next run to keep per-market ordering.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	batchSize int
+	interval  *schedule.Interval
+	logger    *zap.Logger
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(store Store, publisher Publisher, batchSize int, logger *zap.Logger) *Relay {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		batchSize: batchSize,
+		interval:  schedule.NewInterval(0),
+		logger:    logger,
+	}
+}
+
+// RunOnce publishes one batch of pending events and returns how many were published
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	published, err := r.store.ProcessOutbox(ctx, r.batchSize, r.publish)
+	if err != nil {
+		return 0, fmt.Errorf("failed to process outbox: %w", err)
+	}
+	return published, nil
+}
+
+// publish sends events in order and returns the ids of the published ones
+func (r *Relay) publish(ctx context.Context, events []postgres.OutboxEvent) []int64 {
+	blocked := make(map[string]bool)
+	published := make([]int64, 0, len(events))
+
+	for _, event := range events {
+		if blocked[event.Market] {
+			continue
+		}
+
+		err := r.publisher.Publish(ctx, Message{
+			ID:        event.ID,
+			Key:       event.Market,
+			Type:      event.EventType,
+			Payload:   event.Payload,
+			CreatedAt: event.CreatedAt,
+		})
+		if err != nil {
+			r.logger.Warn("Failed to publish outbox event",
+				zap.Int64("event_id", event.ID),
+				zap.String("market", event.Market),
+				zap.Int("attempts", event.Attempts+1),
+				zap.Error(err))
+			blocked[event.Market] = true
+			continue
+		}
+
+		published = append(published, event.ID)
+	}
+
+	return published
+}
+
+// Start publishes pending events every interval until ctx is done.
+// A full batch is followed immediately by the next one to drain backlogs.
+func (r *Relay) Start(ctx context.Context, interval time.Duration) {
+	r.interval.Set(interval)
+	go func() {
+		r.drain(ctx)
+		r.interval.Run(ctx, r.drain)
+	}()
+}
+
+// SetPollInterval changes the poll interval of a started relay
+func (r *Relay) SetPollInterval(interval time.Duration) {
+	r.interval.Set(interval)
+}
+
+// drain publishes batches until the backlog is empty or a run fails
+func (r *Relay) drain(ctx context.Context) {
+	for {
+		published, err := r.RunOnce(ctx)
+		if err != nil {
+			r.logger.Error("Outbox relay run failed", zap.Error(err))
+		}
+		if err != nil || published < r.batchSize {
+			return
+		}
+	}
+}
+
+// StartCleanup periodically removes events published longer than retention ago
+func (r *Relay) StartCleanup(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := r.store.DeletePublishedOutbox(ctx, time.Now().Add(-retention))
+				if err != nil {
+					r.logger.Error("Failed to clean up outbox", zap.Error(err))
+					continue
+				}
+				if deleted > 0 {
+					r.logger.Info("Published outbox events cleaned up", zap.Int64("deleted", deleted))
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,190 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/schedule"
+	"go.uber.org/zap"
+)
+
+// defaultPrecision is used for markets without a known price precision
+const defaultPrecision = 8
+
+// RuleSource loads the active version of every pricing rule
+type RuleSource interface {
+	LoadRules(ctx context.Context) ([]Rule, error)
+}
+
+// PrecisionFunc returns the price precision of a market
+type PrecisionFunc func(market string) (int, bool)
+
+// Engine applies pricing rules to raw exchange rates
+type Engine struct {
+	mu        sync.RWMutex
+	rules     []*compiledRule
+	source    RuleSource
+	precision PrecisionFunc
+	interval  *schedule.Interval
+	logger    *zap.Logger
+}
+
+// NewEngine creates a new pricing engine
+func NewEngine(source RuleSource, precision PrecisionFunc, logger *zap.Logger) *Engine {
+	return &Engine{
+		source:    source,
+		precision: precision,
+		interval:  schedule.NewInterval(0),
+		logger:    logger,
+	}
+}
+
+// Reload loads rules from the source and replaces the active set.
+// Invalid rule sets are rejected as a whole and the previous rules stay active.
+func (e *Engine) Reload(ctx context.Context) error {
+	rules, err := e.source.LoadRules(ctx)
+	if err != nil {
+		e.logger.Error("Failed to load pricing rules", zap.Error(err))
+		return fmt.Errorf("failed to load pricing rules: %w", err)
+	}
+
+	return e.SetRules(rules)
+}
+
+// SetRules validates and activates the given rules
+func (e *Engine) SetRules(rules []Rule) error {
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compile(rule)
+		if err != nil {
+			e.logger.Error("Invalid pricing rule", zap.Error(err))
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+
+	// Most specific rules first, then by id for a stable choice
+	sort.SliceStable(compiled, func(i, j int) bool {
+		if compiled[i].specificity() != compiled[j].specificity() {
+			return compiled[i].specificity() > compiled[j].specificity()
+		}
+		return compiled[i].ID < compiled[j].ID
+	})
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.mu.Unlock()
+
+	e.logger.Info("Pricing rules loaded", zap.Int("count", len(compiled)))
+
+	return nil
+}
+
+// Start reloads rules every interval until ctx is done
+func (e *Engine) Start(ctx context.Context, interval time.Duration) {
+	e.interval.Set(interval)
+	go e.interval.Run(ctx, func(ctx context.Context) {
+		_ = e.Reload(ctx)
+	})
+}
+
+// SetRefreshInterval changes the reload interval of a started engine
+func (e *Engine) SetRefreshInterval(interval time.Duration) {
+	e.interval.Set(interval)
+}
+
+// Apply sets customer prices on the rate using the best matching rule.
+// Without a matching rule customer prices equal the raw prices.
+func (e *Engine) Apply(rate *client.RateData, tier string) {
+	rate.CustomerAsk = rate.Ask
+	rate.CustomerBid = rate.Bid
+
+	rule := e.match(rate.Market, tier)
+	if rule == nil {
+		return
+	}
+
+	precision := defaultPrecision
+	if e.precision != nil {
+		if p, ok := e.precision(rate.Market); ok && p > 0 {
+			precision = p
+		}
+	}
+
+	one := big.NewRat(1, 1)
+	spread := new(big.Rat).Quo(rule.spread, big.NewRat(100, 1))
+
+	// Customers buy above the exchange ask and sell below the exchange bid
+	if ask, ok := new(big.Rat).SetString(rate.Ask); ok {
+		price := new(big.Rat).Mul(ask, new(big.Rat).Add(one, spread))
+		price.Add(price, rule.fee)
+		rate.CustomerAsk = roundUp(rule.clamp(price), precision)
+	}
+	if bid, ok := new(big.Rat).SetString(rate.Bid); ok {
+		price := new(big.Rat).Mul(bid, new(big.Rat).Sub(one, spread))
+		price.Sub(price, rule.fee)
+		price = rule.clamp(price)
+		// A fee above the bid leaves nothing to pay out; quotes refuse a zero bid
+		if price.Sign() < 0 {
+			price.SetInt64(0)
+		}
+		rate.CustomerBid = roundDown(price, precision)
+	}
+
+	rate.PricingRuleID = rule.ID
+	rate.PricingRuleVersion = rule.Version
+}
+
+// match returns the most specific rule for the market and tier
+func (e *Engine) match(market, tier string) *compiledRule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		if rule.matches(market, tier) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// clamp limits the price to the rule bounds
+func (r *compiledRule) clamp(price *big.Rat) *big.Rat {
+	if r.minPrice != nil && price.Cmp(r.minPrice) < 0 {
+		return new(big.Rat).Set(r.minPrice)
+	}
+	if r.maxPrice != nil && price.Cmp(r.maxPrice) > 0 {
+		return new(big.Rat).Set(r.maxPrice)
+	}
+	return price
+}
+
+// roundUp rounds a positive price up to the given number of decimal places
+func roundUp(price *big.Rat, precision int) string {
+	return round(price, precision, true)
+}
+
+// roundDown rounds a positive price down to the given number of decimal places
+func roundDown(price *big.Rat, precision int) string {
+	return round(price, precision, false)
+}
+
+func round(price *big.Rat, precision int, up bool) string {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	scaled := new(big.Rat).Mul(price, new(big.Rat).SetInt(scale))
+
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if up && rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if !up && rem.Sign() < 0 {
+		quo.Sub(quo, big.NewInt(1))
+	}
+
+	return new(big.Rat).SetFrac(quo, scale).FloatString(precision)
+}
@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Wildcard matches any market or client tier
+const Wildcard = "*"
+
+// Rule describes a markup applied on top of raw exchange rates.
+// Decimal values are kept as strings to avoid floating point rounding.
+type Rule struct {
+	ID            string
+	Version       int
+	Market        string
+	ClientTier    string
+	SpreadPercent string
+	FixedFee      string
+	MinPrice      string
+	MaxPrice      string
+}
+
+// compiledRule is a rule with parsed decimal values
+type compiledRule struct {
+	Rule
+	spread   *big.Rat
+	fee      *big.Rat
+	minPrice *big.Rat
+	maxPrice *big.Rat
+}
+
+// ValidateRules reports the first rule that SetRules would reject
+func ValidateRules(rules []Rule) error {
+	for _, rule := range rules {
+		if _, err := compile(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile parses the decimal values of a rule
+func compile(rule Rule) (*compiledRule, error) {
+	if rule.ID == "" {
+		return nil, fmt.Errorf("pricing rule id is required")
+	}
+	if rule.Market == "" {
+		rule.Market = Wildcard
+	}
+	if rule.ClientTier == "" {
+		rule.ClientTier = Wildcard
+	}
+
+	var err error
+	c := &compiledRule{Rule: rule}
+	if c.spread, err = parseDecimal(rule.SpreadPercent, true); err != nil {
+		return nil, fmt.Errorf("rule %s: invalid spread_percent: %w", rule.ID, err)
+	}
+	if c.fee, err = parseDecimal(rule.FixedFee, true); err != nil {
+		return nil, fmt.Errorf("rule %s: invalid fixed_fee: %w", rule.ID, err)
+	}
+	// A negative markup would price customers better than the exchange, and
+	// a spread of 100% or more leaves no bid
+	if c.spread.Sign() < 0 || c.spread.Cmp(big.NewRat(100, 1)) >= 0 {
+		return nil, fmt.Errorf("rule %s: spread_percent must be at least 0 and below 100", rule.ID)
+	}
+	if c.fee.Sign() < 0 {
+		return nil, fmt.Errorf("rule %s: fixed_fee must not be negative", rule.ID)
+	}
+	if c.minPrice, err = parseDecimal(rule.MinPrice, false); err != nil {
+		return nil, fmt.Errorf("rule %s: invalid min_price: %w", rule.ID, err)
+	}
+	if c.maxPrice, err = parseDecimal(rule.MaxPrice, false); err != nil {
+		return nil, fmt.Errorf("rule %s: invalid max_price: %w", rule.ID, err)
+	}
+	if c.minPrice != nil && c.maxPrice != nil && c.minPrice.Cmp(c.maxPrice) > 0 {
+		return nil, fmt.Errorf("rule %s: min_price is greater than max_price", rule.ID)
+	}
+
+	return c, nil
+}
+
+// specificity ranks rules so that exact matches win over wildcards
+func (r *compiledRule) specificity() int {
+	score := 0
+	if r.Market != Wildcard {
+		score += 2
+	}
+	if r.ClientTier != Wildcard {
+		score++
+	}
+	return score
+}
+
+// matches reports whether the rule applies to the market and tier
+func (r *compiledRule) matches(market, tier string) bool {
+	return (r.Market == Wildcard || r.Market == market) &&
+		(r.ClientTier == Wildcard || r.ClientTier == tier)
+}
+
+// parseDecimal parses an optional decimal string.
+// Empty values yield zero when zeroDefault is set and nil otherwise.
+func parseDecimal(value string, zeroDefault bool) (*big.Rat, error) {
+	if value == "" {
+		if zeroDefault {
+			return new(big.Rat), nil
+		}
+		return nil, nil
+	}
+
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("not a decimal: %q", value)
+	}
+	return r, nil
+}
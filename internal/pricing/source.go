@@ -0,0 +1,55 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+// StaticSource serves rules defined in the configuration
+type StaticSource []Rule
+
+// LoadRules returns the configured rules
+func (s StaticSource) LoadRules(_ context.Context) ([]Rule, error) {
+	return s, nil
+}
+
+// RuleStore reads versioned pricing rules from the database
+type RuleStore interface {
+	GetActivePricingRules(ctx context.Context) ([]postgres.PricingRule, error)
+}
+
+// RepositorySource serves the latest active version of rules stored in Postgres
+type RepositorySource struct {
+	store RuleStore
+}
+
+// NewRepositorySource creates a rule source backed by the repository
+func NewRepositorySource(store RuleStore) *RepositorySource {
+	return &RepositorySource{store: store}
+}
+
+// LoadRules returns the active rules from the database
+func (s *RepositorySource) LoadRules(ctx context.Context) ([]Rule, error) {
+	stored, err := s.store.GetActivePricingRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing rules: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(stored))
+	for _, r := range stored {
+		rules = append(rules, Rule{
+			ID:            r.ID,
+			Version:       r.Version,
+			Market:        r.Market,
+			ClientTier:    r.ClientTier,
+			SpreadPercent: r.SpreadPercent,
+			FixedFee:      r.FixedFee,
+			MinPrice:      r.MinPrice.String,
+			MaxPrice:      r.MaxPrice.String,
+		})
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,258 @@
+// Package ratesctl implements a command-line client for the RatesService gRPC API.
+package ratesctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/alik/TestForWork/proto/rates"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc/status"
+)
+
+// command is a ratesctl subcommand
+type command struct {
+	name    string
+	args    string
+	summary string
+	// setup registers the command flags and returns the function that runs it
+	setup func(fs *flag.FlagSet) func(ctx context.Context, env *env, args []string) error
+}
+
+// commands lists the subcommands in help order
+var commands = []command{
+	{"get", "MARKET...", "Show current rates, several markets are fetched in one batch", setupGet},
+	{"markets", "", "List the market catalog", setupMarkets},
+	{"watch", "MARKET...", "Poll rates and print every change until interrupted", setupWatch},
+	{"history", "MARKET", "Show stored rates of a time range", setupHistory},
+	{"candles", "MARKET", "Aggregate stored rates into OHLC candles", setupCandles},
+	{"quote", "MARKET", "Lock a customer price", setupQuote},
+	{"redeem", "QUOTE_ID TOKEN", "Redeem a locked quote", setupRedeem},
+	{"health", "", "Check service health", setupHealth},
+}
+
+// env is what a command runs with
+type env struct {
+	client  pb.RatesServiceClient
+	profile Profile
+	stdout  io.Writer
+}
+
+// printer returns a printer in the selected output format
+func (e *env) printer(columns ...string) (printer, error) {
+	return newPrinter(e.stdout, e.profile.Output, columns...)
+}
+
+// unary limits a unary call to the profile timeout
+func (e *env) unary(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, e.profile.Timeout)
+}
+
+// usageError is reported with exit code 2
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// connectionFlags are the flags shared by all commands
+type connectionFlags struct {
+	config, profile                      *string
+	address, caFile, certFile, keyFile   *string
+	serverName, token, tokenFile, output *string
+	tls, insecureSkipVerify              *bool
+	headers                              *[]string
+	timeout                              *time.Duration
+}
+
+func registerConnectionFlags(fs *flag.FlagSet) *connectionFlags {
+	return &connectionFlags{
+		config:             fs.String("config", "", "Config file with profiles (default $"+configEnv+" or "+DefaultConfigPath()+")"),
+		profile:            fs.String("profile", "", "Profile to use (default $"+profileEnv+" or the current profile)"),
+		address:            fs.StringP("address", "a", "", "Server address host:port (default "+defaultAddress+")"),
+		tls:                fs.Bool("tls", false, "Connect with TLS"),
+		caFile:             fs.String("ca-file", "", "CA certificate for verifying the server"),
+		certFile:           fs.String("cert-file", "", "Client certificate for mutual TLS"),
+		keyFile:            fs.String("key-file", "", "Client key for mutual TLS"),
+		serverName:         fs.String("server-name", "", "Server name checked against the certificate"),
+		insecureSkipVerify: fs.Bool("insecure-skip-verify", false, "Do not verify the server certificate"),
+		token:              fs.String("token", "", "Bearer token sent with every call, requires TLS"),
+		tokenFile:          fs.String("token-file", "", "File with the bearer token"),
+		headers:            fs.StringArrayP("header", "H", nil, "Metadata KEY=VALUE added to every call, repeatable"),
+		timeout:            fs.Duration("timeout", 0, "Timeout of unary calls (default 10s)"),
+		output:             fs.StringP("output", "o", "", "Output format: table, json (one object per line) or csv (default table)"),
+	}
+}
+
+// resolve loads the selected profile and applies the flags set on the command line
+func (c *connectionFlags) resolve(fs *flag.FlagSet) (Profile, error) {
+	path, mustExist := *c.config, true
+	if path == "" {
+		path = os.Getenv(configEnv)
+	}
+	if path == "" {
+		path, mustExist = DefaultConfigPath(), false
+	}
+
+	profiles, err := LoadProfiles(path, mustExist)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := *c.profile
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	profile, err := profiles.Select(name)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if fs.Changed("address") {
+		profile.Address = *c.address
+	}
+	if fs.Changed("tls") {
+		profile.TLS = *c.tls
+	}
+	if fs.Changed("ca-file") {
+		profile.CAFile = *c.caFile
+	}
+	if fs.Changed("cert-file") {
+		profile.CertFile = *c.certFile
+	}
+	if fs.Changed("key-file") {
+		profile.KeyFile = *c.keyFile
+	}
+	if fs.Changed("server-name") {
+		profile.ServerName = *c.serverName
+	}
+	if fs.Changed("insecure-skip-verify") {
+		profile.InsecureSkipVerify = *c.insecureSkipVerify
+	}
+	if fs.Changed("token") {
+		profile.Token = *c.token
+	}
+	if fs.Changed("token-file") {
+		profile.Token, profile.TokenFile = "", *c.tokenFile
+	}
+	if fs.Changed("timeout") {
+		profile.Timeout = *c.timeout
+	}
+	if fs.Changed("output") {
+		profile.Output = *c.output
+	}
+
+	if len(*c.headers) > 0 {
+		headers := make(map[string]string, len(profile.Headers)+len(*c.headers))
+		for k, v := range profile.Headers {
+			headers[k] = v
+		}
+		for _, h := range *c.headers {
+			k, v, ok := strings.Cut(h, "=")
+			if !ok || k == "" {
+				return Profile{}, usagef("invalid header %q, expected KEY=VALUE", h)
+			}
+			headers[strings.ToLower(k)] = v
+		}
+		profile.Headers = headers
+	}
+
+	return profile, nil
+}
+
+// Run runs the command in args and returns the exit code
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("ratesctl "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ratesctl %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	run := cmd.setup(fs)
+	connFlags := registerConnectionFlags(fs)
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	profile, err := connFlags.resolve(fs)
+	if err != nil {
+		return reportError(stderr, err)
+	}
+	if _, err := newPrinter(io.Discard, profile.Output); err != nil {
+		return reportError(stderr, &usageError{msg: err.Error()})
+	}
+
+	cc, client, err := dial(profile)
+	if err != nil {
+		return reportError(stderr, err)
+	}
+	defer cc.Close()
+
+	return reportError(stderr, run(ctx, &env{client: client, profile: profile, stdout: stdout}, fs.Args()))
+}
+
+// reportError prints the error and returns the exit code
+func reportError(stderr io.Writer, err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if st, ok := status.FromError(err); ok {
+		fmt.Fprintf(stderr, "Error: %s: %s\n", st.Code(), st.Message())
+		return 1
+	}
+	fmt.Fprintf(stderr, "Error: %v\n", err)
+	return 1
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ratesctl COMMAND [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'ratesctl COMMAND --help' for the flags of a command.")
+}
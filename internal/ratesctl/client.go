@@ -0,0 +1,113 @@
+package ratesctl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	pb "github.com/alik/TestForWork/proto/rates"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// dial connects to the server described by the profile
+func dial(profile Profile) (*grpc.ClientConn, pb.RatesServiceClient, error) {
+	var opts []grpc.DialOption
+
+	if profile.TLS {
+		tlsConfig, err := clientTLSConfig(profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	token, err := profile.token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if token != "" {
+		if !profile.TLS {
+			return nil, nil, fmt.Errorf("a token is only sent over TLS, enable --tls")
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
+
+	if len(profile.Headers) > 0 {
+		pairs := make([]string, 0, len(profile.Headers)*2)
+		for k, v := range profile.Headers {
+			pairs = append(pairs, k, v)
+		}
+		md := metadata.Pairs(pairs...)
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+				return invoker(withMetadata(ctx, md), method, req, reply, cc, callOpts...)
+			}),
+			grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(withMetadata(ctx, md), desc, cc, method, callOpts...)
+			}),
+		)
+	}
+
+	conn, err := grpc.NewClient(profile.Address, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", profile.Address, err)
+	}
+	return conn, pb.NewRatesServiceClient(conn), nil
+}
+
+// clientTLSConfig builds the TLS settings of the profile
+func clientTLSConfig(profile Profile) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         profile.ServerName,
+		InsecureSkipVerify: profile.InsecureSkipVerify,
+	}
+
+	if profile.CAFile != "" {
+		pem, err := os.ReadFile(profile.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", profile.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if profile.CertFile != "" || profile.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(profile.CertFile, profile.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// withMetadata adds the headers to the outgoing metadata
+func withMetadata(ctx context.Context, md metadata.MD) context.Context {
+	if existing, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(existing, md)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// bearerToken sends a token in the authorization header
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
+}
@@ -0,0 +1,231 @@
+package ratesctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	pb "github.com/alik/TestForWork/proto/rates"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timeLayouts are the accepted --from and --to formats; a date without a
+// time is midnight UTC
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// parseTime parses a time flag, returning nil for an empty value
+func parseTime(name, value string) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return timestamppb.New(t), nil
+		}
+	}
+	return nil, usagef("invalid --%s %q, expected an RFC 3339 time or a date", name, value)
+}
+
+// historyRate is a line of a JSONL export
+type historyRate struct {
+	Market     string    `json:"market"`
+	Ask        string    `json:"ask"`
+	Bid        string    `json:"bid"`
+	Timestamp  time.Time `json:"timestamp"`
+	ReceivedAt time.Time `json:"received_at"`
+	Source     string    `json:"source"`
+}
+
+// rangeFlags select the stored rates of a market
+type rangeFlags struct {
+	from, to *string
+}
+
+func registerRangeFlags(fs *flag.FlagSet) *rangeFlags {
+	return &rangeFlags{
+		from: fs.String("from", "", "Start of the range, inclusive: RFC 3339 time or date (default the whole history)"),
+		to:   fs.String("to", "", "End of the range, exclusive: RFC 3339 time or date (default now)"),
+	}
+}
+
+// stream calls fn for every stored rate of the market in the range, oldest
+// first. The rates are streamed with ExportRates in JSONL format.
+func (f *rangeFlags) stream(ctx context.Context, env *env, market string, resample time.Duration, fn func(historyRate) error) error {
+	req := &pb.ExportRatesRequest{Market: market, Format: pb.ExportFormat_EXPORT_FORMAT_JSONL}
+	var err error
+	if req.From, err = parseTime("from", *f.from); err != nil {
+		return err
+	}
+	if req.To, err = parseTime("to", *f.to); err != nil {
+		return err
+	}
+	if resample > 0 {
+		req.ResampleInterval = durationpb.New(resample)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := env.client.ExportRates(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(&chunkReader{stream: stream})
+	for scanner.Scan() {
+		var rate historyRate
+		if err := json.Unmarshal(scanner.Bytes(), &rate); err != nil {
+			return fmt.Errorf("invalid export line: %w", err)
+		}
+		if err := fn(rate); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// chunkReader reads the data of an ExportRates stream
+type chunkReader struct {
+	stream pb.RatesService_ExportRatesClient
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		chunk, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.buf = chunk.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func setupHistory(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	rng := registerRangeFlags(fs)
+	resample := fs.Duration("resample", 0, "Show the last known rate at this interval instead of every stored rate")
+
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) != 1 {
+			return usagef("exactly one market is required")
+		}
+
+		p, err := env.printer("market", "ask", "bid", "timestamp", "received_at", "source")
+		if err != nil {
+			return err
+		}
+		err = rng.stream(ctx, env, args[0], *resample, func(rate historyRate) error {
+			return p.Row(rate.Market, rate.Ask, rate.Bid,
+				rate.Timestamp.Format(time.RFC3339Nano), rate.ReceivedAt.Format(time.RFC3339Nano), rate.Source)
+		})
+		if flushErr := p.Flush(); err == nil {
+			err = flushErr
+		}
+		return err
+	}
+}
+
+// candle is an OHLC aggregate of the prices in one interval
+type candle struct {
+	start                  time.Time
+	open, high, low, close float64
+	count                  int
+}
+
+func (c *candle) add(price float64) {
+	if c.count == 0 {
+		c.open, c.high, c.low = price, price, price
+	}
+	c.high = math.Max(c.high, price)
+	c.low = math.Min(c.low, price)
+	c.close = price
+	c.count++
+}
+
+// candlePrice returns the price of a rate used for candles
+func candlePrice(rate historyRate, field string) (float64, error) {
+	ask, err := strconv.ParseFloat(rate.Ask, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ask %q", rate.Ask)
+	}
+	bid, err := strconv.ParseFloat(rate.Bid, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bid %q", rate.Bid)
+	}
+	switch field {
+	case "ask":
+		return ask, nil
+	case "bid":
+		return bid, nil
+	default:
+		return (ask + bid) / 2, nil
+	}
+}
+
+// formatPrice formats a price with at most 8 decimal places
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(math.Round(price*1e8)/1e8, 'f', -1, 64)
+}
+
+func setupCandles(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	rng := registerRangeFlags(fs)
+	interval := fs.Duration("interval", time.Hour, "Candle interval; candles start at UTC multiples of the interval, e.g. full hours")
+	field := fs.String("field", "mid", "Price used: ask, bid or mid")
+
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) != 1 {
+			return usagef("exactly one market is required")
+		}
+		if *interval < time.Second {
+			return usagef("--interval must be at least 1s")
+		}
+		if *field != "ask" && *field != "bid" && *field != "mid" {
+			return usagef("invalid --field %q, expected ask, bid or mid", *field)
+		}
+
+		p, err := env.printer("time", "open", "high", "low", "close", "count")
+		if err != nil {
+			return err
+		}
+		emit := func(c *candle) error {
+			return p.Row(c.start.Format(time.RFC3339), formatPrice(c.open), formatPrice(c.high),
+				formatPrice(c.low), formatPrice(c.close), strconv.Itoa(c.count))
+		}
+
+		// Rates arrive oldest first, so a candle is complete when the next one starts
+		var current *candle
+		err = rng.stream(ctx, env, args[0], 0, func(rate historyRate) error {
+			price, err := candlePrice(rate, *field)
+			if err != nil {
+				return err
+			}
+			start := rate.Timestamp.UTC().Truncate(*interval)
+			if current != nil && !current.start.Equal(start) {
+				if err := emit(current); err != nil {
+					return err
+				}
+				current = nil
+			}
+			if current == nil {
+				current = &candle{start: start}
+			}
+			current.add(price)
+			return nil
+		})
+		if err == nil && current != nil {
+			err = emit(current)
+		}
+		if flushErr := p.Flush(); err == nil {
+			err = flushErr
+		}
+		return err
+	}
+}
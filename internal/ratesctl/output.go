@@ -0,0 +1,123 @@
+package ratesctl
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// printer writes rows with fixed columns. Rows are written as they arrive so
+// that streaming commands show output immediately.
+type printer interface {
+	// Row writes one row with a value per column
+	Row(values ...string) error
+	// Flush writes buffered output
+	Flush() error
+}
+
+// newPrinter returns a printer for the format and columns
+func newPrinter(w io.Writer, format string, columns ...string) (printer, error) {
+	switch format {
+	case formatTable:
+		return newTablePrinter(w, columns), nil
+	case formatJSON:
+		return &jsonPrinter{w: w, columns: columns}, nil
+	case formatCSV:
+		return newCSVPrinter(w, columns)
+	default:
+		return nil, fmt.Errorf("unknown output format %q, expected table, json or csv", format)
+	}
+}
+
+// tablePrinter aligns columns under an upper-case header. Columns are aligned
+// over the rows written between two flushes.
+type tablePrinter struct {
+	tw *tabwriter.Writer
+}
+
+func newTablePrinter(w io.Writer, columns []string) *tablePrinter {
+	p := &tablePrinter{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(p.tw, strings.Join(header, "\t"))
+	return p
+}
+
+func (p *tablePrinter) Row(values ...string) error {
+	row := make([]string, len(values))
+	for i, v := range values {
+		if v == "" {
+			v = "-"
+		}
+		row[i] = v
+	}
+	_, err := fmt.Fprintln(p.tw, strings.Join(row, "\t"))
+	return err
+}
+
+func (p *tablePrinter) Flush() error {
+	return p.tw.Flush()
+}
+
+// jsonPrinter writes one JSON object per row with the columns as keys, in column order
+type jsonPrinter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (p *jsonPrinter) Row(values ...string) error {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, c := range p.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(c)
+		value, _ := json.Marshal(values[i])
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	_, err := p.w.Write(buf.Bytes())
+	return err
+}
+
+func (p *jsonPrinter) Flush() error {
+	return nil
+}
+
+// csvPrinter writes CSV with a header row
+type csvPrinter struct {
+	w *csv.Writer
+}
+
+func newCSVPrinter(w io.Writer, columns []string) (*csvPrinter, error) {
+	p := &csvPrinter{w: csv.NewWriter(w)}
+	if err := p.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *csvPrinter) Row(values ...string) error {
+	return p.w.Write(values)
+}
+
+func (p *csvPrinter) Flush() error {
+	p.w.Flush()
+	return p.w.Error()
+}
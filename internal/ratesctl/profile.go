@@ -0,0 +1,132 @@
+package ratesctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// configEnv overrides the default config file path
+	configEnv = "RATESCTL_CONFIG"
+	// profileEnv selects the profile when --profile is not set
+	profileEnv = "RATESCTL_PROFILE"
+
+	defaultAddress = "localhost:8080"
+	defaultTimeout = 10 * time.Second
+)
+
+// Profile holds the connection settings of one environment
+type Profile struct {
+	// Address is the host:port of the gRPC server
+	Address string `yaml:"address"`
+	// TLS enables transport security
+	TLS bool `yaml:"tls"`
+	// CAFile verifies the server with this CA instead of the system roots
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile hold the client certificate for mutual TLS
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName overrides the name checked against the server certificate
+	ServerName string `yaml:"server_name"`
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// Token is sent as a bearer token; TokenFile is read when Token is empty
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+	// Headers are added to the metadata of every call
+	Headers map[string]string `yaml:"headers"`
+	// Timeout limits unary calls
+	Timeout time.Duration `yaml:"timeout"`
+	// Output is the default output format
+	Output string `yaml:"output"`
+	// ClientTier is the default pricing tier
+	ClientTier string `yaml:"client_tier"`
+}
+
+// Profiles is the ratesctl config file
+type Profiles struct {
+	// Current is the profile used when none is selected
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultConfigPath returns the config file used when neither --config nor
+// RATESCTL_CONFIG is set
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ratesctl", "config.yaml")
+}
+
+// LoadProfiles reads a config file. A missing file yields no profiles unless
+// mustExist is set.
+func LoadProfiles(path string, mustExist bool) (*Profiles, error) {
+	if path == "" {
+		return &Profiles{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !mustExist {
+		return &Profiles{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var profiles Profiles
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &profiles, nil
+}
+
+// Select returns the named profile, or the current one for an empty name.
+// Without a name or current profile the defaults are returned.
+func (p *Profiles) Select(name string) (Profile, error) {
+	if name == "" {
+		name = p.Current
+	}
+	if name == "" {
+		return Profile{}.withDefaults(), nil
+	}
+
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile.withDefaults(), nil
+}
+
+// withDefaults fills unset fields
+func (p Profile) withDefaults() Profile {
+	if p.Address == "" {
+		p.Address = defaultAddress
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = defaultTimeout
+	}
+	if p.Output == "" {
+		p.Output = formatTable
+	}
+	return p
+}
+
+// token returns the bearer token, reading the token file if needed
+func (p Profile) token() (string, error) {
+	if p.Token != "" || p.TokenFile == "" {
+		return p.Token, nil
+	}
+	data, err := os.ReadFile(p.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
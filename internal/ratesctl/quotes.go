@@ -0,0 +1,96 @@
+package ratesctl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/alik/TestForWork/proto/rates"
+	flag "github.com/spf13/pflag"
+)
+
+// quoteColumns are the columns of quote and redeem
+var quoteColumns = []string{"quote_id", "market", "side", "amount", "price", "total", "expires_at", "pricing_rule_id", "token"}
+
+// quoteRow formats a locked quote as a row of quoteColumns
+func quoteRow(q *pb.LockedQuote) []string {
+	return []string{q.QuoteId, q.Market, q.Side, q.Amount, q.Price, q.Total,
+		formatTimestamp(q.ExpiresAt), q.PricingRuleId, q.Token}
+}
+
+func setupQuote(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	side := fs.String("side", "", "Side from the customer's point of view: buy or sell")
+	amount := fs.String("amount", "", "Amount of the base currency")
+	tier := fs.String("tier", "", "Client pricing tier (default the profile client_tier)")
+
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) != 1 {
+			return usagef("exactly one market is required")
+		}
+		if *side != "buy" && *side != "sell" {
+			return usagef("--side must be buy or sell")
+		}
+		if *amount == "" {
+			return usagef("--amount is required")
+		}
+		if *tier == "" {
+			*tier = env.profile.ClientTier
+		}
+
+		ctx, cancel := env.unary(ctx)
+		defer cancel()
+
+		quote, err := env.client.CreateLockedQuote(ctx, &pb.CreateLockedQuoteRequest{
+			Market:     args[0],
+			Side:       *side,
+			Amount:     *amount,
+			ClientTier: *tier,
+		})
+		if err != nil {
+			return err
+		}
+
+		p, err := env.printer(quoteColumns...)
+		if err != nil {
+			return err
+		}
+		if err := p.Row(quoteRow(quote)...); err != nil {
+			return err
+		}
+		return p.Flush()
+	}
+}
+
+func setupRedeem(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) != 2 {
+			return usagef("a quote id and token are required")
+		}
+
+		ctx, cancel := env.unary(ctx)
+		defer cancel()
+
+		resp, err := env.client.RedeemQuote(ctx, &pb.RedeemQuoteRequest{QuoteId: args[0], Token: args[1]})
+		if err != nil {
+			return err
+		}
+
+		p, err := env.printer(append([]string{"status"}, quoteColumns[:len(quoteColumns)-1]...)...)
+		if err != nil {
+			return err
+		}
+		quote := quoteRow(resp.Quote)
+		status := strings.ToLower(strings.TrimPrefix(resp.Status.String(), "QUOTE_STATUS_"))
+		if err := p.Row(append([]string{status}, quote[:len(quote)-1]...)...); err != nil {
+			return err
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+
+		if resp.Status != pb.QuoteStatus_QUOTE_STATUS_VALID {
+			return fmt.Errorf("quote is %s", status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,245 @@
+package ratesctl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/alik/TestForWork/proto/rates"
+	flag "github.com/spf13/pflag"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rateColumns are the columns of get and watch
+var rateColumns = []string{"market", "ask", "bid", "customer_ask", "customer_bid", "timestamp", "age", "error"}
+
+// rateRow formats a rates response as a row of rateColumns
+func rateRow(r *pb.GetRatesResponse) []string {
+	return []string{
+		r.Market,
+		r.Ask,
+		r.Bid,
+		r.CustomerAsk,
+		r.CustomerBid,
+		formatTimestamp(r.Timestamp),
+		formatDuration(r.Age),
+		"",
+	}
+}
+
+// errorRow formats a failed market as a row of rateColumns
+func errorRow(market string, code codes.Code, message string) []string {
+	return []string{market, "", "", "", "", "", "", fmt.Sprintf("%s: %s", code, message)}
+}
+
+func formatTimestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.AsTime().UTC().Format(time.RFC3339Nano)
+}
+
+func formatDuration(d *durationpb.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return d.AsDuration().Round(time.Millisecond).String()
+}
+
+// rateFlags are the request flags of get and watch
+type rateFlags struct {
+	tier   *string
+	maxAge *time.Duration
+}
+
+func registerRateFlags(fs *flag.FlagSet) *rateFlags {
+	return &rateFlags{
+		tier:   fs.String("tier", "", "Client pricing tier (default the profile client_tier)"),
+		maxAge: fs.Duration("max-age", 0, "Refuse quotes older than this"),
+	}
+}
+
+// fetch returns one row per market, using BatchGetRates for several markets.
+// It fails only if the call fails; per-market errors are reported in the rows.
+func (f *rateFlags) fetch(ctx context.Context, env *env, markets []string) ([][]string, int, error) {
+	tier := *f.tier
+	if tier == "" {
+		tier = env.profile.ClientTier
+	}
+	var maxAge *durationpb.Duration
+	if *f.maxAge > 0 {
+		maxAge = durationpb.New(*f.maxAge)
+	}
+
+	ctx, cancel := env.unary(ctx)
+	defer cancel()
+
+	if len(markets) == 1 {
+		resp, err := env.client.GetRates(ctx, &pb.GetRatesRequest{Market: markets[0], ClientTier: tier, MaxAge: maxAge})
+		if err != nil {
+			return nil, 0, err
+		}
+		return [][]string{rateRow(resp)}, 0, nil
+	}
+
+	resp, err := env.client.BatchGetRates(ctx, &pb.BatchGetRatesRequest{Markets: markets, ClientTier: tier, MaxAge: maxAge})
+	if err != nil {
+		return nil, 0, err
+	}
+	rows := make([][]string, 0, len(resp.Results))
+	failed := 0
+	for _, result := range resp.Results {
+		if result.GetError() != nil {
+			rows = append(rows, errorRow(result.Market, codes.Code(result.GetError().Code), result.GetError().Message))
+			failed++
+			continue
+		}
+		rows = append(rows, rateRow(result.GetRates()))
+	}
+	return rows, failed, nil
+}
+
+func setupGet(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	flags := registerRateFlags(fs)
+
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) == 0 {
+			return usagef("at least one market is required")
+		}
+
+		rows, failed, err := flags.fetch(ctx, env, args)
+		if err != nil {
+			return err
+		}
+
+		p, err := env.printer(rateColumns...)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := p.Row(row...); err != nil {
+				return err
+			}
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d markets failed", failed, len(rows))
+		}
+		return nil
+	}
+}
+
+func setupWatch(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	flags := registerRateFlags(fs)
+	interval := fs.Duration("interval", time.Second, "Polling interval")
+	all := fs.Bool("all", false, "Print every poll, not only changes")
+
+	return func(ctx context.Context, env *env, args []string) error {
+		if len(args) == 0 {
+			return usagef("at least one market is required")
+		}
+		if *interval <= 0 {
+			return usagef("--interval must be positive")
+		}
+
+		p, err := env.printer(rateColumns...)
+		if err != nil {
+			return err
+		}
+
+		// Rows are compared without the age, which changes on every poll
+		last := make(map[string]string)
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+
+		for {
+			rows, _, err := flags.fetch(ctx, env, args)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				// A failed poll is printed and retried on the next tick
+				st := status.Convert(err)
+				rows = [][]string{errorRow(strings.Join(args, ","), st.Code(), st.Message())}
+			}
+			for _, row := range rows {
+				key := fmt.Sprint(row[1:6], row[7])
+				if !*all && last[row[0]] == key {
+					continue
+				}
+				last[row[0]] = key
+				if err := p.Row(row...); err != nil {
+					return err
+				}
+			}
+			if err := p.Flush(); err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+func setupMarkets(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	return func(ctx context.Context, env *env, _ []string) error {
+		ctx, cancel := env.unary(ctx)
+		defer cancel()
+
+		resp, err := env.client.ListMarkets(ctx, &pb.ListMarketsRequest{})
+		if err != nil {
+			return err
+		}
+
+		p, err := env.printer("id", "base", "quote", "price_precision", "amount_precision", "status")
+		if err != nil {
+			return err
+		}
+		for _, m := range resp.Markets {
+			err := p.Row(m.Id, m.Base, m.Quote, fmt.Sprint(m.PricePrecision), fmt.Sprint(m.AmountPrecision), m.Status)
+			if err != nil {
+				return err
+			}
+		}
+		return p.Flush()
+	}
+}
+
+func setupHealth(fs *flag.FlagSet) func(context.Context, *env, []string) error {
+	return func(ctx context.Context, env *env, _ []string) error {
+		ctx, cancel := env.unary(ctx)
+		defer cancel()
+
+		resp, err := env.client.Healthcheck(ctx, &pb.HealthcheckRequest{})
+		if err != nil {
+			return err
+		}
+
+		p, err := env.printer("status", "version", "timestamp")
+		if err != nil {
+			return err
+		}
+		if err := p.Row(resp.Status, resp.Version, formatTimestamp(resp.Timestamp)); err != nil {
+			return err
+		}
+		if err := p.Flush(); err != nil {
+			return err
+		}
+
+		if resp.Status != "healthy" {
+			return fmt.Errorf("service is %s", resp.Status)
+		}
+		return nil
+	}
+}
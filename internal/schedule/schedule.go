@@ -0,0 +1,67 @@
+// Package schedule runs periodic jobs whose interval can change at runtime.
+package schedule
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Interval is the period of a job. Changes made with Set apply to a running
+// job right away, without waiting for the current period to end.
+type Interval struct {
+	d     atomic.Int64
+	reset chan struct{}
+}
+
+// NewInterval creates an interval; zero or negative pauses the job
+func NewInterval(d time.Duration) *Interval {
+	i := &Interval{reset: make(chan struct{}, 1)}
+	i.d.Store(int64(d))
+	return i
+}
+
+// Get returns the current interval
+func (i *Interval) Get() time.Duration {
+	return time.Duration(i.d.Load())
+}
+
+// Set changes the interval and restarts the running job's timer
+func (i *Interval) Set(d time.Duration) {
+	if time.Duration(i.d.Swap(int64(d))) == d {
+		return
+	}
+	select {
+	case i.reset <- struct{}{}:
+	default:
+	}
+}
+
+// Run calls fn every interval until ctx is done. While the interval is not
+// positive the job waits for it to be set.
+func (i *Interval) Run(ctx context.Context, fn func(ctx context.Context)) {
+	for {
+		var timer *time.Timer
+		var tick <-chan time.Time
+		if d := i.Get(); d > 0 {
+			timer = time.NewTimer(d)
+			tick = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return
+		case <-i.reset:
+			stopTimer(timer)
+		case <-tick:
+			fn(ctx)
+		}
+	}
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
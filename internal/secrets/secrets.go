@@ -0,0 +1,117 @@
+// Package secrets resolves secret references such as ${file:/run/secrets/db}
+// and ${env:PGPASSWORD} through pluggable providers.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Provider resolves references of one kind
+type Provider interface {
+	// Scheme is the kind of references handled, as in ${scheme:ref}
+	Scheme() string
+	// Get returns the current value of the secret
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+// Resolver resolves references with the registered providers
+type Resolver struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver with the given providers
+func NewResolver(providers ...Provider) *Resolver {
+	r := &Resolver{providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Default resolves file and env references
+var Default = NewResolver(FileProvider{}, EnvProvider{})
+
+// Register adds a provider, replacing the one with the same scheme
+func (r *Resolver) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[p.Scheme()] = p
+}
+
+// Reference builds a reference to the secret
+func Reference(scheme, ref string) string {
+	return "${" + scheme + ":" + ref + "}"
+}
+
+// ParseReference splits a reference into its scheme and ref; values that
+// are not a single reference are literals
+func ParseReference(value string) (scheme, ref string, ok bool) {
+	inner, ok := strings.CutPrefix(value, "${")
+	if !ok {
+		return "", "", false
+	}
+	inner, ok = strings.CutSuffix(inner, "}")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(inner, ":")
+}
+
+// Resolve returns the value of a reference, or the value itself when it is
+// a literal
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	r.mu.RLock()
+	p, ok := r.providers[scheme]
+	r.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", scheme)
+	}
+
+	secret, err := p.Get(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret %s: %w", scheme, ref, err)
+	}
+	return secret, nil
+}
+
+// FileProvider reads secrets from files such as Docker and Kubernetes
+// secrets. A trailing newline is not part of the secret.
+type FileProvider struct{}
+
+// Scheme implements Provider
+func (FileProvider) Scheme() string { return "file" }
+
+// Get implements Provider
+func (FileProvider) Get(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvProvider reads secrets from environment variables, so a config value
+// can point at a variable set by the platform
+type EnvProvider struct{}
+
+// Scheme implements Provider
+func (EnvProvider) Scheme() string { return "env" }
+
+// Get implements Provider
+func (EnvProvider) Get(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alik/TestForWork/internal/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// defaultBatchConcurrency is the number of markets fetched in parallel by default
+const defaultBatchConcurrency = 4
+
+// MarketResult holds the outcome of fetching rates for a single market
+type MarketResult struct {
+	Market string
+	Rate   *client.RateData
+	Err    error
+}
+
+// SetBatchConcurrency changes the number of workers used by batches started
+// after the call
+func (s *RatesService) SetBatchConcurrency(n int) {
+	if n > 0 {
+		s.batchConcurrency.Store(int64(n))
+	}
+}
+
+// BatchGetRates retrieves rates for several markets concurrently.
+// Results are returned in the order of the requested markets; a failure
+// for one market is reported in its result and does not affect the others.
+func (s *RatesService) BatchGetRates(ctx context.Context, markets []string, opts ...RequestOption) []MarketResult {
+	ctx, span := tracer.Start(ctx, "RatesService.BatchGetRates", trace.WithAttributes(
+		attribute.Int("rates.markets", len(markets))))
+	defer span.End()
+
+	s.log(ctx).Info("Getting rates for batch of markets", zap.Strings("markets", markets))
+
+	results := make([]MarketResult, len(markets))
+	jobs := make(chan int)
+
+	workers := int(s.batchConcurrency.Load())
+	if workers > len(markets) {
+		workers = len(markets)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				market := markets[i]
+				rate, err := s.GetRates(ctx, market, opts...)
+				results[i] = MarketResult{Market: market, Rate: rate, Err: err}
+			}
+		}()
+	}
+
+	for i := range markets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	span.SetAttributes(attribute.Int("rates.failed_markets", failed))
+
+	return results
+}
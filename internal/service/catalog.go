@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/schedule"
+	"go.uber.org/zap"
+)
+
+// MarketStatusActive marks a market that is open for trading
+const MarketStatusActive = "active"
+
+// Market describes a market known to the catalog
+type Market struct {
+	ID              string
+	Base            string
+	Quote           string
+	PricePrecision  int
+	AmountPrecision int
+	Status          string
+}
+
+// MarketSource loads market metadata from the exchange
+type MarketSource interface {
+	GetMarkets(ctx context.Context) ([]client.MarketInfo, error)
+}
+
+// MarketCatalog keeps the set of available markets and refreshes it periodically
+type MarketCatalog struct {
+	mu       sync.RWMutex
+	markets  map[string]Market
+	source   MarketSource
+	interval *schedule.Interval
+	logger   *zap.Logger
+}
+
+// NewMarketCatalog creates a new market catalog.
+// If source is nil, the catalog is served from the static markets only;
+// otherwise the static markets are used until the first successful refresh.
+func NewMarketCatalog(source MarketSource, static []Market, logger *zap.Logger) *MarketCatalog {
+	c := &MarketCatalog{
+		markets:  make(map[string]Market, len(static)),
+		source:   source,
+		interval: schedule.NewInterval(0),
+		logger:   logger,
+	}
+	c.replace(static)
+	return c
+}
+
+// Refresh reloads markets from the exchange.
+// On failure the previously loaded markets are kept.
+func (c *MarketCatalog) Refresh(ctx context.Context) error {
+	if c.source == nil {
+		return nil
+	}
+
+	infos, err := c.source.GetMarkets(ctx)
+	if err != nil {
+		c.log(ctx).Error("Failed to refresh market catalog", zap.Error(err))
+		return fmt.Errorf("failed to refresh market catalog: %w", err)
+	}
+
+	markets := make([]Market, 0, len(infos))
+	for _, info := range infos {
+		if info.ID == "" {
+			continue
+		}
+		markets = append(markets, Market{
+			ID:              info.ID,
+			Base:            info.BaseUnit,
+			Quote:           info.QuoteUnit,
+			PricePrecision:  info.PricePrecision,
+			AmountPrecision: info.AmountPrecision,
+			Status:          normalizeMarketStatus(info.State),
+		})
+	}
+
+	if len(markets) == 0 {
+		c.log(ctx).Warn("Exchange returned no markets, keeping current catalog")
+		return nil
+	}
+
+	c.replace(markets)
+
+	c.log(ctx).Info("Market catalog refreshed", zap.Int("count", len(markets)))
+
+	return nil
+}
+
+// Start refreshes the catalog every interval until ctx is done
+func (c *MarketCatalog) Start(ctx context.Context, interval time.Duration) {
+	if c.source == nil {
+		return
+	}
+
+	c.interval.Set(interval)
+	go c.interval.Run(ctx, func(ctx context.Context) {
+		refreshCtx, cancel := context.WithTimeout(ctx, c.interval.Get())
+		_ = c.Refresh(refreshCtx)
+		cancel()
+	})
+}
+
+// SetRefreshInterval changes the refresh interval of a started catalog
+func (c *MarketCatalog) SetRefreshInterval(interval time.Duration) {
+	c.interval.Set(interval)
+}
+
+// SetStaticMarkets replaces the markets of a catalog served without an
+// exchange source; with a source the exchange markets are kept
+func (c *MarketCatalog) SetStaticMarkets(markets []Market) {
+	if c.source != nil {
+		return
+	}
+	c.replace(markets)
+	c.logger.Info("Market catalog replaced", zap.Int("count", len(markets)))
+}
+
+// Lookup returns the market with the given id
+func (c *MarketCatalog) Lookup(id string) (Market, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	market, ok := c.markets[id]
+	return market, ok
+}
+
+// Resolve returns the id of the listed market that a market given with a
+// separator, e.g. "usdt/rub", refers to. Other ids are returned unchanged.
+func (c *MarketCatalog) Resolve(id string) string {
+	if _, ok := c.Lookup(id); ok {
+		return id
+	}
+
+	markets := c.List()
+	base, quote, ok := splitMarket(id, currencies(markets))
+	if !ok {
+		return id
+	}
+	for _, market := range markets {
+		if market.Base == base && market.Quote == quote {
+			return market.ID
+		}
+	}
+	return id
+}
+
+// Validate checks that the market is known and active
+func (c *MarketCatalog) Validate(id string) error {
+	market, ok := c.Lookup(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownMarket, id)
+	}
+	if market.Status != MarketStatusActive {
+		return fmt.Errorf("%w: %s", ErrMarketInactive, id)
+	}
+	return nil
+}
+
+// List returns all markets sorted by id
+func (c *MarketCatalog) List() []Market {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	markets := make([]Market, 0, len(c.markets))
+	for _, market := range c.markets {
+		markets = append(markets, market)
+	}
+
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].ID < markets[j].ID
+	})
+
+	return markets
+}
+
+// replace atomically swaps the catalog contents
+func (c *MarketCatalog) replace(markets []Market) {
+	byID := make(map[string]Market, len(markets))
+	for _, market := range markets {
+		if market.Status == "" {
+			market.Status = MarketStatusActive
+		}
+		byID[market.ID] = market
+	}
+
+	c.mu.Lock()
+	c.markets = byID
+	c.mu.Unlock()
+}
+
+// normalizeMarketStatus maps exchange market states to catalog statuses
+func normalizeMarketStatus(state string) string {
+	switch state {
+	case "", "enabled", "active", "online":
+		return MarketStatusActive
+	default:
+		return state
+	}
+}
+
+// ListMarkets returns the markets known to the catalog
+func (s *RatesService) ListMarkets(_ context.Context) []Market {
+	if s.catalog == nil {
+		return nil
+	}
+	return s.catalog.List()
+}
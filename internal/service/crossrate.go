@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultCrossRateHops is the default number of intermediate currencies
+	defaultCrossRateHops = 2
+
+	// crossRatePrecision is the number of decimal places in derived prices
+	crossRatePrecision = 8
+)
+
+// CrossRateEngine derives quotes for markets the exchange does not list
+// by chaining markets through intermediate currencies
+type CrossRateEngine struct {
+	catalog *MarketCatalog
+	maxHops int
+}
+
+// NewCrossRateEngine creates a cross-rate engine on top of the market catalog.
+// maxHops is the maximum number of intermediate currencies in a path.
+func NewCrossRateEngine(catalog *MarketCatalog, maxHops int) *CrossRateEngine {
+	if maxHops <= 0 {
+		maxHops = defaultCrossRateHops
+	}
+	return &CrossRateEngine{
+		catalog: catalog,
+		maxHops: maxHops,
+	}
+}
+
+// FindPath returns the shortest chain of markets converting base into quote.
+// The market id may be given as "usdtkzt" or with a separator, e.g. "usdt/kzt".
+func (e *CrossRateEngine) FindPath(market string) ([]client.RateLeg, error) {
+	markets := e.activeMarkets()
+
+	base, quote, ok := splitMarket(market, currencies(markets))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMarket, market)
+	}
+
+	// Build the currency graph: every market can be traversed in both directions
+	graph := make(map[string][]client.RateLeg)
+	for _, m := range markets {
+		graph[m.Base] = append(graph[m.Base], client.RateLeg{Market: m.ID, From: m.Base, To: m.Quote})
+		graph[m.Quote] = append(graph[m.Quote], client.RateLeg{Market: m.ID, From: m.Quote, To: m.Base, Inverted: true})
+	}
+	for currency := range graph {
+		legs := graph[currency]
+		sort.Slice(legs, func(i, j int) bool { return legs[i].Market < legs[j].Market })
+	}
+
+	// Breadth-first search yields the path with the fewest legs
+	type node struct {
+		currency string
+		path     []client.RateLeg
+	}
+	visited := map[string]bool{base: true}
+	queue := []node{{currency: base}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if len(current.path) > e.maxHops {
+			continue
+		}
+
+		for _, leg := range graph[current.currency] {
+			if visited[leg.To] {
+				continue
+			}
+			path := append(append([]client.RateLeg(nil), current.path...), leg)
+			if leg.To == quote {
+				return path, nil
+			}
+			visited[leg.To] = true
+			queue = append(queue, node{currency: leg.To, path: path})
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no cross-rate path for %s", ErrUnknownMarket, market)
+}
+
+// Derive combines leg rates into a synthetic quote.
+// The ask is the cost of buying one unit of the base currency through the path
+// and the bid is the proceeds of selling it, so the spread compounds across legs.
+func Derive(market string, path []client.RateLeg, legRates []*client.RateData) (*client.RateData, error) {
+	if len(path) == 0 || len(path) != len(legRates) {
+		return nil, fmt.Errorf("invalid cross-rate path for %s", market)
+	}
+
+	ask := big.NewRat(1, 1)
+	bid := big.NewRat(1, 1)
+	var timestamp, receivedAt time.Time
+
+	for i, leg := range path {
+		rate := legRates[i]
+
+		legAsk, ok := new(big.Rat).SetString(rate.Ask)
+		if !ok || legAsk.Sign() <= 0 {
+			return nil, fmt.Errorf("no ask price for %s", leg.Market)
+		}
+		legBid, ok := new(big.Rat).SetString(rate.Bid)
+		if !ok || legBid.Sign() <= 0 {
+			return nil, fmt.Errorf("no bid price for %s", leg.Market)
+		}
+
+		// Travelling against the market direction swaps and inverts the sides
+		if leg.Inverted {
+			legAsk, legBid = new(big.Rat).Inv(legBid), new(big.Rat).Inv(legAsk)
+		}
+
+		ask.Mul(ask, legAsk)
+		bid.Mul(bid, legBid)
+
+		// A derived quote is only as fresh as its oldest leg
+		if legTime := rate.Time(); timestamp.IsZero() || legTime.Before(timestamp) {
+			timestamp = legTime
+		}
+		if receivedAt.IsZero() || rate.ReceivedAt.Before(receivedAt) {
+			receivedAt = rate.ReceivedAt
+		}
+	}
+
+	return &client.RateData{
+		Ask:        ask.FloatString(crossRatePrecision),
+		Bid:        bid.FloatString(crossRatePrecision),
+		Timestamp:  timestamp,
+		ReceivedAt: receivedAt,
+		Market:     market,
+		Derived:    true,
+		Path:       path,
+	}, nil
+}
+
+// getCrossRates derives rates for a market that is not listed on the exchange
+func (s *RatesService) getCrossRates(ctx context.Context, market string) (*client.RateData, error) {
+	ctx, span := tracer.Start(ctx, "RatesService.getCrossRates", trace.WithAttributes(
+		attribute.String("rates.market", market),
+		attribute.String("rates.source", sourceCross)))
+	defer span.End()
+
+	path, err := s.crossRates.FindPath(market)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	legs := make([]string, len(path))
+	for i, leg := range path {
+		legs[i] = leg.Market
+	}
+	span.SetAttributes(attribute.StringSlice("rates.legs", legs))
+
+	s.log(ctx).Info("Deriving cross rate",
+		zap.String("market", market),
+		zap.Int("legs", len(path)))
+
+	legRates := make([]*client.RateData, len(path))
+	for i, leg := range path {
+		rate, err := s.fetchRates(ctx, leg.Market)
+		if err != nil {
+			return nil, spanError(span, err)
+		}
+		legRates[i] = rate
+	}
+
+	rateData, err := Derive(market, path, legRates)
+	if err != nil {
+		s.log(ctx).Error("Failed to derive cross rate", zap.String("market", market), zap.Error(err))
+		return nil, spanError(span, fmt.Errorf("failed to derive cross rate: %w", err))
+	}
+	observeRate(rateData, sourceCross)
+
+	s.log(ctx).Info("Successfully derived cross rate",
+		zap.String("market", market),
+		zap.String("ask", rateData.Ask),
+		zap.String("bid", rateData.Bid))
+
+	return rateData, nil
+}
+
+// activeMarkets returns catalog markets usable as cross-rate legs
+func (e *CrossRateEngine) activeMarkets() []Market {
+	var markets []Market
+	for _, m := range e.catalog.List() {
+		if m.Status == MarketStatusActive && m.Base != "" && m.Quote != "" {
+			markets = append(markets, m)
+		}
+	}
+	return markets
+}
+
+// currencies returns the set of currencies traded in the given markets
+func currencies(markets []Market) map[string]bool {
+	set := make(map[string]bool)
+	for _, m := range markets {
+		set[m.Base] = true
+		set[m.Quote] = true
+	}
+	return set
+}
+
+// splitMarket splits a market id into base and quote currencies
+func splitMarket(market string, known map[string]bool) (string, string, bool) {
+	market = strings.ToLower(market)
+
+	for _, sep := range []string{"/", "_", "-"} {
+		if parts := strings.Split(market, sep); len(parts) == 2 {
+			return parts[0], parts[1], known[parts[0]] && known[parts[1]] && parts[0] != parts[1]
+		}
+	}
+
+	for i := 1; i < len(market); i++ {
+		base, quote := market[:i], market[i:]
+		if known[base] && known[quote] && base != quote {
+			return base, quote, true
+		}
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,17 @@
+package service
+
+import "errors"
+
+var (
+	// ErrUnknownMarket is returned when a market is not present in the catalog
+	ErrUnknownMarket = errors.New("unknown market")
+
+	// ErrMarketInactive is returned when a market exists but is not open for trading
+	ErrMarketInactive = errors.New("market is not active")
+
+	// ErrRejectedQuote is returned when an upstream quote fails sanity checks
+	ErrRejectedQuote = errors.New("upstream quote rejected")
+
+	// ErrStaleQuote is returned when no quote within the requested max age is available
+	ErrStaleQuote = errors.New("quote is older than max age")
+)
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Rejection reasons recorded for quarantined quotes
+const (
+	RejectInvalidPrice     = "invalid_price"
+	RejectNonPositivePrice = "non_positive_price"
+	RejectCrossedBook      = "crossed_book"
+	RejectStaleTimestamp   = "stale_timestamp"
+	RejectPriceJump        = "price_jump"
+)
+
+// quarantinedQuotes counts quotes rejected by the sanity guard
+var quarantinedQuotes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rates_quarantined_quotes_total",
+	Help: "Upstream quotes rejected by sanity checks.",
+}, []string{"market", "reason"})
+
+// QuarantineStore records rejected quotes
+type QuarantineStore interface {
+	QuarantineRate(ctx context.Context, rate *postgres.QuarantinedRate) error
+}
+
+// GuardConfig holds sanity guard thresholds
+type GuardConfig struct {
+	// MaxStaleness rejects quotes whose exchange timestamp is older than this; 0 disables the check
+	MaxStaleness time.Duration
+	// JumpSigma rejects mid price moves beyond this many standard deviations of recent moves; 0 disables the check
+	JumpSigma float64
+	// JumpWindow is the number of recent accepted moves used for the statistics
+	JumpWindow int
+	// JumpMinSamples is the number of moves needed before jumps are checked
+	JumpMinSamples int
+	// JumpMinPercent ignores moves smaller than this, so flat markets do not reject every tick
+	JumpMinPercent float64
+	// MaxConsecutiveJumps accepts the next quote after this many rejected jumps in a row,
+	// treating the new level as a genuine move rather than a glitch
+	MaxConsecutiveJumps int
+}
+
+// guardState holds the recent history of a market
+type guardState struct {
+	lastMid  float64
+	returns  []float64
+	rejected int
+}
+
+// SanityGuard validates upstream quotes before they reach the service.
+// It implements GrinexClient so it can wrap any client.
+type SanityGuard struct {
+	upstream   GrinexClient
+	quarantine QuarantineStore
+	cfg        GuardConfig
+	logger     *zap.Logger
+
+	mu     sync.Mutex
+	states map[string]*guardState
+}
+
+// NewSanityGuard creates a guard in front of the upstream client
+func NewSanityGuard(upstream GrinexClient, quarantine QuarantineStore, cfg GuardConfig, logger *zap.Logger) *SanityGuard {
+	if cfg.JumpWindow <= 0 {
+		cfg.JumpWindow = 30
+	}
+	if cfg.JumpMinSamples <= 0 || cfg.JumpMinSamples > cfg.JumpWindow {
+		cfg.JumpMinSamples = cfg.JumpWindow
+	}
+
+	return &SanityGuard{
+		upstream:   upstream,
+		quarantine: quarantine,
+		cfg:        cfg,
+		logger:     logger,
+		states:     make(map[string]*guardState),
+	}
+}
+
+// GetRates fetches rates from upstream and rejects quotes that fail sanity checks
+func (g *SanityGuard) GetRates(ctx context.Context, market string) (*client.RateData, error) {
+	rate, err := g.upstream.GetRates(ctx, market)
+	if err != nil {
+		return nil, err
+	}
+
+	reason, detail := g.check(ctx, rate)
+	if reason == "" {
+		return rate, nil
+	}
+
+	quarantinedQuotes.WithLabelValues(rate.Market, reason).Inc()
+	trace.SpanFromContext(ctx).AddEvent("quote quarantined", trace.WithAttributes(
+		attribute.String("rates.reject_reason", reason)))
+	g.log(ctx).Warn("Upstream quote rejected",
+		zap.String("market", rate.Market),
+		zap.String("ask", rate.Ask),
+		zap.String("bid", rate.Bid),
+		zap.String("reason", reason),
+		zap.String("detail", detail))
+
+	if g.quarantine != nil {
+		// Recording is best effort, the quote is rejected either way
+		_ = g.quarantine.QuarantineRate(ctx, &postgres.QuarantinedRate{
+			Market:     rate.Market,
+			Ask:        rate.Ask,
+			Bid:        rate.Bid,
+			Timestamp:  rate.Timestamp,
+			ReceivedAt: rate.ReceivedAt,
+			Reason:     reason,
+			Detail:     detail,
+		})
+	}
+
+	return nil, fmt.Errorf("%w: %s: %s", ErrRejectedQuote, reason, detail)
+}
+
+// check returns the rejection reason and detail, or an empty reason if the quote is sane
+func (g *SanityGuard) check(ctx context.Context, rate *client.RateData) (string, string) {
+	ask, err := strconv.ParseFloat(rate.Ask, 64)
+	if err != nil {
+		return RejectInvalidPrice, fmt.Sprintf("ask %q is not a number", rate.Ask)
+	}
+	bid, err := strconv.ParseFloat(rate.Bid, 64)
+	if err != nil {
+		return RejectInvalidPrice, fmt.Sprintf("bid %q is not a number", rate.Bid)
+	}
+
+	if ask <= 0 || bid <= 0 {
+		return RejectNonPositivePrice, fmt.Sprintf("ask %s, bid %s", rate.Ask, rate.Bid)
+	}
+	if bid > ask {
+		return RejectCrossedBook, fmt.Sprintf("bid %s is above ask %s", rate.Bid, rate.Ask)
+	}
+
+	if g.cfg.MaxStaleness > 0 {
+		if age := rate.Age(time.Now()); age > g.cfg.MaxStaleness {
+			return RejectStaleTimestamp, fmt.Sprintf("quote is %s old", age.Truncate(time.Millisecond))
+		}
+	}
+
+	return g.checkJump(ctx, rate.Market, (ask+bid)/2)
+}
+
+// checkJump compares the mid price move with the recent moves of the market
+// and records the move when it is accepted
+func (g *SanityGuard) checkJump(ctx context.Context, market string, mid float64) (string, string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	state, ok := g.states[market]
+	if !ok {
+		g.states[market] = &guardState{lastMid: mid}
+		return "", ""
+	}
+
+	move := math.Log(mid / state.lastMid)
+
+	if g.cfg.JumpSigma > 0 && len(state.returns) >= g.cfg.JumpMinSamples &&
+		math.Abs(mid/state.lastMid-1)*100 > g.cfg.JumpMinPercent {
+		mean, stddev := meanStddev(state.returns)
+		if deviation := math.Abs(move - mean); deviation > g.cfg.JumpSigma*stddev {
+			state.rejected++
+			if g.cfg.MaxConsecutiveJumps <= 0 || state.rejected <= g.cfg.MaxConsecutiveJumps {
+				return RejectPriceJump, fmt.Sprintf("mid moved %.4f%% from %g, %.1f sigma",
+					(mid/state.lastMid-1)*100, state.lastMid, deviation/math.Max(stddev, math.SmallestNonzeroFloat64))
+			}
+
+			// The price stayed at the new level, start over from it
+			g.log(ctx).Info("Accepting sustained price move", zap.String("market", market), zap.Float64("mid", mid))
+			g.states[market] = &guardState{lastMid: mid}
+			return "", ""
+		}
+	}
+
+	state.rejected = 0
+	state.lastMid = mid
+	state.returns = append(state.returns, move)
+	if len(state.returns) > g.cfg.JumpWindow {
+		state.returns = state.returns[len(state.returns)-g.cfg.JumpWindow:]
+	}
+
+	return "", ""
+}
+
+// meanStddev returns the mean and population standard deviation of values
+func meanStddev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+
+	return mean, math.Sqrt(variance)
+}
@@ -13,9 +13,25 @@ type GrinexClient interface {
 	GetRates(ctx context.Context, market string) (*client.RateData, error)
 }
 
+// UpstreamProbe checks that the exchange is reachable without fetching a quote
+type UpstreamProbe interface {
+	Ping(ctx context.Context) error
+}
+
+// Pricer applies customer pricing on top of raw exchange rates
+type Pricer interface {
+	Apply(rate *client.RateData, tier string)
+}
+
+// RateObserver receives every rate fetched here that becomes the latest
+// quote of its market. Implementations must not block.
+type RateObserver interface {
+	ObserveRate(rate *client.RateData)
+}
+
 // Repository interface for data storage
 type Repository interface {
-	SaveRate(ctx context.Context, market, ask, bid string, timestamp time.Time) error
+	SaveRate(ctx context.Context, market, ask, bid string, timestamp, receivedAt time.Time) error
 	GetRates(ctx context.Context, market string, limit, offset int) ([]postgres.Rate, error)
 	GetLatestRate(ctx context.Context, market string) (*postgres.Rate, error)
 	Ping(ctx context.Context) error
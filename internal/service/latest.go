@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.uber.org/zap"
+)
+
+// latestRates holds the latest quote of every market, whether it was
+// fetched here or saved by another instance, and the subscriptions to them
+type latestRates struct {
+	mu            sync.Mutex
+	rates         map[string]client.RateData
+	subscriptions map[*Subscription]struct{}
+}
+
+func newLatestRates() *latestRates {
+	return &latestRates{
+		rates:         make(map[string]client.RateData),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+func (c *latestRates) get(market string) (client.RateData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rate, ok := c.rates[market]
+	return rate, ok
+}
+
+func (c *latestRates) markets() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	markets := make([]string, 0, len(c.rates))
+	for market := range c.rates {
+		markets = append(markets, market)
+	}
+	return markets
+}
+
+// Subscription receives the latest quotes of a set of markets. A quote
+// that arrives before the previous quote of its market was read replaces
+// it, so a slow reader gets the latest quote of each market and never
+// holds up other subscribers.
+type Subscription struct {
+	markets map[string]bool // nil for every market
+	ready   chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]client.RateData
+}
+
+func (sub *Subscription) wants(market string) bool {
+	return sub.markets == nil || sub.markets[market]
+}
+
+func (sub *Subscription) offer(rate client.RateData) {
+	sub.mu.Lock()
+	sub.pending[rate.Market] = rate
+	sub.mu.Unlock()
+
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready receives a value when Next has quotes to return
+func (sub *Subscription) Ready() <-chan struct{} {
+	return sub.ready
+}
+
+// Next returns the quotes received since the previous call, sorted by market
+func (sub *Subscription) Next() []client.RateData {
+	sub.mu.Lock()
+	rates := make([]client.RateData, 0, len(sub.pending))
+	for _, rate := range sub.pending {
+		rates = append(rates, rate)
+	}
+	sub.pending = make(map[string]client.RateData)
+	sub.mu.Unlock()
+
+	slices.SortFunc(rates, func(a, b client.RateData) int {
+		return strings.Compare(a.Market, b.Market)
+	})
+	return rates
+}
+
+// SubscribeRates subscribes to the latest quotes of the markets, or of
+// every market when none are given. The known quotes are ready right away.
+// The subscription must be ended with Unsubscribe.
+func (s *RatesService) SubscribeRates(markets []string) *Subscription {
+	sub := &Subscription{
+		ready:   make(chan struct{}, 1),
+		pending: make(map[string]client.RateData),
+	}
+	if len(markets) > 0 {
+		sub.markets = make(map[string]bool, len(markets))
+		for _, market := range markets {
+			sub.markets[market] = true
+		}
+	}
+
+	s.latest.mu.Lock()
+	defer s.latest.mu.Unlock()
+	for _, rate := range s.latest.rates {
+		if sub.wants(rate.Market) {
+			sub.offer(rate)
+		}
+	}
+	s.latest.subscriptions[sub] = struct{}{}
+	activeSubscriptions.Inc()
+	return sub
+}
+
+// Unsubscribe ends a subscription
+func (s *RatesService) Unsubscribe(sub *Subscription) {
+	s.latest.mu.Lock()
+	defer s.latest.mu.Unlock()
+	if _, ok := s.latest.subscriptions[sub]; ok {
+		delete(s.latest.subscriptions, sub)
+		activeSubscriptions.Dec()
+	}
+}
+
+// publish makes the rate the latest quote of its market, updates the gauges
+// and passes the rate to the subscriptions. A local rate, fetched by this
+// instance, is passed to the observers as well, so alert rules evaluate a
+// rate once, on the instance that fetched it. A rate that is not newer than
+// the latest quote is ignored and publish reports false. Everything is
+// updated under the lock, so nothing goes back to an older quote.
+func (s *RatesService) publish(rate *client.RateData, local bool) bool {
+	s.latest.mu.Lock()
+	defer s.latest.mu.Unlock()
+
+	if last, ok := s.latest.rates[rate.Market]; ok && !rate.Time().After(last.Time()) {
+		return false
+	}
+	// Pricing changes the returned rate, so the cache keeps a copy
+	s.latest.rates[rate.Market] = *rate
+
+	observeRate(rate, sourceExchange)
+	for sub := range s.latest.subscriptions {
+		if sub.wants(rate.Market) {
+			sub.offer(*rate)
+		}
+	}
+	if local {
+		for _, observer := range s.observers {
+			observer.ObserveRate(rate)
+		}
+	}
+	return true
+}
+
+// SyncLatestRates loads the latest saved rate of the catalog markets and of
+// the cached markets from the database and publishes the newer ones. It
+// catches up with the rates other instances saved while their
+// notifications could not be received.
+func (s *RatesService) SyncLatestRates(ctx context.Context) {
+	markets := s.latest.markets()
+	for _, market := range s.ListMarkets(ctx) {
+		markets = append(markets, market.ID)
+	}
+	slices.Sort(markets)
+	markets = slices.Compact(markets)
+
+	applied := 0
+	for _, market := range markets {
+		rate, err := s.repository.GetLatestRate(ctx, market)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.log(ctx).Warn("Failed to load latest rate", zap.String("market", market), zap.Error(err))
+			continue
+		}
+		if rate != nil && s.publish(rateDataOf(rate), false) {
+			applied++
+		}
+	}
+
+	s.log(ctx).Info("Synced latest rates", zap.Int("markets", len(markets)), zap.Int("applied", applied))
+}
+
+// rateDataOf converts a saved rate
+func rateDataOf(rate *postgres.Rate) *client.RateData {
+	return &client.RateData{
+		Market:     rate.Market,
+		Ask:        rate.Ask,
+		Bid:        rate.Bid,
+		Timestamp:  rate.Timestamp,
+		ReceivedAt: rate.ReceivedAt,
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Rate sources used as metric labels
+const (
+	sourceExchange = "grinex"
+	sourceCross    = "cross"
+)
+
+var rateLabels = []string{"market", "source"}
+
+var (
+	lastAsk = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rates_last_ask",
+		Help: "Last ask price received per market and source.",
+	}, rateLabels)
+	lastBid = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rates_last_bid",
+		Help: "Last bid price received per market and source.",
+	}, rateLabels)
+	lastMid = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rates_last_mid",
+		Help: "Midpoint of the last ask and bid per market and source.",
+	}, rateLabels)
+	lastSpread = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rates_last_spread",
+		Help: "Difference between the last ask and bid per market and source.",
+	}, rateLabels)
+	activeSubscriptions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rates_subscriptions",
+		Help: "Open rate subscriptions.",
+	})
+)
+
+// quoteAges reports how old the last quote of every market is at scrape time
+var quoteAges = newQuoteAgeCollector()
+
+func init() {
+	prometheus.MustRegister(quoteAges)
+}
+
+// observeRate updates the price gauges with a received quote. Prices that
+// are not numbers, such as "N/A" for an empty book side, are skipped.
+func observeRate(rate *client.RateData, source string) {
+	ask, askErr := strconv.ParseFloat(rate.Ask, 64)
+	if askErr == nil {
+		lastAsk.WithLabelValues(rate.Market, source).Set(ask)
+	}
+	bid, bidErr := strconv.ParseFloat(rate.Bid, 64)
+	if bidErr == nil {
+		lastBid.WithLabelValues(rate.Market, source).Set(bid)
+	}
+	if askErr == nil && bidErr == nil {
+		lastMid.WithLabelValues(rate.Market, source).Set((ask + bid) / 2)
+		lastSpread.WithLabelValues(rate.Market, source).Set(ask - bid)
+	}
+
+	quoteAges.observe(rate.Market, source, rate.Time())
+}
+
+// quoteAgeCollector exports rates_quote_age_seconds computed from the time
+// of the last quote, so the age keeps growing while no quotes arrive
+type quoteAgeCollector struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	mu     sync.Mutex
+	quotes map[[2]string]time.Time
+}
+
+func newQuoteAgeCollector() *quoteAgeCollector {
+	return &quoteAgeCollector{
+		desc: prometheus.NewDesc("rates_quote_age_seconds",
+			"Age of the last quote per market and source.", rateLabels, nil),
+		now:    time.Now,
+		quotes: make(map[[2]string]time.Time),
+	}
+}
+
+func (c *quoteAgeCollector) observe(market, source string, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quotes[[2]string{market, source}] = t
+}
+
+func (c *quoteAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *quoteAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, t := range c.quotes {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(t).Seconds(), key[0], key[1])
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import "time"
+
+// Option configures optional RatesService behaviour
+type Option func(*RatesService)
+
+// WithBatchConcurrency sets the number of workers used by BatchGetRates
+func WithBatchConcurrency(n int) Option {
+	return func(s *RatesService) {
+		if n > 0 {
+			s.batchConcurrency.Store(int64(n))
+		}
+	}
+}
+
+// WithMarketCatalog validates requested markets against the catalog
+// before any upstream call is made
+func WithMarketCatalog(catalog *MarketCatalog) Option {
+	return func(s *RatesService) {
+		s.catalog = catalog
+	}
+}
+
+// WithCrossRates derives quotes for markets missing from the catalog
+func WithCrossRates(engine *CrossRateEngine) Option {
+	return func(s *RatesService) {
+		s.crossRates = engine
+	}
+}
+
+// WithPricing applies customer pricing rules to returned rates
+func WithPricing(pricer Pricer) Option {
+	return func(s *RatesService) {
+		s.pricer = pricer
+	}
+}
+
+// WithRateObservers notifies observers of every new latest quote fetched by this instance
+func WithRateObservers(observers ...RateObserver) Option {
+	return func(s *RatesService) {
+		s.observers = append(s.observers, observers...)
+	}
+}
+
+// WithUpstreamProbe makes health checks report whether the exchange is reachable
+func WithUpstreamProbe(probe UpstreamProbe) Option {
+	return func(s *RatesService) {
+		s.probe = probe
+	}
+}
+
+// RequestOption configures a single rates request
+type RequestOption func(*ratesRequest)
+
+// ratesRequest holds per-request parameters
+type ratesRequest struct {
+	clientTier string
+	maxAge     time.Duration
+}
+
+// WithClientTier selects the pricing tier of the requesting client
+func WithClientTier(tier string) RequestOption {
+	return func(r *ratesRequest) {
+		r.clientTier = tier
+	}
+}
+
+// WithMaxAge refuses quotes older than maxAge after one refresh attempt; 0 accepts any age
+func WithMaxAge(maxAge time.Duration) RequestOption {
+	return func(r *ratesRequest) {
+		r.maxAge = maxAge
+	}
+}
+
+// newRatesRequest applies request options
+func newRatesRequest(opts []RequestOption) ratesRequest {
+	var req ratesRequest
+	for _, opt := range opts {
+		opt(&req)
+	}
+	return req
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidQuoteToken is returned when a quote token is malformed or its signature does not match
+var ErrInvalidQuoteToken = errors.New("invalid quote token")
+
+// quoteClaims are the quote fields covered by the token signature
+type quoteClaims struct {
+	ID        string `json:"id"`
+	Market    string `json:"market"`
+	Side      string `json:"side"`
+	Amount    string `json:"amount"`
+	Price     string `json:"price"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// QuoteSigner issues and verifies tamper-evident quote tokens.
+// A token is the base64url encoded claims followed by their HMAC-SHA256 signature.
+type QuoteSigner struct {
+	keys atomic.Pointer[signingKeys]
+}
+
+// signingKeys are the key tokens are signed with and the key it replaced
+type signingKeys struct {
+	current  []byte
+	previous []byte
+}
+
+// NewQuoteSigner creates a quote signer with the given secret key
+func NewQuoteSigner(key []byte) *QuoteSigner {
+	s := &QuoteSigner{}
+	s.keys.Store(&signingKeys{current: key})
+	return s
+}
+
+// SetKey replaces the signing key, e.g. when it is rotated. Tokens signed
+// with the replaced key still verify until the key is rotated again, so
+// quotes issued before the rotation can be redeemed.
+func (s *QuoteSigner) SetKey(key []byte) {
+	for {
+		old := s.keys.Load()
+		if s.keys.CompareAndSwap(old, &signingKeys{current: key, previous: old.current}) {
+			return
+		}
+	}
+}
+
+// Sign returns a token for the quote
+func (s *QuoteSigner) Sign(quote *Quote) (string, error) {
+	payload, err := json.Marshal(claimsOf(quote))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode quote claims: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac(s.keys.Load().current, encoded)), nil
+}
+
+// Verify checks the token signature and that it was issued for the quote
+func (s *QuoteSigner) Verify(token string, quote *Quote) error {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidQuoteToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !s.signed(sig, encoded) {
+		return ErrInvalidQuoteToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidQuoteToken
+	}
+
+	var claims quoteClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ErrInvalidQuoteToken
+	}
+
+	if !claims.matches(claimsOf(quote)) {
+		return ErrInvalidQuoteToken
+	}
+
+	return nil
+}
+
+// matches compares claims, treating decimals numerically since the
+// database may return them with a different scale
+func (c quoteClaims) matches(other quoteClaims) bool {
+	return c.ID == other.ID &&
+		c.Market == other.Market &&
+		c.Side == other.Side &&
+		c.ExpiresAt == other.ExpiresAt &&
+		decimalEqual(c.Amount, other.Amount) &&
+		decimalEqual(c.Price, other.Price)
+}
+
+// signed reports whether sig is the signature of data with the current or
+// the previous key
+func (s *QuoteSigner) signed(sig []byte, data string) bool {
+	keys := s.keys.Load()
+	if hmac.Equal(sig, mac(keys.current, data)) {
+		return true
+	}
+	return keys.previous != nil && hmac.Equal(sig, mac(keys.previous, data))
+}
+
+func mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// claimsOf extracts the signed fields of a quote
+func claimsOf(quote *Quote) quoteClaims {
+	return quoteClaims{
+		ID:        quote.ID,
+		Market:    quote.Market,
+		Side:      quote.Side,
+		Amount:    quote.Amount,
+		Price:     quote.Price,
+		ExpiresAt: quote.ExpiresAt.UnixMilli(),
+	}
+}
+
+// decimalEqual reports whether two decimal strings hold the same value
+func decimalEqual(a, b string) bool {
+	x, ok := new(big.Rat).SetString(a)
+	if !ok {
+		return false
+	}
+	y, ok := new(big.Rat).SetString(b)
+	if !ok {
+		return false
+	}
+	return x.Cmp(y) == 0
+}
+
+// truncateExpiry drops sub-millisecond precision so that stored and signed expiry times match
+func truncateExpiry(t time.Time) time.Time {
+	return t.Truncate(time.Millisecond)
+}
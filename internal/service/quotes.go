@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Quote sides
+const (
+	QuoteSideBuy  = "buy"
+	QuoteSideSell = "sell"
+)
+
+// QuoteStatus is the outcome of redeeming a quote
+type QuoteStatus string
+
+// Quote statuses
+const (
+	QuoteStatusValid   QuoteStatus = "valid"
+	QuoteStatusExpired QuoteStatus = "expired"
+	QuoteStatusUsed    QuoteStatus = "used"
+	QuoteStatusInvalid QuoteStatus = "invalid"
+)
+
+var (
+	// ErrQuoteNotFound is returned when a quote id is unknown
+	ErrQuoteNotFound = errors.New("quote not found")
+
+	// ErrInvalidQuoteRequest is returned for malformed quote requests
+	ErrInvalidQuoteRequest = errors.New("invalid quote request")
+
+	// ErrNoLiquidity is returned when the order book has no price for the requested side
+	ErrNoLiquidity = errors.New("no liquidity")
+)
+
+// amountPattern matches decimals that fit the DECIMAL(30, 8) amount column
+// exactly, so the stored amount matches the signed one
+var amountPattern = regexp.MustCompile(`^[0-9]{1,22}(\.[0-9]{1,8})?$`)
+
+// pricePattern matches decimals that fit the DECIMAL(20, 8) price column
+// exactly; other prices are rounded to quotePriceScale before signing
+var pricePattern = regexp.MustCompile(`^[0-9]{1,12}(\.[0-9]{1,8})?$`)
+
+// quotePriceScale is the number of decimal places of the price column
+const quotePriceScale = 8
+
+// Quote is a price locked for a customer until it expires
+type Quote struct {
+	ID            string
+	Market        string
+	Side          string
+	Amount        string
+	Price         string
+	Total         string
+	ClientTier    string
+	PricingRuleID string
+	ExpiresAt     time.Time
+	Token         string
+}
+
+// QuoteRepository stores locked quotes
+type QuoteRepository interface {
+	SaveQuote(ctx context.Context, quote *postgres.LockedQuote) error
+	GetQuote(ctx context.Context, id string) (*postgres.LockedQuote, error)
+	RedeemQuote(ctx context.Context, id string) (bool, error)
+	DeleteExpiredQuotes(ctx context.Context, before time.Time) (int64, error)
+}
+
+// QuoteRates provides the prices quotes are locked at
+type QuoteRates interface {
+	GetRates(ctx context.Context, market string, opts ...RequestOption) (*client.RateData, error)
+}
+
+// QuoteService creates and redeems locked quotes
+type QuoteService struct {
+	rates      QuoteRates
+	repository QuoteRepository
+	signer     *QuoteSigner
+	ttl        time.Duration
+	logger     *zap.Logger
+}
+
+// NewQuoteService creates a new quote service
+func NewQuoteService(rates QuoteRates, repository QuoteRepository, signer *QuoteSigner, ttl time.Duration, logger *zap.Logger) *QuoteService {
+	return &QuoteService{
+		rates:      rates,
+		repository: repository,
+		signer:     signer,
+		ttl:        ttl,
+		logger:     logger,
+	}
+}
+
+// CreateQuote locks the current customer price for the market and side
+func (s *QuoteService) CreateQuote(ctx context.Context, market, side, amount, tier string) (*Quote, error) {
+	ctx, span := tracer.Start(ctx, "QuoteService.CreateQuote", trace.WithAttributes(
+		attribute.String("rates.market", market),
+		attribute.String("quotes.side", side),
+		attribute.String("rates.client_tier", tier)))
+	defer span.End()
+
+	quote, err := s.createQuote(ctx, market, side, amount, tier)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+	span.SetAttributes(attribute.String("quotes.id", quote.ID))
+	return quote, nil
+}
+
+func (s *QuoteService) createQuote(ctx context.Context, market, side, amount, tier string) (*Quote, error) {
+	s.log(ctx).Info("Creating locked quote",
+		zap.String("market", market),
+		zap.String("side", side),
+		zap.String("amount", amount))
+
+	if side != QuoteSideBuy && side != QuoteSideSell {
+		return nil, fmt.Errorf("%w: side must be %q or %q", ErrInvalidQuoteRequest, QuoteSideBuy, QuoteSideSell)
+	}
+	if !amountPattern.MatchString(amount) {
+		return nil, fmt.Errorf("%w: amount must be a positive decimal with at most 8 decimal places", ErrInvalidQuoteRequest)
+	}
+	qty, ok := new(big.Rat).SetString(amount)
+	if !ok || qty.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: amount must be a positive decimal with at most 8 decimal places", ErrInvalidQuoteRequest)
+	}
+
+	rateData, err := s.rates.GetRates(ctx, market, WithClientTier(tier))
+	if err != nil {
+		return nil, err
+	}
+
+	// Customers buy at the ask and sell at the bid
+	price := customerPrice(rateData.Ask, rateData.CustomerAsk)
+	if side == QuoteSideSell {
+		price = customerPrice(rateData.Bid, rateData.CustomerBid)
+	}
+	unitPrice, ok := new(big.Rat).SetString(price)
+	if ok && !pricePattern.MatchString(price) {
+		// Round in favour of the house: buyers pay up, sellers get less
+		price = roundPrice(unitPrice, side == QuoteSideBuy)
+		unitPrice.SetString(price)
+	}
+	if !ok || unitPrice.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: no %s price for %s", ErrNoLiquidity, side, market)
+	}
+	if !pricePattern.MatchString(price) {
+		return nil, fmt.Errorf("%s price %s for %s does not fit a locked quote", side, price, market)
+	}
+
+	quote := &Quote{
+		ID:            uuid.NewString(),
+		Market:        market,
+		Side:          side,
+		Amount:        amount,
+		Price:         price,
+		Total:         new(big.Rat).Mul(unitPrice, qty).FloatString(crossRatePrecision),
+		ClientTier:    tier,
+		PricingRuleID: rateData.PricingRuleID,
+		ExpiresAt:     truncateExpiry(time.Now().Add(s.ttl)),
+	}
+
+	quote.Token, err = s.signer.Sign(quote)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repository.SaveQuote(ctx, &postgres.LockedQuote{
+		ID:            quote.ID,
+		Market:        quote.Market,
+		Side:          quote.Side,
+		Amount:        quote.Amount,
+		Price:         quote.Price,
+		ClientTier:    quote.ClientTier,
+		PricingRuleID: quote.PricingRuleID,
+		ExpiresAt:     quote.ExpiresAt,
+	})
+	if err != nil {
+		s.log(ctx).Error("Failed to save locked quote", zap.Error(err))
+		return nil, fmt.Errorf("failed to save locked quote: %w", err)
+	}
+
+	s.log(ctx).Info("Locked quote created",
+		zap.String("id", quote.ID),
+		zap.String("price", quote.Price),
+		zap.Time("expires_at", quote.ExpiresAt))
+
+	return quote, nil
+}
+
+// RedeemQuote verifies the token and marks the quote as used.
+// The quote is returned together with its status; only the first successful
+// redemption of an unexpired quote is reported as valid.
+func (s *QuoteService) RedeemQuote(ctx context.Context, id, token string) (*Quote, QuoteStatus, error) {
+	ctx, span := tracer.Start(ctx, "QuoteService.RedeemQuote", trace.WithAttributes(attribute.String("quotes.id", id)))
+	defer span.End()
+
+	quote, status, err := s.redeemQuote(ctx, id, token)
+	if err != nil {
+		return nil, "", spanError(span, err)
+	}
+	span.SetAttributes(attribute.String("quotes.status", string(status)))
+	return quote, status, nil
+}
+
+func (s *QuoteService) redeemQuote(ctx context.Context, id, token string) (*Quote, QuoteStatus, error) {
+	s.log(ctx).Info("Redeeming locked quote", zap.String("id", id))
+
+	stored, err := s.repository.GetQuote(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get locked quote: %w", err)
+	}
+	if stored == nil {
+		return nil, "", fmt.Errorf("%w: %s", ErrQuoteNotFound, id)
+	}
+
+	quote := quoteFromRecord(stored)
+	quote.Token = token
+
+	if err := s.signer.Verify(token, quote); err != nil {
+		s.log(ctx).Warn("Locked quote token rejected", zap.String("id", id))
+		return quote, QuoteStatusInvalid, nil
+	}
+
+	if stored.RedeemedAt.Valid {
+		return quote, QuoteStatusUsed, nil
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return quote, QuoteStatusExpired, nil
+	}
+
+	redeemed, err := s.repository.RedeemQuote(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to redeem locked quote: %w", err)
+	}
+	if !redeemed {
+		// Lost a race with a concurrent redemption or the quote expired meanwhile
+		if time.Now().Before(stored.ExpiresAt) {
+			return quote, QuoteStatusUsed, nil
+		}
+		return quote, QuoteStatusExpired, nil
+	}
+
+	s.log(ctx).Info("Locked quote redeemed", zap.String("id", id))
+
+	return quote, QuoteStatusValid, nil
+}
+
+// StartCleanup periodically deletes quotes that expired more than retention ago
+func (s *QuoteService) StartCleanup(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				deleted, err := s.repository.DeleteExpiredQuotes(ctx, time.Now().Add(-retention))
+				if err != nil {
+					s.log(ctx).Error("Failed to clean up expired quotes", zap.Error(err))
+					continue
+				}
+				if deleted > 0 {
+					s.log(ctx).Info("Expired quotes cleaned up", zap.Int64("count", deleted))
+				}
+			}
+		}
+	}()
+}
+
+// roundPrice rounds a positive price to quotePriceScale decimal places
+func roundPrice(price *big.Rat, up bool) string {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(quotePriceScale), nil)
+	scaled := new(big.Rat).Mul(price, new(big.Rat).SetInt(scale))
+
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if up && rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	return new(big.Rat).SetFrac(quo, scale).FloatString(quotePriceScale)
+}
+
+// customerPrice prefers the customer price when pricing rules are applied
+func customerPrice(raw, customer string) string {
+	if customer != "" {
+		return customer
+	}
+	return raw
+}
+
+// quoteFromRecord converts a stored quote
+func quoteFromRecord(record *postgres.LockedQuote) *Quote {
+	quote := &Quote{
+		ID:            record.ID,
+		Market:        record.Market,
+		Side:          record.Side,
+		Amount:        record.Amount,
+		Price:         record.Price,
+		ClientTier:    record.ClientTier,
+		PricingRuleID: record.PricingRuleID,
+		ExpiresAt:     record.ExpiresAt,
+	}
+
+	price, okPrice := new(big.Rat).SetString(record.Price)
+	amount, okAmount := new(big.Rat).SetString(record.Amount)
+	if okPrice && okAmount {
+		quote.Total = new(big.Rat).Mul(price, amount).FloatString(crossRatePrecision)
+	}
+
+	return quote
+}
@@ -2,49 +2,159 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/alik/TestForWork/internal/client"
 	"github.com/alik/TestForWork/internal/storage/postgres"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // RatesService handles business logic for exchange rates
 type RatesService struct {
-	grinexClient GrinexClient
-	repository   Repository
-	logger       *zap.Logger
+	grinexClient     GrinexClient
+	repository       Repository
+	logger           *zap.Logger
+	batchConcurrency atomic.Int64
+	catalog          *MarketCatalog
+	crossRates       *CrossRateEngine
+	pricer           Pricer
+	observers        []RateObserver
+	probe            UpstreamProbe
+	latest           *latestRates
 }
 
 // NewRatesService creates a new rates service
-func NewRatesService(grinexClient GrinexClient, repository Repository, logger *zap.Logger) *RatesService {
-	return &RatesService{
+func NewRatesService(grinexClient GrinexClient, repository Repository, logger *zap.Logger, opts ...Option) *RatesService {
+	s := &RatesService{
 		grinexClient: grinexClient,
 		repository:   repository,
 		logger:       logger,
+		latest:       newLatestRates(),
 	}
+	s.batchConcurrency.Store(defaultBatchConcurrency)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // GetRates retrieves exchange rates and saves them to the database
-func (s *RatesService) GetRates(ctx context.Context, market string) (*client.RateData, error) {
-	s.logger.Info("Getting rates for market", zap.String("market", market))
+func (s *RatesService) GetRates(ctx context.Context, market string, opts ...RequestOption) (*client.RateData, error) {
+	req := newRatesRequest(opts)
+
+	ctx, span := tracer.Start(ctx, "RatesService.GetRates", trace.WithAttributes(
+		attribute.String("rates.market", market),
+		attribute.String("rates.client_tier", req.clientTier),
+		attribute.Int64("rates.max_age_ms", req.maxAge.Milliseconds())))
+	defer span.End()
+
+	s.log(ctx).Info("Getting rates for market", zap.String("market", market))
+
+	rateData, err := s.getFreshRates(ctx, market, req.maxAge)
+	if err != nil {
+		return nil, spanError(span, err)
+	}
+
+	// Apply customer pricing on top of the raw rates
+	if s.pricer != nil {
+		s.pricer.Apply(rateData, req.clientTier)
+		span.SetAttributes(attribute.String("rates.pricing_rule", rateData.PricingRuleID))
+	}
+
+	return rateData, nil
+}
+
+// getFreshRates retrieves rates and, when maxAge is set, refetches a quote
+// older than maxAge once before refusing it
+func (s *RatesService) getFreshRates(ctx context.Context, market string, maxAge time.Duration) (*client.RateData, error) {
+	rateData, err := s.getRawRates(ctx, market)
+	if err != nil || maxAge <= 0 {
+		return rateData, err
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	if age := rateData.Age(time.Now()); age > maxAge {
+		span.AddEvent("refreshing stale quote", trace.WithAttributes(attribute.Int64("rates.age_ms", age.Milliseconds())))
+		s.log(ctx).Info("Quote is older than max age, refreshing",
+			zap.String("market", market),
+			zap.Duration("age", age),
+			zap.Duration("max_age", maxAge))
+
+		rateData, err = s.getRawRates(ctx, market)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if age := rateData.Age(time.Now()); age > maxAge {
+		span.AddEvent("refusing stale quote", trace.WithAttributes(attribute.Int64("rates.age_ms", age.Milliseconds())))
+		s.log(ctx).Warn("Refusing stale quote",
+			zap.String("market", market),
+			zap.Duration("age", age),
+			zap.Duration("max_age", maxAge))
+		return nil, fmt.Errorf("%w: quote is %s old, max age is %s", ErrStaleQuote, age.Truncate(time.Millisecond), maxAge)
+	}
+
+	return rateData, nil
+}
+
+// getRawRates retrieves exchange rates for listed or derived markets
+func (s *RatesService) getRawRates(ctx context.Context, market string) (*client.RateData, error) {
+	// Reject unknown markets before calling the exchange; a listed market
+	// given with a separator is fetched, not derived
+	if s.catalog != nil {
+		market = s.catalog.Resolve(market)
+		err := s.catalog.Validate(market)
+		if errors.Is(err, ErrUnknownMarket) && s.crossRates != nil {
+			return s.getCrossRates(ctx, market)
+		}
+		if err != nil {
+			trace.SpanFromContext(ctx).AddEvent("market rejected by catalog")
+			s.log(ctx).Warn("Market rejected by catalog", zap.String("market", market), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return s.fetchRates(ctx, market)
+}
+
+// fetchRates retrieves rates for a listed market and saves them to the database
+func (s *RatesService) fetchRates(ctx context.Context, market string) (*client.RateData, error) {
+	ctx, span := tracer.Start(ctx, "RatesService.fetchRates", trace.WithAttributes(
+		attribute.String("rates.market", market),
+		attribute.String("rates.source", sourceExchange)))
+	defer span.End()
 
 	// Get rates from Grinex API
 	rateData, err := s.grinexClient.GetRates(ctx, market)
 	if err != nil {
-		s.logger.Error("Failed to get rates from Grinex", zap.Error(err))
-		return nil, fmt.Errorf("failed to get rates from Grinex: %w", err)
+		s.log(ctx).Error("Failed to get rates from Grinex", zap.Error(err))
+		return nil, spanError(span, fmt.Errorf("failed to get rates from Grinex: %w", err))
 	}
 
 	// Save to database
-	if err := s.repository.SaveRate(ctx, rateData.Market, rateData.Ask, rateData.Bid, rateData.Timestamp); err != nil {
-		s.logger.Error("Failed to save rate to database", zap.Error(err))
+	err = s.repository.SaveRate(ctx, rateData.Market, rateData.Ask, rateData.Bid, rateData.Timestamp, rateData.ReceivedAt)
+	span.SetAttributes(attribute.Bool("rates.persisted", err == nil))
+	if err != nil {
+		span.AddEvent("rate not persisted", trace.WithAttributes(attribute.String("error", err.Error())))
+		s.log(ctx).Error("Failed to save rate to database", zap.Error(err))
 		// Don't return error here - we still want to return the rate data
 		// even if saving to DB fails
 	}
 
-	s.logger.Info("Successfully retrieved and saved rates",
+	// Update the latest quote and feed observers such as alert rules,
+	// unless another instance already applied a newer quote
+	s.publish(rateData, true)
+
+	s.log(ctx).Info("Successfully retrieved and saved rates",
 		zap.String("market", rateData.Market),
 		zap.String("ask", rateData.Ask),
 		zap.String("bid", rateData.Bid))
@@ -52,13 +162,55 @@ func (s *RatesService) GetRates(ctx context.Context, market string) (*client.Rat
 	return rateData, nil
 }
 
-// GetLatestRate retrieves the latest rate from the database
+// ObserveRemoteRate applies a rate another instance fetched and saved: it
+// updates the latest quote of the market and passes it to the
+// subscriptions. Observers such as alert rules are not fed, since the
+// instance that fetched the rate feeds its own. Rates not newer than the
+// latest quote are ignored.
+func (s *RatesService) ObserveRemoteRate(n postgres.RateNotification) {
+	rateData := &client.RateData{
+		Market:     n.Market,
+		Ask:        n.Ask,
+		Bid:        n.Bid,
+		ReceivedAt: n.ReceivedAt,
+	}
+	if n.Timestamp != nil {
+		rateData.Timestamp = *n.Timestamp
+	}
+
+	if !s.publish(rateData, false) {
+		s.logger.Debug("Ignoring rate older than the latest quote",
+			zap.String("market", rateData.Market),
+			zap.String("origin", n.Origin))
+		return
+	}
+
+	s.logger.Debug("Applied rate saved by another instance",
+		zap.String("market", rateData.Market),
+		zap.String("ask", rateData.Ask),
+		zap.String("bid", rateData.Bid),
+		zap.String("origin", n.Origin))
+}
+
+// GetLatestRate returns the latest quote of the market fetched here or
+// saved by another instance, and reads the database for a market without
+// one. A rate that was not read from the database has no ID and CreatedAt.
 func (s *RatesService) GetLatestRate(ctx context.Context, market string) (*postgres.Rate, error) {
-	s.logger.Debug("Getting latest rate from database", zap.String("market", market))
+	if rate, ok := s.latest.get(market); ok {
+		return &postgres.Rate{
+			Market:     rate.Market,
+			Ask:        rate.Ask,
+			Bid:        rate.Bid,
+			Timestamp:  rate.Timestamp,
+			ReceivedAt: rate.ReceivedAt,
+		}, nil
+	}
+
+	s.log(ctx).Debug("Getting latest rate from database", zap.String("market", market))
 
 	rate, err := s.repository.GetLatestRate(ctx, market)
 	if err != nil {
-		s.logger.Error("Failed to get latest rate from database", zap.Error(err))
+		s.log(ctx).Error("Failed to get latest rate from database", zap.Error(err))
 		return nil, fmt.Errorf("failed to get latest rate: %w", err)
 	}
 
@@ -67,41 +219,42 @@ func (s *RatesService) GetLatestRate(ctx context.Context, market string) (*postg
 
 // GetRatesHistory retrieves historical rates from the database
 func (s *RatesService) GetRatesHistory(ctx context.Context, market string, limit, offset int) ([]postgres.Rate, error) {
-	s.logger.Debug("Getting rates history from database",
+	s.log(ctx).Debug("Getting rates history from database",
 		zap.String("market", market),
 		zap.Int("limit", limit),
 		zap.Int("offset", offset))
 
 	rates, err := s.repository.GetRates(ctx, market, limit, offset)
 	if err != nil {
-		s.logger.Error("Failed to get rates history from database", zap.Error(err))
+		s.log(ctx).Error("Failed to get rates history from database", zap.Error(err))
 		return nil, fmt.Errorf("failed to get rates history: %w", err)
 	}
 
 	return rates, nil
 }
 
-// HealthCheck checks the health of the service
+// HealthCheck checks the health of the service. The exchange is only
+// probed for reachability, so a check never consumes or validates quotes.
 func (s *RatesService) HealthCheck(ctx context.Context) error {
-	s.logger.Debug("Performing health check")
+	s.log(ctx).Debug("Performing health check")
 
 	// Check database connection
 	if err := s.repository.Ping(ctx); err != nil {
-		s.logger.Error("Database health check failed", zap.Error(err))
+		s.log(ctx).Error("Database health check failed", zap.Error(err))
 		return fmt.Errorf("database health check failed: %w", err)
 	}
 
-	// Try to get rates from Grinex API (with timeout)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	if s.probe != nil {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
 
-	_, err := s.grinexClient.GetRates(ctx, "usdtrub") // Use default market for health check
-	if err != nil {
-		s.logger.Warn("Grinex API health check failed", zap.Error(err))
-		// Don't fail the health check if external API is down
-		// as this might be temporary
+		if err := s.probe.Ping(ctx); err != nil {
+			s.log(ctx).Warn("Grinex API health check failed", zap.Error(err))
+			// Don't fail the health check if external API is down
+			// as this might be temporary
+		}
 	}
 
-	s.logger.Debug("Health check completed successfully")
+	s.log(ctx).Debug("Health check completed successfully")
 	return nil
 }
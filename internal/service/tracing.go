@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+
+	"github.com/alik/TestForWork/pkg/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracer creates the service spans
+var tracer = otel.Tracer("github.com/alik/TestForWork/internal/service")
+
+// spanError records err on the span and returns it
+func spanError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// log returns the service logger with the trace of the request
+func (s *RatesService) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, s.logger)
+}
+
+// log returns the service logger with the trace of the request
+func (s *QuoteService) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, s.logger)
+}
+
+// log returns the guard logger with the trace of the request
+func (g *SanityGuard) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, g.logger)
+}
+
+// log returns the catalog logger with the trace of the request
+func (c *MarketCatalog) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, c.logger)
+}
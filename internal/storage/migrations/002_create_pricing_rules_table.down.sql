@@ -0,0 +1 @@
+DROP TABLE IF EXISTS pricing_rules;
\ No newline at end of file
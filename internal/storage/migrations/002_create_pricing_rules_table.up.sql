@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id VARCHAR(64) NOT NULL,
+    version INTEGER NOT NULL,
+    market VARCHAR(20) NOT NULL DEFAULT '*',
+    client_tier VARCHAR(32) NOT NULL DEFAULT '*',
+    spread_percent DECIMAL(10, 4) NOT NULL DEFAULT 0,
+    fixed_fee DECIMAL(20, 8) NOT NULL DEFAULT 0,
+    min_price DECIMAL(20, 8),
+    max_price DECIMAL(20, 8),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (id, version)
+);
+
+CREATE INDEX idx_pricing_rules_market ON pricing_rules(market);
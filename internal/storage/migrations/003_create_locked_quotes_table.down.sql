@@ -0,0 +1 @@
+DROP TABLE IF EXISTS locked_quotes;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS locked_quotes (
+    id VARCHAR(64) PRIMARY KEY,
+    market VARCHAR(20) NOT NULL,
+    side VARCHAR(4) NOT NULL,
+    amount DECIMAL(30, 8) NOT NULL,
+    price DECIMAL(20, 8) NOT NULL,
+    client_tier VARCHAR(32) NOT NULL DEFAULT '',
+    pricing_rule_id VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    redeemed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_locked_quotes_expires_at ON locked_quotes(expires_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS quarantined_rates;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS quarantined_rates (
+    id BIGSERIAL PRIMARY KEY,
+    market VARCHAR(20) NOT NULL,
+    ask TEXT NOT NULL,
+    bid TEXT NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason VARCHAR(32) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_quarantined_rates_market_created_at ON quarantined_rates(market, created_at);
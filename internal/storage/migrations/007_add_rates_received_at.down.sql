@@ -0,0 +1,7 @@
+ALTER TABLE quarantined_rates DROP COLUMN IF EXISTS received_at;
+UPDATE quarantined_rates SET timestamp = created_at WHERE timestamp IS NULL;
+ALTER TABLE quarantined_rates ALTER COLUMN timestamp SET NOT NULL;
+
+UPDATE rates SET timestamp = received_at WHERE timestamp IS NULL;
+ALTER TABLE rates DROP COLUMN IF EXISTS received_at;
+ALTER TABLE rates ALTER COLUMN timestamp SET NOT NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE rates ALTER COLUMN timestamp DROP NOT NULL;
+ALTER TABLE rates ADD COLUMN IF NOT EXISTS received_at TIMESTAMP WITH TIME ZONE;
+UPDATE rates SET received_at = COALESCE(created_at, timestamp) WHERE received_at IS NULL;
+ALTER TABLE rates ALTER COLUMN received_at SET DEFAULT NOW();
+ALTER TABLE rates ALTER COLUMN received_at SET NOT NULL;
+
+ALTER TABLE quarantined_rates ALTER COLUMN timestamp DROP NOT NULL;
+ALTER TABLE quarantined_rates ADD COLUMN IF NOT EXISTS received_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_rates_market_timestamp;
+
+ALTER TABLE rates DROP COLUMN IF EXISTS source;
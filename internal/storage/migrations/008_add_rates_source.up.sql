@@ -0,0 +1,3 @@
+ALTER TABLE rates ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'grinex';
+
+CREATE INDEX IF NOT EXISTS idx_rates_market_timestamp ON rates(market, timestamp);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS outbox_lease;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS txid;
@@ -0,0 +1,10 @@
+-- The transaction that wrote an event, so the relay can wait for the
+-- transactions that wrote earlier ids to finish
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+-- Lets one relay publish at a time without holding a transaction open
+CREATE TABLE IF NOT EXISTS outbox_lease (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    holder VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
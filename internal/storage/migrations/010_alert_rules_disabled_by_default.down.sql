@@ -0,0 +1 @@
+ALTER TABLE alert_rules ALTER COLUMN enabled SET DEFAULT TRUE;
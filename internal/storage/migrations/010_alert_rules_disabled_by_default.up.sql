@@ -0,0 +1,2 @@
+-- Matches the API, where a rule created without enabled is disabled
+ALTER TABLE alert_rules ALTER COLUMN enabled SET DEFAULT FALSE;
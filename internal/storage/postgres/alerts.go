@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// AlertRule represents an alert rule in the database
+type AlertRule struct {
+	ID            int64         `db:"id" json:"id"`
+	Name          string        `db:"name" json:"name"`
+	Market        string        `db:"market" json:"market"`
+	Type          string        `db:"type" json:"type"`
+	Field         string        `db:"field" json:"field"`
+	Value         string        `db:"value" json:"value"`
+	Window        time.Duration `db:"window_seconds" json:"window"`
+	Cooldown      time.Duration `db:"cooldown_seconds" json:"cooldown"`
+	WebhookURL    string        `db:"webhook_url" json:"webhook_url"`
+	WebhookSecret string        `db:"webhook_secret" json:"-"`
+	Enabled       bool          `db:"enabled" json:"enabled"`
+	CreatedAt     time.Time     `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time     `db:"updated_at" json:"updated_at"`
+}
+
+// AlertDelivery represents a webhook delivery log entry in the database
+type AlertDelivery struct {
+	ID           int64         `db:"id" json:"id"`
+	RuleID       int64         `db:"rule_id" json:"rule_id"`
+	DedupKey     string        `db:"dedup_key" json:"dedup_key"`
+	Payload      []byte        `db:"payload" json:"payload"`
+	Status       string        `db:"status" json:"status"`
+	Attempts     int           `db:"attempts" json:"attempts"`
+	ResponseCode sql.NullInt32 `db:"response_code" json:"response_code"`
+	LastError    string        `db:"last_error" json:"last_error"`
+	CreatedAt    time.Time     `db:"created_at" json:"created_at"`
+	DeliveredAt  sql.NullTime  `db:"delivered_at" json:"delivered_at"`
+}
+
+const alertRuleColumns = `id, name, market, type, field, value, window_seconds, cooldown_seconds,
+		       webhook_url, webhook_secret, enabled, created_at, updated_at`
+
+// CreateAlertRule saves a new alert rule and fills in its id and timestamps
+func (r *Repository) CreateAlertRule(ctx context.Context, rule *AlertRule) error {
+	query := `
+		INSERT INTO alert_rules (name, market, type, field, value, window_seconds, cooldown_seconds,
+		                         webhook_url, webhook_secret, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	r.log(ctx).Debug("Saving alert rule to database",
+		zap.String("name", rule.Name),
+		zap.String("market", rule.Market))
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx, query, rule.Name, rule.Market, rule.Type, rule.Field, rule.Value,
+		int64(rule.Window.Seconds()), int64(rule.Cooldown.Seconds()), rule.WebhookURL, rule.WebhookSecret, rule.Enabled,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		r.log(ctx).Error("Failed to save alert rule", zap.Error(err))
+		return fmt.Errorf("failed to save alert rule: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAlertRule updates an alert rule; it returns false if the rule does not exist.
+// An empty webhook secret keeps the stored one, since rules are read back without it.
+func (r *Repository) UpdateAlertRule(ctx context.Context, rule *AlertRule) (bool, error) {
+	query := `
+		UPDATE alert_rules
+		SET name = $2, market = $3, type = $4, field = $5, value = $6, window_seconds = $7,
+		    cooldown_seconds = $8, webhook_url = $9, webhook_secret = COALESCE(NULLIF($10, ''), webhook_secret),
+		    enabled = $11, updated_at = NOW()
+		WHERE id = $1
+		RETURNING created_at, updated_at
+	`
+
+	r.log(ctx).Debug("Updating alert rule", zap.Int64("id", rule.ID))
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx, query, rule.ID, rule.Name, rule.Market, rule.Type, rule.Field, rule.Value,
+		int64(rule.Window.Seconds()), int64(rule.Cooldown.Seconds()), rule.WebhookURL, rule.WebhookSecret, rule.Enabled,
+	).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		r.log(ctx).Error("Failed to update alert rule", zap.Error(err))
+		return false, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteAlertRule deletes an alert rule; it returns false if the rule does not exist
+func (r *Repository) DeleteAlertRule(ctx context.Context, id int64) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM alert_rules WHERE id = $1`, id)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete alert rule", zap.Error(err))
+		return false, fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetAlertRule retrieves an alert rule by id
+func (r *Repository) GetAlertRule(ctx context.Context, id int64) (*AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE id = $1`
+
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rule, err := scanAlertRule(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log(ctx).Debug("Alert rule not found", zap.Int64("id", id))
+			return nil, nil
+		}
+		r.log(ctx).Error("Failed to query alert rule", zap.Error(err))
+		return nil, fmt.Errorf("failed to query alert rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// ListAlertRules retrieves all alert rules
+func (r *Repository) ListAlertRules(ctx context.Context) ([]AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY id`
+
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.log(ctx).Error("Failed to query alert rules", zap.Error(err))
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			r.log(ctx).Error("Failed to scan alert rule", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log(ctx).Error("Error during rows iteration", zap.Error(err))
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return rules, nil
+}
+
+// CreateAlertDelivery records a pending delivery.
+// It returns false if a delivery with the same rule and dedup key already exists.
+func (r *Repository) CreateAlertDelivery(ctx context.Context, delivery *AlertDelivery) (bool, error) {
+	query := `
+		INSERT INTO alert_deliveries (rule_id, dedup_key, payload, status, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (rule_id, dedup_key) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	err := r.db.QueryRow(ctx, query, delivery.RuleID, delivery.DedupKey, delivery.Payload, delivery.Status).
+		Scan(&delivery.ID, &delivery.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log(ctx).Debug("Duplicate alert delivery skipped",
+				zap.Int64("rule_id", delivery.RuleID),
+				zap.String("dedup_key", delivery.DedupKey))
+			return false, nil
+		}
+		r.log(ctx).Error("Failed to save alert delivery", zap.Error(err))
+		return false, fmt.Errorf("failed to save alert delivery: %w", err)
+	}
+
+	return true, nil
+}
+
+// UpdateAlertDelivery records the outcome of delivery attempts
+func (r *Repository) UpdateAlertDelivery(ctx context.Context, delivery *AlertDelivery) error {
+	query := `
+		UPDATE alert_deliveries
+		SET status = $2, attempts = $3, response_code = $4, last_error = $5, delivered_at = $6
+		WHERE id = $1
+	`
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	_, err := r.db.Exec(ctx, query, delivery.ID, delivery.Status, delivery.Attempts,
+		delivery.ResponseCode, delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		r.log(ctx).Error("Failed to update alert delivery", zap.Error(err), zap.Int64("id", delivery.ID))
+		return fmt.Errorf("failed to update alert delivery: %w", err)
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAlertRule scans an alert rule row
+func scanAlertRule(row rowScanner) (*AlertRule, error) {
+	var rule AlertRule
+	var windowSeconds, cooldownSeconds int64
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Market, &rule.Type, &rule.Field, &rule.Value,
+		&windowSeconds, &cooldownSeconds, &rule.WebhookURL, &rule.WebhookSecret, &rule.Enabled,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Window = time.Duration(windowSeconds) * time.Second
+	rule.Cooldown = time.Duration(cooldownSeconds) * time.Second
+
+	return &rule, nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// exportFetchSize is the number of rows fetched from the export cursor at a time
+const exportFetchSize = 1000
+
+// ExportedRate is a stored rate read for an export. Timestamp is the exchange
+// timestamp, or the receive time for rates without one.
+type ExportedRate struct {
+	Market     string
+	Ask        string
+	Bid        string
+	Timestamp  time.Time
+	ReceivedAt time.Time
+	Source     string
+}
+
+// StreamRates calls fn for every rate of the market with a timestamp in
+// [from, to), oldest first. A zero from or to leaves that end of the range
+// open. The rows are read through a server-side cursor in a read-only
+// transaction, so memory use does not grow with the size of the range.
+// The rates are read from a replica when one is configured and healthy.
+// An error returned by fn stops the stream and is returned as is.
+func (r *Repository) StreamRates(ctx context.Context, market string, from, to time.Time, fn func(ExportedRate) error) error {
+	tx, err := r.reader(ctx, false).BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		r.log(ctx).Error("Failed to begin export transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.setStatementTimeout(ctx, tx, classBulk); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DECLARE rates_export NO SCROLL CURSOR FOR
+		SELECT market, ask, bid, COALESCE(timestamp, received_at) AS ts, received_at, source
+		FROM rates
+		WHERE market = $1
+		  AND ($2::timestamptz IS NULL OR COALESCE(timestamp, received_at) >= $2)
+		  AND ($3::timestamptz IS NULL OR COALESCE(timestamp, received_at) < $3)
+		ORDER BY ts, id
+	`, market, nullTime(from), nullTime(to))
+	if err != nil {
+		r.log(ctx).Error("Failed to declare export cursor", zap.Error(err), zap.String("market", market))
+		return fmt.Errorf("failed to declare export cursor: %w", err)
+	}
+
+	query := fmt.Sprintf("FETCH FORWARD %d FROM rates_export", exportFetchSize)
+	for {
+		n, err := r.fetchExportBatch(ctx, tx, query, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log(ctx).Error("Failed to commit export transaction", zap.Error(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// fetchExportBatch fetches the next rows from the export cursor and returns
+// how many were read
+func (r *Repository) fetchExportBatch(ctx context.Context, tx pgx.Tx, query string, fn func(ExportedRate) error) (int, error) {
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		r.log(ctx).Error("Failed to fetch from export cursor", zap.Error(err))
+		return 0, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var rate ExportedRate
+		if err := rows.Scan(&rate.Market, &rate.Ask, &rate.Bid, &rate.Timestamp, &rate.ReceivedAt, &rate.Source); err != nil {
+			r.log(ctx).Error("Failed to scan exported rate", zap.Error(err))
+			return n, fmt.Errorf("failed to scan rate: %w", err)
+		}
+		n++
+		if err := fn(rate); err != nil {
+			return n, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		r.log(ctx).Error("Error during rows iteration", zap.Error(err))
+		return n, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return n, nil
+}
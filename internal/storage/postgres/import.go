@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ImportedRate is a historical rate loaded from an external source
+type ImportedRate struct {
+	Market    string
+	Ask       string
+	Bid       string
+	Timestamp time.Time
+	Source    string
+}
+
+// ImportRates copies the rates into the rates table, skipping rates whose
+// market and timestamp already exist in the table or earlier in the batch.
+// The rates are staged with COPY and inserted in one transaction, which is
+// rolled back in dry-run mode. Imported rates do not produce outbox events.
+// It returns the number of inserted rates.
+func (r *Repository) ImportRates(ctx context.Context, rates []ImportedRate, dryRun bool) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		r.log(ctx).Error("Failed to begin import transaction", zap.Error(err))
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.setStatementTimeout(ctx, tx, classBulk); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		CREATE TEMP TABLE rates_import (
+			market VARCHAR(20) NOT NULL,
+			ask DECIMAL(20, 8) NOT NULL,
+			bid DECIMAL(20, 8) NOT NULL,
+			timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+			source VARCHAR(50) NOT NULL
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		r.log(ctx).Error("Failed to create import staging table", zap.Error(err))
+		return 0, fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"rates_import"},
+		[]string{"market", "ask", "bid", "timestamp", "source"},
+		pgx.CopyFromSlice(len(rates), func(i int) ([]any, error) {
+			rate := rates[i]
+			return []any{rate.Market, rate.Ask, rate.Bid, rate.Timestamp, rate.Source}, nil
+		}))
+	if err != nil {
+		r.log(ctx).Error("Failed to copy rates", zap.Error(err))
+		return 0, fmt.Errorf("failed to copy rates: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO rates (market, ask, bid, timestamp, received_at, source, created_at)
+		SELECT DISTINCT ON (i.market, i.timestamp) i.market, i.ask, i.bid, i.timestamp, i.timestamp, i.source, NOW()
+		FROM rates_import i
+		WHERE NOT EXISTS (
+			SELECT 1 FROM rates r WHERE r.market = i.market AND r.timestamp = i.timestamp
+		)
+		ORDER BY i.market, i.timestamp
+	`)
+	if err != nil {
+		r.log(ctx).Error("Failed to insert imported rates", zap.Error(err))
+		return 0, fmt.Errorf("failed to insert imported rates: %w", err)
+	}
+	inserted := tag.RowsAffected()
+
+	if dryRun {
+		return inserted, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log(ctx).Error("Failed to commit import transaction", zap.Error(err))
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return inserted, nil
+}
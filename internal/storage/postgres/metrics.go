@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// dbWriteDuration measures database writes on the request path
+var dbWriteDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rates_db_write_duration_seconds",
+	Help:    "Latency of database writes by operation and result.",
+	Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+}, []string{"operation", "status"})
+
+// observeWrite records the latency of a write started at start
+func observeWrite(operation string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	dbWriteDuration.WithLabelValues(operation, status).Observe(time.Since(start).Seconds())
+}
+
+// replicaLag is the replication lag measured by the last replica check
+var replicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "rates_db_replica_lag_seconds",
+	Help: "Replication lag of the read replicas at the last check.",
+}, []string{"replica"})
+
+// replicaHealthy is 1 while a read replica is used for reads
+var replicaHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "rates_db_replica_healthy",
+	Help: "Whether a read replica passed the last health and lag check.",
+}, []string{"replica"})
+
+// dbReads counts read-only queries by the pool they were routed to
+var dbReads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rates_db_reads_total",
+	Help: "Read-only queries by target: replica, primary when consistency is requested, or fallback when no replica is healthy.",
+}, []string{"target"})
+
+// notificationsReceived counts rate notifications by what was done with
+// them: applied, own for ones this instance sent, or invalid
+var notificationsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "rates_db_notifications_received_total",
+	Help: "Rate notifications received from the database by result: applied, own or invalid.",
+}, []string{"result"})
+
+// listenerConnected is 1 while the notification listener is connected
+var listenerConnected = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "rates_db_listener_connected",
+	Help: "Whether the rate notification listener is connected and listening.",
+})
+
+// poolStats exports the statistics of the open connection pools
+var poolStats = newPoolCollector()
+
+func init() {
+	prometheus.MustRegister(poolStats)
+}
+
+// poolCollector reads the statistics of the pools at scrape time
+type poolCollector struct {
+	acquired      *prometheus.Desc
+	idle          *prometheus.Desc
+	total         *prometheus.Desc
+	max           *prometheus.Desc
+	acquires      *prometheus.Desc
+	emptyAcquires *prometheus.Desc
+	waitDuration  *prometheus.Desc
+	canceled      *prometheus.Desc
+
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool
+}
+
+func newPoolCollector() *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("rates_db_pool_"+name, help, []string{"pool"}, nil)
+	}
+	return &poolCollector{
+		acquired:      desc("acquired_connections", "Connections currently in use."),
+		idle:          desc("idle_connections", "Idle connections in the pool."),
+		total:         desc("total_connections", "Open connections, including ones being established."),
+		max:           desc("max_connections", "Maximum size of the pool."),
+		acquires:      desc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquires: desc("empty_acquires_total", "Acquires that waited because no connection was idle."),
+		waitDuration:  desc("acquire_wait_seconds_total", "Time spent waiting for a connection when none was idle."),
+		canceled:      desc("canceled_acquires_total", "Acquires canceled by their context while waiting."),
+		pools:         make(map[string]*pgxpool.Pool),
+	}
+}
+
+func (c *poolCollector) add(name string, pool *pgxpool.Pool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools[name] = pool
+}
+
+// remove stops exporting the pool unless another pool has taken its name
+func (c *poolCollector) remove(name string, pool *pgxpool.Pool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pools[name] == pool {
+		delete(c.pools, name)
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.acquired, c.idle, c.total, c.max, c.acquires, c.emptyAcquires, c.waitDuration, c.canceled} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, pool := range c.pools {
+		s := pool.Stat()
+		ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.IdleConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(s.TotalConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(s.MaxConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.EmptyAcquireWaitTime().Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.canceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()), name)
+	}
+}
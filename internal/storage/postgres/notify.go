@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// RatesChannel is the channel a notification is sent on for every saved rate
+const RatesChannel = "rates_updated"
+
+const (
+	listenMinBackoff = time.Second
+	listenMaxBackoff = 30 * time.Second
+
+	// listenCloseTimeout bounds closing a lost notification connection
+	listenCloseTimeout = time.Second
+)
+
+// RateNotification is the payload of a rates_updated notification
+type RateNotification struct {
+	Market     string     `json:"market"`
+	Ask        string     `json:"ask"`
+	Bid        string     `json:"bid"`
+	Timestamp  *time.Time `json:"timestamp,omitempty"`
+	ReceivedAt time.Time  `json:"received_at"`
+	// Origin identifies the instance that saved the rate
+	Origin string `json:"origin"`
+}
+
+// WithNotifications sends a rates_updated notification for every saved
+// rate, tagged with the origin of this instance. The notification is sent
+// when the rate is committed and never for a rate that is rolled back.
+func WithNotifications(origin string) Option {
+	return func(r *Repository) {
+		r.notifyOrigin = origin
+	}
+}
+
+// notification encodes the rates_updated payload of a saved rate
+func (r *Repository) notification(market, ask, bid string, timestamp, receivedAt time.Time) (string, error) {
+	n := RateNotification{Market: market, Ask: ask, Bid: bid, ReceivedAt: receivedAt, Origin: r.notifyOrigin}
+	if !timestamp.IsZero() {
+		n.Timestamp = &timestamp
+	}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return string(payload), nil
+}
+
+// NotificationConn is a connection dedicated to receiving notifications.
+// It is implemented by *pgx.Conn.
+type NotificationConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// ListenConn takes a connection out of the pool for a Listener. The
+// connection does not count against the pool and is not recycled with it.
+func (p *Pool) ListenConn(ctx context.Context) (NotificationConn, error) {
+	conn, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.Hijack(), nil
+}
+
+// Listener passes the rates saved by other instances to a handler. It keeps
+// a dedicated connection listening on RatesChannel, and after the
+// connection is lost it reconnects with backoff and listens again.
+// Notifications sent while it is reconnecting are not delivered; use
+// WithResync to catch up with them.
+type Listener struct {
+	connect func(ctx context.Context) (NotificationConn, error)
+	origin  string
+	handle  func(RateNotification)
+	resync  func(ctx context.Context)
+	logger  *zap.Logger
+}
+
+// ListenerOption configures a Listener
+type ListenerOption func(*Listener)
+
+// WithResync calls resync after every LISTEN, before any notification is
+// passed on, so the rates saved while the listener was not listening can be
+// loaded. Notifications sent meanwhile wait on the connection.
+func WithResync(resync func(ctx context.Context)) ListenerOption {
+	return func(l *Listener) {
+		l.resync = resync
+	}
+}
+
+// NewListener creates a listener that opens connections with connect and
+// passes notifications from origins other than origin to handle. handle
+// is called on the listener goroutine and must not block.
+func NewListener(connect func(ctx context.Context) (NotificationConn, error), origin string, handle func(RateNotification), logger *zap.Logger, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		connect: connect,
+		origin:  origin,
+		handle:  handle,
+		logger:  logger,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Run listens for notifications until ctx is done
+func (l *Listener) Run(ctx context.Context) {
+	backoff := listenMinBackoff
+	for ctx.Err() == nil {
+		conn, err := l.listen(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			l.logger.Warn("Failed to listen for rate notifications",
+				zap.Duration("retry_in", backoff),
+				zap.Error(err))
+			if !sleepContext(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, listenMaxBackoff)
+			continue
+		}
+		backoff = listenMinBackoff
+
+		listenerConnected.Set(1)
+		l.logger.Info("Listening for rate notifications", zap.String("channel", RatesChannel))
+		if l.resync != nil {
+			l.resync(ctx)
+		}
+		err = l.receive(ctx, conn)
+		listenerConnected.Set(0)
+
+		closeCtx, cancel := context.WithTimeout(context.Background(), listenCloseTimeout)
+		conn.Close(closeCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger.Warn("Rate notification connection lost, reconnecting", zap.Error(err))
+	}
+}
+
+// listen opens a connection and subscribes it to RatesChannel
+func (l *Listener) listen(ctx context.Context) (NotificationConn, error) {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+RatesChannel); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("failed to listen on %s: %w", RatesChannel, err)
+	}
+	return conn, nil
+}
+
+// receive passes notifications to the handler until the connection fails
+func (l *Listener) receive(ctx context.Context, conn NotificationConn) error {
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		l.dispatch(n)
+	}
+}
+
+// dispatch decodes a notification and passes it on unless this instance
+// sent it
+func (l *Listener) dispatch(n *pgconn.Notification) {
+	var rate RateNotification
+	if err := json.Unmarshal([]byte(n.Payload), &rate); err != nil || rate.Market == "" {
+		notificationsReceived.WithLabelValues("invalid").Inc()
+		l.logger.Warn("Ignoring malformed rate notification", zap.String("payload", n.Payload), zap.Error(err))
+		return
+	}
+	if rate.Origin == l.origin {
+		notificationsReceived.WithLabelValues("own").Inc()
+		return
+	}
+	notificationsReceived.WithLabelValues("applied").Inc()
+	l.handle(rate)
+}
+
+// sleepContext waits for the duration and reports false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// RateChangedEventType is the outbox event type written for every saved rate
+const RateChangedEventType = "rate.changed"
+
+// outboxLeaseTTL is how long a relay holds the outbox lease after a run
+// starts; publishing is cancelled before it runs out
+const outboxLeaseTTL = 30 * time.Second
+
+// OutboxEvent represents a pending event in the outbox table
+type OutboxEvent struct {
+	ID          int64           `db:"id" json:"id"`
+	Market      string          `db:"market" json:"market"`
+	EventType   string          `db:"event_type" json:"event_type"`
+	Payload     json.RawMessage `db:"payload" json:"payload"`
+	Attempts    int             `db:"attempts" json:"attempts"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+	PublishedAt sql.NullTime    `db:"published_at" json:"published_at"`
+}
+
+// RateChangedEvent is the payload of a rate-change event.
+// Timestamp is omitted when the exchange did not report one.
+type RateChangedEvent struct {
+	RateID     int64      `json:"rate_id"`
+	Market     string     `json:"market"`
+	Ask        string     `json:"ask"`
+	Bid        string     `json:"bid"`
+	Timestamp  *time.Time `json:"timestamp,omitempty"`
+	ReceivedAt time.Time  `json:"received_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// saveRateWithEvent saves a rate and its outbox event in one transaction
+func (r *Repository) saveRateWithEvent(ctx context.Context, market, ask, bid string, timestamp, receivedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.setStatementTimeout(ctx, tx, classWrite); err != nil {
+		return err
+	}
+
+	event := RateChangedEvent{Market: market, Ask: ask, Bid: bid, ReceivedAt: receivedAt}
+	if !timestamp.IsZero() {
+		event.Timestamp = &timestamp
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO rates (market, ask, bid, timestamp, received_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`, market, ask, bid, nullTime(timestamp), receivedAt).Scan(&event.RateID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert rate: %w", err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (market, event_type, payload, created_at)
+		VALUES ($1, $2, $3, NOW())
+	`, market, RateChangedEventType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox event: %w", err)
+	}
+
+	// Other instances are notified when the transaction commits
+	if r.notifyOrigin != "" {
+		notification, err := r.notification(market, ask, bid, timestamp, receivedAt)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, RatesChannel, notification); err != nil {
+			return fmt.Errorf("failed to notify: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessOutbox passes the oldest pending events to handle in id order and
+// marks the events whose ids handle returns as published; the others stay
+// pending and are retried. Only one relay processes the outbox at a time:
+// it returns 0 without calling handle while another relay holds the lease.
+//
+// Events are read only once the transactions that wrote them and every
+// concurrent transaction have finished, so an event is never passed on
+// before an event with a lower id. handle runs without a transaction and
+// its context is cancelled before the lease runs out.
+func (r *Repository) ProcessOutbox(ctx context.Context, limit int, handle func(ctx context.Context, events []OutboxEvent) []int64) (int, error) {
+	leaseCtx, cancel := context.WithTimeout(ctx, outboxLeaseTTL)
+	defer cancel()
+
+	leased, err := r.leaseOutbox(leaseCtx)
+	if err != nil || !leased {
+		return 0, err
+	}
+
+	events, err := r.pendingOutbox(leaseCtx, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	published := handle(leaseCtx, events)
+
+	if err := r.markOutbox(ctx, events, published); err != nil {
+		return 0, err
+	}
+	return len(published), nil
+}
+
+// leaseOutbox takes or renews the outbox lease and reports false when
+// another relay holds it
+func (r *Repository) leaseOutbox(ctx context.Context) (bool, error) {
+	queryCtx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	var holder string
+	err := r.db.QueryRow(queryCtx, `
+		INSERT INTO outbox_lease (id, holder, expires_at)
+		VALUES (TRUE, $1, NOW() + $2::interval)
+		ON CONFLICT (id) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE outbox_lease.holder = EXCLUDED.holder OR outbox_lease.expires_at < NOW()
+		RETURNING holder
+	`, r.outboxHolder, outboxLeaseTTL.String()).Scan(&holder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.log(ctx).Error("Failed to acquire outbox lease", zap.Error(err))
+		return false, fmt.Errorf("failed to acquire outbox lease: %w", err)
+	}
+	return true, nil
+}
+
+// pendingOutbox returns the oldest pending events whose transactions
+// committed before every transaction still running
+func (r *Repository) pendingOutbox(ctx context.Context, limit int) ([]OutboxEvent, error) {
+	queryCtx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rows, err := r.db.Query(queryCtx, `
+		SELECT id, market, event_type, payload, attempts, created_at
+		FROM outbox
+		WHERE published_at IS NULL
+		  AND txid < pg_snapshot_xmin(pg_current_snapshot())
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		r.log(ctx).Error("Failed to query outbox", zap.Error(err))
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []OutboxEvent
+	for rows.Next() {
+		var event OutboxEvent
+		if err := rows.Scan(&event.ID, &event.Market, &event.EventType, &event.Payload,
+			&event.Attempts, &event.CreatedAt); err != nil {
+			r.log(ctx).Error("Failed to scan outbox event", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		r.log(ctx).Error("Error during rows iteration", zap.Error(err))
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	return events, nil
+}
+
+// markOutbox marks the published events and counts an attempt for the others
+func (r *Repository) markOutbox(ctx context.Context, events []OutboxEvent, published []int64) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		r.log(ctx).Error("Failed to begin outbox transaction", zap.Error(err))
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.setStatementTimeout(ctx, tx, classWrite); err != nil {
+		return err
+	}
+
+	if len(published) > 0 {
+		_, err = tx.Exec(ctx, `
+			UPDATE outbox SET published_at = NOW(), attempts = attempts + 1
+			WHERE id = ANY($1)
+		`, published)
+		if err != nil {
+			r.log(ctx).Error("Failed to mark outbox events published", zap.Error(err))
+			return fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+	}
+
+	if len(published) < len(events) {
+		_, err = tx.Exec(ctx, `
+			UPDATE outbox SET attempts = attempts + 1
+			WHERE id = ANY($1) AND published_at IS NULL
+		`, eventIDs(events))
+		if err != nil {
+			r.log(ctx).Error("Failed to update outbox attempts", zap.Error(err))
+			return fmt.Errorf("failed to update outbox attempts: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.log(ctx).Error("Failed to commit outbox transaction", zap.Error(err))
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeletePublishedOutbox removes events published before the given time
+func (r *Repository) DeletePublishedOutbox(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx, classMaintenance)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete published outbox events", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// newLeaseHolder returns a random id for the outbox lease of this repository
+func newLeaseHolder() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// eventIDs returns the ids of the events
+func eventIDs(events []OutboxEvent) []int64 {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// DB is the part of a connection pool used by the repository. It is
+// implemented by *Pool and *pgxpool.Pool.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// PoolConfig sizes a connection pool
+type PoolConfig struct {
+	// Name labels the pool metrics, e.g. primary or the replica address
+	Name            string
+	MaxConns        int
+	MinConns        int
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// Pool is a pgx connection pool whose credentials can be replaced at
+// runtime, e.g. when they are rotated. Every statement is traced with its
+// SQL text as a span attribute.
+type Pool struct {
+	*pgxpool.Pool
+	name        string
+	credentials atomic.Pointer[credentials]
+}
+
+type credentials struct {
+	user     string
+	password string
+}
+
+// NewPool creates a pool for the DSN without connecting, so a replica that
+// is down at startup does not stop the service; the replica set finds out
+// with its health checks
+func NewPool(dsn string, cfg PoolConfig) (*Pool, error) {
+	pc, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database DSN: %w", err)
+	}
+
+	pc.MaxConns = int32(cfg.MaxConns)
+	pc.MinConns = int32(cfg.MinConns)
+	pc.MaxConnLifetime = cfg.MaxConnLifetime
+	pc.MaxConnIdleTime = cfg.MaxConnIdleTime
+	pc.ConnConfig.Tracer = otelpgx.NewTracer()
+
+	p := &Pool{name: cfg.Name}
+	p.credentials.Store(&credentials{user: pc.ConnConfig.User, password: pc.ConnConfig.Password})
+	pc.BeforeConnect = func(_ context.Context, cc *pgx.ConnConfig) error {
+		c := p.credentials.Load()
+		cc.User, cc.Password = c.user, c.password
+		return nil
+	}
+
+	p.Pool, err = pgxpool.NewWithConfig(context.Background(), pc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	poolStats.add(p.name, p.Pool)
+
+	return p, nil
+}
+
+// OpenPool creates a pool for the DSN and checks that it can connect
+func OpenPool(dsn string, cfg PoolConfig, logger *zap.Logger) (*Pool, error) {
+	p, err := NewPool(dsn, cfg)
+	if err != nil {
+		logger.Error("Failed to open database", zap.Error(err))
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Test the connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.Ping(ctx); err != nil {
+		p.Close()
+		logger.Error("Failed to ping database", zap.Error(err))
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	logger.Info("Database connection established successfully")
+
+	return p, nil
+}
+
+// RotateCredentials switches the pool to the user and password of the DSN
+// without downtime. They are checked with a test connection first and
+// rejected if they do not work. Idle connections are closed right away so
+// that the next queries connect with the new credentials; connections in
+// use finish their work and are closed when they are released.
+func (p *Pool) RotateCredentials(ctx context.Context, dsn string) error {
+	cc, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return fmt.Errorf("invalid database DSN: %w", err)
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, cc)
+	if err != nil {
+		return fmt.Errorf("failed to connect with new credentials: %w", err)
+	}
+	if err := conn.Close(ctx); err != nil {
+		return fmt.Errorf("failed to close test connection: %w", err)
+	}
+
+	p.credentials.Store(&credentials{user: cc.User, password: cc.Password})
+	p.Reset()
+
+	return nil
+}
+
+// Close closes the connections of the pool and stops exporting its metrics
+func (p *Pool) Close() {
+	poolStats.remove(p.name, p.Pool)
+	p.Pool.Close()
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PricingRule represents a versioned pricing rule in the database
+type PricingRule struct {
+	ID            string         `db:"id" json:"id"`
+	Version       int            `db:"version" json:"version"`
+	Market        string         `db:"market" json:"market"`
+	ClientTier    string         `db:"client_tier" json:"client_tier"`
+	SpreadPercent string         `db:"spread_percent" json:"spread_percent"`
+	FixedFee      string         `db:"fixed_fee" json:"fixed_fee"`
+	MinPrice      sql.NullString `db:"min_price" json:"min_price"`
+	MaxPrice      sql.NullString `db:"max_price" json:"max_price"`
+	Active        bool           `db:"active" json:"active"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+}
+
+// GetActivePricingRules retrieves the latest version of every pricing rule
+// that is still active
+func (r *Repository) GetActivePricingRules(ctx context.Context) ([]PricingRule, error) {
+	query := `
+		SELECT id, version, market, client_tier, spread_percent, fixed_fee,
+		       min_price, max_price, active, created_at
+		FROM (
+			SELECT DISTINCT ON (id) *
+			FROM pricing_rules
+			ORDER BY id, version DESC
+		) latest
+		WHERE active
+		ORDER BY id
+	`
+
+	r.log(ctx).Debug("Retrieving pricing rules from database")
+
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.log(ctx).Error("Failed to query pricing rules", zap.Error(err))
+		return nil, fmt.Errorf("failed to query pricing rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []PricingRule
+	for rows.Next() {
+		var rule PricingRule
+		err := rows.Scan(&rule.ID, &rule.Version, &rule.Market, &rule.ClientTier,
+			&rule.SpreadPercent, &rule.FixedFee, &rule.MinPrice, &rule.MaxPrice,
+			&rule.Active, &rule.CreatedAt)
+		if err != nil {
+			r.log(ctx).Error("Failed to scan pricing rule", zap.Error(err))
+			return nil, fmt.Errorf("failed to scan pricing rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.log(ctx).Error("Error during rows iteration", zap.Error(err))
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	r.log(ctx).Debug("Retrieved pricing rules from database", zap.Int("count", len(rules)))
+
+	return rules, nil
+}
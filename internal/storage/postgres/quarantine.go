@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// QuarantinedRate represents an upstream rate rejected by sanity checks.
+// Prices are stored as received, so they may not be valid numbers.
+type QuarantinedRate struct {
+	ID         int64     `db:"id" json:"id"`
+	Market     string    `db:"market" json:"market"`
+	Ask        string    `db:"ask" json:"ask"`
+	Bid        string    `db:"bid" json:"bid"`
+	Timestamp  time.Time `db:"timestamp" json:"timestamp"`
+	ReceivedAt time.Time `db:"received_at" json:"received_at"`
+	Reason     string    `db:"reason" json:"reason"`
+	Detail     string    `db:"detail" json:"detail"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// QuarantineRate saves a rejected rate with the rejection reason
+func (r *Repository) QuarantineRate(ctx context.Context, rate *QuarantinedRate) error {
+	query := `
+		INSERT INTO quarantined_rates (market, ask, bid, timestamp, received_at, reason, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	start := time.Now()
+	_, err := r.db.Exec(ctx, query, rate.Market, rate.Ask, rate.Bid,
+		nullTime(rate.Timestamp), nullTime(rate.ReceivedAt), rate.Reason, rate.Detail)
+	observeWrite("quarantine_rate", start, err)
+	if err != nil {
+		r.log(ctx).Error("Failed to save quarantined rate",
+			zap.Error(err),
+			zap.String("market", rate.Market),
+			zap.String("reason", rate.Reason))
+		return fmt.Errorf("failed to save quarantined rate: %w", err)
+	}
+
+	return nil
+}
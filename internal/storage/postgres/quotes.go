@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// LockedQuote represents a locked quote record in the database
+type LockedQuote struct {
+	ID            string       `db:"id" json:"id"`
+	Market        string       `db:"market" json:"market"`
+	Side          string       `db:"side" json:"side"`
+	Amount        string       `db:"amount" json:"amount"`
+	Price         string       `db:"price" json:"price"`
+	ClientTier    string       `db:"client_tier" json:"client_tier"`
+	PricingRuleID string       `db:"pricing_rule_id" json:"pricing_rule_id"`
+	ExpiresAt     time.Time    `db:"expires_at" json:"expires_at"`
+	RedeemedAt    sql.NullTime `db:"redeemed_at" json:"redeemed_at"`
+	CreatedAt     time.Time    `db:"created_at" json:"created_at"`
+}
+
+// SaveQuote saves a new locked quote to the database
+func (r *Repository) SaveQuote(ctx context.Context, quote *LockedQuote) error {
+	query := `
+		INSERT INTO locked_quotes (id, market, side, amount, price, client_tier, pricing_rule_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+	`
+
+	r.log(ctx).Debug("Saving locked quote to database",
+		zap.String("id", quote.ID),
+		zap.String("market", quote.Market),
+		zap.String("side", quote.Side))
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	start := time.Now()
+	_, err := r.db.Exec(ctx, query, quote.ID, quote.Market, quote.Side, quote.Amount,
+		quote.Price, quote.ClientTier, quote.PricingRuleID, quote.ExpiresAt)
+	observeWrite("save_quote", start, err)
+	if err != nil {
+		r.log(ctx).Error("Failed to save locked quote", zap.Error(err), zap.String("id", quote.ID))
+		return fmt.Errorf("failed to save locked quote: %w", err)
+	}
+
+	return nil
+}
+
+// GetQuote retrieves a locked quote by id
+func (r *Repository) GetQuote(ctx context.Context, id string) (*LockedQuote, error) {
+	query := `
+		SELECT id, market, side, amount, price, client_tier, pricing_rule_id, expires_at, redeemed_at, created_at
+		FROM locked_quotes
+		WHERE id = $1
+	`
+
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	var quote LockedQuote
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&quote.ID, &quote.Market, &quote.Side, &quote.Amount, &quote.Price, &quote.ClientTier,
+		&quote.PricingRuleID, &quote.ExpiresAt, &quote.RedeemedAt, &quote.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log(ctx).Debug("Locked quote not found", zap.String("id", id))
+			return nil, nil
+		}
+		r.log(ctx).Error("Failed to query locked quote", zap.Error(err))
+		return nil, fmt.Errorf("failed to query locked quote: %w", err)
+	}
+
+	return &quote, nil
+}
+
+// RedeemQuote marks an unexpired quote as used.
+// It returns false if the quote was already used or has expired,
+// so that a quote can be redeemed only once even under concurrent calls.
+func (r *Repository) RedeemQuote(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE locked_quotes
+		SET redeemed_at = NOW()
+		WHERE id = $1 AND redeemed_at IS NULL AND expires_at > NOW()
+	`
+
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	start := time.Now()
+	tag, err := r.db.Exec(ctx, query, id)
+	observeWrite("redeem_quote", start, err)
+	if err != nil {
+		r.log(ctx).Error("Failed to redeem locked quote", zap.Error(err), zap.String("id", id))
+		return false, fmt.Errorf("failed to redeem locked quote: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteExpiredQuotes removes quotes that expired before the given time
+func (r *Repository) DeleteExpiredQuotes(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM locked_quotes WHERE expires_at < $1`
+
+	ctx, cancel := r.withTimeout(ctx, classMaintenance)
+	defer cancel()
+
+	tag, err := r.db.Exec(ctx, query, before)
+	if err != nil {
+		r.log(ctx).Error("Failed to delete expired quotes", zap.Error(err))
+		return 0, fmt.Errorf("failed to delete expired quotes: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
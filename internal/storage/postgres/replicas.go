@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/schedule"
+)
+
+// replicaCheckTimeout bounds a single health check of a replica
+const replicaCheckTimeout = 5 * time.Second
+
+// replicaLagQuery returns how far a standby is behind the primary in
+// seconds and whether it is streaming WAL from the primary. A streaming
+// standby that has replayed everything it received is not lagging even
+// when the primary has been idle; a server that is not in recovery reports
+// no lag. Reading the receiver status needs the pg_read_all_stats role.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END,
+	NOT pg_is_in_recovery() OR EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming')
+`
+
+// Replica is a read-only standby of the primary database
+type Replica struct {
+	Name string
+	DB   DB
+}
+
+type replica struct {
+	Replica
+	healthy atomic.Bool
+}
+
+// ReplicaSet tracks the health and lag of the read replicas and picks a
+// healthy one for read-only queries. Replicas start unhealthy until the
+// first check.
+type ReplicaSet struct {
+	replicas []*replica
+	maxLag   atomic.Int64
+	interval *schedule.Interval
+	next     atomic.Uint64
+	logger   *zap.Logger
+}
+
+// NewReplicaSet creates a replica set. Replicas lagging more than maxLag
+// behind the primary are not used until they catch up.
+func NewReplicaSet(maxLag, checkInterval time.Duration, logger *zap.Logger, replicas ...Replica) *ReplicaSet {
+	s := &ReplicaSet{
+		interval: schedule.NewInterval(checkInterval),
+		logger:   logger,
+	}
+	s.maxLag.Store(int64(maxLag))
+	for _, r := range replicas {
+		s.replicas = append(s.replicas, &replica{Replica: r})
+	}
+	return s
+}
+
+// SetMaxLag changes the lag above which replicas are not used, from the
+// next check on
+func (s *ReplicaSet) SetMaxLag(d time.Duration) {
+	s.maxLag.Store(int64(d))
+}
+
+// SetCheckInterval changes how often the replicas are checked
+func (s *ReplicaSet) SetCheckInterval(d time.Duration) {
+	s.interval.Set(d)
+}
+
+// Run checks the replicas right away and then every check interval until
+// ctx is done
+func (s *ReplicaSet) Run(ctx context.Context) {
+	s.Check(ctx)
+	s.interval.Run(ctx, s.Check)
+}
+
+// Check measures the lag of every replica and updates its health
+func (s *ReplicaSet) Check(ctx context.Context) {
+	maxLag := time.Duration(s.maxLag.Load())
+	for _, r := range s.replicas {
+		lag, err := r.lag(ctx)
+		if err == nil {
+			replicaLag.WithLabelValues(r.Name).Set(lag.Seconds())
+			if lag > maxLag {
+				err = fmt.Errorf("replication lag %s exceeds %s", lag.Round(time.Millisecond), maxLag)
+			}
+		}
+		s.setHealthy(r, err)
+	}
+}
+
+// lag returns how far the replica is behind the primary. A replica that is
+// not streaming from the primary is an error: it may have replayed all it
+// received and still miss everything written since it was cut off.
+func (r *replica) lag(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+
+	var seconds float64
+	var streaming bool
+	if err := r.DB.QueryRow(ctx, replicaLagQuery).Scan(&seconds, &streaming); err != nil {
+		return 0, fmt.Errorf("failed to query replication lag: %w", err)
+	}
+	if !streaming {
+		return 0, errors.New("replica is not streaming from the primary")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// setHealthy records the result of a check and logs health changes
+func (s *ReplicaSet) setHealthy(r *replica, err error) {
+	healthy := err == nil
+	if healthy {
+		replicaHealthy.WithLabelValues(r.Name).Set(1)
+	} else {
+		replicaHealthy.WithLabelValues(r.Name).Set(0)
+	}
+
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		s.logger.Info("Read replica is healthy", zap.String("replica", r.Name))
+	} else {
+		s.logger.Warn("Read replica is unhealthy, reads fall back to other replicas or the primary",
+			zap.String("replica", r.Name), zap.Error(err))
+	}
+}
+
+// Healthy returns the names of the replicas that passed the last check
+func (s *ReplicaSet) Healthy() []string {
+	var names []string
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// pick returns a healthy replica, spreading reads over them in turn, or
+// nil when none is healthy
+func (s *ReplicaSet) pick() DB {
+	n := len(s.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(s.next.Add(1) % uint64(n))
+	for i := 0; i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.DB
+		}
+	}
+	return nil
+}
+
+// Close closes the connection pools of the replicas
+func (s *ReplicaSet) Close() {
+	for _, r := range s.replicas {
+		r.DB.Close()
+	}
+}
+
+// readYourWritesKey marks contexts whose reads must see earlier writes
+type readYourWritesKey struct{}
+
+// ReadYourWrites returns a context whose reads go to the primary, so they
+// see the writes made before them
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+func isReadYourWrites(ctx context.Context) bool {
+	v, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return v
+}
+
+// reader returns the pool for a read-only query: a healthy replica, or the
+// primary when none is healthy, when the context requests read-your-writes
+// consistency or when consistent is set
+func (r *Repository) reader(ctx context.Context, consistent bool) DB {
+	if r.replicas == nil {
+		return r.db
+	}
+	if consistent || isReadYourWrites(ctx) {
+		dbReads.WithLabelValues("primary").Inc()
+		return r.db
+	}
+	if db := r.replicas.pick(); db != nil {
+		dbReads.WithLabelValues("replica").Inc()
+		return db
+	}
+	dbReads.WithLabelValues("fallback").Inc()
+	return r.db
+}
@@ -3,58 +3,105 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/alik/TestForWork/pkg/logger"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 // Repository represents the PostgreSQL repository
 type Repository struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db             DB
+	replicas       *ReplicaSet
+	readYourWrites bool
+	timeouts       atomic.Pointer[StatementTimeouts]
+	logger         *zap.Logger
+	outbox         bool
+	outboxHolder   string
+	notifyOrigin   string
 }
 
-// Rate represents a rate record in the database
+// Rate represents a rate record in the database.
+// Timestamp is the exchange timestamp and is zero when the exchange did not report one.
 type Rate struct {
-	ID        int64     `db:"id" json:"id"`
-	Market    string    `db:"market" json:"market"`
-	Ask       string    `db:"ask" json:"ask"`
-	Bid       string    `db:"bid" json:"bid"`
-	Timestamp time.Time `db:"timestamp" json:"timestamp"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ID         int64     `db:"id" json:"id"`
+	Market     string    `db:"market" json:"market"`
+	Ask        string    `db:"ask" json:"ask"`
+	Bid        string    `db:"bid" json:"bid"`
+	Timestamp  time.Time `db:"timestamp" json:"timestamp"`
+	ReceivedAt time.Time `db:"received_at" json:"received_at"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// Option configures the repository
+type Option func(*Repository)
+
+// WithOutbox writes a rate-change event to the outbox table in the same
+// transaction as every saved rate
+func WithOutbox() Option {
+	return func(r *Repository) {
+		r.outbox = true
+	}
+}
+
+// WithReplicas routes history and export queries to the healthy replicas
+// of the set, falling back to the primary when none is healthy
+func WithReplicas(replicas *ReplicaSet) Option {
+	return func(r *Repository) {
+		r.replicas = replicas
+	}
+}
+
+// WithReadYourWrites keeps GetLatestRate on the primary, so the latest rate
+// read after a save is never older than the saved one
+func WithReadYourWrites() Option {
+	return func(r *Repository) {
+		r.readYourWrites = true
+	}
 }
 
 // NewRepository creates a new PostgreSQL repository
-func NewRepository(db *sql.DB, logger *zap.Logger) *Repository {
-	return &Repository{
-		db:     db,
-		logger: logger,
+func NewRepository(db DB, logger *zap.Logger, opts ...Option) *Repository {
+	r := &Repository{
+		db:           db,
+		logger:       logger,
+		outboxHolder: newLeaseHolder(),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // SaveRate saves a rate to the database
-func (r *Repository) SaveRate(ctx context.Context, market, ask, bid string, timestamp time.Time) error {
-	query := `
-		INSERT INTO rates (market, ask, bid, timestamp, created_at)
-		VALUES ($1, $2, $3, $4, NOW())
-	`
-
-	r.logger.Debug("Saving rate to database",
+func (r *Repository) SaveRate(ctx context.Context, market, ask, bid string, timestamp, receivedAt time.Time) error {
+	r.log(ctx).Debug("Saving rate to database",
 		zap.String("market", market),
 		zap.String("ask", ask),
 		zap.String("bid", bid),
-		zap.Time("timestamp", timestamp))
-
-	_, err := r.db.ExecContext(ctx, query, market, ask, bid, timestamp)
+		zap.Time("timestamp", timestamp),
+		zap.Time("received_at", receivedAt))
+
+	start := time.Now()
+	var err error
+	if r.outbox {
+		err = r.saveRateWithEvent(ctx, market, ask, bid, timestamp, receivedAt)
+	} else {
+		err = r.saveRate(ctx, market, ask, bid, timestamp, receivedAt)
+	}
+	observeWrite("save_rate", start, err)
 	if err != nil {
-		r.logger.Error("Failed to save rate",
+		r.log(ctx).Error("Failed to save rate",
 			zap.Error(err),
 			zap.String("market", market))
 		return fmt.Errorf("failed to save rate: %w", err)
 	}
 
-	r.logger.Info("Rate saved successfully",
+	r.log(ctx).Info("Rate saved successfully",
 		zap.String("market", market),
 		zap.String("ask", ask),
 		zap.String("bid", bid))
@@ -62,45 +109,76 @@ func (r *Repository) SaveRate(ctx context.Context, market, ask, bid string, time
 	return nil
 }
 
+// saveRate inserts a rate, notifying other instances in the same statement
+// when notifications are enabled
+func (r *Repository) saveRate(ctx context.Context, market, ask, bid string, timestamp, receivedAt time.Time) error {
+	ctx, cancel := r.withTimeout(ctx, classWrite)
+	defer cancel()
+
+	if r.notifyOrigin == "" {
+		_, err := r.db.Exec(ctx, `
+			INSERT INTO rates (market, ask, bid, timestamp, received_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+		`, market, ask, bid, nullTime(timestamp), receivedAt)
+		return err
+	}
+
+	payload, err := r.notification(market, ask, bid, timestamp, receivedAt)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `
+		WITH saved AS (
+			INSERT INTO rates (market, ask, bid, timestamp, received_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			RETURNING id
+		)
+		SELECT pg_notify($6, $7) FROM saved
+	`, market, ask, bid, nullTime(timestamp), receivedAt, RatesChannel, payload)
+	return err
+}
+
 // GetRates retrieves rates from the database with pagination
 func (r *Repository) GetRates(ctx context.Context, market string, limit, offset int) ([]Rate, error) {
 	query := `
-		SELECT id, market, ask, bid, timestamp, created_at
+		SELECT id, market, ask, bid, timestamp, received_at, created_at
 		FROM rates
 		WHERE market = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
 
-	r.logger.Debug("Retrieving rates from database",
+	r.log(ctx).Debug("Retrieving rates from database",
 		zap.String("market", market),
 		zap.Int("limit", limit),
 		zap.Int("offset", offset))
 
-	rows, err := r.db.QueryContext(ctx, query, market, limit, offset)
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rows, err := r.reader(ctx, false).Query(ctx, query, market, limit, offset)
 	if err != nil {
-		r.logger.Error("Failed to query rates", zap.Error(err))
+		r.log(ctx).Error("Failed to query rates", zap.Error(err))
 		return nil, fmt.Errorf("failed to query rates: %w", err)
 	}
 	defer rows.Close()
 
 	var rates []Rate
 	for rows.Next() {
-		var rate Rate
-		err := rows.Scan(&rate.ID, &rate.Market, &rate.Ask, &rate.Bid, &rate.Timestamp, &rate.CreatedAt)
+		rate, err := scanRate(rows)
 		if err != nil {
-			r.logger.Error("Failed to scan rate", zap.Error(err))
+			r.log(ctx).Error("Failed to scan rate", zap.Error(err))
 			return nil, fmt.Errorf("failed to scan rate: %w", err)
 		}
 		rates = append(rates, rate)
 	}
 
 	if err = rows.Err(); err != nil {
-		r.logger.Error("Error during rows iteration", zap.Error(err))
+		r.log(ctx).Error("Error during rows iteration", zap.Error(err))
 		return nil, fmt.Errorf("error during rows iteration: %w", err)
 	}
 
-	r.logger.Debug("Retrieved rates from database", zap.Int("count", len(rates)))
+	r.log(ctx).Debug("Retrieved rates from database", zap.Int("count", len(rates)))
 
 	return rates, nil
 }
@@ -108,69 +186,63 @@ func (r *Repository) GetRates(ctx context.Context, market string, limit, offset
 // GetLatestRate retrieves the latest rate for a market
 func (r *Repository) GetLatestRate(ctx context.Context, market string) (*Rate, error) {
 	query := `
-		SELECT id, market, ask, bid, timestamp, created_at
+		SELECT id, market, ask, bid, timestamp, received_at, created_at
 		FROM rates
 		WHERE market = $1
 		ORDER BY created_at DESC
 		LIMIT 1
 	`
 
-	r.logger.Debug("Retrieving latest rate from database", zap.String("market", market))
+	r.log(ctx).Debug("Retrieving latest rate from database", zap.String("market", market))
 
-	var rate Rate
-	err := r.db.QueryRowContext(ctx, query, market).Scan(
-		&rate.ID, &rate.Market, &rate.Ask, &rate.Bid, &rate.Timestamp, &rate.CreatedAt)
+	ctx, cancel := r.withTimeout(ctx, classRead)
+	defer cancel()
+
+	rate, err := scanRate(r.reader(ctx, r.readYourWrites).QueryRow(ctx, query, market))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			r.logger.Debug("No rates found", zap.String("market", market))
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.log(ctx).Debug("No rates found", zap.String("market", market))
 			return nil, nil
 		}
-		r.logger.Error("Failed to query latest rate", zap.Error(err))
+		r.log(ctx).Error("Failed to query latest rate", zap.Error(err))
 		return nil, fmt.Errorf("failed to query latest rate: %w", err)
 	}
 
-	r.logger.Debug("Retrieved latest rate from database", zap.String("market", market))
+	r.log(ctx).Debug("Retrieved latest rate from database", zap.String("market", market))
 
 	return &rate, nil
 }
 
+// scanRate scans a rate row selected with id, market, ask, bid, timestamp, received_at, created_at
+func scanRate(row rowScanner) (Rate, error) {
+	var rate Rate
+	var timestamp sql.NullTime
+	err := row.Scan(&rate.ID, &rate.Market, &rate.Ask, &rate.Bid, &timestamp, &rate.ReceivedAt, &rate.CreatedAt)
+	rate.Timestamp = timestamp.Time
+	return rate, err
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 // Ping checks the database connection
 func (r *Repository) Ping(ctx context.Context) error {
-	if err := r.db.PingContext(ctx); err != nil {
-		r.logger.Error("Database ping failed", zap.Error(err))
+	if err := r.db.Ping(ctx); err != nil {
+		r.log(ctx).Error("Database ping failed", zap.Error(err))
 		return fmt.Errorf("database ping failed: %w", err)
 	}
 	return nil
 }
 
-// Close closes the database connection
-func (r *Repository) Close() error {
-	return r.db.Close()
+// log returns the repository logger with the trace of the request
+func (r *Repository) log(ctx context.Context) *zap.Logger {
+	return logger.WithTrace(ctx, r.logger)
 }
 
-// NewDB creates a new database connection
-func NewDB(dsn string, maxOpenConns, maxIdleConns int, connMaxLifetime time.Duration, logger *zap.Logger) (*sql.DB, error) {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		logger.Error("Failed to open database", zap.Error(err))
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Set connection pool settings
-	db.SetMaxOpenConns(maxOpenConns)
-	db.SetMaxIdleConns(maxIdleConns)
-	db.SetConnMaxLifetime(connMaxLifetime)
-
-	// Test the connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		logger.Error("Failed to ping database", zap.Error(err))
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	logger.Info("Database connection established successfully")
-
-	return db, nil
+// Close closes the database connection pool
+func (r *Repository) Close() error {
+	r.db.Close()
+	return nil
 }
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StatementTimeouts bound the statements of each query class; zero leaves
+// the class unbounded
+type StatementTimeouts struct {
+	// Read covers point and history reads
+	Read time.Duration
+	// Write covers saves and updates, including the outbox relay
+	Write time.Duration
+	// Bulk covers each statement of imports and exports
+	Bulk time.Duration
+	// Maintenance covers the cleanup of expired and published rows
+	Maintenance time.Duration
+}
+
+// queryClass selects a statement timeout
+type queryClass int
+
+const (
+	classRead queryClass = iota
+	classWrite
+	classBulk
+	classMaintenance
+)
+
+// WithStatementTimeouts bounds the statements of every query class
+func WithStatementTimeouts(t StatementTimeouts) Option {
+	return func(r *Repository) {
+		r.timeouts.Store(&t)
+	}
+}
+
+// SetStatementTimeouts replaces the statement timeouts for the following
+// queries
+func (r *Repository) SetStatementTimeouts(t StatementTimeouts) {
+	r.timeouts.Store(&t)
+}
+
+func (r *Repository) statementTimeout(class queryClass) time.Duration {
+	t := r.timeouts.Load()
+	if t == nil {
+		return 0
+	}
+	switch class {
+	case classRead:
+		return t.Read
+	case classWrite:
+		return t.Write
+	case classBulk:
+		return t.Bulk
+	default:
+		return t.Maintenance
+	}
+}
+
+// withTimeout bounds a single statement of the class. pgx cancels the
+// statement on the server when the timeout expires.
+func (r *Repository) withTimeout(ctx context.Context, class queryClass) (context.Context, context.CancelFunc) {
+	if d := r.statementTimeout(class); d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
+
+// setStatementTimeout bounds every following statement of the transaction
+// on the server, so a long transaction such as an export is not limited as
+// a whole
+func (r *Repository) setStatementTimeout(ctx context.Context, tx pgx.Tx, class queryClass) error {
+	d := r.statementTimeout(class)
+	if d <= 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`, strconv.FormatInt(d.Milliseconds(), 10))
+	if err != nil {
+		return fmt.Errorf("failed to set statement timeout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package tracing configures the OpenTelemetry tracer provider and propagators.
+package tracing
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alik/TestForWork/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"google.golang.org/grpc/credentials"
+)
+
+// Exporter types
+const (
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterStdout   = "stdout"
+	ExporterNone     = "none"
+)
+
+// Option configures the tracer provider
+type Option func(*options)
+
+type options struct {
+	stdout io.Writer
+}
+
+// WithStdout sets where the stdout exporter writes, os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(o *options) {
+		o.stdout = w
+	}
+}
+
+// NewTracerProvider creates a tracer provider with the configured exporter,
+// sampler and resource. With the none exporter spans are still created, so
+// trace IDs reach the logs and downstream services, but nothing is exported.
+func NewTracerProvider(ctx context.Context, cfg config.TracingConfig, version string, opts ...Option) (*sdktrace.TracerProvider, error) {
+	o := options{stdout: os.Stdout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, fmt.Errorf("sample ratio must be between 0 and 1, got %g", cfg.SampleRatio)
+	}
+
+	res, err := newResource(ctx, cfg, version)
+	if err != nil {
+		return nil, err
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		// Follow the caller's sampling decision and sample new traces by ratio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+
+	exporter, err := newExporter(ctx, cfg, o)
+	if err != nil {
+		return nil, err
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(providerOpts...), nil
+}
+
+// Setup installs the tracer provider and the W3C trace context and baggage
+// propagators globally. The returned function flushes and stops the provider.
+func Setup(ctx context.Context, cfg config.TracingConfig, version string, opts ...Option) (func(context.Context) error, error) {
+	tp, err := NewTracerProvider(ctx, cfg, version, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator())
+
+	return tp.Shutdown, nil
+}
+
+// Propagator returns the W3C trace context and baggage propagator
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// newResource describes the service. Configured attributes override the
+// defaults, and OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME override both.
+func newResource(ctx context.Context, cfg config.TracingConfig, version string) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(cfg.ServiceName),
+		semconv.ServiceVersionKey.String(version),
+	}
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	return res, nil
+}
+
+// newExporter creates the configured span exporter, nil for the none exporter
+func newExporter(ctx context.Context, cfg config.TracingConfig, o options) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		return newGRPCExporter(ctx, cfg)
+	case ExporterOTLPHTTP, "":
+		return newHTTPExporter(ctx, cfg)
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(o.stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected %s, %s, %s or %s",
+			cfg.Exporter, ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterStdout, ExporterNone)
+	}
+}
+
+// newGRPCExporter creates an OTLP/gRPC exporter. Unset options fall back to
+// the OTEL_EXPORTER_OTLP_* environment variables.
+func newGRPCExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	var opts []otlptracegrpc.Option
+	if cfg.Endpoint != "" {
+		if isURL(cfg.Endpoint) {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+	}
+
+	tlsConfig, err := exporterTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cfg.Insecure:
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	case tlsConfig != nil:
+		opts = append(opts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP gRPC exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// newHTTPExporter creates an OTLP/HTTP exporter. Unset options fall back to
+// the OTEL_EXPORTER_OTLP_* environment variables.
+func newHTTPExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		if isURL(cfg.Endpoint) {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+
+	tlsConfig, err := exporterTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cfg.Insecure:
+		opts = append(opts, otlptracehttp.WithInsecure())
+	case tlsConfig != nil:
+		opts = append(opts, otlptracehttp.WithTLSClientConfig(tlsConfig))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// isURL reports whether the endpoint includes a scheme, as in
+// http://collector:4318, rather than being a bare host:port
+func isURL(endpoint string) bool {
+	return strings.Contains(endpoint, "://")
+}
+
+// exporterTLSConfig builds the TLS settings of the exporter, nil when none are configured
+func exporterTLSConfig(cfg config.TracingTLSConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && cfg.ServerName == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tracing CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tracing client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -8,6 +8,8 @@ import (
 // Logger wraps zap logger
 type Logger struct {
 	*zap.Logger
+	// Level can be changed at runtime and applies to all child loggers
+	Level zap.AtomicLevel
 }
 
 // New creates a new logger with the specified level and format
@@ -42,12 +44,12 @@ func New(level, format string) (*Logger, error) {
 		return nil, err
 	}
 
-	return &Logger{zapLogger}, nil
+	return &Logger{Logger: zapLogger, Level: config.Level}, nil
 }
 
 // With creates a child logger with additional fields
 func (l *Logger) With(fields ...zap.Field) *Logger {
-	return &Logger{l.Logger.With(fields...)}
+	return &Logger{Logger: l.Logger.With(fields...), Level: l.Level}
 }
 
 // Close closes the logger
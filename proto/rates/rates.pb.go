@@ -9,6 +9,8 @@ package rates
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
@@ -22,30 +24,1374 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// QuoteStatus is the outcome of a redemption
+type QuoteStatus int32
+
+const (
+	QuoteStatus_QUOTE_STATUS_UNSPECIFIED QuoteStatus = 0
+	// The quote was valid and is now used
+	QuoteStatus_QUOTE_STATUS_VALID QuoteStatus = 1
+	// The quote expired before redemption
+	QuoteStatus_QUOTE_STATUS_EXPIRED QuoteStatus = 2
+	// The quote was already redeemed
+	QuoteStatus_QUOTE_STATUS_USED QuoteStatus = 3
+	// The token does not match the quote
+	QuoteStatus_QUOTE_STATUS_INVALID QuoteStatus = 4
+)
+
+// Enum value maps for QuoteStatus.
+var (
+	QuoteStatus_name = map[int32]string{
+		0: "QUOTE_STATUS_UNSPECIFIED",
+		1: "QUOTE_STATUS_VALID",
+		2: "QUOTE_STATUS_EXPIRED",
+		3: "QUOTE_STATUS_USED",
+		4: "QUOTE_STATUS_INVALID",
+	}
+	QuoteStatus_value = map[string]int32{
+		"QUOTE_STATUS_UNSPECIFIED": 0,
+		"QUOTE_STATUS_VALID":       1,
+		"QUOTE_STATUS_EXPIRED":     2,
+		"QUOTE_STATUS_USED":        3,
+		"QUOTE_STATUS_INVALID":     4,
+	}
+)
+
+func (x QuoteStatus) Enum() *QuoteStatus {
+	p := new(QuoteStatus)
+	*p = x
+	return p
+}
+
+func (x QuoteStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (QuoteStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_rates_rates_proto_enumTypes[0].Descriptor()
+}
+
+func (QuoteStatus) Type() protoreflect.EnumType {
+	return &file_proto_rates_rates_proto_enumTypes[0]
+}
+
+func (x QuoteStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use QuoteStatus.Descriptor instead.
+func (QuoteStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{0}
+}
+
+// ExportFormat is the file format of an export
+type ExportFormat int32
+
+const (
+	// Defaults to CSV
+	ExportFormat_EXPORT_FORMAT_UNSPECIFIED ExportFormat = 0
+	// Comma-separated values with a header row
+	ExportFormat_EXPORT_FORMAT_CSV ExportFormat = 1
+	// One JSON object per line
+	ExportFormat_EXPORT_FORMAT_JSONL ExportFormat = 2
+	// Apache Parquet
+	ExportFormat_EXPORT_FORMAT_PARQUET ExportFormat = 3
+)
+
+// Enum value maps for ExportFormat.
+var (
+	ExportFormat_name = map[int32]string{
+		0: "EXPORT_FORMAT_UNSPECIFIED",
+		1: "EXPORT_FORMAT_CSV",
+		2: "EXPORT_FORMAT_JSONL",
+		3: "EXPORT_FORMAT_PARQUET",
+	}
+	ExportFormat_value = map[string]int32{
+		"EXPORT_FORMAT_UNSPECIFIED": 0,
+		"EXPORT_FORMAT_CSV":         1,
+		"EXPORT_FORMAT_JSONL":       2,
+		"EXPORT_FORMAT_PARQUET":     3,
+	}
+)
+
+func (x ExportFormat) Enum() *ExportFormat {
+	p := new(ExportFormat)
+	*p = x
+	return p
+}
+
+func (x ExportFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ExportFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_rates_rates_proto_enumTypes[1].Descriptor()
+}
+
+func (ExportFormat) Type() protoreflect.EnumType {
+	return &file_proto_rates_rates_proto_enumTypes[1]
+}
+
+func (x ExportFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ExportFormat.Descriptor instead.
+func (ExportFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{1}
+}
+
 // GetRatesRequest for retrieving exchange rates
 type GetRatesRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Market pair, e.g., "usdtrub"
-	Market        string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Market pair, e.g., "usdtrub"
+	Market string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Client pricing tier used to select markup rules
+	ClientTier string `protobuf:"bytes,2,opt,name=client_tier,json=clientTier,proto3" json:"client_tier,omitempty"`
+	// Refuse quotes older than this after one refresh attempt; unset accepts any age
+	MaxAge        *durationpb.Duration `protobuf:"bytes,3,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRatesRequest) Reset() {
+	*x = GetRatesRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRatesRequest) ProtoMessage() {}
+
+func (x *GetRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRatesRequest.ProtoReflect.Descriptor instead.
+func (*GetRatesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetRatesRequest) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *GetRatesRequest) GetClientTier() string {
+	if x != nil {
+		return x.ClientTier
+	}
+	return ""
+}
+
+func (x *GetRatesRequest) GetMaxAge() *durationpb.Duration {
+	if x != nil {
+		return x.MaxAge
+	}
+	return nil
+}
+
+// GetRatesResponse contains exchange rate information
+type GetRatesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ask price (selling price)
+	Ask string `protobuf:"bytes,1,opt,name=ask,proto3" json:"ask,omitempty"`
+	// Bid price (buying price)
+	Bid string `protobuf:"bytes,2,opt,name=bid,proto3" json:"bid,omitempty"`
+	// Quote time: the exchange timestamp, or received_at when the exchange did not report one
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Market pair
+	Market string `protobuf:"bytes,4,opt,name=market,proto3" json:"market,omitempty"`
+	// Derived is true for synthetic quotes built from other markets
+	Derived bool `protobuf:"varint,5,opt,name=derived,proto3" json:"derived,omitempty"`
+	// Markets used to derive a synthetic quote, in conversion order
+	Path []*CrossRateLeg `protobuf:"bytes,6,rep,name=path,proto3" json:"path,omitempty"`
+	// Customer ask price after markup
+	CustomerAsk string `protobuf:"bytes,7,opt,name=customer_ask,json=customerAsk,proto3" json:"customer_ask,omitempty"`
+	// Customer bid price after markup
+	CustomerBid string `protobuf:"bytes,8,opt,name=customer_bid,json=customerBid,proto3" json:"customer_bid,omitempty"`
+	// Id of the pricing rule applied, empty when no rule matched
+	PricingRuleId string `protobuf:"bytes,9,opt,name=pricing_rule_id,json=pricingRuleId,proto3" json:"pricing_rule_id,omitempty"`
+	// Version of the pricing rule applied
+	PricingRuleVersion int32 `protobuf:"varint,10,opt,name=pricing_rule_version,json=pricingRuleVersion,proto3" json:"pricing_rule_version,omitempty"`
+	// Timestamp reported by the exchange, unset when the exchange did not report one
+	ExchangeTimestamp *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=exchange_timestamp,json=exchangeTimestamp,proto3" json:"exchange_timestamp,omitempty"`
+	// Time the quote was received from the exchange
+	ReceivedAt *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"`
+	// Age of the quote when the response was built
+	Age           *durationpb.Duration `protobuf:"bytes,13,opt,name=age,proto3" json:"age,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRatesResponse) Reset() {
+	*x = GetRatesResponse{}
+	mi := &file_proto_rates_rates_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRatesResponse) ProtoMessage() {}
+
+func (x *GetRatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRatesResponse.ProtoReflect.Descriptor instead.
+func (*GetRatesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetRatesResponse) GetAsk() string {
+	if x != nil {
+		return x.Ask
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetBid() string {
+	if x != nil {
+		return x.Bid
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *GetRatesResponse) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetDerived() bool {
+	if x != nil {
+		return x.Derived
+	}
+	return false
+}
+
+func (x *GetRatesResponse) GetPath() []*CrossRateLeg {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+func (x *GetRatesResponse) GetCustomerAsk() string {
+	if x != nil {
+		return x.CustomerAsk
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetCustomerBid() string {
+	if x != nil {
+		return x.CustomerBid
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetPricingRuleId() string {
+	if x != nil {
+		return x.PricingRuleId
+	}
+	return ""
+}
+
+func (x *GetRatesResponse) GetPricingRuleVersion() int32 {
+	if x != nil {
+		return x.PricingRuleVersion
+	}
+	return 0
+}
+
+func (x *GetRatesResponse) GetExchangeTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExchangeTimestamp
+	}
+	return nil
+}
+
+func (x *GetRatesResponse) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+func (x *GetRatesResponse) GetAge() *durationpb.Duration {
+	if x != nil {
+		return x.Age
+	}
+	return nil
+}
+
+// CrossRateLeg is one step of a cross-rate path
+type CrossRateLeg struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market used for this step
+	Market string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Currency converted from
+	From string `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	// Currency converted to
+	To string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// Inverted is true when the market is traversed from quote to base
+	Inverted      bool `protobuf:"varint,4,opt,name=inverted,proto3" json:"inverted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrossRateLeg) Reset() {
+	*x = CrossRateLeg{}
+	mi := &file_proto_rates_rates_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrossRateLeg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrossRateLeg) ProtoMessage() {}
+
+func (x *CrossRateLeg) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrossRateLeg.ProtoReflect.Descriptor instead.
+func (*CrossRateLeg) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CrossRateLeg) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *CrossRateLeg) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *CrossRateLeg) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *CrossRateLeg) GetInverted() bool {
+	if x != nil {
+		return x.Inverted
+	}
+	return false
+}
+
+// BatchGetRatesRequest for retrieving rates of several markets
+type BatchGetRatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market pairs, e.g., ["usdtrub", "btcusdt"]
+	Markets []string `protobuf:"bytes,1,rep,name=markets,proto3" json:"markets,omitempty"`
+	// Client pricing tier used to select markup rules
+	ClientTier string `protobuf:"bytes,2,opt,name=client_tier,json=clientTier,proto3" json:"client_tier,omitempty"`
+	// Refuse quotes older than this after one refresh attempt; unset accepts any age
+	MaxAge        *durationpb.Duration `protobuf:"bytes,3,opt,name=max_age,json=maxAge,proto3" json:"max_age,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetRatesRequest) Reset() {
+	*x = BatchGetRatesRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetRatesRequest) ProtoMessage() {}
+
+func (x *BatchGetRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetRatesRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetRatesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchGetRatesRequest) GetMarkets() []string {
+	if x != nil {
+		return x.Markets
+	}
+	return nil
+}
+
+func (x *BatchGetRatesRequest) GetClientTier() string {
+	if x != nil {
+		return x.ClientTier
+	}
+	return ""
+}
+
+func (x *BatchGetRatesRequest) GetMaxAge() *durationpb.Duration {
+	if x != nil {
+		return x.MaxAge
+	}
+	return nil
+}
+
+// BatchGetRatesResponse contains one result per requested market
+type BatchGetRatesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Results in the same order as the requested markets
+	Results       []*MarketRatesResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetRatesResponse) Reset() {
+	*x = BatchGetRatesResponse{}
+	mi := &file_proto_rates_rates_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetRatesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetRatesResponse) ProtoMessage() {}
+
+func (x *BatchGetRatesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetRatesResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetRatesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchGetRatesResponse) GetResults() []*MarketRatesResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// MarketRatesResult holds either the rates or the error for one market
+type MarketRatesResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market pair
+	Market string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*MarketRatesResult_Rates
+	//	*MarketRatesResult_Error
+	Result        isMarketRatesResult_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketRatesResult) Reset() {
+	*x = MarketRatesResult{}
+	mi := &file_proto_rates_rates_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketRatesResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketRatesResult) ProtoMessage() {}
+
+func (x *MarketRatesResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketRatesResult.ProtoReflect.Descriptor instead.
+func (*MarketRatesResult) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MarketRatesResult) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *MarketRatesResult) GetResult() isMarketRatesResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *MarketRatesResult) GetRates() *GetRatesResponse {
+	if x != nil {
+		if x, ok := x.Result.(*MarketRatesResult_Rates); ok {
+			return x.Rates
+		}
+	}
+	return nil
+}
+
+func (x *MarketRatesResult) GetError() *MarketError {
+	if x != nil {
+		if x, ok := x.Result.(*MarketRatesResult_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isMarketRatesResult_Result interface {
+	isMarketRatesResult_Result()
+}
+
+type MarketRatesResult_Rates struct {
+	// Rates when the market was fetched successfully
+	Rates *GetRatesResponse `protobuf:"bytes,2,opt,name=rates,proto3,oneof"`
+}
+
+type MarketRatesResult_Error struct {
+	// Error when the market could not be fetched
+	Error *MarketError `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+func (*MarketRatesResult_Rates) isMarketRatesResult_Result() {}
+
+func (*MarketRatesResult_Error) isMarketRatesResult_Result() {}
+
+// MarketError describes a per-market failure
+type MarketError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// gRPC status code
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// Error message
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketError) Reset() {
+	*x = MarketError{}
+	mi := &file_proto_rates_rates_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketError) ProtoMessage() {}
+
+func (x *MarketError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketError.ProtoReflect.Descriptor instead.
+func (*MarketError) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *MarketError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *MarketError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// ListMarketsRequest for listing available markets
+type ListMarketsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMarketsRequest) Reset() {
+	*x = ListMarketsRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMarketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMarketsRequest) ProtoMessage() {}
+
+func (x *ListMarketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMarketsRequest.ProtoReflect.Descriptor instead.
+func (*ListMarketsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{7}
+}
+
+// ListMarketsResponse contains the market catalog
+type ListMarketsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Available markets
+	Markets       []*Market `protobuf:"bytes,1,rep,name=markets,proto3" json:"markets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMarketsResponse) Reset() {
+	*x = ListMarketsResponse{}
+	mi := &file_proto_rates_rates_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMarketsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMarketsResponse) ProtoMessage() {}
+
+func (x *ListMarketsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMarketsResponse.ProtoReflect.Descriptor instead.
+func (*ListMarketsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListMarketsResponse) GetMarkets() []*Market {
+	if x != nil {
+		return x.Markets
+	}
+	return nil
+}
+
+// Market describes a market from the catalog
+type Market struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market id, e.g., "usdtrub"
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Base currency, e.g., "usdt"
+	Base string `protobuf:"bytes,2,opt,name=base,proto3" json:"base,omitempty"`
+	// Quote currency, e.g., "rub"
+	Quote string `protobuf:"bytes,3,opt,name=quote,proto3" json:"quote,omitempty"`
+	// Number of decimal places in prices
+	PricePrecision int32 `protobuf:"varint,4,opt,name=price_precision,json=pricePrecision,proto3" json:"price_precision,omitempty"`
+	// Number of decimal places in amounts
+	AmountPrecision int32 `protobuf:"varint,5,opt,name=amount_precision,json=amountPrecision,proto3" json:"amount_precision,omitempty"`
+	// Market status, e.g., "active"
+	Status        string `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Market) Reset() {
+	*x = Market{}
+	mi := &file_proto_rates_rates_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Market) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Market) ProtoMessage() {}
+
+func (x *Market) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Market.ProtoReflect.Descriptor instead.
+func (*Market) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Market) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Market) GetBase() string {
+	if x != nil {
+		return x.Base
+	}
+	return ""
+}
+
+func (x *Market) GetQuote() string {
+	if x != nil {
+		return x.Quote
+	}
+	return ""
+}
+
+func (x *Market) GetPricePrecision() int32 {
+	if x != nil {
+		return x.PricePrecision
+	}
+	return 0
+}
+
+func (x *Market) GetAmountPrecision() int32 {
+	if x != nil {
+		return x.AmountPrecision
+	}
+	return 0
+}
+
+func (x *Market) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// CreateLockedQuoteRequest for locking a price
+type CreateLockedQuoteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market pair, e.g., "usdtrub"
+	Market string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Side from the customer's point of view: "buy" or "sell"
+	Side string `protobuf:"bytes,2,opt,name=side,proto3" json:"side,omitempty"`
+	// Amount of the base currency, as a decimal string
+	Amount string `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Client pricing tier used to select markup rules
+	ClientTier    string `protobuf:"bytes,4,opt,name=client_tier,json=clientTier,proto3" json:"client_tier,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLockedQuoteRequest) Reset() {
+	*x = CreateLockedQuoteRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLockedQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLockedQuoteRequest) ProtoMessage() {}
+
+func (x *CreateLockedQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLockedQuoteRequest.ProtoReflect.Descriptor instead.
+func (*CreateLockedQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CreateLockedQuoteRequest) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *CreateLockedQuoteRequest) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *CreateLockedQuoteRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *CreateLockedQuoteRequest) GetClientTier() string {
+	if x != nil {
+		return x.ClientTier
+	}
+	return ""
+}
+
+// LockedQuote is a price locked until it expires
+type LockedQuote struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Quote id
+	QuoteId string `protobuf:"bytes,1,opt,name=quote_id,json=quoteId,proto3" json:"quote_id,omitempty"`
+	// Market pair
+	Market string `protobuf:"bytes,2,opt,name=market,proto3" json:"market,omitempty"`
+	// Side: "buy" or "sell"
+	Side string `protobuf:"bytes,3,opt,name=side,proto3" json:"side,omitempty"`
+	// Amount of the base currency
+	Amount string `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// Locked unit price
+	Price string `protobuf:"bytes,5,opt,name=price,proto3" json:"price,omitempty"`
+	// Total price for the amount
+	Total string `protobuf:"bytes,6,opt,name=total,proto3" json:"total,omitempty"`
+	// Time after which the quote can no longer be redeemed
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Signed token that must be presented on redemption
+	Token string `protobuf:"bytes,8,opt,name=token,proto3" json:"token,omitempty"`
+	// Id of the pricing rule applied
+	PricingRuleId string `protobuf:"bytes,9,opt,name=pricing_rule_id,json=pricingRuleId,proto3" json:"pricing_rule_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LockedQuote) Reset() {
+	*x = LockedQuote{}
+	mi := &file_proto_rates_rates_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LockedQuote) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LockedQuote) ProtoMessage() {}
+
+func (x *LockedQuote) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LockedQuote.ProtoReflect.Descriptor instead.
+func (*LockedQuote) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *LockedQuote) GetQuoteId() string {
+	if x != nil {
+		return x.QuoteId
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetSide() string {
+	if x != nil {
+		return x.Side
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetPrice() string {
+	if x != nil {
+		return x.Price
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetTotal() string {
+	if x != nil {
+		return x.Total
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *LockedQuote) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *LockedQuote) GetPricingRuleId() string {
+	if x != nil {
+		return x.PricingRuleId
+	}
+	return ""
+}
+
+// RedeemQuoteRequest for redeeming a locked quote
+type RedeemQuoteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Quote id
+	QuoteId string `protobuf:"bytes,1,opt,name=quote_id,json=quoteId,proto3" json:"quote_id,omitempty"`
+	// Token returned by CreateLockedQuote
+	Token         string `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedeemQuoteRequest) Reset() {
+	*x = RedeemQuoteRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedeemQuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedeemQuoteRequest) ProtoMessage() {}
+
+func (x *RedeemQuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedeemQuoteRequest.ProtoReflect.Descriptor instead.
+func (*RedeemQuoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RedeemQuoteRequest) GetQuoteId() string {
+	if x != nil {
+		return x.QuoteId
+	}
+	return ""
+}
+
+func (x *RedeemQuoteRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// RedeemQuoteResponse with the redemption outcome
+type RedeemQuoteResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Redemption status
+	Status QuoteStatus `protobuf:"varint,1,opt,name=status,proto3,enum=rates.QuoteStatus" json:"status,omitempty"`
+	// The locked quote
+	Quote         *LockedQuote `protobuf:"bytes,2,opt,name=quote,proto3" json:"quote,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RedeemQuoteResponse) Reset() {
+	*x = RedeemQuoteResponse{}
+	mi := &file_proto_rates_rates_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RedeemQuoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RedeemQuoteResponse) ProtoMessage() {}
+
+func (x *RedeemQuoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RedeemQuoteResponse.ProtoReflect.Descriptor instead.
+func (*RedeemQuoteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RedeemQuoteResponse) GetStatus() QuoteStatus {
+	if x != nil {
+		return x.Status
+	}
+	return QuoteStatus_QUOTE_STATUS_UNSPECIFIED
+}
+
+func (x *RedeemQuoteResponse) GetQuote() *LockedQuote {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+// AlertRule describes when an alert fires and where it is delivered
+type AlertRule struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rule id, assigned by the server
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Human readable name
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Market pair, e.g., "usdtrub"
+	Market string `protobuf:"bytes,3,opt,name=market,proto3" json:"market,omitempty"`
+	// Rule type: "threshold_above", "threshold_below", "percent_change" or "spread_above"
+	Type string `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	// Watched price: "ask", "bid" or "mid" (default)
+	Field string `protobuf:"bytes,5,opt,name=field,proto3" json:"field,omitempty"`
+	// Threshold: a price, a percentage for percent_change or a price difference for spread_above
+	Value string `protobuf:"bytes,6,opt,name=value,proto3" json:"value,omitempty"`
+	// Window for percent_change rules
+	Window *durationpb.Duration `protobuf:"bytes,7,opt,name=window,proto3" json:"window,omitempty"`
+	// Minimum time between two alerts of the rule
+	Cooldown *durationpb.Duration `protobuf:"bytes,8,opt,name=cooldown,proto3" json:"cooldown,omitempty"`
+	// Webhook URL alerts are posted to
+	WebhookUrl string `protobuf:"bytes,9,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`
+	// Secret used to sign webhooks; write-only, never returned. An update
+	// without a secret keeps the stored one
+	WebhookSecret string `protobuf:"bytes,10,opt,name=webhook_secret,json=webhookSecret,proto3" json:"webhook_secret,omitempty"`
+	// Disabled rules are kept but not evaluated. Defaults to false, so new
+	// rules must set it to be evaluated
+	Enabled bool `protobuf:"varint,11,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	// Creation time
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Last update time
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlertRule) Reset() {
+	*x = AlertRule{}
+	mi := &file_proto_rates_rates_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlertRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlertRule) ProtoMessage() {}
+
+func (x *AlertRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlertRule.ProtoReflect.Descriptor instead.
+func (*AlertRule) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AlertRule) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AlertRule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AlertRule) GetMarket() string {
+	if x != nil {
+		return x.Market
+	}
+	return ""
+}
+
+func (x *AlertRule) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AlertRule) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *AlertRule) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *AlertRule) GetWindow() *durationpb.Duration {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+func (x *AlertRule) GetCooldown() *durationpb.Duration {
+	if x != nil {
+		return x.Cooldown
+	}
+	return nil
+}
+
+func (x *AlertRule) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+func (x *AlertRule) GetWebhookSecret() string {
+	if x != nil {
+		return x.WebhookSecret
+	}
+	return ""
+}
+
+func (x *AlertRule) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *AlertRule) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *AlertRule) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// CreateAlertRuleRequest for creating an alert rule
+type CreateAlertRuleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rule to create; the id is ignored
+	Rule          *AlertRule `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateAlertRuleRequest) Reset() {
+	*x = CreateAlertRuleRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateAlertRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateAlertRuleRequest) ProtoMessage() {}
+
+func (x *CreateAlertRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateAlertRuleRequest.ProtoReflect.Descriptor instead.
+func (*CreateAlertRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CreateAlertRuleRequest) GetRule() *AlertRule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+// GetAlertRuleRequest for retrieving an alert rule
+type GetAlertRuleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rule id
+	Id            int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetRatesRequest) Reset() {
-	*x = GetRatesRequest{}
-	mi := &file_proto_rates_rates_proto_msgTypes[0]
+func (x *GetAlertRuleRequest) Reset() {
+	*x = GetAlertRuleRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetRatesRequest) String() string {
+func (x *GetAlertRuleRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRatesRequest) ProtoMessage() {}
+func (*GetAlertRuleRequest) ProtoMessage() {}
 
-func (x *GetRatesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_rates_rates_proto_msgTypes[0]
+func (x *GetAlertRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,48 +1402,79 @@ func (x *GetRatesRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRatesRequest.ProtoReflect.Descriptor instead.
-func (*GetRatesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_rates_rates_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use GetAlertRuleRequest.ProtoReflect.Descriptor instead.
+func (*GetAlertRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *GetRatesRequest) GetMarket() string {
+func (x *GetAlertRuleRequest) GetId() int64 {
 	if x != nil {
-		return x.Market
+		return x.Id
 	}
-	return ""
+	return 0
 }
 
-// GetRatesResponse contains exchange rate information
-type GetRatesResponse struct {
+// ListAlertRulesRequest for listing alert rules
+type ListAlertRulesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAlertRulesRequest) Reset() {
+	*x = ListAlertRulesRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAlertRulesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAlertRulesRequest) ProtoMessage() {}
+
+func (x *ListAlertRulesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAlertRulesRequest.ProtoReflect.Descriptor instead.
+func (*ListAlertRulesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{17}
+}
+
+// ListAlertRulesResponse contains all alert rules
+type ListAlertRulesResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
-	// Ask price (selling price)
-	Ask string `protobuf:"bytes,1,opt,name=ask,proto3" json:"ask,omitempty"`
-	// Bid price (buying price)
-	Bid string `protobuf:"bytes,2,opt,name=bid,proto3" json:"bid,omitempty"`
-	// Timestamp when the rate was retrieved
-	Timestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	// Market pair
-	Market        string `protobuf:"bytes,4,opt,name=market,proto3" json:"market,omitempty"`
+	// Alert rules
+	Rules         []*AlertRule `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetRatesResponse) Reset() {
-	*x = GetRatesResponse{}
-	mi := &file_proto_rates_rates_proto_msgTypes[1]
+func (x *ListAlertRulesResponse) Reset() {
+	*x = ListAlertRulesResponse{}
+	mi := &file_proto_rates_rates_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetRatesResponse) String() string {
+func (x *ListAlertRulesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetRatesResponse) ProtoMessage() {}
+func (*ListAlertRulesResponse) ProtoMessage() {}
 
-func (x *GetRatesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_rates_rates_proto_msgTypes[1]
+func (x *ListAlertRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -108,39 +1485,286 @@ func (x *GetRatesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetRatesResponse.ProtoReflect.Descriptor instead.
-func (*GetRatesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_rates_rates_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use ListAlertRulesResponse.ProtoReflect.Descriptor instead.
+func (*ListAlertRulesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *GetRatesResponse) GetAsk() string {
+func (x *ListAlertRulesResponse) GetRules() []*AlertRule {
 	if x != nil {
-		return x.Ask
+		return x.Rules
 	}
-	return ""
+	return nil
 }
 
-func (x *GetRatesResponse) GetBid() string {
+// UpdateAlertRuleRequest for replacing an alert rule
+type UpdateAlertRuleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rule with the id of the rule to replace
+	Rule          *AlertRule `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateAlertRuleRequest) Reset() {
+	*x = UpdateAlertRuleRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateAlertRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateAlertRuleRequest) ProtoMessage() {}
+
+func (x *UpdateAlertRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[19]
 	if x != nil {
-		return x.Bid
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *GetRatesResponse) GetTimestamp() *timestamppb.Timestamp {
+// Deprecated: Use UpdateAlertRuleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateAlertRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UpdateAlertRuleRequest) GetRule() *AlertRule {
 	if x != nil {
-		return x.Timestamp
+		return x.Rule
 	}
 	return nil
 }
 
-func (x *GetRatesResponse) GetMarket() string {
+// DeleteAlertRuleRequest for deleting an alert rule
+type DeleteAlertRuleRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Rule id
+	Id            int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteAlertRuleRequest) Reset() {
+	*x = DeleteAlertRuleRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteAlertRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAlertRuleRequest) ProtoMessage() {}
+
+func (x *DeleteAlertRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAlertRuleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteAlertRuleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *DeleteAlertRuleRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// ExportRatesRequest for exporting the rates history of a market
+type ExportRatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market pair, e.g., "usdtrub"
+	Market string `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	// Start of the range, inclusive; the whole history if unset
+	From *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	// End of the range, exclusive; the current time if unset
+	To *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// File format
+	Format ExportFormat `protobuf:"varint,4,opt,name=format,proto3,enum=rates.ExportFormat" json:"format,omitempty"`
+	// Emits the last known rate at every interval instead of every stored rate
+	ResampleInterval *durationpb.Duration `protobuf:"bytes,5,opt,name=resample_interval,json=resampleInterval,proto3" json:"resample_interval,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ExportRatesRequest) Reset() {
+	*x = ExportRatesRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRatesRequest) ProtoMessage() {}
+
+func (x *ExportRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRatesRequest.ProtoReflect.Descriptor instead.
+func (*ExportRatesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ExportRatesRequest) GetMarket() string {
 	if x != nil {
 		return x.Market
 	}
 	return ""
 }
 
+func (x *ExportRatesRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *ExportRatesRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ExportRatesRequest) GetFormat() ExportFormat {
+	if x != nil {
+		return x.Format
+	}
+	return ExportFormat_EXPORT_FORMAT_UNSPECIFIED
+}
+
+func (x *ExportRatesRequest) GetResampleInterval() *durationpb.Duration {
+	if x != nil {
+		return x.ResampleInterval
+	}
+	return nil
+}
+
+// ExportRatesChunk is the next part of the exported file
+type ExportRatesChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// File content; the concatenated chunks form the file
+	Data          []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRatesChunk) Reset() {
+	*x = ExportRatesChunk{}
+	mi := &file_proto_rates_rates_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRatesChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRatesChunk) ProtoMessage() {}
+
+func (x *ExportRatesChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRatesChunk.ProtoReflect.Descriptor instead.
+func (*ExportRatesChunk) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ExportRatesChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// SubscribeRatesRequest selects the markets to stream. The streamed rates are
+// exchange quotes without customer pricing; a subscriber that reads slower
+// than quotes arrive receives only the latest quote of each market.
+type SubscribeRatesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Market pairs, e.g., "usdtrub"; every market when empty
+	Markets       []string `protobuf:"bytes,1,rep,name=markets,proto3" json:"markets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRatesRequest) Reset() {
+	*x = SubscribeRatesRequest{}
+	mi := &file_proto_rates_rates_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRatesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRatesRequest) ProtoMessage() {}
+
+func (x *SubscribeRatesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_rates_rates_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRatesRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRatesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SubscribeRatesRequest) GetMarkets() []string {
+	if x != nil {
+		return x.Markets
+	}
+	return nil
+}
+
 // HealthcheckRequest for health status check
 type HealthcheckRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -150,7 +1774,7 @@ type HealthcheckRequest struct {
 
 func (x *HealthcheckRequest) Reset() {
 	*x = HealthcheckRequest{}
-	mi := &file_proto_rates_rates_proto_msgTypes[2]
+	mi := &file_proto_rates_rates_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -162,7 +1786,7 @@ func (x *HealthcheckRequest) String() string {
 func (*HealthcheckRequest) ProtoMessage() {}
 
 func (x *HealthcheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_rates_rates_proto_msgTypes[2]
+	mi := &file_proto_rates_rates_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -175,7 +1799,7 @@ func (x *HealthcheckRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthcheckRequest.ProtoReflect.Descriptor instead.
 func (*HealthcheckRequest) Descriptor() ([]byte, []int) {
-	return file_proto_rates_rates_proto_rawDescGZIP(), []int{2}
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{24}
 }
 
 // HealthcheckResponse with service status
@@ -193,7 +1817,7 @@ type HealthcheckResponse struct {
 
 func (x *HealthcheckResponse) Reset() {
 	*x = HealthcheckResponse{}
-	mi := &file_proto_rates_rates_proto_msgTypes[3]
+	mi := &file_proto_rates_rates_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -205,7 +1829,7 @@ func (x *HealthcheckResponse) String() string {
 func (*HealthcheckResponse) ProtoMessage() {}
 
 func (x *HealthcheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_rates_rates_proto_msgTypes[3]
+	mi := &file_proto_rates_rates_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -218,7 +1842,7 @@ func (x *HealthcheckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthcheckResponse.ProtoReflect.Descriptor instead.
 func (*HealthcheckResponse) Descriptor() ([]byte, []int) {
-	return file_proto_rates_rates_proto_rawDescGZIP(), []int{3}
+	return file_proto_rates_rates_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *HealthcheckResponse) GetStatus() string {
@@ -246,21 +1870,149 @@ var File_proto_rates_rates_proto protoreflect.FileDescriptor
 
 const file_proto_rates_rates_proto_rawDesc = "" +
 	"\n" +
-	"\x17proto/rates/rates.proto\x12\x05rates\x1a\x1fgoogle/protobuf/timestamp.proto\")\n" +
+	"\x17proto/rates/rates.proto\x12\x05rates\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"~\n" +
 	"\x0fGetRatesRequest\x12\x16\n" +
-	"\x06market\x18\x01 \x01(\tR\x06market\"\x88\x01\n" +
+	"\x06market\x18\x01 \x01(\tR\x06market\x12\x1f\n" +
+	"\vclient_tier\x18\x02 \x01(\tR\n" +
+	"clientTier\x122\n" +
+	"\amax_age\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x06maxAge\"\xa0\x04\n" +
 	"\x10GetRatesResponse\x12\x10\n" +
 	"\x03ask\x18\x01 \x01(\tR\x03ask\x12\x10\n" +
 	"\x03bid\x18\x02 \x01(\tR\x03bid\x128\n" +
 	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x16\n" +
-	"\x06market\x18\x04 \x01(\tR\x06market\"\x14\n" +
+	"\x06market\x18\x04 \x01(\tR\x06market\x12\x18\n" +
+	"\aderived\x18\x05 \x01(\bR\aderived\x12'\n" +
+	"\x04path\x18\x06 \x03(\v2\x13.rates.CrossRateLegR\x04path\x12!\n" +
+	"\fcustomer_ask\x18\a \x01(\tR\vcustomerAsk\x12!\n" +
+	"\fcustomer_bid\x18\b \x01(\tR\vcustomerBid\x12&\n" +
+	"\x0fpricing_rule_id\x18\t \x01(\tR\rpricingRuleId\x120\n" +
+	"\x14pricing_rule_version\x18\n" +
+	" \x01(\x05R\x12pricingRuleVersion\x12I\n" +
+	"\x12exchange_timestamp\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\x11exchangeTimestamp\x12;\n" +
+	"\vreceived_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\x12+\n" +
+	"\x03age\x18\r \x01(\v2\x19.google.protobuf.DurationR\x03age\"f\n" +
+	"\fCrossRateLeg\x12\x16\n" +
+	"\x06market\x18\x01 \x01(\tR\x06market\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x1a\n" +
+	"\binverted\x18\x04 \x01(\bR\binverted\"\x85\x01\n" +
+	"\x14BatchGetRatesRequest\x12\x18\n" +
+	"\amarkets\x18\x01 \x03(\tR\amarkets\x12\x1f\n" +
+	"\vclient_tier\x18\x02 \x01(\tR\n" +
+	"clientTier\x122\n" +
+	"\amax_age\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x06maxAge\"K\n" +
+	"\x15BatchGetRatesResponse\x122\n" +
+	"\aresults\x18\x01 \x03(\v2\x18.rates.MarketRatesResultR\aresults\"\x92\x01\n" +
+	"\x11MarketRatesResult\x12\x16\n" +
+	"\x06market\x18\x01 \x01(\tR\x06market\x12/\n" +
+	"\x05rates\x18\x02 \x01(\v2\x17.rates.GetRatesResponseH\x00R\x05rates\x12*\n" +
+	"\x05error\x18\x03 \x01(\v2\x12.rates.MarketErrorH\x00R\x05errorB\b\n" +
+	"\x06result\";\n" +
+	"\vMarketError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x14\n" +
+	"\x12ListMarketsRequest\">\n" +
+	"\x13ListMarketsResponse\x12'\n" +
+	"\amarkets\x18\x01 \x03(\v2\r.rates.MarketR\amarkets\"\xae\x01\n" +
+	"\x06Market\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04base\x18\x02 \x01(\tR\x04base\x12\x14\n" +
+	"\x05quote\x18\x03 \x01(\tR\x05quote\x12'\n" +
+	"\x0fprice_precision\x18\x04 \x01(\x05R\x0epricePrecision\x12)\n" +
+	"\x10amount_precision\x18\x05 \x01(\x05R\x0famountPrecision\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\"\x7f\n" +
+	"\x18CreateLockedQuoteRequest\x12\x16\n" +
+	"\x06market\x18\x01 \x01(\tR\x06market\x12\x12\n" +
+	"\x04side\x18\x02 \x01(\tR\x04side\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\x12\x1f\n" +
+	"\vclient_tier\x18\x04 \x01(\tR\n" +
+	"clientTier\"\x91\x02\n" +
+	"\vLockedQuote\x12\x19\n" +
+	"\bquote_id\x18\x01 \x01(\tR\aquoteId\x12\x16\n" +
+	"\x06market\x18\x02 \x01(\tR\x06market\x12\x12\n" +
+	"\x04side\x18\x03 \x01(\tR\x04side\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\tR\x05price\x12\x14\n" +
+	"\x05total\x18\x06 \x01(\tR\x05total\x129\n" +
+	"\n" +
+	"expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x14\n" +
+	"\x05token\x18\b \x01(\tR\x05token\x12&\n" +
+	"\x0fpricing_rule_id\x18\t \x01(\tR\rpricingRuleId\"E\n" +
+	"\x12RedeemQuoteRequest\x12\x19\n" +
+	"\bquote_id\x18\x01 \x01(\tR\aquoteId\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\"k\n" +
+	"\x13RedeemQuoteResponse\x12*\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x12.rates.QuoteStatusR\x06status\x12(\n" +
+	"\x05quote\x18\x02 \x01(\v2\x12.rates.LockedQuoteR\x05quote\"\xc9\x03\n" +
+	"\tAlertRule\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06market\x18\x03 \x01(\tR\x06market\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x14\n" +
+	"\x05field\x18\x05 \x01(\tR\x05field\x12\x14\n" +
+	"\x05value\x18\x06 \x01(\tR\x05value\x121\n" +
+	"\x06window\x18\a \x01(\v2\x19.google.protobuf.DurationR\x06window\x125\n" +
+	"\bcooldown\x18\b \x01(\v2\x19.google.protobuf.DurationR\bcooldown\x12\x1f\n" +
+	"\vwebhook_url\x18\t \x01(\tR\n" +
+	"webhookUrl\x12%\n" +
+	"\x0ewebhook_secret\x18\n" +
+	" \x01(\tR\rwebhookSecret\x12\x18\n" +
+	"\aenabled\x18\v \x01(\bR\aenabled\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\">\n" +
+	"\x16CreateAlertRuleRequest\x12$\n" +
+	"\x04rule\x18\x01 \x01(\v2\x10.rates.AlertRuleR\x04rule\"%\n" +
+	"\x13GetAlertRuleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x17\n" +
+	"\x15ListAlertRulesRequest\"@\n" +
+	"\x16ListAlertRulesResponse\x12&\n" +
+	"\x05rules\x18\x01 \x03(\v2\x10.rates.AlertRuleR\x05rules\">\n" +
+	"\x16UpdateAlertRuleRequest\x12$\n" +
+	"\x04rule\x18\x01 \x01(\v2\x10.rates.AlertRuleR\x04rule\"(\n" +
+	"\x16DeleteAlertRuleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\xfd\x01\n" +
+	"\x12ExportRatesRequest\x12\x16\n" +
+	"\x06market\x18\x01 \x01(\tR\x06market\x12.\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12+\n" +
+	"\x06format\x18\x04 \x01(\x0e2\x13.rates.ExportFormatR\x06format\x12F\n" +
+	"\x11resample_interval\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x10resampleInterval\"&\n" +
+	"\x10ExportRatesChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"1\n" +
+	"\x15SubscribeRatesRequest\x12\x18\n" +
+	"\amarkets\x18\x01 \x03(\tR\amarkets\"\x14\n" +
 	"\x12HealthcheckRequest\"\x81\x01\n" +
 	"\x13HealthcheckResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x128\n" +
-	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp2\x91\x01\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp*\x8e\x01\n" +
+	"\vQuoteStatus\x12\x1c\n" +
+	"\x18QUOTE_STATUS_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12QUOTE_STATUS_VALID\x10\x01\x12\x18\n" +
+	"\x14QUOTE_STATUS_EXPIRED\x10\x02\x12\x15\n" +
+	"\x11QUOTE_STATUS_USED\x10\x03\x12\x18\n" +
+	"\x14QUOTE_STATUS_INVALID\x10\x04*x\n" +
+	"\fExportFormat\x12\x1d\n" +
+	"\x19EXPORT_FORMAT_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11EXPORT_FORMAT_CSV\x10\x01\x12\x17\n" +
+	"\x13EXPORT_FORMAT_JSONL\x10\x02\x12\x19\n" +
+	"\x15EXPORT_FORMAT_PARQUET\x10\x032\xa2\a\n" +
 	"\fRatesService\x12;\n" +
-	"\bGetRates\x12\x16.rates.GetRatesRequest\x1a\x17.rates.GetRatesResponse\x12D\n" +
+	"\bGetRates\x12\x16.rates.GetRatesRequest\x1a\x17.rates.GetRatesResponse\x12J\n" +
+	"\rBatchGetRates\x12\x1b.rates.BatchGetRatesRequest\x1a\x1c.rates.BatchGetRatesResponse\x12D\n" +
+	"\vListMarkets\x12\x19.rates.ListMarketsRequest\x1a\x1a.rates.ListMarketsResponse\x12H\n" +
+	"\x11CreateLockedQuote\x12\x1f.rates.CreateLockedQuoteRequest\x1a\x12.rates.LockedQuote\x12D\n" +
+	"\vRedeemQuote\x12\x19.rates.RedeemQuoteRequest\x1a\x1a.rates.RedeemQuoteResponse\x12B\n" +
+	"\x0fCreateAlertRule\x12\x1d.rates.CreateAlertRuleRequest\x1a\x10.rates.AlertRule\x12<\n" +
+	"\fGetAlertRule\x12\x1a.rates.GetAlertRuleRequest\x1a\x10.rates.AlertRule\x12M\n" +
+	"\x0eListAlertRules\x12\x1c.rates.ListAlertRulesRequest\x1a\x1d.rates.ListAlertRulesResponse\x12B\n" +
+	"\x0fUpdateAlertRule\x12\x1d.rates.UpdateAlertRuleRequest\x1a\x10.rates.AlertRule\x12H\n" +
+	"\x0fDeleteAlertRule\x12\x1d.rates.DeleteAlertRuleRequest\x1a\x16.google.protobuf.Empty\x12C\n" +
+	"\vExportRates\x12\x19.rates.ExportRatesRequest\x1a\x17.rates.ExportRatesChunk0\x01\x12I\n" +
+	"\x0eSubscribeRates\x12\x1c.rates.SubscribeRatesRequest\x1a\x17.rates.GetRatesResponse0\x01\x12D\n" +
 	"\vHealthcheck\x12\x19.rates.HealthcheckRequest\x1a\x1a.rates.HealthcheckResponseB\x0fZ\r./proto/ratesb\x06proto3"
 
 var (
@@ -275,26 +2027,99 @@ func file_proto_rates_rates_proto_rawDescGZIP() []byte {
 	return file_proto_rates_rates_proto_rawDescData
 }
 
-var file_proto_rates_rates_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_rates_rates_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_proto_rates_rates_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
 var file_proto_rates_rates_proto_goTypes = []any{
-	(*GetRatesRequest)(nil),       // 0: rates.GetRatesRequest
-	(*GetRatesResponse)(nil),      // 1: rates.GetRatesResponse
-	(*HealthcheckRequest)(nil),    // 2: rates.HealthcheckRequest
-	(*HealthcheckResponse)(nil),   // 3: rates.HealthcheckResponse
-	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+	(QuoteStatus)(0),                 // 0: rates.QuoteStatus
+	(ExportFormat)(0),                // 1: rates.ExportFormat
+	(*GetRatesRequest)(nil),          // 2: rates.GetRatesRequest
+	(*GetRatesResponse)(nil),         // 3: rates.GetRatesResponse
+	(*CrossRateLeg)(nil),             // 4: rates.CrossRateLeg
+	(*BatchGetRatesRequest)(nil),     // 5: rates.BatchGetRatesRequest
+	(*BatchGetRatesResponse)(nil),    // 6: rates.BatchGetRatesResponse
+	(*MarketRatesResult)(nil),        // 7: rates.MarketRatesResult
+	(*MarketError)(nil),              // 8: rates.MarketError
+	(*ListMarketsRequest)(nil),       // 9: rates.ListMarketsRequest
+	(*ListMarketsResponse)(nil),      // 10: rates.ListMarketsResponse
+	(*Market)(nil),                   // 11: rates.Market
+	(*CreateLockedQuoteRequest)(nil), // 12: rates.CreateLockedQuoteRequest
+	(*LockedQuote)(nil),              // 13: rates.LockedQuote
+	(*RedeemQuoteRequest)(nil),       // 14: rates.RedeemQuoteRequest
+	(*RedeemQuoteResponse)(nil),      // 15: rates.RedeemQuoteResponse
+	(*AlertRule)(nil),                // 16: rates.AlertRule
+	(*CreateAlertRuleRequest)(nil),   // 17: rates.CreateAlertRuleRequest
+	(*GetAlertRuleRequest)(nil),      // 18: rates.GetAlertRuleRequest
+	(*ListAlertRulesRequest)(nil),    // 19: rates.ListAlertRulesRequest
+	(*ListAlertRulesResponse)(nil),   // 20: rates.ListAlertRulesResponse
+	(*UpdateAlertRuleRequest)(nil),   // 21: rates.UpdateAlertRuleRequest
+	(*DeleteAlertRuleRequest)(nil),   // 22: rates.DeleteAlertRuleRequest
+	(*ExportRatesRequest)(nil),       // 23: rates.ExportRatesRequest
+	(*ExportRatesChunk)(nil),         // 24: rates.ExportRatesChunk
+	(*SubscribeRatesRequest)(nil),    // 25: rates.SubscribeRatesRequest
+	(*HealthcheckRequest)(nil),       // 26: rates.HealthcheckRequest
+	(*HealthcheckResponse)(nil),      // 27: rates.HealthcheckResponse
+	(*durationpb.Duration)(nil),      // 28: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),    // 29: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),            // 30: google.protobuf.Empty
 }
 var file_proto_rates_rates_proto_depIdxs = []int32{
-	4, // 0: rates.GetRatesResponse.timestamp:type_name -> google.protobuf.Timestamp
-	4, // 1: rates.HealthcheckResponse.timestamp:type_name -> google.protobuf.Timestamp
-	0, // 2: rates.RatesService.GetRates:input_type -> rates.GetRatesRequest
-	2, // 3: rates.RatesService.Healthcheck:input_type -> rates.HealthcheckRequest
-	1, // 4: rates.RatesService.GetRates:output_type -> rates.GetRatesResponse
-	3, // 5: rates.RatesService.Healthcheck:output_type -> rates.HealthcheckResponse
-	4, // [4:6] is the sub-list for method output_type
-	2, // [2:4] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	28, // 0: rates.GetRatesRequest.max_age:type_name -> google.protobuf.Duration
+	29, // 1: rates.GetRatesResponse.timestamp:type_name -> google.protobuf.Timestamp
+	4,  // 2: rates.GetRatesResponse.path:type_name -> rates.CrossRateLeg
+	29, // 3: rates.GetRatesResponse.exchange_timestamp:type_name -> google.protobuf.Timestamp
+	29, // 4: rates.GetRatesResponse.received_at:type_name -> google.protobuf.Timestamp
+	28, // 5: rates.GetRatesResponse.age:type_name -> google.protobuf.Duration
+	28, // 6: rates.BatchGetRatesRequest.max_age:type_name -> google.protobuf.Duration
+	7,  // 7: rates.BatchGetRatesResponse.results:type_name -> rates.MarketRatesResult
+	3,  // 8: rates.MarketRatesResult.rates:type_name -> rates.GetRatesResponse
+	8,  // 9: rates.MarketRatesResult.error:type_name -> rates.MarketError
+	11, // 10: rates.ListMarketsResponse.markets:type_name -> rates.Market
+	29, // 11: rates.LockedQuote.expires_at:type_name -> google.protobuf.Timestamp
+	0,  // 12: rates.RedeemQuoteResponse.status:type_name -> rates.QuoteStatus
+	13, // 13: rates.RedeemQuoteResponse.quote:type_name -> rates.LockedQuote
+	28, // 14: rates.AlertRule.window:type_name -> google.protobuf.Duration
+	28, // 15: rates.AlertRule.cooldown:type_name -> google.protobuf.Duration
+	29, // 16: rates.AlertRule.created_at:type_name -> google.protobuf.Timestamp
+	29, // 17: rates.AlertRule.updated_at:type_name -> google.protobuf.Timestamp
+	16, // 18: rates.CreateAlertRuleRequest.rule:type_name -> rates.AlertRule
+	16, // 19: rates.ListAlertRulesResponse.rules:type_name -> rates.AlertRule
+	16, // 20: rates.UpdateAlertRuleRequest.rule:type_name -> rates.AlertRule
+	29, // 21: rates.ExportRatesRequest.from:type_name -> google.protobuf.Timestamp
+	29, // 22: rates.ExportRatesRequest.to:type_name -> google.protobuf.Timestamp
+	1,  // 23: rates.ExportRatesRequest.format:type_name -> rates.ExportFormat
+	28, // 24: rates.ExportRatesRequest.resample_interval:type_name -> google.protobuf.Duration
+	29, // 25: rates.HealthcheckResponse.timestamp:type_name -> google.protobuf.Timestamp
+	2,  // 26: rates.RatesService.GetRates:input_type -> rates.GetRatesRequest
+	5,  // 27: rates.RatesService.BatchGetRates:input_type -> rates.BatchGetRatesRequest
+	9,  // 28: rates.RatesService.ListMarkets:input_type -> rates.ListMarketsRequest
+	12, // 29: rates.RatesService.CreateLockedQuote:input_type -> rates.CreateLockedQuoteRequest
+	14, // 30: rates.RatesService.RedeemQuote:input_type -> rates.RedeemQuoteRequest
+	17, // 31: rates.RatesService.CreateAlertRule:input_type -> rates.CreateAlertRuleRequest
+	18, // 32: rates.RatesService.GetAlertRule:input_type -> rates.GetAlertRuleRequest
+	19, // 33: rates.RatesService.ListAlertRules:input_type -> rates.ListAlertRulesRequest
+	21, // 34: rates.RatesService.UpdateAlertRule:input_type -> rates.UpdateAlertRuleRequest
+	22, // 35: rates.RatesService.DeleteAlertRule:input_type -> rates.DeleteAlertRuleRequest
+	23, // 36: rates.RatesService.ExportRates:input_type -> rates.ExportRatesRequest
+	25, // 37: rates.RatesService.SubscribeRates:input_type -> rates.SubscribeRatesRequest
+	26, // 38: rates.RatesService.Healthcheck:input_type -> rates.HealthcheckRequest
+	3,  // 39: rates.RatesService.GetRates:output_type -> rates.GetRatesResponse
+	6,  // 40: rates.RatesService.BatchGetRates:output_type -> rates.BatchGetRatesResponse
+	10, // 41: rates.RatesService.ListMarkets:output_type -> rates.ListMarketsResponse
+	13, // 42: rates.RatesService.CreateLockedQuote:output_type -> rates.LockedQuote
+	15, // 43: rates.RatesService.RedeemQuote:output_type -> rates.RedeemQuoteResponse
+	16, // 44: rates.RatesService.CreateAlertRule:output_type -> rates.AlertRule
+	16, // 45: rates.RatesService.GetAlertRule:output_type -> rates.AlertRule
+	20, // 46: rates.RatesService.ListAlertRules:output_type -> rates.ListAlertRulesResponse
+	16, // 47: rates.RatesService.UpdateAlertRule:output_type -> rates.AlertRule
+	30, // 48: rates.RatesService.DeleteAlertRule:output_type -> google.protobuf.Empty
+	24, // 49: rates.RatesService.ExportRates:output_type -> rates.ExportRatesChunk
+	3,  // 50: rates.RatesService.SubscribeRates:output_type -> rates.GetRatesResponse
+	27, // 51: rates.RatesService.Healthcheck:output_type -> rates.HealthcheckResponse
+	39, // [39:52] is the sub-list for method output_type
+	26, // [26:39] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_proto_rates_rates_proto_init() }
@@ -302,18 +2127,23 @@ func file_proto_rates_rates_proto_init() {
 	if File_proto_rates_rates_proto != nil {
 		return
 	}
+	file_proto_rates_rates_proto_msgTypes[5].OneofWrappers = []any{
+		(*MarketRatesResult_Rates)(nil),
+		(*MarketRatesResult_Error)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_rates_rates_proto_rawDesc), len(file_proto_rates_rates_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      2,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_rates_rates_proto_goTypes,
 		DependencyIndexes: file_proto_rates_rates_proto_depIdxs,
+		EnumInfos:         file_proto_rates_rates_proto_enumTypes,
 		MessageInfos:      file_proto_rates_rates_proto_msgTypes,
 	}.Build()
 	File_proto_rates_rates_proto = out.File
@@ -11,6 +11,7 @@ import (
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -19,8 +20,19 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	RatesService_GetRates_FullMethodName    = "/rates.RatesService/GetRates"
-	RatesService_Healthcheck_FullMethodName = "/rates.RatesService/Healthcheck"
+	RatesService_GetRates_FullMethodName          = "/rates.RatesService/GetRates"
+	RatesService_BatchGetRates_FullMethodName     = "/rates.RatesService/BatchGetRates"
+	RatesService_ListMarkets_FullMethodName       = "/rates.RatesService/ListMarkets"
+	RatesService_CreateLockedQuote_FullMethodName = "/rates.RatesService/CreateLockedQuote"
+	RatesService_RedeemQuote_FullMethodName       = "/rates.RatesService/RedeemQuote"
+	RatesService_CreateAlertRule_FullMethodName   = "/rates.RatesService/CreateAlertRule"
+	RatesService_GetAlertRule_FullMethodName      = "/rates.RatesService/GetAlertRule"
+	RatesService_ListAlertRules_FullMethodName    = "/rates.RatesService/ListAlertRules"
+	RatesService_UpdateAlertRule_FullMethodName   = "/rates.RatesService/UpdateAlertRule"
+	RatesService_DeleteAlertRule_FullMethodName   = "/rates.RatesService/DeleteAlertRule"
+	RatesService_ExportRates_FullMethodName       = "/rates.RatesService/ExportRates"
+	RatesService_SubscribeRates_FullMethodName    = "/rates.RatesService/SubscribeRates"
+	RatesService_Healthcheck_FullMethodName       = "/rates.RatesService/Healthcheck"
 )
 
 // RatesServiceClient is the client API for RatesService service.
@@ -31,6 +43,29 @@ const (
 type RatesServiceClient interface {
 	// GetRates retrieves current USDT exchange rates
 	GetRates(ctx context.Context, in *GetRatesRequest, opts ...grpc.CallOption) (*GetRatesResponse, error)
+	// BatchGetRates retrieves rates for several markets in one call
+	BatchGetRates(ctx context.Context, in *BatchGetRatesRequest, opts ...grpc.CallOption) (*BatchGetRatesResponse, error)
+	// ListMarkets lists markets available for rate requests
+	ListMarkets(ctx context.Context, in *ListMarketsRequest, opts ...grpc.CallOption) (*ListMarketsResponse, error)
+	// CreateLockedQuote locks the current customer price for a limited time
+	CreateLockedQuote(ctx context.Context, in *CreateLockedQuoteRequest, opts ...grpc.CallOption) (*LockedQuote, error)
+	// RedeemQuote verifies a locked quote and marks it as used
+	RedeemQuote(ctx context.Context, in *RedeemQuoteRequest, opts ...grpc.CallOption) (*RedeemQuoteResponse, error)
+	// CreateAlertRule creates a new alert rule
+	CreateAlertRule(ctx context.Context, in *CreateAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error)
+	// GetAlertRule retrieves an alert rule by id
+	GetAlertRule(ctx context.Context, in *GetAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error)
+	// ListAlertRules lists all alert rules
+	ListAlertRules(ctx context.Context, in *ListAlertRulesRequest, opts ...grpc.CallOption) (*ListAlertRulesResponse, error)
+	// UpdateAlertRule replaces an existing alert rule
+	UpdateAlertRule(ctx context.Context, in *UpdateAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error)
+	// DeleteAlertRule deletes an alert rule
+	DeleteAlertRule(ctx context.Context, in *DeleteAlertRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ExportRates streams the stored rates of a market as a CSV, JSONL or Parquet file
+	ExportRates(ctx context.Context, in *ExportRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportRatesChunk], error)
+	// SubscribeRates streams the latest exchange quote of the markets, fetched by
+	// this or another instance: the known quotes first, then every newer one
+	SubscribeRates(ctx context.Context, in *SubscribeRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetRatesResponse], error)
 	// Healthcheck checks service health status
 	Healthcheck(ctx context.Context, in *HealthcheckRequest, opts ...grpc.CallOption) (*HealthcheckResponse, error)
 }
@@ -53,6 +88,134 @@ func (c *ratesServiceClient) GetRates(ctx context.Context, in *GetRatesRequest,
 	return out, nil
 }
 
+func (c *ratesServiceClient) BatchGetRates(ctx context.Context, in *BatchGetRatesRequest, opts ...grpc.CallOption) (*BatchGetRatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetRatesResponse)
+	err := c.cc.Invoke(ctx, RatesService_BatchGetRates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) ListMarkets(ctx context.Context, in *ListMarketsRequest, opts ...grpc.CallOption) (*ListMarketsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMarketsResponse)
+	err := c.cc.Invoke(ctx, RatesService_ListMarkets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) CreateLockedQuote(ctx context.Context, in *CreateLockedQuoteRequest, opts ...grpc.CallOption) (*LockedQuote, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LockedQuote)
+	err := c.cc.Invoke(ctx, RatesService_CreateLockedQuote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) RedeemQuote(ctx context.Context, in *RedeemQuoteRequest, opts ...grpc.CallOption) (*RedeemQuoteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RedeemQuoteResponse)
+	err := c.cc.Invoke(ctx, RatesService_RedeemQuote_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) CreateAlertRule(ctx context.Context, in *CreateAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlertRule)
+	err := c.cc.Invoke(ctx, RatesService_CreateAlertRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) GetAlertRule(ctx context.Context, in *GetAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlertRule)
+	err := c.cc.Invoke(ctx, RatesService_GetAlertRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) ListAlertRules(ctx context.Context, in *ListAlertRulesRequest, opts ...grpc.CallOption) (*ListAlertRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAlertRulesResponse)
+	err := c.cc.Invoke(ctx, RatesService_ListAlertRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) UpdateAlertRule(ctx context.Context, in *UpdateAlertRuleRequest, opts ...grpc.CallOption) (*AlertRule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlertRule)
+	err := c.cc.Invoke(ctx, RatesService_UpdateAlertRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) DeleteAlertRule(ctx context.Context, in *DeleteAlertRuleRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, RatesService_DeleteAlertRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ratesServiceClient) ExportRates(ctx context.Context, in *ExportRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExportRatesChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RatesService_ServiceDesc.Streams[0], RatesService_ExportRates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportRatesRequest, ExportRatesChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RatesService_ExportRatesClient = grpc.ServerStreamingClient[ExportRatesChunk]
+
+func (c *ratesServiceClient) SubscribeRates(ctx context.Context, in *SubscribeRatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GetRatesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RatesService_ServiceDesc.Streams[1], RatesService_SubscribeRates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRatesRequest, GetRatesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RatesService_SubscribeRatesClient = grpc.ServerStreamingClient[GetRatesResponse]
+
 func (c *ratesServiceClient) Healthcheck(ctx context.Context, in *HealthcheckRequest, opts ...grpc.CallOption) (*HealthcheckResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(HealthcheckResponse)
@@ -71,6 +234,29 @@ func (c *ratesServiceClient) Healthcheck(ctx context.Context, in *HealthcheckReq
 type RatesServiceServer interface {
 	// GetRates retrieves current USDT exchange rates
 	GetRates(context.Context, *GetRatesRequest) (*GetRatesResponse, error)
+	// BatchGetRates retrieves rates for several markets in one call
+	BatchGetRates(context.Context, *BatchGetRatesRequest) (*BatchGetRatesResponse, error)
+	// ListMarkets lists markets available for rate requests
+	ListMarkets(context.Context, *ListMarketsRequest) (*ListMarketsResponse, error)
+	// CreateLockedQuote locks the current customer price for a limited time
+	CreateLockedQuote(context.Context, *CreateLockedQuoteRequest) (*LockedQuote, error)
+	// RedeemQuote verifies a locked quote and marks it as used
+	RedeemQuote(context.Context, *RedeemQuoteRequest) (*RedeemQuoteResponse, error)
+	// CreateAlertRule creates a new alert rule
+	CreateAlertRule(context.Context, *CreateAlertRuleRequest) (*AlertRule, error)
+	// GetAlertRule retrieves an alert rule by id
+	GetAlertRule(context.Context, *GetAlertRuleRequest) (*AlertRule, error)
+	// ListAlertRules lists all alert rules
+	ListAlertRules(context.Context, *ListAlertRulesRequest) (*ListAlertRulesResponse, error)
+	// UpdateAlertRule replaces an existing alert rule
+	UpdateAlertRule(context.Context, *UpdateAlertRuleRequest) (*AlertRule, error)
+	// DeleteAlertRule deletes an alert rule
+	DeleteAlertRule(context.Context, *DeleteAlertRuleRequest) (*emptypb.Empty, error)
+	// ExportRates streams the stored rates of a market as a CSV, JSONL or Parquet file
+	ExportRates(*ExportRatesRequest, grpc.ServerStreamingServer[ExportRatesChunk]) error
+	// SubscribeRates streams the latest exchange quote of the markets, fetched by
+	// this or another instance: the known quotes first, then every newer one
+	SubscribeRates(*SubscribeRatesRequest, grpc.ServerStreamingServer[GetRatesResponse]) error
 	// Healthcheck checks service health status
 	Healthcheck(context.Context, *HealthcheckRequest) (*HealthcheckResponse, error)
 	mustEmbedUnimplementedRatesServiceServer()
@@ -86,6 +272,39 @@ type UnimplementedRatesServiceServer struct{}
 func (UnimplementedRatesServiceServer) GetRates(context.Context, *GetRatesRequest) (*GetRatesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRates not implemented")
 }
+func (UnimplementedRatesServiceServer) BatchGetRates(context.Context, *BatchGetRatesRequest) (*BatchGetRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetRates not implemented")
+}
+func (UnimplementedRatesServiceServer) ListMarkets(context.Context, *ListMarketsRequest) (*ListMarketsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMarkets not implemented")
+}
+func (UnimplementedRatesServiceServer) CreateLockedQuote(context.Context, *CreateLockedQuoteRequest) (*LockedQuote, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateLockedQuote not implemented")
+}
+func (UnimplementedRatesServiceServer) RedeemQuote(context.Context, *RedeemQuoteRequest) (*RedeemQuoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemQuote not implemented")
+}
+func (UnimplementedRatesServiceServer) CreateAlertRule(context.Context, *CreateAlertRuleRequest) (*AlertRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAlertRule not implemented")
+}
+func (UnimplementedRatesServiceServer) GetAlertRule(context.Context, *GetAlertRuleRequest) (*AlertRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAlertRule not implemented")
+}
+func (UnimplementedRatesServiceServer) ListAlertRules(context.Context, *ListAlertRulesRequest) (*ListAlertRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlertRules not implemented")
+}
+func (UnimplementedRatesServiceServer) UpdateAlertRule(context.Context, *UpdateAlertRuleRequest) (*AlertRule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAlertRule not implemented")
+}
+func (UnimplementedRatesServiceServer) DeleteAlertRule(context.Context, *DeleteAlertRuleRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAlertRule not implemented")
+}
+func (UnimplementedRatesServiceServer) ExportRates(*ExportRatesRequest, grpc.ServerStreamingServer[ExportRatesChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportRates not implemented")
+}
+func (UnimplementedRatesServiceServer) SubscribeRates(*SubscribeRatesRequest, grpc.ServerStreamingServer[GetRatesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeRates not implemented")
+}
 func (UnimplementedRatesServiceServer) Healthcheck(context.Context, *HealthcheckRequest) (*HealthcheckResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Healthcheck not implemented")
 }
@@ -128,6 +347,190 @@ func _RatesService_GetRates_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RatesService_BatchGetRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).BatchGetRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_BatchGetRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).BatchGetRates(ctx, req.(*BatchGetRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_ListMarkets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMarketsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).ListMarkets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_ListMarkets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).ListMarkets(ctx, req.(*ListMarketsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_CreateLockedQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateLockedQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).CreateLockedQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_CreateLockedQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).CreateLockedQuote(ctx, req.(*CreateLockedQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_RedeemQuote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemQuoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).RedeemQuote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_RedeemQuote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).RedeemQuote(ctx, req.(*RedeemQuoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_CreateAlertRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAlertRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).CreateAlertRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_CreateAlertRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).CreateAlertRule(ctx, req.(*CreateAlertRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_GetAlertRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAlertRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).GetAlertRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_GetAlertRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).GetAlertRule(ctx, req.(*GetAlertRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_ListAlertRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAlertRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).ListAlertRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_ListAlertRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).ListAlertRules(ctx, req.(*ListAlertRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_UpdateAlertRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAlertRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).UpdateAlertRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_UpdateAlertRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).UpdateAlertRule(ctx, req.(*UpdateAlertRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_DeleteAlertRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAlertRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RatesServiceServer).DeleteAlertRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RatesService_DeleteAlertRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RatesServiceServer).DeleteAlertRule(ctx, req.(*DeleteAlertRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RatesService_ExportRates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RatesServiceServer).ExportRates(m, &grpc.GenericServerStream[ExportRatesRequest, ExportRatesChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RatesService_ExportRatesServer = grpc.ServerStreamingServer[ExportRatesChunk]
+
+func _RatesService_SubscribeRates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RatesServiceServer).SubscribeRates(m, &grpc.GenericServerStream[SubscribeRatesRequest, GetRatesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RatesService_SubscribeRatesServer = grpc.ServerStreamingServer[GetRatesResponse]
+
 func _RatesService_Healthcheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(HealthcheckRequest)
 	if err := dec(in); err != nil {
@@ -157,11 +560,58 @@ var RatesService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRates",
 			Handler:    _RatesService_GetRates_Handler,
 		},
+		{
+			MethodName: "BatchGetRates",
+			Handler:    _RatesService_BatchGetRates_Handler,
+		},
+		{
+			MethodName: "ListMarkets",
+			Handler:    _RatesService_ListMarkets_Handler,
+		},
+		{
+			MethodName: "CreateLockedQuote",
+			Handler:    _RatesService_CreateLockedQuote_Handler,
+		},
+		{
+			MethodName: "RedeemQuote",
+			Handler:    _RatesService_RedeemQuote_Handler,
+		},
+		{
+			MethodName: "CreateAlertRule",
+			Handler:    _RatesService_CreateAlertRule_Handler,
+		},
+		{
+			MethodName: "GetAlertRule",
+			Handler:    _RatesService_GetAlertRule_Handler,
+		},
+		{
+			MethodName: "ListAlertRules",
+			Handler:    _RatesService_ListAlertRules_Handler,
+		},
+		{
+			MethodName: "UpdateAlertRule",
+			Handler:    _RatesService_UpdateAlertRule_Handler,
+		},
+		{
+			MethodName: "DeleteAlertRule",
+			Handler:    _RatesService_DeleteAlertRule_Handler,
+		},
 		{
 			MethodName: "Healthcheck",
 			Handler:    _RatesService_Healthcheck_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ExportRates",
+			Handler:       _RatesService_ExportRates_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeRates",
+			Handler:       _RatesService_SubscribeRates_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "proto/rates/rates.proto",
 }
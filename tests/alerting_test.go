@@ -0,0 +1,375 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/alerting"
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingSink collects fired alerts
+type recordingSink struct {
+	mu     sync.Mutex
+	alerts []alerting.Alert
+}
+
+func (s *recordingSink) Enqueue(alert alerting.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alerts = append(s.alerts, alert)
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+// MockDeliveryLog is a mock implementation of DeliveryLog
+type MockDeliveryLog struct {
+	mock.Mock
+}
+
+func (m *MockDeliveryLog) CreateAlertDelivery(ctx context.Context, delivery *postgres.AlertDelivery) (bool, error) {
+	args := m.Called(ctx, delivery)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDeliveryLog) UpdateAlertDelivery(ctx context.Context, delivery *postgres.AlertDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func alertRule(id int64, ruleType, value string) postgres.AlertRule {
+	return postgres.AlertRule{
+		ID:         id,
+		Name:       ruleType,
+		Market:     "usdtrub",
+		Type:       ruleType,
+		Field:      alerting.FieldMid,
+		Value:      value,
+		WebhookURL: "http://localhost/hook",
+		Enabled:    true,
+	}
+}
+
+func TestEvaluator_ThresholdFiresOnceUntilCleared(t *testing.T) {
+	sink := &recordingSink{}
+	evaluator := alerting.NewEvaluator(sink, zap.NewNop())
+	evaluator.SetRules([]postgres.AlertRule{alertRule(1, alerting.RuleThresholdAbove, "100")})
+
+	now := time.Now()
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 99, Bid: 98, Timestamp: now})
+	assert.Equal(t, 0, sink.count())
+
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 102, Bid: 101, Timestamp: now.Add(time.Second)})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 103, Bid: 102, Timestamp: now.Add(2 * time.Second)})
+	assert.Equal(t, 1, sink.count())
+
+	// Clearing and crossing again fires a new alert
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 99, Bid: 98, Timestamp: now.Add(3 * time.Second)})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 102, Bid: 101, Timestamp: now.Add(4 * time.Second)})
+	assert.Equal(t, 2, sink.count())
+
+	// Other markets are not evaluated
+	evaluator.Observe(alerting.Sample{Market: "btcusdt", Ask: 200, Bid: 199, Timestamp: now})
+	assert.Equal(t, 2, sink.count())
+}
+
+func TestEvaluator_Cooldown(t *testing.T) {
+	sink := &recordingSink{}
+	evaluator := alerting.NewEvaluator(sink, zap.NewNop())
+	rule := alertRule(1, alerting.RuleThresholdBelow, "90")
+	rule.Cooldown = time.Minute
+	evaluator.SetRules([]postgres.AlertRule{rule})
+
+	now := time.Now()
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 95, Bid: 94, Timestamp: now.Add(time.Second)})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now.Add(2 * time.Second)})
+	assert.Equal(t, 1, sink.count())
+
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 95, Bid: 94, Timestamp: now.Add(2 * time.Minute)})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now.Add(3 * time.Minute)})
+	assert.Equal(t, 2, sink.count())
+}
+
+func TestEvaluator_ConditionHoldingThroughCooldownFires(t *testing.T) {
+	sink := &recordingSink{}
+	evaluator := alerting.NewEvaluator(sink, zap.NewNop())
+	rule := alertRule(1, alerting.RuleThresholdBelow, "90")
+	rule.Cooldown = time.Minute
+	evaluator.SetRules([]postgres.AlertRule{rule})
+
+	now := time.Now()
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 95, Bid: 94, Timestamp: now.Add(time.Second)})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now.Add(2 * time.Second)})
+	assert.Equal(t, 1, sink.count())
+
+	// Still below after the cooldown
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now.Add(61 * time.Second)})
+	assert.Equal(t, 2, sink.count())
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 89, Bid: 88, Timestamp: now.Add(3 * time.Minute)})
+	assert.Equal(t, 2, sink.count())
+}
+
+func TestEvaluator_PercentChangeWithinWindow(t *testing.T) {
+	sink := &recordingSink{}
+	evaluator := alerting.NewEvaluator(sink, zap.NewNop())
+	rule := alertRule(1, alerting.RulePercentChange, "5")
+	rule.Window = 10 * time.Minute
+	evaluator.SetRules([]postgres.AlertRule{rule})
+
+	now := time.Now()
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 100, Bid: 100, Timestamp: now})
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 103, Bid: 103, Timestamp: now.Add(5 * time.Minute)})
+	assert.Equal(t, 0, sink.count())
+
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 94, Bid: 94, Timestamp: now.Add(8 * time.Minute)})
+	require.Equal(t, 1, sink.count())
+	assert.InDelta(t, -6, sink.alerts[0].Observed, 0.0001)
+
+	// A slow drift spread over more than the window does not fire
+	sink2 := &recordingSink{}
+	slow := alerting.NewEvaluator(sink2, zap.NewNop())
+	slow.SetRules([]postgres.AlertRule{rule})
+	for i := 0; i < 10; i++ {
+		price := 100 + float64(i)
+		slow.Observe(alerting.Sample{Market: "usdtrub", Ask: price, Bid: price, Timestamp: now.Add(time.Duration(i) * 10 * time.Minute)})
+	}
+	assert.Equal(t, 0, sink2.count())
+}
+
+func TestEvaluator_SpreadAbove(t *testing.T) {
+	sink := &recordingSink{}
+	evaluator := alerting.NewEvaluator(sink, zap.NewNop())
+	rule := alertRule(7, alerting.RuleSpreadAbove, "0.5")
+	rule.Enabled = true
+	disabled := alertRule(8, alerting.RuleSpreadAbove, "0.1")
+	disabled.Enabled = false
+	evaluator.SetRules([]postgres.AlertRule{rule, disabled})
+
+	now := time.Now()
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 95.3, Bid: 95.0, Timestamp: now})
+	assert.Equal(t, 0, sink.count())
+
+	evaluator.Observe(alerting.Sample{Market: "usdtrub", Ask: 96, Bid: 95, Timestamp: now.Add(time.Second)})
+	require.Equal(t, 1, sink.count())
+	assert.Equal(t, int64(7), sink.alerts[0].RuleID)
+	assert.NotEmpty(t, sink.alerts[0].DedupKey)
+}
+
+func TestValidateAlertRule(t *testing.T) {
+	valid := alertRule(0, alerting.RuleThresholdAbove, "100")
+	valid.Field = ""
+	require.NoError(t, alerting.Validate(&valid))
+	assert.Equal(t, alerting.FieldMid, valid.Field)
+
+	tests := []struct {
+		name   string
+		modify func(rule *postgres.AlertRule)
+	}{
+		{"unknown type", func(r *postgres.AlertRule) { r.Type = "sideways" }},
+		{"bad value", func(r *postgres.AlertRule) { r.Value = "abc" }},
+		{"nan value", func(r *postgres.AlertRule) { r.Value = "NaN" }},
+		{"infinite value", func(r *postgres.AlertRule) { r.Value = "Inf" }},
+		{"unknown field", func(r *postgres.AlertRule) { r.Field = "last" }},
+		{"bad webhook", func(r *postgres.AlertRule) { r.WebhookURL = "ftp://example.com" }},
+		{"percent without window", func(r *postgres.AlertRule) { r.Type = alerting.RulePercentChange }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := alertRule(0, alerting.RuleThresholdAbove, "100")
+			tt.modify(&rule)
+			assert.ErrorIs(t, alerting.Validate(&rule), alerting.ErrInvalidRule)
+		})
+	}
+}
+
+func TestDispatcher_SignsAndRetries(t *testing.T) {
+	var attempts int32
+	received := make(chan *http.Request, 1)
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer server.Close()
+
+	deliveryLog := new(MockDeliveryLog)
+	deliveryLog.On("CreateAlertDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*postgres.AlertDelivery).ID = 42
+	}).Return(true, nil)
+	updated := make(chan *postgres.AlertDelivery, 1)
+	deliveryLog.On("UpdateAlertDelivery", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated <- args.Get(1).(*postgres.AlertDelivery)
+	}).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dispatcher := alerting.NewDispatcher(alerting.DispatcherConfig{
+		Secret:       "default-secret",
+		Timeout:      time.Second,
+		MaxAttempts:  5,
+		RetryBackoff: time.Millisecond,
+	}, deliveryLog, zap.NewNop())
+	dispatcher.Start(ctx)
+
+	dispatcher.Enqueue(alerting.Alert{RuleID: 1, Market: "usdtrub", DedupKey: "1:100", WebhookURL: server.URL})
+
+	var req *http.Request
+	select {
+	case req = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	delivery := <-updated
+	assert.Equal(t, alerting.DeliveryDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, "42", req.Header.Get(alerting.DeliveryHeader))
+
+	timestamp := req.Header.Get(alerting.TimestampHeader)
+	assert.Equal(t, alerting.Sign("default-secret", timestamp, body), req.Header.Get(alerting.SignatureHeader))
+}
+
+func TestDispatcher_SkipsDuplicates(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	deliveryLog := new(MockDeliveryLog)
+	recorded := make(chan struct{}, 1)
+	deliveryLog.On("CreateAlertDelivery", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		recorded <- struct{}{}
+	}).Return(false, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dispatcher := alerting.NewDispatcher(alerting.DispatcherConfig{MaxAttempts: 1}, deliveryLog, zap.NewNop())
+	dispatcher.Start(ctx)
+
+	dispatcher.Enqueue(alerting.Alert{RuleID: 1, DedupKey: "1:100", WebhookURL: server.URL})
+	<-recorded
+	cancel()
+	dispatcher.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	deliveryLog.AssertNotCalled(t, "UpdateAlertDelivery", mock.Anything, mock.Anything)
+}
+
+func TestGRPCHandler_AlertRulesDisabled(t *testing.T) {
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test")
+
+	_, err := handler.ListAlertRules(context.Background(), &pb.ListAlertRulesRequest{})
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.Unimplemented, st.Code())
+}
+
+func TestGRPCHandler_AlertRuleErrors(t *testing.T) {
+	store := new(MockAlertStore)
+	store.On("GetAlertRule", mock.Anything, int64(5)).Return(nil, nil)
+	store.On("CreateAlertRule", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	manager := alerting.NewManager(store, alerting.NewEvaluator(&recordingSink{}, zap.NewNop()), zap.NewNop())
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test", grpc.WithAlertService(manager))
+
+	_, err := handler.GetAlertRule(context.Background(), &pb.GetAlertRuleRequest{Id: 5})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = handler.CreateAlertRule(context.Background(), &pb.CreateAlertRuleRequest{
+		Rule: &pb.AlertRule{Name: "bad", Market: "usdtrub", Type: "sideways", Value: "1", WebhookUrl: "http://localhost"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = handler.CreateAlertRule(context.Background(), &pb.CreateAlertRuleRequest{
+		Rule: &pb.AlertRule{Name: "ok", Market: "usdtrub", Type: alerting.RuleThresholdAbove, Value: "1", WebhookUrl: "http://localhost"},
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestGRPCHandler_UpdateAlertRuleKeepsWebhookSecret(t *testing.T) {
+	db := newFakeDB("rule")
+	repo := postgres.NewRepository(db, zap.NewNop())
+	manager := alerting.NewManager(repo, alerting.NewEvaluator(&recordingSink{}, zap.NewNop()), zap.NewNop())
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test", grpc.WithAlertService(manager))
+	ctx := context.Background()
+
+	rule, err := handler.GetAlertRule(ctx, &pb.GetAlertRuleRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Empty(t, rule.WebhookSecret, "the secret is write-only")
+
+	rule.Value = "105"
+	_, err = handler.UpdateAlertRule(ctx, &pb.UpdateAlertRuleRequest{Rule: rule})
+	require.NoError(t, err)
+
+	var update fakeStatement
+	for _, statement := range db.statements {
+		if strings.Contains(statement.query, "UPDATE alert_rules") {
+			update = statement
+		}
+	}
+	require.Len(t, update.args, 11)
+	assert.Equal(t, "", update.args[9])
+	assert.Contains(t, update.query, "webhook_secret = COALESCE(NULLIF($10, ''), webhook_secret)",
+		"an empty secret keeps the stored one")
+}
+
+// MockAlertStore is a mock implementation of RuleStore
+type MockAlertStore struct {
+	mock.Mock
+}
+
+func (m *MockAlertStore) CreateAlertRule(ctx context.Context, rule *postgres.AlertRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertStore) UpdateAlertRule(ctx context.Context, rule *postgres.AlertRule) (bool, error) {
+	args := m.Called(ctx, rule)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAlertStore) DeleteAlertRule(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAlertStore) GetAlertRule(ctx context.Context, id int64) (*postgres.AlertRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*postgres.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertStore) ListAlertRules(ctx context.Context) ([]postgres.AlertRule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]postgres.AlertRule), args.Error(1)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMarketCatalog_Refresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/markets", r.URL.Path)
+		if err := json.NewEncoder(w).Encode([]client.MarketInfo{
+			{ID: "usdtrub", BaseUnit: "usdt", QuoteUnit: "rub", PricePrecision: 2, State: "enabled"},
+			{ID: "btcusdt", BaseUnit: "btc", QuoteUnit: "usdt", PricePrecision: 1, State: "disabled"},
+		}); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	logger := zap.NewNop()
+	c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, logger)
+	catalog := service.NewMarketCatalog(c, []service.Market{{ID: "ethusdt"}}, logger)
+
+	// Static markets are served until the first refresh
+	require.NoError(t, catalog.Validate("ethusdt"))
+
+	require.NoError(t, catalog.Refresh(context.Background()))
+
+	markets := catalog.List()
+	require.Len(t, markets, 2)
+	assert.Equal(t, "btcusdt", markets[0].ID)
+	assert.Equal(t, "usdtrub", markets[1].ID)
+	assert.Equal(t, service.MarketStatusActive, markets[1].Status)
+
+	assert.NoError(t, catalog.Validate("usdtrub"))
+	assert.True(t, errors.Is(catalog.Validate("btcusdt"), service.ErrMarketInactive))
+	assert.True(t, errors.Is(catalog.Validate("ethusdt"), service.ErrUnknownMarket))
+}
+
+func TestMarketCatalog_RefreshFailureKeepsMarkets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, zap.NewNop())
+	catalog := service.NewMarketCatalog(c, []service.Market{{ID: "usdtrub", Base: "usdt", Quote: "rub"}}, zap.New(core))
+
+	ctx, root := startTestTrace(t)
+	defer root.End()
+	assert.Error(t, catalog.Refresh(ctx))
+	assert.NoError(t, catalog.Validate("usdtrub"))
+
+	// The failure is logged with the trace of the refresh
+	failures := logs.FilterMessage("Failed to refresh market catalog").All()
+	require.Len(t, failures, 1)
+	assert.Equal(t, root.SpanContext().TraceID().String(), failures[0].ContextMap()["trace_id"])
+}
+
+func TestRatesService_GetRates_UnknownMarket(t *testing.T) {
+	// Setup mocks
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+
+	// Create service
+	logger := zap.NewNop()
+	catalog := service.NewMarketCatalog(nil, []service.Market{{ID: "usdtrub"}}, logger)
+	s := service.NewRatesService(mockGrinex, mockRepo, logger, service.WithMarketCatalog(catalog))
+
+	// Execute
+	rateData, err := s.GetRates(context.Background(), "usdtrub&market=btcusdt")
+
+	// Assert: the exchange is never called for unknown markets
+	assert.True(t, errors.Is(err, service.ErrUnknownMarket))
+	assert.Nil(t, rateData)
+	mockGrinex.AssertNotCalled(t, "GetRates")
+}
@@ -102,6 +102,23 @@ func TestGrinexClient_GetRates(t *testing.T) {
 	}
 }
 
+func TestGrinexClient_GetRates_EscapesMarket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "usdt&rub", r.URL.Query().Get("market"))
+		assert.Len(t, r.URL.Query(), 1)
+		if err := json.NewEncoder(w).Encode(client.DepthResponse{}); err != nil {
+			t.Errorf("Failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	logger := zap.NewNop()
+	c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, logger)
+
+	_, err := c.GetRates(context.Background(), "usdt&rub")
+	require.NoError(t, err)
+}
+
 func TestGrinexClient_GetRates_Timeout(t *testing.T) {
 	// Create server with delay
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -154,3 +171,20 @@ func TestGrinexClient_GetRates_ContextCanceled(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, rateData)
 }
+
+func TestGrinexClient_SetTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_ = json.NewEncoder(w).Encode(client.DepthResponse{})
+	}))
+	defer server.Close()
+
+	c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, zap.NewNop())
+	_, err := c.GetRates(context.Background(), "usdtrub")
+	require.NoError(t, err)
+
+	c.SetTimeout(50 * time.Millisecond)
+	_, err = c.GetRates(context.Background(), "usdtrub")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deadline exceeded")
+}
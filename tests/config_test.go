@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alik/TestForWork/internal/config"
+)
+
+// validConfig mirrors the flag defaults
+func validConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Port:              8080,
+			GracefulTimeout:   30 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			MaxConnectionIdle: 2 * time.Minute,
+		},
+		Database: config.DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "postgres",
+			Database:        "usdt_rates",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Grinex: config.GrinexConfig{
+			BaseURL:          "https://grinex.io",
+			Timeout:          10 * time.Second,
+			Market:           "usdtrub",
+			BatchConcurrency: 4,
+			Transport:        "http",
+		},
+		Catalog: config.CatalogConfig{
+			Source:          "exchange",
+			RefreshInterval: 10 * time.Minute,
+			Markets:         []config.MarketConfig{{ID: "usdtrub", Base: "usdt", Quote: "rub", PricePrecision: 2}},
+		},
+		CrossRates: config.CrossRatesConfig{Enabled: true, MaxHops: 2},
+		Logging:    config.LoggingConfig{Level: "info", Format: "json"},
+		Metrics:    config.MetricsConfig{Enabled: true, Path: "/metrics", Port: 9090},
+	}
+}
+
+func TestConfigValidate_Defaults(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+}
+
+func TestConfigValidate_ReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Port = -1
+	cfg.Server.ReadTimeout = 0
+	cfg.Logging.Format = "xml"
+	cfg.Grinex.Market = ""
+	cfg.Grinex.BaseURL = "grinex.io"
+	cfg.Database.SSLMode = "on"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	var invalid *config.ValidationError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []string{
+		"server.port: must be between 1 and 65535, got -1",
+		"server.read_timeout: must be positive, got 0s",
+		`database.ssl_mode: must be one of disable, allow, prefer, require, verify-ca, verify-full, got "on"`,
+		`grinex.base_url: must be an absolute URL, got "grinex.io"`,
+		"grinex.market: is required",
+		`logging.format: must be one of json, console, got "xml"`,
+	}, invalid.Problems)
+}
+
+func TestConfigValidate_CrossField(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*config.Config)
+		problem string
+	}{
+		{
+			name:    "admin without token",
+			modify:  func(c *config.Config) { c.Admin.Enabled = true },
+			problem: "admin.token: is required",
+		},
+		{
+			name: "admin without metrics",
+			modify: func(c *config.Config) {
+				c.Admin = config.AdminConfig{Enabled: true, Token: "secret"}
+				c.Metrics.Enabled = false
+			},
+			problem: "admin.enabled: requires metrics.enabled, the admin endpoints are served by the metrics server",
+		},
+		{
+			name:    "metrics on the server port",
+			modify:  func(c *config.Config) { c.Metrics.Port = 8080 },
+			problem: "metrics.port: must differ from server.port (8080)",
+		},
+		{
+			name:    "upstream timeout beyond the request deadline",
+			modify:  func(c *config.Config) { c.Grinex.Timeout = 20 * time.Second },
+			problem: "grinex.timeout: must not exceed server.write_timeout (10s), got 20s",
+		},
+		{
+			name:    "min connections beyond open connections",
+			modify:  func(c *config.Config) { c.Database.MinConns = 50 },
+			problem: "database.min_conns: must not exceed database.max_open_conns (25), got 50",
+		},
+		{
+			name: "quotes without signing key",
+			modify: func(c *config.Config) {
+				c.Quotes = config.QuotesConfig{Enabled: true, TTL: time.Second, CleanupInterval: time.Minute}
+			},
+			problem: "quotes.signing_key: is required",
+		},
+		{
+			name: "websocket transport URL",
+			modify: func(c *config.Config) {
+				c.Grinex.Transport = "websocket"
+				c.Grinex.WSURL = "https://grinex.io"
+				c.Grinex.SnapshotTimeout = time.Second
+			},
+			problem: `grinex.ws_url scheme: must be one of ws, wss, got "https"`,
+		},
+		{
+			name: "tracing sample ratio",
+			modify: func(c *config.Config) {
+				c.Tracing = config.TracingConfig{Enabled: true, Exporter: "none", ServiceName: "rates", SampleRatio: 1.5}
+			},
+			problem: "tracing.sample_ratio: must be between 0 and 1, got 1.5",
+		},
+		{
+			name: "pricing rule bounds",
+			modify: func(c *config.Config) {
+				c.Pricing = config.PricingConfig{Enabled: true, Source: "config", Rules: []config.PricingRuleConfig{
+					{ID: "vip", MinPrice: "100", MaxPrice: "90"},
+				}}
+			},
+			problem: "pricing.rules[0].min_price: must not exceed max_price",
+		},
+		{
+			name: "duplicate replica",
+			modify: func(c *config.Config) {
+				c.Database.Replicas = []string{"replica-1:5433", "replica-1:5433"}
+				c.Database.ReplicaMaxLag = 10 * time.Second
+				c.Database.ReplicaCheckInterval = 5 * time.Second
+			},
+			problem: `database.replicas[1]: duplicate replica "replica-1:5433"`,
+		},
+		{
+			name: "replica port",
+			modify: func(c *config.Config) {
+				c.Database.Replicas = []string{"replica-1:99999"}
+				c.Database.ReplicaMaxLag = 10 * time.Second
+				c.Database.ReplicaCheckInterval = 5 * time.Second
+			},
+			problem: "database.replicas[0] port: must be between 1 and 65535, got 99999",
+		},
+		{
+			name: "replica lag",
+			modify: func(c *config.Config) {
+				c.Database.Replicas = []string{"replica-1"}
+				c.Database.ReplicaCheckInterval = 5 * time.Second
+			},
+			problem: "database.replica_max_lag: must be positive, got 0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			var invalid *config.ValidationError
+			require.True(t, errors.As(cfg.Validate(), &invalid))
+			assert.Equal(t, []string{tt.problem}, invalid.Problems)
+		})
+	}
+}
+
+func TestDatabaseDSN(t *testing.T) {
+	cfg := validConfig().Database
+	cfg.SSLMode = "verify-full"
+	cfg.Password = `it's a \secret`
+
+	assert.Equal(t,
+		`host=localhost port=5432 user=postgres password='it\'s a \\secret' dbname=usdt_rates sslmode=verify-full connect_timeout=10`,
+		cfg.DatabaseDSN())
+}
+
+func TestReplicaDSN(t *testing.T) {
+	cfg := validConfig().Database
+
+	assert.Equal(t,
+		"host=replica-1 port=5433 user=postgres password=postgres dbname=usdt_rates sslmode=disable connect_timeout=10",
+		cfg.ReplicaDSN("replica-1:5433"))
+	assert.Equal(t,
+		"host=replica-2 port=5432 user=postgres password=postgres dbname=usdt_rates sslmode=disable connect_timeout=10",
+		cfg.ReplicaDSN("replica-2"))
+}
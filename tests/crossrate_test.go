@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newCrossRateCatalog() *service.MarketCatalog {
+	return service.NewMarketCatalog(nil, []service.Market{
+		{ID: "usdtrub", Base: "usdt", Quote: "rub"},
+		{ID: "usdtkzt", Base: "usdt", Quote: "kzt"},
+		{ID: "btcusdt", Base: "btc", Quote: "usdt"},
+		{ID: "eurbtc", Base: "eur", Quote: "btc"},
+	}, zap.NewNop())
+}
+
+func TestCrossRateEngine_FindPath(t *testing.T) {
+	engine := service.NewCrossRateEngine(newCrossRateCatalog(), 2)
+
+	tests := []struct {
+		name        string
+		market      string
+		expected    []client.RateLeg
+		expectError bool
+	}{
+		{
+			name:   "inverted direct market",
+			market: "rubusdt",
+			expected: []client.RateLeg{
+				{Market: "usdtrub", From: "rub", To: "usdt", Inverted: true},
+			},
+		},
+		{
+			name:   "one intermediate currency",
+			market: "rub/kzt",
+			expected: []client.RateLeg{
+				{Market: "usdtrub", From: "rub", To: "usdt", Inverted: true},
+				{Market: "usdtkzt", From: "usdt", To: "kzt"},
+			},
+		},
+		{
+			name:   "two intermediate currencies",
+			market: "rubeur",
+			expected: []client.RateLeg{
+				{Market: "usdtrub", From: "rub", To: "usdt", Inverted: true},
+				{Market: "btcusdt", From: "usdt", To: "btc", Inverted: true},
+				{Market: "eurbtc", From: "btc", To: "eur", Inverted: true},
+			},
+		},
+		{
+			name:        "unknown currency",
+			market:      "rubgbp",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := engine.FindPath(tt.market)
+			if tt.expectError {
+				assert.True(t, errors.Is(err, service.ErrUnknownMarket))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+}
+
+func TestCrossRateEngine_FindPath_MaxHops(t *testing.T) {
+	engine := service.NewCrossRateEngine(newCrossRateCatalog(), 1)
+
+	_, err := engine.FindPath("rubeur")
+	assert.True(t, errors.Is(err, service.ErrUnknownMarket))
+}
+
+func TestDerive(t *testing.T) {
+	older := time.Now().Add(-time.Minute)
+	path := []client.RateLeg{
+		{Market: "usdtrub", From: "rub", To: "usdt", Inverted: true},
+		{Market: "usdtkzt", From: "usdt", To: "kzt"},
+	}
+	legRates := []*client.RateData{
+		{Market: "usdtrub", Ask: "100", Bid: "99", Timestamp: time.Now()},
+		{Market: "usdtkzt", Ask: "500", Bid: "495", Timestamp: older},
+	}
+
+	rateData, err := service.Derive("rubkzt", path, legRates)
+	require.NoError(t, err)
+
+	// RUB->USDT uses the inverted usdtrub book: ask 1/99, bid 1/100
+	assert.Equal(t, "5.05050505", rateData.Ask)
+	assert.Equal(t, "4.95000000", rateData.Bid)
+	assert.True(t, rateData.Derived)
+	assert.Equal(t, path, rateData.Path)
+	assert.Equal(t, older, rateData.Timestamp)
+
+	// Legs without liquidity cannot be derived
+	legRates[1].Ask = "N/A"
+	_, err = service.Derive("rubkzt", path, legRates)
+	assert.Error(t, err)
+}
+
+func TestRatesService_GetRates_CrossRate(t *testing.T) {
+	// Setup mocks
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+	mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{
+		Market: "usdtrub", Ask: "100", Bid: "99", Timestamp: time.Now(),
+	}, nil)
+	mockGrinex.On("GetRates", mock.Anything, "usdtkzt").Return(&client.RateData{
+		Market: "usdtkzt", Ask: "500", Bid: "495", Timestamp: time.Now(),
+	}, nil)
+	mockRepo.On("SaveRate", mock.Anything, "usdtrub", "100", "99", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("SaveRate", mock.Anything, "usdtkzt", "500", "495", mock.Anything, mock.Anything).Return(nil)
+
+	// Create service
+	logger := zap.NewNop()
+	catalog := newCrossRateCatalog()
+	s := service.NewRatesService(mockGrinex, mockRepo, logger,
+		service.WithMarketCatalog(catalog),
+		service.WithCrossRates(service.NewCrossRateEngine(catalog, 2)),
+	)
+
+	// Execute
+	rateData, err := s.GetRates(context.Background(), "rubkzt")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "rubkzt", rateData.Market)
+	assert.True(t, rateData.Derived)
+	assert.Len(t, rateData.Path, 2)
+
+	// A listed market given with a separator is not derived
+	for _, market := range []string{"usdt/rub", "USDT_RUB"} {
+		rateData, err = s.GetRates(context.Background(), market)
+		require.NoError(t, err)
+		assert.Equal(t, "usdtrub", rateData.Market, market)
+		assert.False(t, rateData.Derived, market)
+		assert.Empty(t, rateData.Path, market)
+	}
+	assert.Equal(t, "usdtrub", catalog.Resolve("usdt-rub"))
+	assert.Equal(t, "rub/kzt", catalog.Resolve("rub/kzt"))
+
+	// Verify mock expectations
+	mockGrinex.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/export"
+	"github.com/alik/TestForWork/internal/importer"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeExportStore streams stored rates ordered by timestamp
+type fakeExportStore struct {
+	rates []postgres.ExportedRate
+}
+
+func (s *fakeExportStore) StreamRates(_ context.Context, market string, from, to time.Time, fn func(postgres.ExportedRate) error) error {
+	for _, rate := range s.rates {
+		if rate.Market != market || (!from.IsZero() && rate.Timestamp.Before(from)) || (!to.IsZero() && !rate.Timestamp.Before(to)) {
+			continue
+		}
+		if err := fn(rate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var exportStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func exportRate(offset time.Duration, ask, bid string) postgres.ExportedRate {
+	return postgres.ExportedRate{
+		Market:     "usdtrub",
+		Ask:        ask,
+		Bid:        bid,
+		Timestamp:  exportStart.Add(offset),
+		ReceivedAt: exportStart.Add(offset + 150*time.Millisecond),
+		Source:     "grinex",
+	}
+}
+
+func newExportStore() *fakeExportStore {
+	return &fakeExportStore{rates: []postgres.ExportedRate{
+		exportRate(10*time.Second, "95.50000000", "95.30000000"),
+		exportRate(50*time.Second, "95.60000000", "95.40000000"),
+		{Market: "btcusdt", Ask: "65000.1", Bid: "64999.9", Timestamp: exportStart.Add(time.Minute), ReceivedAt: exportStart.Add(time.Minute), Source: "grinex"},
+		exportRate(150*time.Second, "95.70000000", "95.50000000"),
+	}}
+}
+
+func TestExporter_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	rows, err := export.New(newExportStore(), zap.NewNop()).Export(context.Background(), &buf, export.Request{
+		Market: "usdtrub",
+		From:   exportStart,
+		To:     exportStart.Add(2 * time.Minute),
+		Format: export.FormatCSV,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, "market,ask,bid,timestamp,received_at,source\n"+
+		"usdtrub,95.50000000,95.30000000,2026-01-01T00:00:10Z,2026-01-01T00:00:10.15Z,grinex\n"+
+		"usdtrub,95.60000000,95.40000000,2026-01-01T00:00:50Z,2026-01-01T00:00:50.15Z,grinex\n", buf.String())
+
+	// The export can be imported back
+	r, err := importer.NewCSVReader(&buf)
+	require.NoError(t, err)
+	records, rowErrors := readAll(t, r)
+	require.Empty(t, rowErrors)
+	require.Len(t, records, 2)
+	rate, err := importer.Validate(records[0], "", time.Now())
+	require.NoError(t, err)
+	assert.True(t, rate.Timestamp.Equal(exportStart.Add(10*time.Second)))
+	assert.Equal(t, "grinex", rate.Source)
+}
+
+func TestExporter_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	rows, err := export.New(newExportStore(), zap.NewNop()).Export(context.Background(), &buf, export.Request{
+		Market: "usdtrub",
+		Format: export.FormatJSONL,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{"market":"usdtrub","ask":"95.70000000","bid":"95.50000000",
+		"timestamp":"2026-01-01T00:02:30Z","received_at":"2026-01-01T00:02:30.15Z","source":"grinex"}`, lines[2])
+}
+
+// parquetExportRow matches the columns of a Parquet export
+type parquetExportRow struct {
+	Market     string    `parquet:"market"`
+	Ask        string    `parquet:"ask"`
+	Bid        string    `parquet:"bid"`
+	Timestamp  time.Time `parquet:"timestamp,timestamp(microsecond)"`
+	ReceivedAt time.Time `parquet:"received_at,timestamp(microsecond)"`
+	Source     string    `parquet:"source"`
+}
+
+func TestExporter_Parquet(t *testing.T) {
+	var buf bytes.Buffer
+	rows, err := export.New(newExportStore(), zap.NewNop()).Export(context.Background(), &buf, export.Request{
+		Market: "usdtrub",
+		Format: export.FormatParquet,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rows)
+
+	reader := parquet.NewGenericReader[parquetExportRow](bytes.NewReader(buf.Bytes()))
+	defer reader.Close()
+	require.Equal(t, int64(3), reader.NumRows())
+
+	got := make([]parquetExportRow, 3)
+	n, err := reader.Read(got)
+	require.Equal(t, 3, n)
+	if err != nil {
+		assert.ErrorContains(t, err, "EOF")
+	}
+	assert.Equal(t, "95.50000000", got[0].Ask)
+	assert.Equal(t, "95.30000000", got[0].Bid)
+	assert.True(t, got[1].Timestamp.Equal(exportStart.Add(50*time.Second)))
+	assert.True(t, got[2].ReceivedAt.Equal(exportStart.Add(150*time.Second+150*time.Millisecond)))
+	assert.Equal(t, "grinex", got[2].Source)
+}
+
+func TestExporter_Resample(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  time.Time
+		times []string
+		asks  []string
+	}{
+		{
+			name:  "range start",
+			from:  exportStart,
+			times: []string{"00:01:00", "00:02:00", "00:03:00"},
+			asks:  []string{"95.60000000", "95.60000000", "95.70000000"},
+		},
+		{
+			name:  "open start aligns to the first rate",
+			times: []string{"00:01:00", "00:02:00", "00:03:00"},
+			asks:  []string{"95.60000000", "95.60000000", "95.70000000"},
+		},
+		{
+			name:  "unaligned start",
+			from:  exportStart.Add(-time.Hour + 30*time.Second),
+			times: []string{"00:00:30", "00:01:30", "00:02:30", "00:03:30"},
+			asks:  []string{"95.50000000", "95.60000000", "95.70000000", "95.70000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			rows, err := export.New(newExportStore(), zap.NewNop()).Export(context.Background(), &buf, export.Request{
+				Market:   "usdtrub",
+				From:     tt.from,
+				To:       exportStart.Add(4 * time.Minute),
+				Format:   export.FormatJSONL,
+				Resample: time.Minute,
+			})
+			require.NoError(t, err)
+
+			var times, asks []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var row struct{ Timestamp, Ask string }
+				require.NoError(t, json.Unmarshal([]byte(line), &row))
+				times = append(times, strings.TrimSuffix(strings.TrimPrefix(row.Timestamp, "2026-01-01T"), "Z"))
+				asks = append(asks, row.Ask)
+			}
+			assert.Equal(t, int64(len(tt.times)), rows)
+			assert.Equal(t, tt.times, times)
+			assert.Equal(t, tt.asks, asks)
+		})
+	}
+}
+
+func TestExporter_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  export.Request
+		err  string
+	}{
+		{"missing market", export.Request{Format: export.FormatCSV}, "market is required"},
+		{"empty range", export.Request{Market: "usdtrub", Format: export.FormatCSV, From: exportStart, To: exportStart}, "must be after"},
+		{"unknown format", export.Request{Market: "usdtrub", Format: "xlsx"}, "unknown format"},
+		{"short interval", export.Request{Market: "usdtrub", Format: export.FormatCSV, Resample: time.Millisecond}, "at least 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := export.New(newExportStore(), zap.NewNop()).Export(context.Background(), &bytes.Buffer{}, tt.req)
+			assert.ErrorIs(t, err, export.ErrInvalidRequest)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestExport_DetectFormat(t *testing.T) {
+	for path, format := range map[string]string{
+		"rates.csv":        export.FormatCSV,
+		"rates.JSONL":      export.FormatJSONL,
+		"rates.ndjson":     export.FormatJSONL,
+		"out/2026.parquet": export.FormatParquet,
+	} {
+		got, err := export.DetectFormat(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, format, got, path)
+	}
+
+	_, err := export.DetectFormat("rates.xlsx")
+	assert.Error(t, err)
+}
+
+// exportStream collects the chunks sent by ExportRates
+type exportStream struct {
+	grpclib.ServerStream
+	ctx    context.Context
+	chunks [][]byte
+}
+
+func (s *exportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *exportStream) Send(chunk *pb.ExportRatesChunk) error {
+	s.chunks = append(s.chunks, chunk.Data)
+	return nil
+}
+
+func TestRatesHandler_ExportRates(t *testing.T) {
+	store := &fakeExportStore{}
+	for i := 0; i < 5000; i++ {
+		store.rates = append(store.rates, exportRate(time.Duration(i)*time.Second, fmt.Sprintf("95.%04d", i), "95.0000"))
+	}
+	exporter := export.New(store, zap.NewNop())
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test", grpc.WithExporter(exporter))
+
+	stream := &exportStream{ctx: context.Background()}
+	err := handler.ExportRates(&pb.ExportRatesRequest{
+		Market: "usdtrub",
+		From:   timestamppb.New(exportStart),
+		To:     timestamppb.New(exportStart.Add(time.Hour)),
+		Format: pb.ExportFormat_EXPORT_FORMAT_UNSPECIFIED,
+	}, stream)
+	require.NoError(t, err)
+
+	require.Greater(t, len(stream.chunks), 1)
+	for _, chunk := range stream.chunks {
+		assert.LessOrEqual(t, len(chunk), 64*1024)
+	}
+
+	var want bytes.Buffer
+	_, err = exporter.Export(context.Background(), &want, export.Request{
+		Market: "usdtrub",
+		From:   exportStart,
+		To:     exportStart.Add(time.Hour),
+		Format: export.FormatCSV,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want.Bytes(), bytes.Join(stream.chunks, nil))
+}
+
+func TestRatesHandler_ExportRatesErrors(t *testing.T) {
+	stream := &exportStream{ctx: context.Background()}
+
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test")
+	err := handler.ExportRates(&pb.ExportRatesRequest{Market: "usdtrub"}, stream)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	handler = grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "test",
+		grpc.WithExporter(export.New(newExportStore(), zap.NewNop())))
+
+	err = handler.ExportRates(&pb.ExportRatesRequest{}, stream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = handler.ExportRates(&pb.ExportRatesRequest{
+		Market:           "usdtrub",
+		ResampleInterval: durationpb.New(-time.Second),
+	}, stream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	assert.Empty(t, stream.chunks)
+}
@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB is a database for repository tests that answers the replica lag
+// query, selects of rates, alert rule and outbox statements, and records
+// every statement. Rates read from it carry its name as the market, so tests
+// can tell which database answered a query. Transactions record BEGIN,
+// COMMIT and ROLLBACK as statements.
+type fakeDB struct {
+	name         string
+	lag          atomic.Int64 // seconds
+	disconnected atomic.Bool  // the replica does not stream from the primary
+	down         atomic.Bool
+
+	mu          sync.Mutex
+	deadlines   []time.Duration // time left until the deadline of each query, -1 for none
+	statements  []fakeStatement
+	leaseHolder string
+	outbox      [][]any
+}
+
+// fakeStatement is a statement executed on a fakeDB
+type fakeStatement struct {
+	query string
+	args  []any
+}
+
+func newFakeDB(name string) *fakeDB {
+	return &fakeDB{name: name}
+}
+
+// lastDeadline returns the time that was left for the last query
+func (db *fakeDB) lastDeadline() time.Duration {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.deadlines) == 0 {
+		return 0
+	}
+	return db.deadlines[len(db.deadlines)-1]
+}
+
+// queries returns the executed statements
+func (db *fakeDB) queries() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	queries := make([]string, 0, len(db.statements))
+	for _, statement := range db.statements {
+		queries = append(queries, statement.query)
+	}
+	return queries
+}
+
+func (db *fakeDB) record(query string, args ...any) {
+	db.mu.Lock()
+	db.statements = append(db.statements, fakeStatement{query: query, args: args})
+	db.mu.Unlock()
+}
+
+// lastStatement returns the last executed statement
+func (db *fakeDB) lastStatement() fakeStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.statements) == 0 {
+		return fakeStatement{}
+	}
+	return db.statements[len(db.statements)-1]
+}
+
+func (db *fakeDB) Exec(_ context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	if db.down.Load() {
+		return pgconn.CommandTag{}, errors.New("connection refused")
+	}
+	db.record(query, args...)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (db *fakeDB) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	if db.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+
+	left := time.Duration(-1)
+	if deadline, ok := ctx.Deadline(); ok {
+		left = time.Until(deadline)
+	}
+	db.mu.Lock()
+	db.deadlines = append(db.deadlines, left)
+	db.statements = append(db.statements, fakeStatement{query: query, args: args})
+	db.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case strings.Contains(query, "outbox_lease"):
+		// The first holder keeps the lease
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		if db.leaseHolder == "" {
+			db.leaseHolder = args[0].(string)
+		}
+		if db.leaseHolder != args[0] {
+			return &fakeRows{}, nil
+		}
+		return &fakeRows{values: [][]any{{db.leaseHolder}}}, nil
+	case strings.Contains(query, "FROM outbox"):
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		return &fakeRows{values: db.outbox}, nil
+	case strings.Contains(query, "pg_is_in_recovery"):
+		return &fakeRows{values: [][]any{{float64(db.lag.Load()), !db.disconnected.Load()}}}, nil
+	case strings.Contains(query, "UPDATE alert_rules"):
+		return &fakeRows{values: [][]any{{now, now}}}, nil
+	case strings.Contains(query, "FROM alert_rules"):
+		return &fakeRows{values: [][]any{{int64(1), db.name, "usdtrub", "threshold_above", "ask", "100",
+			int64(0), int64(60), "http://localhost/hook", "stored-secret", true, now, now}}}, nil
+	}
+	return &fakeRows{values: [][]any{{int64(1), db.name, "100.5", "99.5", now, now, now}}}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	rows, err := db.Query(ctx, query, args...)
+	return &fakeRow{rows: rows, err: err}
+}
+
+func (db *fakeDB) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	if db.down.Load() {
+		return nil, errors.New("connection refused")
+	}
+	db.record("BEGIN")
+	return &fakeTx{db: db}, nil
+}
+
+func (db *fakeDB) Ping(context.Context) error {
+	if db.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (db *fakeDB) Close() {}
+
+// fakeTx runs the statements of a transaction on its fakeDB
+type fakeTx struct {
+	pgx.Tx
+	db   *fakeDB
+	done bool
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, query, args...)
+}
+
+func (tx *fakeTx) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	return tx.db.Query(ctx, query, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	return tx.db.QueryRow(ctx, query, args...)
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.db.record("COMMIT")
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.done {
+		return pgx.ErrTxClosed
+	}
+	tx.done = true
+	tx.db.record("ROLLBACK")
+	return nil
+}
+
+type fakeRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+func (r *fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		return pgx.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+// fakeRows implements pgx.Rows over fixed values
+type fakeRows struct {
+	values  [][]any
+	current []any
+}
+
+func (r *fakeRows) Close() {}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) CommandTag() pgconn.CommandTag { return pgconn.CommandTag{} }
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+
+func (r *fakeRows) Next() bool {
+	if len(r.values) == 0 {
+		return false
+	}
+	r.current, r.values = r.values[0], r.values[1:]
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) != len(r.current) {
+		return fmt.Errorf("scan into %d values, got %d columns", len(dest), len(r.current))
+	}
+	for i, v := range r.current {
+		if scanner, ok := dest[i].(sql.Scanner); ok {
+			if err := scanner.Scan(v); err != nil {
+				return err
+			}
+			continue
+		}
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	return nil
+}
+
+func (r *fakeRows) Values() ([]any, error) { return r.current, nil }
+
+func (r *fakeRows) RawValues() [][]byte { return nil }
+
+func (r *fakeRows) Conn() *pgx.Conn { return nil }
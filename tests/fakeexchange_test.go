@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/fakeexchange"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newFakeExchange serves the config and returns a client pointed at it
+func newFakeExchange(t *testing.T, cfg fakeexchange.Config) (*fakeexchange.Exchange, *client.GrinexClient) {
+	exchange, err := fakeexchange.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	server := httptest.NewServer(exchange)
+	t.Cleanup(server.Close)
+
+	return exchange, client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, zap.NewNop())
+}
+
+func TestFakeExchange_Scenario(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Seed = 1
+	cfg.Markets[0].Scenario = []fakeexchange.Step{
+		{Ask: "95.60", Bid: "95.40"},
+		{Status: http.StatusTooManyRequests},
+		{Malformed: true},
+		{Empty: true},
+		{Ask: "95.60", Bid: "95.40", TimestampOffset: -10 * time.Minute},
+		{NoTimestamp: true},
+	}
+	exchange, c := newFakeExchange(t, cfg)
+	ctx := context.Background()
+
+	rate, err := c.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.Equal(t, "95.60", rate.Ask)
+	assert.Equal(t, "95.40", rate.Bid)
+	assert.WithinDuration(t, time.Now(), rate.Timestamp, 2*time.Second)
+
+	_, err = c.GetRates(ctx, "usdtrub")
+	assert.ErrorContains(t, err, "429")
+
+	_, err = c.GetRates(ctx, "usdtrub")
+	assert.ErrorContains(t, err, "failed to decode response")
+
+	rate, err = c.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.Equal(t, "N/A", rate.Ask)
+	assert.Equal(t, "N/A", rate.Bid)
+
+	rate, err = c.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(-10*time.Minute), rate.Timestamp, 2*time.Second)
+
+	rate, err = c.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.True(t, rate.Timestamp.IsZero())
+
+	// The random walk takes over after the scenario
+	rate, err = c.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	ask, err := strconv.ParseFloat(rate.Ask, 64)
+	require.NoError(t, err)
+	bid, err := strconv.ParseFloat(rate.Bid, 64)
+	require.NoError(t, err)
+	assert.Greater(t, ask, bid)
+	assert.InDelta(t, 95.5, (ask+bid)/2, 1)
+
+	assert.Equal(t, 7, exchange.Requests("usdtrub"))
+}
+
+func TestFakeExchange_LoopingScenario(t *testing.T) {
+	cfg := fakeexchange.Config{
+		Markets: []fakeexchange.Market{{
+			ID:   "usdtrub",
+			Loop: true,
+			Scenario: []fakeexchange.Step{
+				{Ask: "95.60", Bid: "95.40"},
+				{Status: http.StatusInternalServerError},
+			},
+		}},
+	}
+	_, c := newFakeExchange(t, cfg)
+
+	for i := 0; i < 3; i++ {
+		rate, err := c.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+		assert.Equal(t, "95.60", rate.Ask)
+
+		_, err = c.GetRates(context.Background(), "usdtrub")
+		assert.ErrorContains(t, err, "500")
+	}
+}
+
+func TestFakeExchange_RandomWalkIsReproducible(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Seed = 42
+	cfg.Markets[0].Volatility = 0.01
+
+	_, first := newFakeExchange(t, cfg)
+	_, second := newFakeExchange(t, cfg)
+
+	var asks []string
+	for i := 0; i < 10; i++ {
+		a, err := first.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+		b, err := second.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+
+		assert.Equal(t, a.Ask, b.Ask)
+		assert.Equal(t, a.Bid, b.Bid)
+		asks = append(asks, a.Ask)
+	}
+	assert.NotEqual(t, asks[0], asks[9], "prices should move")
+}
+
+func TestFakeExchange_Faults(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Faults.RateLimitRate = 1
+	exchange, err := fakeexchange.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	exchange.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v2/depth?market=usdtrub", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	recorder = httptest.NewRecorder()
+	exchange.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v2/depth?market=btcusdt", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestFakeExchange_Latency(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Markets[0].Scenario = []fakeexchange.Step{{Latency: 200 * time.Millisecond}}
+	_, c := newFakeExchange(t, cfg)
+
+	start := time.Now()
+	_, err := c.GetRates(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestFakeExchange_DepthLevels(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Levels = 3
+	cfg.Markets[0].Scenario = []fakeexchange.Step{{Ask: "95.60", Bid: "95.40"}}
+	exchange, err := fakeexchange.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	exchange.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v2/depth?market=usdtrub", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var depth client.DepthResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &depth))
+	require.Len(t, depth.Asks, 3)
+	require.Len(t, depth.Bids, 3)
+	assert.Equal(t, []string{"95.60", "95.61", "95.62"}, []string{depth.Asks[0].Price, depth.Asks[1].Price, depth.Asks[2].Price})
+	assert.Equal(t, []string{"95.40", "95.39", "95.38"}, []string{depth.Bids[0].Price, depth.Bids[1].Price, depth.Bids[2].Price})
+}
+
+func TestFakeExchange_Markets(t *testing.T) {
+	cfg := fakeexchange.DefaultConfig()
+	_, c := newFakeExchange(t, cfg)
+
+	markets, err := c.GetMarkets(context.Background())
+	require.NoError(t, err)
+	require.Len(t, markets, 1)
+	assert.Equal(t, "usdtrub", markets[0].ID)
+	assert.Equal(t, "USDT/RUB", markets[0].Name)
+	assert.Equal(t, "enabled", markets[0].State)
+	assert.Equal(t, 2, markets[0].PricePrecision)
+}
+
+func TestFakeExchange_LoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "exchange.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+seed: 7
+latency: 50ms
+faults:
+  error_rate: 0.1
+markets:
+  - id: usdtrub
+    price: 95.5
+    price_precision: 2
+    scenario:
+      - {ask: "95.60", bid: "95.40", timestamp_offset: -10m}
+      - {status: 429, latency: 1s}
+`), 0o600))
+
+	cfg, err := fakeexchange.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), cfg.Seed)
+	assert.Equal(t, 50*time.Millisecond, cfg.Latency)
+	assert.Equal(t, 5, cfg.Levels)
+	assert.Equal(t, 0.1, cfg.Faults.ErrorRate)
+	require.Len(t, cfg.Markets[0].Scenario, 2)
+	assert.Equal(t, -10*time.Minute, cfg.Markets[0].Scenario[0].TimestampOffset)
+	assert.Equal(t, time.Second, cfg.Markets[0].Scenario[1].Latency)
+
+	invalid := map[string]string{
+		"no markets":      `seed: 1`,
+		"bad fault rate":  "faults: {error_rate: 2}\nmarkets: [{id: usdtrub, price: 1}]",
+		"missing price":   `markets: [{id: usdtrub}]`,
+		"duplicate id":    `markets: [{id: usdtrub, price: 1}, {id: usdtrub, price: 2}]`,
+		"invalid latency": "latency: soon\nmarkets: [{id: usdtrub, price: 1}]",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := fakeexchange.LoadConfig(path)
+			assert.Error(t, err)
+		})
+	}
+}
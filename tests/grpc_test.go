@@ -3,11 +3,13 @@ package tests
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/alik/TestForWork/internal/api/grpc"
 	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
 	pb "github.com/alik/TestForWork/proto/rates"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,7 +24,7 @@ type MockRatesService struct {
 	mock.Mock
 }
 
-func (m *MockRatesService) GetRates(ctx context.Context, market string) (*client.RateData, error) {
+func (m *MockRatesService) GetRates(ctx context.Context, market string, _ ...service.RequestOption) (*client.RateData, error) {
 	args := m.Called(ctx, market)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,6 +32,25 @@ func (m *MockRatesService) GetRates(ctx context.Context, market string) (*client
 	return args.Get(0).(*client.RateData), args.Error(1)
 }
 
+func (m *MockRatesService) BatchGetRates(ctx context.Context, markets []string, _ ...service.RequestOption) []service.MarketResult {
+	args := m.Called(ctx, markets)
+	return args.Get(0).([]service.MarketResult)
+}
+
+func (m *MockRatesService) ListMarkets(ctx context.Context) []service.Market {
+	args := m.Called(ctx)
+	return args.Get(0).([]service.Market)
+}
+
+func (m *MockRatesService) SubscribeRates(markets []string) *service.Subscription {
+	args := m.Called(markets)
+	return args.Get(0).(*service.Subscription)
+}
+
+func (m *MockRatesService) Unsubscribe(sub *service.Subscription) {
+	m.Called(sub)
+}
+
 func (m *MockRatesService) HealthCheck(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -74,6 +95,17 @@ func TestRatesHandler_GetRates(t *testing.T) {
 			expectError:  true,
 			expectedCode: codes.InvalidArgument,
 		},
+		{
+			name: "unknown market",
+			request: &pb.GetRatesRequest{
+				Market: "foobar",
+			},
+			setupMocks: func(svc *MockRatesService) {
+				svc.On("GetRates", mock.Anything, "foobar").Return(nil, fmt.Errorf("%w: foobar", service.ErrUnknownMarket))
+			},
+			expectError:  true,
+			expectedCode: codes.NotFound,
+		},
 		{
 			name: "service error",
 			request: &pb.GetRatesRequest{
@@ -125,6 +157,113 @@ func TestRatesHandler_GetRates(t *testing.T) {
 	}
 }
 
+func TestRatesHandler_BatchGetRates(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       *pb.BatchGetRatesRequest
+		setupMocks    func(*MockRatesService)
+		expectError   bool
+		expectedCode  codes.Code
+		expectedCodes []codes.Code
+	}{
+		{
+			name: "partial failure",
+			request: &pb.BatchGetRatesRequest{
+				Markets: []string{"usdtrub", "", "btcusdt"},
+			},
+			setupMocks: func(svc *MockRatesService) {
+				svc.On("BatchGetRates", mock.Anything, []string{"usdtrub", "btcusdt"}).Return([]service.MarketResult{
+					{Market: "usdtrub", Rate: &client.RateData{Ask: "95.5", Bid: "95.3", Market: "usdtrub", Timestamp: time.Now()}},
+					{Market: "btcusdt", Err: errors.New("service error")},
+				})
+			},
+			expectedCodes: []codes.Code{codes.OK, codes.InvalidArgument, codes.Internal},
+		},
+		{
+			name:         "empty markets",
+			request:      &pb.BatchGetRatesRequest{},
+			setupMocks:   func(service *MockRatesService) {},
+			expectError:  true,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "too many markets",
+			request: &pb.BatchGetRatesRequest{
+				Markets: make([]string, 101),
+			},
+			setupMocks:   func(service *MockRatesService) {},
+			expectError:  true,
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Setup mocks
+			mockService := new(MockRatesService)
+			tt.setupMocks(mockService)
+
+			// Create handler
+			logger := zap.NewNop()
+			handler := grpc.NewRatesHandler(mockService, logger, "1.0.0")
+
+			// Execute
+			ctx := context.Background()
+			response, err := handler.BatchGetRates(ctx, tt.request)
+
+			// Assert
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Nil(t, response)
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				require.Len(t, response.Results, len(tt.expectedCodes))
+				for i, code := range tt.expectedCodes {
+					result := response.Results[i]
+					assert.Equal(t, tt.request.Markets[i], result.Market)
+					if code == codes.OK {
+						require.NotNil(t, result.GetRates())
+						assert.Equal(t, "95.5", result.GetRates().Ask)
+					} else {
+						require.NotNil(t, result.GetError())
+						assert.Equal(t, int32(code), result.GetError().Code)
+					}
+				}
+			}
+
+			// Verify mock expectations
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRatesHandler_ListMarkets(t *testing.T) {
+	// Setup mocks
+	mockService := new(MockRatesService)
+	mockService.On("ListMarkets", mock.Anything).Return([]service.Market{
+		{ID: "usdtrub", Base: "usdt", Quote: "rub", PricePrecision: 2, Status: service.MarketStatusActive},
+	})
+
+	// Create handler
+	logger := zap.NewNop()
+	handler := grpc.NewRatesHandler(mockService, logger, "1.0.0")
+
+	// Execute
+	response, err := handler.ListMarkets(context.Background(), &pb.ListMarketsRequest{})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, response.Markets, 1)
+	assert.Equal(t, "usdtrub", response.Markets[0].Id)
+	assert.Equal(t, "usdt", response.Markets[0].Base)
+	assert.Equal(t, "rub", response.Markets[0].Quote)
+	assert.Equal(t, int32(2), response.Markets[0].PricePrecision)
+
+	// Verify mock expectations
+	mockService.AssertExpectations(t)
+}
+
 func TestRatesHandler_Healthcheck(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MockQuarantineStore is a mock implementation of QuarantineStore
+type MockQuarantineStore struct {
+	mock.Mock
+}
+
+func (m *MockQuarantineStore) QuarantineRate(ctx context.Context, rate *postgres.QuarantinedRate) error {
+	args := m.Called(ctx, rate)
+	return args.Error(0)
+}
+
+// sequenceClient returns the queued rates in order
+type sequenceClient struct {
+	rates []*client.RateData
+}
+
+func (c *sequenceClient) GetRates(_ context.Context, _ string) (*client.RateData, error) {
+	if len(c.rates) == 0 {
+		return nil, errors.New("no more rates")
+	}
+	rate := c.rates[0]
+	c.rates = c.rates[1:]
+	return rate, nil
+}
+
+func guardRate(ask, bid string) *client.RateData {
+	return &client.RateData{Market: "usdtrub", Ask: ask, Bid: bid, Timestamp: time.Now()}
+}
+
+func TestSanityGuard_RejectsInvalidQuotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   *client.RateData
+		reason string
+	}{
+		{"crossed book", guardRate("95.0", "95.5"), service.RejectCrossedBook},
+		{"zero price", guardRate("0", "0"), service.RejectNonPositivePrice},
+		{"negative price", guardRate("95.5", "-1"), service.RejectNonPositivePrice},
+		{"not a number", guardRate("abc", "95.3"), service.RejectInvalidPrice},
+		{"stale", &client.RateData{Market: "usdtrub", Ask: "95.5", Bid: "95.3", Timestamp: time.Now().Add(-time.Hour)}, service.RejectStaleTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := new(MockQuarantineStore)
+			store.On("QuarantineRate", mock.Anything, mock.MatchedBy(func(r *postgres.QuarantinedRate) bool {
+				return r.Reason == tt.reason && r.Market == "usdtrub" && r.Ask == tt.rate.Ask
+			})).Return(nil)
+
+			guard := service.NewSanityGuard(&sequenceClient{rates: []*client.RateData{tt.rate}}, store,
+				service.GuardConfig{MaxStaleness: time.Minute}, zap.NewNop())
+
+			rate, err := guard.GetRates(context.Background(), "usdtrub")
+			assert.Nil(t, rate)
+			assert.ErrorIs(t, err, service.ErrRejectedQuote)
+			store.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSanityGuard_AcceptsLockedBook(t *testing.T) {
+	guard := service.NewSanityGuard(&sequenceClient{rates: []*client.RateData{guardRate("95.5", "95.5")}}, nil,
+		service.GuardConfig{}, zap.NewNop())
+
+	rate, err := guard.GetRates(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	assert.Equal(t, "95.5", rate.Bid)
+}
+
+func TestSanityGuard_RejectsJumpsAndAcceptsSustainedMoves(t *testing.T) {
+	upstream := &sequenceClient{}
+	// Small oscillations build the statistics
+	for i := 0; i < 20; i++ {
+		price := 95.0 + float64(i%2)*0.1
+		upstream.rates = append(upstream.rates, guardRate(fmt.Sprintf("%.2f", price+0.1), fmt.Sprintf("%.2f", price)))
+	}
+	// A 50% spike, then a revert
+	upstream.rates = append(upstream.rates, guardRate("142.6", "142.5"), guardRate("95.1", "95.0"))
+	// A sustained move to a new level
+	for i := 0; i < 3; i++ {
+		upstream.rates = append(upstream.rates, guardRate("105.1", "105.0"))
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+
+	store := new(MockQuarantineStore)
+	store.On("QuarantineRate", mock.Anything, mock.Anything).Return(nil)
+
+	guard := service.NewSanityGuard(upstream, store, service.GuardConfig{
+		JumpSigma:           6,
+		JumpWindow:          30,
+		JumpMinSamples:      10,
+		JumpMinPercent:      1,
+		MaxConsecutiveJumps: 2,
+	}, zap.New(core))
+
+	ctx, root := startTestTrace(t)
+	defer root.End()
+	for i := 0; i < 20; i++ {
+		_, err := guard.GetRates(ctx, "usdtrub")
+		require.NoError(t, err, "sample %d", i)
+	}
+
+	_, err := guard.GetRates(ctx, "usdtrub")
+	assert.ErrorIs(t, err, service.ErrRejectedQuote)
+
+	_, err = guard.GetRates(ctx, "usdtrub")
+	assert.NoError(t, err)
+
+	// The first two samples at the new level are rejected, the third is accepted
+	_, err = guard.GetRates(ctx, "usdtrub")
+	assert.ErrorIs(t, err, service.ErrRejectedQuote)
+	_, err = guard.GetRates(ctx, "usdtrub")
+	assert.ErrorIs(t, err, service.ErrRejectedQuote)
+	rate, err := guard.GetRates(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.Equal(t, "105.1", rate.Ask)
+
+	store.AssertNumberOfCalls(t, "QuarantineRate", 3)
+
+	// Every log line of the guard carries the trace of the request
+	accepted := logs.FilterMessage("Accepting sustained price move").All()
+	require.Len(t, accepted, 1)
+	for _, entry := range logs.All() {
+		assert.Equal(t, root.SpanContext().TraceID().String(), entry.ContextMap()["trace_id"], entry.Message)
+	}
+}
+
+func TestGRPCHandler_RejectedQuoteIsUnavailable(t *testing.T) {
+	mockService := new(MockRatesService)
+	mockService.On("GetRates", mock.Anything, "usdtrub").
+		Return(nil, fmt.Errorf("failed to get rates from Grinex: %w", service.ErrRejectedQuote))
+
+	handler := grpc.NewRatesHandler(mockService, zap.NewNop(), "test")
+	_, err := handler.GetRates(context.Background(), &pb.GetRatesRequest{Market: "usdtrub"})
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.Unavailable, st.Code())
+}
@@ -0,0 +1,283 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/importer"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeImportStore keeps imported rates and skips duplicates by market and
+// timestamp within the batch and against earlier batches
+type fakeImportStore struct {
+	rates   []postgres.ImportedRate
+	batches int
+	dryRuns int
+	// failAt fails the batch with this number, counting from 1
+	failAt int
+}
+
+func (s *fakeImportStore) ImportRates(_ context.Context, rates []postgres.ImportedRate, dryRun bool) (int64, error) {
+	s.batches++
+	if s.batches == s.failAt {
+		return 0, errors.New("connection reset")
+	}
+	if dryRun {
+		s.dryRuns++
+	}
+
+	committed := len(s.rates)
+	for _, rate := range rates {
+		if !s.contains(rate) {
+			s.rates = append(s.rates, rate)
+		}
+	}
+	inserted := int64(len(s.rates) - committed)
+	if dryRun {
+		s.rates = s.rates[:committed]
+	}
+	return inserted, nil
+}
+
+func (s *fakeImportStore) contains(rate postgres.ImportedRate) bool {
+	for _, r := range s.rates {
+		if r.Market == rate.Market && r.Timestamp.Equal(rate.Timestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeImportFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func readAll(t *testing.T, r importer.Reader) ([]importer.Record, []*importer.RowError) {
+	var records []importer.Record
+	var rowErrors []*importer.RowError
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, rowErrors
+		}
+		var rowErr *importer.RowError
+		if errors.As(err, &rowErr) {
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestImporter_CSVReader(t *testing.T) {
+	input := "\ufefftimestamp,Market,bid,ask,source\n" +
+		"2026-01-01T00:00:00Z,usdtrub,95.3,95.5,binance\n" +
+		"1767225660,usdtrub,95.4,95.6\n" +
+		"\"2026-01-01T00:02:00Z,usdtrub,95.4,95.6\n"
+
+	r, err := importer.NewCSVReader(strings.NewReader(input))
+	require.NoError(t, err)
+
+	records, rowErrors := readAll(t, r)
+	require.Len(t, records, 2)
+	assert.Equal(t, importer.Record{Line: 2, Market: "usdtrub", Ask: "95.5", Bid: "95.3",
+		Timestamp: "2026-01-01T00:00:00Z", Source: "binance"}, records[0])
+	assert.Equal(t, 3, records[1].Line)
+	assert.Equal(t, "", records[1].Source)
+	require.Len(t, rowErrors, 1)
+	assert.Equal(t, 4, rowErrors[0].Line)
+
+	_, err = importer.NewCSVReader(strings.NewReader("market,ask,timestamp\n"))
+	assert.ErrorContains(t, err, "bid")
+}
+
+func TestImporter_JSONLReader(t *testing.T) {
+	input := `{"market":"usdtrub","ask":95.5,"bid":"95.3","timestamp":1767225600000,"source":"archive"}
+
+{"market":"usdtrub","ask":"95.6"
+{"market":"btcusdt","ask":"65000.1","bid":"64999.9","timestamp":"2026-01-01 00:00:00"}
+`
+	records, rowErrors := readAll(t, importer.NewJSONLReader(strings.NewReader(input)))
+
+	require.Len(t, records, 2)
+	assert.Equal(t, importer.Record{Line: 1, Market: "usdtrub", Ask: "95.5", Bid: "95.3",
+		Timestamp: "1767225600000", Source: "archive"}, records[0])
+	assert.Equal(t, 4, records[1].Line)
+	require.Len(t, rowErrors, 1)
+	assert.Equal(t, 3, rowErrors[0].Line)
+}
+
+func TestImporter_Validate(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	valid := importer.Record{Market: "USDTRUB", Ask: "95.5", Bid: "95.3", Timestamp: "2026-01-01T03:00:00+03:00"}
+
+	rate, err := importer.Validate(valid, "import", now)
+	require.NoError(t, err)
+	assert.Equal(t, "usdtrub", rate.Market)
+	assert.Equal(t, "import", rate.Source)
+	assert.True(t, rate.Timestamp.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	for _, ts := range []string{"1767225600", "1767225600000", "2026-01-01 00:00:00", "2026-01-01T00:00:00.000Z"} {
+		rec := valid
+		rec.Timestamp = ts
+		rate, err := importer.Validate(rec, "import", now)
+		require.NoError(t, err, ts)
+		assert.True(t, rate.Timestamp.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)), ts)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*importer.Record)
+		source string
+		err    string
+	}{
+		{"invalid market", func(r *importer.Record) { r.Market = "usdt/rub" }, "import", "invalid market"},
+		{"missing ask", func(r *importer.Record) { r.Ask = "" }, "import", "invalid ask"},
+		{"non-numeric bid", func(r *importer.Record) { r.Bid = "N/A" }, "import", "invalid bid"},
+		{"zero price", func(r *importer.Record) { r.Bid = "0" }, "import", "must be positive"},
+		{"too many decimals", func(r *importer.Record) { r.Ask = "95.123456789" }, "import", "invalid ask"},
+		{"crossed", func(r *importer.Record) { r.Bid = "96" }, "import", "above ask"},
+		{"bad timestamp", func(r *importer.Record) { r.Timestamp = "yesterday" }, "import", "invalid timestamp"},
+		{"future timestamp", func(r *importer.Record) { r.Timestamp = "2027-01-01T00:00:00Z" }, "import", "in the future"},
+		{"missing source", func(r *importer.Record) {}, "", "missing source"},
+		{"long source", func(r *importer.Record) { r.Source = strings.Repeat("x", 51) }, "import", "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := valid
+			tt.modify(&rec)
+			_, err := importer.Validate(rec, tt.source, now)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+const importCSV = `market,ask,bid,timestamp,source
+usdtrub,95.5,95.3,2026-01-01T00:00:00Z,archive
+usdtrub,95.6,95.4,2026-01-01T00:01:00Z,archive
+usdtrub,95.6,95.4,2026-01-01T00:01:00Z,archive
+usdtrub,95.6,95.7,2026-01-01T00:02:00Z,archive
+usdtrub,95.7,95.5,2026-01-01T00:03:00Z,archive
+btcusdt,65000.1,64999.9,2026-01-01T00:03:00Z,archive
+`
+
+func TestImporter_ImportFile(t *testing.T) {
+	path := writeImportFile(t, "rates.csv", importCSV)
+	store := &fakeImportStore{}
+
+	imp := importer.New(store, zap.NewNop(), importer.WithBatchSize(2))
+	stats, err := imp.ImportFile(context.Background(), path, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, importer.Stats{Read: 6, Inserted: 4, Duplicates: 1, Invalid: 1}, stats)
+	assert.Len(t, store.rates, 4)
+	assert.Equal(t, 3, store.batches)
+	assert.NoFileExists(t, path+".import-checkpoint")
+
+	// Importing again only finds duplicates
+	stats, err = imp.ImportFile(context.Background(), path, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Inserted)
+	assert.Equal(t, int64(5), stats.Duplicates)
+}
+
+func TestImporter_DryRun(t *testing.T) {
+	path := writeImportFile(t, "rates.csv", importCSV)
+	store := &fakeImportStore{}
+
+	imp := importer.New(store, zap.NewNop(), importer.WithDryRun())
+	stats, err := imp.ImportFile(context.Background(), path, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Inserted)
+	assert.Equal(t, 1, store.dryRuns)
+	assert.Empty(t, store.rates)
+}
+
+func TestImporter_DryRunDuplicatesAcrossBatches(t *testing.T) {
+	path := writeImportFile(t, "rates.csv", importCSV)
+	store := &fakeImportStore{}
+
+	// The repeated row lands in the second batch, after the first was rolled back
+	imp := importer.New(store, zap.NewNop(), importer.WithDryRun(), importer.WithBatchSize(2))
+	stats, err := imp.ImportFile(context.Background(), path, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, importer.Stats{Read: 6, Inserted: 4, Duplicates: 1, Invalid: 1}, stats,
+		"a dry run counts what a real run would insert")
+	assert.Empty(t, store.rates)
+}
+
+func TestImporter_MaxErrors(t *testing.T) {
+	path := writeImportFile(t, "rates.jsonl", `{"market":"usdtrub","ask":"x","bid":"1","timestamp":1767225600}
+{"market":"usdtrub","ask":"y","bid":"1","timestamp":1767225600}
+`)
+	store := &fakeImportStore{}
+
+	_, err := importer.New(store, zap.NewNop(), importer.WithMaxErrors(1), importer.WithDefaultSource("import")).
+		ImportFile(context.Background(), path, "")
+	assert.ErrorContains(t, err, "too many invalid rows")
+
+	stats, err := importer.New(store, zap.NewNop(), importer.WithMaxErrors(-1), importer.WithDefaultSource("import")).
+		ImportFile(context.Background(), path, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Invalid)
+}
+
+func TestImporter_Resume(t *testing.T) {
+	path := writeImportFile(t, "rates.csv", importCSV)
+	store := &fakeImportStore{failAt: 2}
+
+	_, err := importer.New(store, zap.NewNop(), importer.WithBatchSize(2)).ImportFile(context.Background(), path, "")
+	require.ErrorContains(t, err, "connection reset")
+	require.FileExists(t, path+".import-checkpoint")
+	require.Len(t, store.rates, 2)
+
+	// The next run continues after the first committed batch
+	store.failAt = 0
+	store.batches = 0
+	stats, err := importer.New(store, zap.NewNop(), importer.WithBatchSize(2)).ImportFile(context.Background(), path, "")
+
+	require.NoError(t, err)
+	assert.Equal(t, importer.Stats{Read: 6, Inserted: 4, Duplicates: 1, Invalid: 1}, stats)
+	assert.Equal(t, 2, store.batches)
+	assert.Len(t, store.rates, 4)
+	assert.NoFileExists(t, path+".import-checkpoint")
+}
+
+func TestImporter_ResumeRejectsChangedFile(t *testing.T) {
+	path := writeImportFile(t, "rates.csv", importCSV)
+	store := &fakeImportStore{failAt: 2}
+
+	_, err := importer.New(store, zap.NewNop(), importer.WithBatchSize(2)).ImportFile(context.Background(), path, "")
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(importCSV+"usdtrub,95.8,95.6,2026-01-01T00:04:00Z,archive\n"), 0o644))
+
+	_, err = importer.New(store, zap.NewNop()).ImportFile(context.Background(), path, "")
+	assert.ErrorContains(t, err, "changed since the interrupted import")
+}
+
+func TestImporter_UnknownFormat(t *testing.T) {
+	path := writeImportFile(t, "rates.txt", importCSV)
+
+	_, err := importer.New(&fakeImportStore{}, zap.NewNop()).ImportFile(context.Background(), path, "")
+	assert.ErrorContains(t, err, "cannot detect the format")
+
+	stats, err := importer.New(&fakeImportStore{}, zap.NewNop()).ImportFile(context.Background(), path, importer.FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats.Inserted)
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// metricValue returns the value of a gauge or counter, or the sample count
+// of a histogram, with the given labels from the default registry
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case m.GetGauge() != nil:
+				return m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				return m.GetCounter().GetValue()
+			case m.GetHistogram() != nil:
+				return float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func TestGrinexClient_Metrics(t *testing.T) {
+	const endpoint = "/api/v2/depth"
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  string
+		class   string
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`{"asks":[{"price":"95.5"}],"bids":[{"price":"95.3"}]}`))
+			},
+			status: "200",
+		},
+		{
+			name: "non-200",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			status: "502",
+			class:  client.ErrorClassStatus,
+		},
+		{
+			name: "decode",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Write([]byte(`<html>`))
+			},
+			status: "200",
+			class:  client.ErrorClassDecode,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			status: "error",
+			class:  client.ErrorClassTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			requestLabels := map[string]string{"endpoint": endpoint, "status": tt.status}
+			errorLabels := map[string]string{"endpoint": endpoint, "class": tt.class}
+			requests := metricValue(t, "rates_upstream_request_duration_seconds", requestLabels)
+			errs := metricValue(t, "rates_upstream_errors_total", errorLabels)
+
+			c := client.NewGrinexClient(server.URL, "usdtrub", 100*time.Millisecond, zap.NewNop())
+			_, err := c.GetRates(context.Background(), "usdtrub")
+
+			assert.Equal(t, requests+1, metricValue(t, "rates_upstream_request_duration_seconds", requestLabels))
+			if tt.class == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, errs+1, metricValue(t, "rates_upstream_errors_total", errorLabels))
+		})
+	}
+}
+
+func TestGrinexClient_Metrics_CanceledNotCounted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer server.Close()
+
+	before := metricValue(t, "rates_upstream_errors_total", map[string]string{"endpoint": "/api/v2/depth"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := client.NewGrinexClient(server.URL, "usdtrub", time.Second, zap.NewNop())
+	_, err := c.GetRates(ctx, "usdtrub")
+
+	require.Error(t, err)
+	assert.Equal(t, before, metricValue(t, "rates_upstream_errors_total", map[string]string{"endpoint": "/api/v2/depth"}))
+}
+
+func TestRatesService_Metrics(t *testing.T) {
+	grinex := new(MockGrinexClient)
+	repo := new(MockRepository)
+	quoteTime := time.Now().Add(-30 * time.Second)
+	grinex.On("GetRates", mock.Anything, "metricsusdt").Return(&client.RateData{
+		Market: "metricsusdt", Ask: "96", Bid: "95", Timestamp: quoteTime, ReceivedAt: time.Now(),
+	}, nil)
+	grinex.On("GetRates", mock.Anything, "metricsempty").Return(&client.RateData{
+		Market: "metricsempty", Ask: "N/A", Bid: "10", ReceivedAt: time.Now(),
+	}, nil)
+	repo.On("SaveRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	svc := service.NewRatesService(grinex, repo, zap.NewNop())
+	_, err := svc.GetRates(context.Background(), "metricsusdt")
+	require.NoError(t, err)
+
+	labels := map[string]string{"market": "metricsusdt", "source": "grinex"}
+	assert.Equal(t, 96.0, metricValue(t, "rates_last_ask", labels))
+	assert.Equal(t, 95.0, metricValue(t, "rates_last_bid", labels))
+	assert.Equal(t, 95.5, metricValue(t, "rates_last_mid", labels))
+	assert.Equal(t, 1.0, metricValue(t, "rates_last_spread", labels))
+	assert.InDelta(t, 30, metricValue(t, "rates_quote_age_seconds", labels), 5)
+
+	// A missing side is not exported and leaves mid and spread unset
+	_, err = svc.GetRates(context.Background(), "metricsempty")
+	require.NoError(t, err)
+
+	labels = map[string]string{"market": "metricsempty", "source": "grinex"}
+	assert.Equal(t, 10.0, metricValue(t, "rates_last_bid", labels))
+	assert.Zero(t, metricValue(t, "rates_last_ask", labels))
+	assert.Zero(t, metricValue(t, "rates_last_mid", labels))
+}
@@ -0,0 +1,391 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+)
+
+// fakeNotificationConn delivers the notifications sent to it until it is
+// broken
+type fakeNotificationConn struct {
+	notifications chan *pgconn.Notification
+	broken        chan struct{}
+	breakOnce     sync.Once
+
+	mu     sync.Mutex
+	listen []string
+}
+
+func newFakeNotificationConn() *fakeNotificationConn {
+	return &fakeNotificationConn{
+		notifications: make(chan *pgconn.Notification, 10),
+		broken:        make(chan struct{}),
+	}
+}
+
+func (c *fakeNotificationConn) notify(payload string) {
+	c.notifications <- &pgconn.Notification{Channel: postgres.RatesChannel, Payload: payload}
+}
+
+func (c *fakeNotificationConn) fail() {
+	c.breakOnce.Do(func() { close(c.broken) })
+}
+
+func (c *fakeNotificationConn) listened() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.listen...)
+}
+
+func (c *fakeNotificationConn) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listen = append(c.listen, sql)
+	return pgconn.NewCommandTag("LISTEN"), nil
+}
+
+func (c *fakeNotificationConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case n := <-c.notifications:
+		return n, nil
+	case <-c.broken:
+		return nil, errors.New("connection reset by peer")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakeNotificationConn) Close(context.Context) error {
+	c.fail()
+	return nil
+}
+
+func ratePayload(t *testing.T, market, ask, origin string) string {
+	payload, err := json.Marshal(postgres.RateNotification{
+		Market:     market,
+		Ask:        ask,
+		Bid:        "99.5",
+		ReceivedAt: time.Now(),
+		Origin:     origin,
+	})
+	require.NoError(t, err)
+	return string(payload)
+}
+
+func TestRepository_SaveRateNotifies(t *testing.T) {
+	db := newFakeDB("primary")
+	ctx := context.Background()
+
+	repo := postgres.NewRepository(db, zap.NewNop())
+	require.NoError(t, repo.SaveRate(ctx, "usdtrub", "100.5", "99.5", time.Time{}, time.Now()))
+	assert.NotContains(t, db.lastStatement().query, "pg_notify", "notifications are off by default")
+
+	repo = postgres.NewRepository(db, zap.NewNop(), postgres.WithNotifications("instance-a"))
+	ts := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.SaveRate(ctx, "usdtrub", "100.5", "99.5", ts, time.Now()))
+
+	exec := db.lastStatement()
+	assert.Contains(t, exec.query, "pg_notify")
+	require.Len(t, exec.args, 7)
+	assert.Equal(t, postgres.RatesChannel, exec.args[5])
+
+	var n postgres.RateNotification
+	require.NoError(t, json.Unmarshal([]byte(exec.args[6].(string)), &n))
+	assert.Equal(t, "usdtrub", n.Market)
+	assert.Equal(t, "100.5", n.Ask)
+	assert.Equal(t, "99.5", n.Bid)
+	assert.Equal(t, "instance-a", n.Origin)
+	require.NotNil(t, n.Timestamp)
+	assert.True(t, ts.Equal(*n.Timestamp))
+}
+
+func TestListener_ReconnectsAndListensAgain(t *testing.T) {
+	conns := make(chan *fakeNotificationConn, 10)
+	attempts := 0
+	connect := func(context.Context) (postgres.NotificationConn, error) {
+		attempts++
+		if attempts == 2 {
+			return nil, errors.New("connection refused")
+		}
+		conn := newFakeNotificationConn()
+		conns <- conn
+		return conn, nil
+	}
+
+	received := make(chan postgres.RateNotification, 10)
+	resyncs := make(chan struct{}, 10)
+	listener := postgres.NewListener(connect, "instance-a", func(n postgres.RateNotification) {
+		received <- n
+	}, zap.NewNop(), postgres.WithResync(func(context.Context) { resyncs <- struct{}{} }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		listener.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	first := <-conns
+	<-resyncs
+	first.notify(ratePayload(t, "usdtrub", "100.5", "instance-a"))
+	first.notify("not json")
+	first.notify(ratePayload(t, "usdtrub", "101.5", "instance-b"))
+
+	select {
+	case n := <-received:
+		assert.Equal(t, "101.5", n.Ask, "own and malformed notifications are skipped")
+		assert.Equal(t, "instance-b", n.Origin)
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered")
+	}
+	assert.Equal(t, []string{"LISTEN " + postgres.RatesChannel}, first.listened())
+
+	// The listener reconnects after a failed attempt and listens again
+	first.fail()
+	var second *fakeNotificationConn
+	select {
+	case second = <-conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not reconnect")
+	}
+	// Rates saved while reconnecting are loaded before notifications resume
+	select {
+	case <-resyncs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("listener did not resync after reconnect")
+	}
+	second.notify(ratePayload(t, "usdtrub", "102.5", "instance-c"))
+
+	select {
+	case n := <-received:
+		assert.Equal(t, "102.5", n.Ask)
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered after reconnect")
+	}
+	assert.Equal(t, []string{"LISTEN " + postgres.RatesChannel}, second.listened())
+	assert.Empty(t, received)
+}
+
+// recordingObserver remembers the rates it observed
+type recordingObserver struct {
+	mu    sync.Mutex
+	rates []client.RateData
+}
+
+func (o *recordingObserver) ObserveRate(rate *client.RateData) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rates = append(o.rates, *rate)
+}
+
+func TestRatesService_ObserveRemoteRate(t *testing.T) {
+	observer := &recordingObserver{}
+	svc := service.NewRatesService(new(MockGrinexClient), new(MockRepository), zap.NewNop(),
+		service.WithRateObservers(observer))
+	sub := svc.SubscribeRates(nil)
+	defer svc.Unsubscribe(sub)
+
+	now := time.Now()
+	older := now.Add(-time.Minute)
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "remoteusdt", Ask: "100.5", Bid: "99.5", Timestamp: &now, Origin: "instance-b"})
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "remoteusdt", Ask: "90.5", Bid: "89.5", Timestamp: &older, Origin: "instance-c"})
+
+	rates := sub.Next()
+	require.Len(t, rates, 1, "rates older than the latest quote are ignored")
+	assert.Equal(t, "100.5", rates[0].Ask)
+	assert.True(t, now.Equal(rates[0].Timestamp))
+
+	// Alert rules are evaluated by the instance that fetched the rate
+	assert.Empty(t, observer.rates)
+
+	labels := map[string]string{"market": "remoteusdt", "source": "grinex"}
+	assert.Equal(t, 100.5, metricValue(t, "rates_last_ask", labels))
+	assert.Less(t, metricValue(t, "rates_quote_age_seconds", labels), 5.0)
+
+	// The latest rate is served without reading the database
+	latest, err := svc.GetLatestRate(context.Background(), "remoteusdt")
+	require.NoError(t, err)
+	assert.Equal(t, "100.5", latest.Ask)
+	assert.True(t, now.Equal(latest.Timestamp))
+}
+
+func TestRatesService_FetchDoesNotOverrideNewerRemoteRate(t *testing.T) {
+	now := time.Now()
+	grinex := new(MockGrinexClient)
+	grinex.On("GetRates", mock.Anything, "raceusdt").Return(&client.RateData{
+		Market: "raceusdt", Ask: "90.5", Bid: "89.5", Timestamp: now.Add(-time.Minute), ReceivedAt: now,
+	}, nil)
+	repo := new(MockRepository)
+	repo.On("SaveRate", mock.Anything, "raceusdt", "90.5", "89.5", mock.Anything, mock.Anything).Return(nil)
+
+	observer := &recordingObserver{}
+	svc := service.NewRatesService(grinex, repo, zap.NewNop(), service.WithRateObservers(observer))
+	sub := svc.SubscribeRates([]string{"raceusdt"})
+	defer svc.Unsubscribe(sub)
+
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "raceusdt", Ask: "100.5", Bid: "99.5", Timestamp: &now, Origin: "instance-b"})
+
+	// A quote fetched here that is older than the applied one is still
+	// returned, but the latest rate, the gauges and the subscribers keep the
+	// newer quote
+	rate, err := svc.GetRates(context.Background(), "raceusdt")
+	require.NoError(t, err)
+	assert.Equal(t, "90.5", rate.Ask)
+
+	assert.Empty(t, observer.rates)
+	assert.Equal(t, 100.5, metricValue(t, "rates_last_ask", map[string]string{"market": "raceusdt", "source": "grinex"}))
+	rates := sub.Next()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "100.5", rates[0].Ask)
+
+	latest, err := svc.GetLatestRate(context.Background(), "raceusdt")
+	require.NoError(t, err)
+	assert.Equal(t, "100.5", latest.Ask)
+}
+
+func TestRatesService_SubscribeRates(t *testing.T) {
+	grinex := new(MockGrinexClient)
+	grinex.On("GetRates", mock.Anything, "subusdt").Return(&client.RateData{
+		Market: "subusdt", Ask: "101.5", Bid: "100.5", ReceivedAt: time.Now(),
+	}, nil)
+	repo := new(MockRepository)
+	repo.On("SaveRate", mock.Anything, "subusdt", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	observer := &recordingObserver{}
+	svc := service.NewRatesService(grinex, repo, zap.NewNop(), service.WithRateObservers(observer))
+
+	earlier := time.Now().Add(-time.Second)
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "subusdt", Ask: "100.5", Bid: "99.5", Timestamp: &earlier})
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "otherusdt", Ask: "1.5", Bid: "1.4", Timestamp: &earlier})
+
+	// The known quote is ready right away
+	sub := svc.SubscribeRates([]string{"subusdt"})
+	defer svc.Unsubscribe(sub)
+	<-sub.Ready()
+	rates := sub.Next()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "100.5", rates[0].Ask)
+
+	// A fetched rate reaches the subscribers and the observers
+	_, err := svc.GetRates(context.Background(), "subusdt")
+	require.NoError(t, err)
+	select {
+	case <-sub.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("subscriber was not notified")
+	}
+	rates = sub.Next()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "101.5", rates[0].Ask)
+	require.Len(t, observer.rates, 1)
+	assert.Equal(t, "101.5", observer.rates[0].Ask)
+
+	// Quotes arriving before the previous ones are read are coalesced
+	later := time.Now().Add(time.Second)
+	latest := later.Add(time.Second)
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "subusdt", Ask: "102.5", Bid: "101.5", Timestamp: &later})
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "subusdt", Ask: "103.5", Bid: "102.5", Timestamp: &latest})
+	rates = sub.Next()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "103.5", rates[0].Ask)
+	assert.Empty(t, sub.Next())
+}
+
+func TestRatesService_SyncLatestRates(t *testing.T) {
+	saved := time.Now().Add(-time.Second)
+	repo := new(MockRepository)
+	repo.On("GetLatestRate", mock.Anything, "syncusdt").Return(&postgres.Rate{
+		ID: 7, Market: "syncusdt", Ask: "100.5", Bid: "99.5", Timestamp: saved, ReceivedAt: saved,
+	}, nil)
+	repo.On("GetLatestRate", mock.Anything, "emptyusdt").Return(nil, nil)
+
+	catalog := service.NewMarketCatalog(nil, []service.Market{{ID: "syncusdt"}, {ID: "emptyusdt"}}, zap.NewNop())
+	svc := service.NewRatesService(new(MockGrinexClient), repo, zap.NewNop(), service.WithMarketCatalog(catalog))
+	sub := svc.SubscribeRates(nil)
+	defer svc.Unsubscribe(sub)
+
+	svc.SyncLatestRates(context.Background())
+	rates := sub.Next()
+	require.Len(t, rates, 1)
+	assert.Equal(t, "syncusdt", rates[0].Market)
+	assert.Equal(t, 100.5, metricValue(t, "rates_last_ask", map[string]string{"market": "syncusdt", "source": "grinex"}))
+
+	// A rate that was already applied is not passed on again
+	svc.SyncLatestRates(context.Background())
+	assert.Empty(t, sub.Next())
+	repo.AssertNumberOfCalls(t, "GetLatestRate", 4)
+}
+
+// rateStream collects the rates sent by SubscribeRates
+type rateStream struct {
+	grpclib.ServerStream
+	ctx   context.Context
+	rates chan *pb.GetRatesResponse
+}
+
+func (s *rateStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *rateStream) Send(rate *pb.GetRatesResponse) error {
+	s.rates <- rate
+	return nil
+}
+
+func TestRatesHandler_SubscribeRates(t *testing.T) {
+	svc := service.NewRatesService(new(MockGrinexClient), new(MockRepository), zap.NewNop())
+	handler := grpc.NewRatesHandler(svc, zap.NewNop(), "test")
+
+	stream := &rateStream{ctx: context.Background(), rates: make(chan *pb.GetRatesResponse, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.SubscribeRates(&pb.SubscribeRatesRequest{Markets: []string{"streamusdt"}}, stream)
+	}()
+
+	// Quotes saved by other instances are pushed to the subscribers
+	now := time.Now()
+	svc.ObserveRemoteRate(postgres.RateNotification{Market: "streamusdt", Ask: "100.5", Bid: "99.5", Timestamp: &now, Origin: "instance-b"})
+
+	select {
+	case rate := <-stream.rates:
+		assert.Equal(t, "streamusdt", rate.Market)
+		assert.Equal(t, "100.5", rate.Ask)
+	case <-time.After(2 * time.Second):
+		t.Fatal("rate was not streamed")
+	}
+
+	// Open subscriptions end when the server stops
+	handler.StopStreams()
+	select {
+	case err := <-done:
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription did not end")
+	}
+
+	err := handler.SubscribeRates(&pb.SubscribeRatesRequest{Markets: []string{""}}, stream)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/outbox"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeOutboxStore keeps events in memory and mimics ProcessOutbox
+type fakeOutboxStore struct {
+	events    []postgres.OutboxEvent
+	published map[int64]bool
+}
+
+func newFakeOutboxStore(markets ...string) *fakeOutboxStore {
+	store := &fakeOutboxStore{published: make(map[int64]bool)}
+	for i, market := range markets {
+		store.events = append(store.events, postgres.OutboxEvent{
+			ID:        int64(i + 1),
+			Market:    market,
+			EventType: postgres.RateChangedEventType,
+			Payload:   json.RawMessage(`{"market":"` + market + `"}`),
+			CreatedAt: time.Now(),
+		})
+	}
+	return store
+}
+
+func (s *fakeOutboxStore) ProcessOutbox(ctx context.Context, limit int, handle func(context.Context, []postgres.OutboxEvent) []int64) (int, error) {
+	var pending []postgres.OutboxEvent
+	for _, event := range s.events {
+		if !s.published[event.ID] && len(pending) < limit {
+			pending = append(pending, event)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	ids := handle(ctx, pending)
+	for _, id := range ids {
+		s.published[id] = true
+	}
+	return len(ids), nil
+}
+
+func (s *fakeOutboxStore) DeletePublishedOutbox(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// recordingPublisher records published message ids and fails the listed ids once
+type recordingPublisher struct {
+	ids    []int64
+	failOn map[int64]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, msg outbox.Message) error {
+	if p.failOn[msg.ID] {
+		delete(p.failOn, msg.ID)
+		return errors.New("broker unavailable")
+	}
+	p.ids = append(p.ids, msg.ID)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	store := newFakeOutboxStore("usdtrub", "btcusdt", "usdtrub")
+	publisher := &recordingPublisher{}
+	relay := outbox.NewRelay(store, publisher, 10, zap.NewNop())
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []int64{1, 2, 3}, publisher.ids)
+
+	// Nothing left to publish
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestRelay_FailureHoldsBackMarket(t *testing.T) {
+	store := newFakeOutboxStore("usdtrub", "btcusdt", "usdtrub", "btcusdt")
+	publisher := &recordingPublisher{failOn: map[int64]bool{1: true}}
+	relay := outbox.NewRelay(store, publisher, 10, zap.NewNop())
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	// usdtrub events wait for the failed one, other markets continue
+	assert.Equal(t, []int64{2, 4}, publisher.ids)
+
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{2, 4, 1, 3}, publisher.ids)
+}
+
+func TestRelay_RespectsBatchSize(t *testing.T) {
+	store := newFakeOutboxStore("usdtrub", "usdtrub", "usdtrub")
+	publisher := &recordingPublisher{}
+	relay := outbox.NewRelay(store, publisher, 2, zap.NewNop())
+
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, []int64{1, 2}, publisher.ids)
+}
+
+func TestRepository_ProcessOutboxPublishesOutsideTransaction(t *testing.T) {
+	db := newFakeDB("primary")
+	now := time.Now()
+	db.outbox = [][]any{
+		{int64(1), "usdtrub", postgres.RateChangedEventType, json.RawMessage(`{}`), 0, now},
+		{int64(2), "btcusdt", postgres.RateChangedEventType, json.RawMessage(`{}`), 0, now},
+	}
+	repo := postgres.NewRepository(db, zap.NewNop())
+	other := postgres.NewRepository(db, zap.NewNop())
+	ctx := context.Background()
+
+	var seen []string
+	published, err := repo.ProcessOutbox(ctx, 10, func(ctx context.Context, events []postgres.OutboxEvent) []int64 {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok, "publishing stops before the lease runs out")
+		seen = db.queries()
+		return []int64{events[0].ID}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	// Events of still open transactions are not read, and nothing is
+	// locked while the events are published
+	require.Len(t, seen, 2)
+	assert.Contains(t, seen[0], "outbox_lease")
+	assert.Contains(t, seen[1], "pg_snapshot_xmin(pg_current_snapshot())")
+
+	queries := db.queries()[len(seen):]
+	require.Len(t, queries, 4)
+	assert.Equal(t, "BEGIN", queries[0])
+	assert.Contains(t, queries[1], "published_at = NOW()")
+	assert.Contains(t, queries[2], "attempts = attempts + 1")
+	assert.Equal(t, "COMMIT", queries[3])
+
+	// Another relay waits while the lease is held
+	published, err = other.ProcessOutbox(ctx, 10, func(context.Context, []postgres.OutboxEvent) []int64 {
+		t.Error("events are handled without the lease")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func TestWriterPublisher_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := outbox.NewWriterPublisher(&buf)
+
+	require.NoError(t, publisher.Publish(context.Background(), outbox.Message{
+		ID: 1, Key: "usdtrub", Type: postgres.RateChangedEventType, Payload: json.RawMessage(`{"ask":"95.5"}`),
+	}))
+	require.NoError(t, publisher.Publish(context.Background(), outbox.Message{
+		ID: 2, Key: "usdtrub", Type: postgres.RateChangedEventType, Payload: json.RawMessage(`{"ask":"95.6"}`),
+	}))
+
+	scanner := bufio.NewScanner(&buf)
+	var messages []outbox.Message
+	for scanner.Scan() {
+		var msg outbox.Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		messages = append(messages, msg)
+	}
+
+	require.Len(t, messages, 2)
+	assert.Equal(t, int64(2), messages[1].ID)
+	assert.Equal(t, "usdtrub", messages[1].Key)
+	assert.JSONEq(t, `{"ask":"95.6"}`, string(messages[1].Payload))
+}
+
+func TestFilePublisher_AppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	publisher, err := outbox.NewFilePublisher(path)
+	require.NoError(t, err)
+
+	require.NoError(t, publisher.Publish(context.Background(), outbox.Message{ID: 1, Key: "usdtrub", Payload: json.RawMessage(`{}`)}))
+	require.NoError(t, publisher.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"key":"usdtrub"`)
+}
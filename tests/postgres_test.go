@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+func TestRepository_StatementTimeouts(t *testing.T) {
+	db := newFakeDB("primary")
+	repo := postgres.NewRepository(db, zap.NewNop())
+	ctx := context.Background()
+
+	_, err := repo.GetRates(ctx, "usdtrub", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(-1), db.lastDeadline(), "no timeout is set by default")
+
+	repo.SetStatementTimeouts(postgres.StatementTimeouts{Read: 2 * time.Second, Write: time.Minute})
+
+	_, err = repo.GetRates(ctx, "usdtrub", 10, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 2*time.Second, db.lastDeadline(), float64(100*time.Millisecond))
+
+	_, err = repo.GetLatestRate(ctx, "usdtrub")
+	require.NoError(t, err)
+	assert.InDelta(t, 2*time.Second, db.lastDeadline(), float64(100*time.Millisecond))
+
+	// A shorter deadline of the caller is kept
+	short, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	_, err = repo.GetRates(short, "usdtrub", 10, 0)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, db.lastDeadline(), 500*time.Millisecond)
+}
+
+func TestPool_Metrics(t *testing.T) {
+	// The pool does not connect until it is used
+	pool, err := postgres.NewPool("host=127.0.0.1 port=1 user=rates dbname=rates sslmode=disable",
+		postgres.PoolConfig{Name: "metrics-test", MaxConns: 7, MaxConnLifetime: time.Minute, MaxConnIdleTime: time.Minute})
+	require.NoError(t, err)
+
+	labels := map[string]string{"pool": "metrics-test"}
+	assert.Equal(t, 7.0, metricValue(t, "rates_db_pool_max_connections", labels))
+	assert.Equal(t, 0.0, metricValue(t, "rates_db_pool_acquired_connections", labels))
+
+	pool.Close()
+	assert.Equal(t, 0.0, metricValue(t, "rates_db_pool_max_connections", labels), "closed pools are not exported")
+}
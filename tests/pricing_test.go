@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/pricing"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newPricingEngine(t *testing.T, rules ...pricing.Rule) *pricing.Engine {
+	precision := func(market string) (int, bool) {
+		if market == "usdtrub" {
+			return 2, true
+		}
+		return 0, false
+	}
+	engine := pricing.NewEngine(pricing.StaticSource(rules), precision, zap.NewNop())
+	require.NoError(t, engine.Reload(context.Background()))
+	return engine
+}
+
+func TestPricingEngine_Apply(t *testing.T) {
+	engine := newPricingEngine(t,
+		pricing.Rule{ID: "default", Version: 1, SpreadPercent: "1"},
+		pricing.Rule{ID: "rub-retail", Version: 3, Market: "usdtrub", ClientTier: "retail", SpreadPercent: "2", FixedFee: "0.1"},
+		pricing.Rule{ID: "rub-vip", Version: 1, Market: "usdtrub", ClientTier: "vip", SpreadPercent: "0.5", MinPrice: "95", MaxPrice: "100"},
+	)
+
+	tests := []struct {
+		name            string
+		market          string
+		tier            string
+		ask             string
+		bid             string
+		expectedAsk     string
+		expectedBid     string
+		expectedRuleID  string
+		expectedVersion int
+	}{
+		{
+			name:            "market and tier rule with fixed fee",
+			market:          "usdtrub",
+			tier:            "retail",
+			ask:             "95.5",
+			bid:             "95.3",
+			expectedAsk:     "97.51", // 95.5 * 1.02 + 0.1 = 97.51
+			expectedBid:     "93.29", // 95.3 * 0.98 - 0.1 = 93.294, rounded down
+			expectedRuleID:  "rub-retail",
+			expectedVersion: 3,
+		},
+		{
+			name:            "clamped to min and max price",
+			market:          "usdtrub",
+			tier:            "vip",
+			ask:             "99.9",
+			bid:             "95.1",
+			expectedAsk:     "100.00",
+			expectedBid:     "95.00",
+			expectedRuleID:  "rub-vip",
+			expectedVersion: 1,
+		},
+		{
+			name:            "fee above the bid floors it at zero",
+			market:          "usdtrub",
+			tier:            "retail",
+			ask:             "0.05",
+			bid:             "0.05",
+			expectedAsk:     "0.16",
+			expectedBid:     "0.00",
+			expectedRuleID:  "rub-retail",
+			expectedVersion: 3,
+		},
+		{
+			name:            "wildcard rule with default precision",
+			market:          "btcusdt",
+			tier:            "retail",
+			ask:             "60000",
+			bid:             "59990",
+			expectedAsk:     "60600.00000000",
+			expectedBid:     "59390.10000000",
+			expectedRuleID:  "default",
+			expectedVersion: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate := &client.RateData{Market: tt.market, Ask: tt.ask, Bid: tt.bid}
+
+			engine.Apply(rate, tt.tier)
+
+			assert.Equal(t, tt.ask, rate.Ask)
+			assert.Equal(t, tt.bid, rate.Bid)
+			assert.Equal(t, tt.expectedAsk, rate.CustomerAsk)
+			assert.Equal(t, tt.expectedBid, rate.CustomerBid)
+			assert.Equal(t, tt.expectedRuleID, rate.PricingRuleID)
+			assert.Equal(t, tt.expectedVersion, rate.PricingRuleVersion)
+		})
+	}
+}
+
+func TestPricingEngine_NoMatchingRule(t *testing.T) {
+	engine := newPricingEngine(t, pricing.Rule{ID: "vip", ClientTier: "vip", SpreadPercent: "1"})
+
+	rate := &client.RateData{Market: "usdtrub", Ask: "95.5", Bid: "N/A"}
+	engine.Apply(rate, "retail")
+
+	assert.Equal(t, "95.5", rate.CustomerAsk)
+	assert.Equal(t, "N/A", rate.CustomerBid)
+	assert.Empty(t, rate.PricingRuleID)
+}
+
+func TestPricingEngine_InvalidRulesKeepPrevious(t *testing.T) {
+	engine := newPricingEngine(t, pricing.Rule{ID: "default", SpreadPercent: "1"})
+
+	broken := []pricing.Rule{{ID: "broken", SpreadPercent: "abc"}}
+	assert.Error(t, pricing.ValidateRules(broken), "reloads validate rules before changing anything")
+	assert.NoError(t, pricing.ValidateRules([]pricing.Rule{{ID: "default", SpreadPercent: "1"}}))
+	for _, rule := range []pricing.Rule{
+		{ID: "negative-spread", SpreadPercent: "-1"},
+		{ID: "full-spread", SpreadPercent: "100"},
+		{ID: "negative-fee", FixedFee: "-0.1"},
+	} {
+		assert.Error(t, pricing.ValidateRules([]pricing.Rule{rule}), rule.ID)
+	}
+
+	err := engine.SetRules(broken)
+	assert.Error(t, err)
+
+	rate := &client.RateData{Market: "usdtrub", Ask: "100", Bid: "100"}
+	engine.Apply(rate, "")
+	assert.Equal(t, "default", rate.PricingRuleID)
+}
+
+func TestRatesService_GetRates_Pricing(t *testing.T) {
+	// Setup mocks
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+	mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{
+		Market: "usdtrub", Ask: "100", Bid: "99", Timestamp: time.Now(),
+	}, nil)
+	mockRepo.On("SaveRate", mock.Anything, "usdtrub", "100", "99", mock.Anything, mock.Anything).Return(nil)
+
+	// Create service
+	engine := newPricingEngine(t, pricing.Rule{ID: "retail", ClientTier: "retail", SpreadPercent: "1"})
+	s := service.NewRatesService(mockGrinex, mockRepo, zap.NewNop(), service.WithPricing(engine))
+
+	// Execute
+	rateData, err := s.GetRates(context.Background(), "usdtrub", service.WithClientTier("retail"))
+
+	// Assert: raw prices are stored, customer prices are returned alongside
+	require.NoError(t, err)
+	assert.Equal(t, "100", rateData.Ask)
+	assert.Equal(t, "101.00", rateData.CustomerAsk)
+	assert.Equal(t, "98.01", rateData.CustomerBid)
+	assert.Equal(t, "retail", rateData.PricingRuleID)
+
+	// Verify mock expectations
+	mockGrinex.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/storage/postgres"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MockQuoteRepository is a mock implementation of QuoteRepository
+type MockQuoteRepository struct {
+	mock.Mock
+}
+
+func (m *MockQuoteRepository) SaveQuote(ctx context.Context, quote *postgres.LockedQuote) error {
+	args := m.Called(ctx, quote)
+	return args.Error(0)
+}
+
+func (m *MockQuoteRepository) GetQuote(ctx context.Context, id string) (*postgres.LockedQuote, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*postgres.LockedQuote), args.Error(1)
+}
+
+func (m *MockQuoteRepository) RedeemQuote(ctx context.Context, id string) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockQuoteRepository) DeleteExpiredQuotes(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// newQuoteFixture creates a quote service and a quote stored the way the database returns it
+func newQuoteFixture(t *testing.T) (*service.QuoteService, *MockQuoteRepository, *service.Quote, *postgres.LockedQuote) {
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+	mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{
+		Market: "usdtrub", Ask: "95.5", Bid: "95.3", Timestamp: time.Now(),
+	}, nil)
+	mockRepo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything, mock.Anything).Return(nil)
+
+	logger := zap.NewNop()
+	rates := service.NewRatesService(mockGrinex, mockRepo, logger)
+	quoteRepo := new(MockQuoteRepository)
+	signer := service.NewQuoteSigner([]byte("secret"))
+	quotes := service.NewQuoteService(rates, quoteRepo, signer, time.Minute, logger)
+
+	var stored *postgres.LockedQuote
+	quoteRepo.On("SaveQuote", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*postgres.LockedQuote)
+	}).Return(nil).Once()
+
+	quote, err := quotes.CreateQuote(context.Background(), "usdtrub", service.QuoteSideBuy, "10", "")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+
+	// The database returns decimals with its own scale
+	record := *stored
+	record.Amount = "10.00000000"
+	record.Price = "95.50000000"
+
+	return quotes, quoteRepo, quote, &record
+}
+
+func TestQuoteService_CreateQuote(t *testing.T) {
+	_, _, quote, _ := newQuoteFixture(t)
+
+	assert.NotEmpty(t, quote.ID)
+	assert.Equal(t, "95.5", quote.Price)
+	assert.Equal(t, "955.00000000", quote.Total)
+	assert.NotEmpty(t, quote.Token)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), quote.ExpiresAt, time.Second)
+}
+
+func TestQuoteService_CreateQuote_InvalidRequest(t *testing.T) {
+	logger := zap.NewNop()
+	quotes := service.NewQuoteService(nil, new(MockQuoteRepository), service.NewQuoteSigner([]byte("secret")), time.Minute, logger)
+
+	_, err := quotes.CreateQuote(context.Background(), "usdtrub", "hold", "10", "")
+	assert.True(t, errors.Is(err, service.ErrInvalidQuoteRequest))
+
+	_, err = quotes.CreateQuote(context.Background(), "usdtrub", service.QuoteSideSell, "-1", "")
+	assert.True(t, errors.Is(err, service.ErrInvalidQuoteRequest))
+
+	// Amounts the DECIMAL(30, 8) column cannot store exactly are refused
+	for _, amount := range []string{"1.123456789", "1/3", "0", "1e3", "12345678901234567890123"} {
+		_, err = quotes.CreateQuote(context.Background(), "usdtrub", service.QuoteSideBuy, amount, "")
+		assert.True(t, errors.Is(err, service.ErrInvalidQuoteRequest), amount)
+	}
+}
+
+func TestQuoteService_CreateQuote_RoundsPriceToColumnScale(t *testing.T) {
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+	mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{
+		Market: "usdtrub", Ask: "95.123456781", Bid: "95.123456789", Timestamp: time.Now(),
+	}, nil)
+	mockRepo.On("SaveRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	logger := zap.NewNop()
+	rates := service.NewRatesService(mockGrinex, mockRepo, logger)
+	quoteRepo := new(MockQuoteRepository)
+	quoteRepo.On("SaveQuote", mock.Anything, mock.Anything).Return(nil)
+	quoteRepo.On("RedeemQuote", mock.Anything, mock.Anything).Return(true, nil)
+	quotes := service.NewQuoteService(rates, quoteRepo, service.NewQuoteSigner([]byte("secret")), time.Minute, logger)
+
+	buy, err := quotes.CreateQuote(context.Background(), "usdtrub", service.QuoteSideBuy, "2", "")
+	require.NoError(t, err)
+	assert.Equal(t, "95.12345679", buy.Price)
+	assert.Equal(t, "190.24691358", buy.Total)
+
+	sell, err := quotes.CreateQuote(context.Background(), "usdtrub", service.QuoteSideSell, "2", "")
+	require.NoError(t, err)
+	assert.Equal(t, "95.12345678", sell.Price)
+
+	// The price the column stores redeems
+	stored := quoteRepo.Calls[0].Arguments.Get(1).(*postgres.LockedQuote)
+	assert.Equal(t, buy.Price, stored.Price)
+	quoteRepo.On("GetQuote", mock.Anything, buy.ID).Return(stored, nil)
+	_, status, err := quotes.RedeemQuote(context.Background(), buy.ID, buy.Token)
+	require.NoError(t, err)
+	assert.Equal(t, service.QuoteStatusValid, status)
+}
+
+func TestQuoteService_RedeemQuote(t *testing.T) {
+	tests := []struct {
+		name           string
+		setup          func(record *postgres.LockedQuote, repo *MockQuoteRepository)
+		token          func(token string) string
+		expectedStatus service.QuoteStatus
+	}{
+		{
+			name: "valid quote",
+			setup: func(record *postgres.LockedQuote, repo *MockQuoteRepository) {
+				repo.On("RedeemQuote", mock.Anything, record.ID).Return(true, nil)
+			},
+			expectedStatus: service.QuoteStatusValid,
+		},
+		{
+			name: "concurrent redemption wins",
+			setup: func(record *postgres.LockedQuote, repo *MockQuoteRepository) {
+				repo.On("RedeemQuote", mock.Anything, record.ID).Return(false, nil)
+			},
+			expectedStatus: service.QuoteStatusUsed,
+		},
+		{
+			name: "already used",
+			setup: func(record *postgres.LockedQuote, repo *MockQuoteRepository) {
+				record.RedeemedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			},
+			expectedStatus: service.QuoteStatusUsed,
+		},
+		{
+			name: "tampered price",
+			setup: func(record *postgres.LockedQuote, repo *MockQuoteRepository) {
+				record.Price = "90"
+			},
+			expectedStatus: service.QuoteStatusInvalid,
+		},
+		{
+			name:  "forged signature",
+			setup: func(record *postgres.LockedQuote, repo *MockQuoteRepository) {},
+			token: func(token string) string {
+				payload, _, _ := strings.Cut(token, ".")
+				return payload + ".AAAA"
+			},
+			expectedStatus: service.QuoteStatusInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes, repo, quote, record := newQuoteFixture(t)
+			tt.setup(record, repo)
+			repo.On("GetQuote", mock.Anything, quote.ID).Return(record, nil)
+
+			token := quote.Token
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+
+			redeemed, quoteStatus, err := quotes.RedeemQuote(context.Background(), quote.ID, token)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, quoteStatus)
+			assert.Equal(t, quote.ID, redeemed.ID)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQuoteSigner_SetKey(t *testing.T) {
+	signer := service.NewQuoteSigner([]byte("old-key"))
+	quote := &service.Quote{
+		ID:        "rotated",
+		Market:    "usdtrub",
+		Side:      service.QuoteSideBuy,
+		Amount:    "1",
+		Price:     "95.3",
+		ExpiresAt: time.Now().Add(time.Minute).Truncate(time.Millisecond),
+	}
+	oldToken, err := signer.Sign(quote)
+	require.NoError(t, err)
+
+	signer.SetKey([]byte("new-key"))
+	newToken, err := signer.Sign(quote)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldToken, newToken)
+
+	// Quotes issued before the rotation can still be redeemed
+	assert.NoError(t, signer.Verify(oldToken, quote))
+	assert.NoError(t, signer.Verify(newToken, quote))
+
+	signer.SetKey([]byte("newest-key"))
+	assert.ErrorIs(t, signer.Verify(oldToken, quote), service.ErrInvalidQuoteToken)
+	assert.NoError(t, signer.Verify(newToken, quote))
+}
+
+func TestQuoteService_RedeemQuote_Expired(t *testing.T) {
+	logger := zap.NewNop()
+	repo := new(MockQuoteRepository)
+	signer := service.NewQuoteSigner([]byte("secret"))
+	quotes := service.NewQuoteService(nil, repo, signer, time.Minute, logger)
+
+	quote := &service.Quote{
+		ID:        "expired",
+		Market:    "usdtrub",
+		Side:      service.QuoteSideSell,
+		Amount:    "1",
+		Price:     "95.3",
+		ExpiresAt: time.Now().Add(-time.Second).Truncate(time.Millisecond),
+	}
+	token, err := signer.Sign(quote)
+	require.NoError(t, err)
+
+	repo.On("GetQuote", mock.Anything, "expired").Return(&postgres.LockedQuote{
+		ID: quote.ID, Market: quote.Market, Side: quote.Side, Amount: quote.Amount,
+		Price: quote.Price, ExpiresAt: quote.ExpiresAt,
+	}, nil)
+
+	_, quoteStatus, err := quotes.RedeemQuote(context.Background(), "expired", token)
+
+	require.NoError(t, err)
+	assert.Equal(t, service.QuoteStatusExpired, quoteStatus)
+	repo.AssertNotCalled(t, "RedeemQuote", mock.Anything, mock.Anything)
+}
+
+func TestQuoteService_RedeemQuote_NotFound(t *testing.T) {
+	repo := new(MockQuoteRepository)
+	repo.On("GetQuote", mock.Anything, "missing").Return(nil, nil)
+	quotes := service.NewQuoteService(nil, repo, service.NewQuoteSigner([]byte("secret")), time.Minute, zap.NewNop())
+
+	_, _, err := quotes.RedeemQuote(context.Background(), "missing", "token")
+
+	assert.True(t, errors.Is(err, service.ErrQuoteNotFound))
+}
+
+func TestRatesHandler_LockedQuotesDisabled(t *testing.T) {
+	handler := grpc.NewRatesHandler(new(MockRatesService), zap.NewNop(), "1.0.0")
+
+	_, err := handler.CreateLockedQuote(context.Background(), &pb.CreateLockedQuoteRequest{Market: "usdtrub"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, err = handler.RedeemQuote(context.Background(), &pb.RedeemQuoteRequest{QuoteId: "id", Token: "token"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
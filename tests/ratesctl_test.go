@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/export"
+	"github.com/alik/TestForWork/internal/ratesctl"
+	"github.com/alik/TestForWork/internal/service"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ratesctlServer runs a RatesService server and records the metadata of the last call
+type ratesctlServer struct {
+	addr     string
+	rates    *MockRatesService
+	metadata metadata.MD
+}
+
+func startRatesctlServer(t *testing.T) *ratesctlServer {
+	s := &ratesctlServer{rates: new(MockRatesService)}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.addr = listener.Addr().String()
+
+	handler := grpc.NewRatesHandler(s.rates, zap.NewNop(), "1.2.3",
+		grpc.WithExporter(export.New(newExportStore(), zap.NewNop())))
+	server := grpclib.NewServer(grpclib.UnaryInterceptor(
+		func(ctx context.Context, req any, _ *grpclib.UnaryServerInfo, next grpclib.UnaryHandler) (any, error) {
+			s.metadata, _ = metadata.FromIncomingContext(ctx)
+			return next(ctx, req)
+		}))
+	pb.RegisterRatesServiceServer(server, handler)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	// Keep the test independent of the user's ratesctl config
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("RATESCTL_CONFIG", "")
+	t.Setenv("RATESCTL_PROFILE", "")
+
+	return s
+}
+
+func (s *ratesctlServer) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := ratesctl.Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func ratesctlRate(market, ask, bid string) *client.RateData {
+	return &client.RateData{
+		Market:     market,
+		Ask:        ask,
+		Bid:        bid,
+		Timestamp:  exportStart,
+		ReceivedAt: exportStart,
+	}
+}
+
+func TestRatesctl_Get(t *testing.T) {
+	s := startRatesctlServer(t)
+	s.rates.On("GetRates", mock.Anything, "usdtrub").Return(ratesctlRate("usdtrub", "95.5", "95.3"), nil)
+
+	code, stdout, stderr := s.run("get", "usdtrub", "-a", s.addr)
+
+	require.Equal(t, 0, code, stderr)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"MARKET", "ASK", "BID", "CUSTOMER_ASK", "CUSTOMER_BID", "TIMESTAMP", "AGE", "ERROR"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"usdtrub", "95.5", "95.3", "-", "-", "2026-01-01T00:00:00Z"}, strings.Fields(lines[1])[:6])
+}
+
+func TestRatesctl_GetBatchJSON(t *testing.T) {
+	s := startRatesctlServer(t)
+	s.rates.On("BatchGetRates", mock.Anything, []string{"usdtrub", "eurrub"}).Return([]service.MarketResult{
+		{Market: "usdtrub", Rate: ratesctlRate("usdtrub", "95.5", "95.3")},
+		{Market: "eurrub", Err: service.ErrUnknownMarket},
+	})
+
+	code, stdout, stderr := s.run("get", "usdtrub", "eurrub", "-a", s.addr, "-o", "json")
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "1 of 2 markets failed")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], `{"market":"usdtrub","ask":"95.5","bid":"95.3",`), lines[0])
+	assert.Contains(t, lines[1], `"error":"NotFound: market not found"`)
+}
+
+func TestRatesctl_History(t *testing.T) {
+	s := startRatesctlServer(t)
+
+	code, stdout, stderr := s.run("history", "usdtrub", "-a", s.addr, "-o", "csv",
+		"--from", "2026-01-01", "--to", "2026-01-01T00:02:00Z")
+
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "market,ask,bid,timestamp,received_at,source\n"+
+		"usdtrub,95.50000000,95.30000000,2026-01-01T00:00:10Z,2026-01-01T00:00:10.15Z,grinex\n"+
+		"usdtrub,95.60000000,95.40000000,2026-01-01T00:00:50Z,2026-01-01T00:00:50.15Z,grinex\n", stdout)
+
+	code, _, stderr = s.run("history", "usdtrub", "-a", s.addr, "--from", "yesterday")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "invalid --from")
+}
+
+func TestRatesctl_Candles(t *testing.T) {
+	s := startRatesctlServer(t)
+
+	code, stdout, stderr := s.run("candles", "usdtrub", "-a", s.addr, "-o", "csv", "--interval", "2m", "--field", "ask")
+
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "time,open,high,low,close,count\n"+
+		"2026-01-01T00:00:00Z,95.5,95.6,95.5,95.6,2\n"+
+		"2026-01-01T00:02:00Z,95.7,95.7,95.7,95.7,1\n", stdout)
+
+	code, stdout, stderr = s.run("candles", "usdtrub", "-a", s.addr, "-o", "csv", "--interval", "1h")
+	require.Equal(t, 0, code, stderr)
+	assert.Equal(t, "time,open,high,low,close,count\n"+
+		"2026-01-01T00:00:00Z,95.4,95.6,95.4,95.6,3\n", stdout)
+}
+
+func TestRatesctl_Health(t *testing.T) {
+	s := startRatesctlServer(t)
+	s.rates.On("HealthCheck", mock.Anything).Return(errors.New("database is down")).Once()
+	s.rates.On("HealthCheck", mock.Anything).Return(nil)
+
+	code, stdout, stderr := s.run("health", "-a", s.addr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "unhealthy")
+	assert.Contains(t, stderr, "service is unhealthy")
+
+	code, stdout, _ = s.run("health", "-a", s.addr, "-o", "json")
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(stdout, `{"status":"healthy","version":"1.2.3",`), stdout)
+}
+
+func TestRatesctl_Profiles(t *testing.T) {
+	s := startRatesctlServer(t)
+	s.rates.On("HealthCheck", mock.Anything).Return(nil)
+
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`current: local
+profiles:
+  local:
+    address: `+s.addr+`
+    output: csv
+    timeout: 5s
+    headers:
+      x-team: finance
+  prod:
+    address: rates.example.com:443
+    tls: true
+    token: secret
+`), 0o644))
+
+	// The current profile is used by default
+	code, stdout, stderr := s.run("health", "--config", config, "-H", "x-request-id=42")
+	require.Equal(t, 0, code, stderr)
+	assert.True(t, strings.HasPrefix(stdout, "status,version,timestamp\nhealthy,1.2.3,"), stdout)
+	assert.Equal(t, []string{"finance"}, s.metadata.Get("x-team"))
+	assert.Equal(t, []string{"42"}, s.metadata.Get("x-request-id"))
+
+	// Flags override the profile
+	code, stdout, _ = s.run("health", "--config", config, "-o", "json")
+	require.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(stdout, `{"status":"healthy"`), stdout)
+
+	// RATESCTL_PROFILE selects a profile, unknown profiles are rejected
+	t.Setenv("RATESCTL_PROFILE", "staging")
+	code, _, stderr = s.run("health", "--config", config)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `unknown profile "staging"`)
+
+	// A token is never sent without TLS
+	code, _, stderr = s.run("health", "--config", config, "--profile", "prod", "--tls=false")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "only sent over TLS")
+
+	// An explicit config file must exist
+	code, _, stderr = s.run("health", "--config", filepath.Join(t.TempDir(), "none.yaml"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "failed to read config")
+}
+
+func TestRatesctl_Usage(t *testing.T) {
+	s := startRatesctlServer(t)
+
+	code, _, stderr := s.run("rates")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `Unknown command "rates"`)
+
+	code, _, stderr = s.run("get", "-a", s.addr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "at least one market is required")
+
+	code, _, stderr = s.run("get", "usdtrub", "-a", s.addr, "-o", "xml")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown output format")
+
+	code, _, stderr = s.run("quote", "usdtrub", "-a", s.addr, "--side", "hold")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "--side must be buy or sell")
+
+	code, _, _ = s.run("help")
+	assert.Equal(t, 0, code)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/schedule"
+	"github.com/alik/TestForWork/internal/service"
+)
+
+func TestConfigDiff(t *testing.T) {
+	old := validConfig()
+	next := validConfig()
+	next.Logging.Level = "debug"
+	next.Quotes.SigningKey = "rotated"
+	next.Server.Port = 9090
+	next.Catalog.Markets = append(next.Catalog.Markets, config.MarketConfig{ID: "btcusdt", Base: "btc", Quote: "usdt"})
+
+	changes := config.Diff(old, next)
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, "catalog.markets", changes[0].Key)
+	assert.True(t, config.IsReloadable(changes[0].Key))
+
+	assert.Equal(t, `logging.level: "info" -> "debug"`, changes[1].String())
+	assert.True(t, config.IsReloadable(changes[1].Key))
+
+	// Secrets are reported as changed without their values
+	assert.Equal(t, `quotes.signing_key: "" -> "[REDACTED]"`, changes[2].String())
+	assert.True(t, config.IsReloadable(changes[2].Key))
+
+	assert.Equal(t, "server.port: 8080 -> 9090", changes[3].String())
+	assert.False(t, config.IsReloadable(changes[3].Key))
+
+	assert.Empty(t, config.Diff(old, validConfig()))
+}
+
+func TestConfigKeepFixed(t *testing.T) {
+	old := validConfig()
+	next := validConfig()
+	next.Logging.Level = "debug"
+	next.Server.Port = 9090
+	next.Database.Port = 6432
+
+	kept := config.KeepFixed(old, next, config.Diff(old, next))
+
+	assert.Equal(t, "debug", kept.Logging.Level)
+	assert.Equal(t, 8080, kept.Server.Port)
+	assert.Equal(t, 5432, kept.Database.Port)
+	assert.Equal(t, 9090, next.Server.Port, "next is not modified")
+
+	changes := config.Diff(old, kept)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "logging.level", changes[0].Key)
+}
+
+func TestInterval_Set(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A job without an interval waits until one is set
+	interval := schedule.NewInterval(0)
+	var runs atomic.Int32
+	go interval.Run(ctx, func(context.Context) { runs.Add(1) })
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, runs.Load())
+
+	interval.Set(10 * time.Millisecond)
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 5*time.Millisecond)
+
+	// A longer interval applies right away
+	interval.Set(time.Hour)
+	time.Sleep(30 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+}
+
+func TestMarketCatalog_SetStaticMarkets(t *testing.T) {
+	catalog := service.NewMarketCatalog(nil, []service.Market{{ID: "usdtrub"}}, zap.NewNop())
+
+	catalog.SetStaticMarkets([]service.Market{{ID: "btcusdt"}})
+
+	_, ok := catalog.Lookup("usdtrub")
+	assert.False(t, ok)
+	market, ok := catalog.Lookup("btcusdt")
+	require.True(t, ok)
+	assert.Equal(t, service.MarketStatusActive, market.Status)
+}
+
+func TestConfigWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: info\n"), 0o600))
+
+	viper.SetConfigFile(path)
+	t.Cleanup(viper.Reset)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan struct{}, 10)
+	watching, err := config.Watch(ctx, func() { changed <- struct{}{} }, func(err error) { t.Error(err) })
+	require.NoError(t, err)
+	require.True(t, watching)
+
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: debug\n"), 0o600))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not noticed")
+	}
+
+	// Bursts of writes are reported once
+	select {
+	case <-changed:
+		t.Fatal("config change reported twice")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
@@ -0,0 +1,296 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/fakeexchange"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// quoteAge matches the age reported for stale quotes, which grows while the test runs
+var quoteAge = regexp.MustCompile(`quote is (\S+) old`)
+
+// stableAge rounds reported quote ages to seconds
+func stableAge(s string) string {
+	return quoteAge.ReplaceAllStringFunc(s, func(m string) string {
+		age, err := time.ParseDuration(quoteAge.FindStringSubmatch(m)[1])
+		if err != nil {
+			return m
+		}
+		return fmt.Sprintf("quote is %s old", age.Round(time.Second))
+	})
+}
+
+// replayOutcome describes a GetRates result in a stable form
+func replayOutcome(rate *client.RateData, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	lag := "none"
+	if !rate.Timestamp.IsZero() {
+		lag = rate.ReceivedAt.Sub(rate.Timestamp).String()
+	}
+	return fmt.Sprintf("ask=%s bid=%s lag=%s", rate.Ask, rate.Bid, lag)
+}
+
+// recordScenario runs the steps against a fake exchange through a recording client
+// and returns the outcomes
+func recordScenario(t *testing.T, steps []fakeexchange.Step, recorder *client.Recorder) []string {
+	cfg := fakeexchange.DefaultConfig()
+	cfg.Markets[0].Scenario = steps
+	exchange, err := fakeexchange.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+	server := httptest.NewServer(exchange)
+	defer server.Close()
+
+	c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, zap.NewNop(), client.WithRecorder(recorder))
+
+	outcomes := make([]string, len(steps))
+	for i := range steps {
+		outcomes[i] = replayOutcome(c.GetRates(context.Background(), "usdtrub"))
+	}
+	return outcomes
+}
+
+var recordedSteps = []fakeexchange.Step{
+	{Ask: "95.60", Bid: "95.40", TimestampOffset: -2 * time.Second},
+	{Status: http.StatusTooManyRequests},
+	{Malformed: true},
+	{Empty: true},
+	{Ask: "95.50", Bid: "95.70", NoTimestamp: true},
+}
+
+func TestRecordReplay_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	recorder, err := client.NewRecorder(&buf)
+	require.NoError(t, err)
+
+	recorded := recordScenario(t, recordedSteps, recorder)
+	require.NoError(t, recorder.Close())
+
+	replay, err := client.NewReplayClient(&buf, zap.NewNop(), client.WithReplaySpeed(0), client.WithOriginalTimes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"usdtrub"}, replay.Markets())
+
+	for i, expected := range recorded {
+		rate, err := replay.GetRates(context.Background(), "usdtrub")
+		actual := replayOutcome(rate, err)
+		if strings.HasPrefix(expected, "ask=") {
+			// The exchange timestamp has second precision, the lag is only roughly kept
+			expected, _, _ = strings.Cut(expected, " lag=")
+			actual, _, _ = strings.Cut(actual, " lag=")
+		}
+		assert.Equal(t, expected, actual, "response %d", i)
+	}
+
+	_, err = replay.GetRates(context.Background(), "usdtrub")
+	assert.ErrorIs(t, err, client.ErrReplayExhausted)
+
+	_, err = replay.GetRates(context.Background(), "btcusdt")
+	assert.Error(t, err)
+}
+
+func TestRecordReplay_CompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "depth.jsonl.gz")
+	recorder, err := client.CreateRecording(path)
+	require.NoError(t, err)
+
+	recorded := recordScenario(t, recordedSteps[:1], recorder)
+	require.NoError(t, recorder.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, data[:2])
+
+	replay, err := client.OpenReplay(path, zap.NewNop(), client.WithReplaySpeed(0))
+	require.NoError(t, err)
+
+	rate, err := replay.GetRates(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(recorded[0], fmt.Sprintf("ask=%s bid=%s", rate.Ask, rate.Bid)))
+}
+
+func TestCreateRecording_KeepsExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "depth.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("earlier recording\n"), 0o644))
+
+	_, err := client.CreateRecording(path)
+	assert.Error(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "earlier recording\n", string(data))
+
+	recorder, err := client.CreateRecording(filepath.Join(t.TempDir(), "closed.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+	assert.Error(t, recorder.Record("usdtrub", 200, []byte("{}"), nil, time.Now(), time.Millisecond),
+		"responses are not written after close")
+}
+
+func TestRecordingPath(t *testing.T) {
+	at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "/var/rec/depth-20261018T120000Z.jsonl.gz", client.RecordingPath("/var/rec/depth.jsonl.gz", at))
+	assert.Equal(t, "depth-20261018T120000Z.jsonl", client.RecordingPath("depth.jsonl", at))
+	assert.Equal(t, "depth-20261018T120000Z", client.RecordingPath("depth", at))
+}
+
+func TestReplayClient_Timing(t *testing.T) {
+	const recording = `{"version":1,"started_at":"2026-10-01T12:00:00Z"}
+{"t":0,"m":"usdtrub","s":200,"b":{"timestamp":1790856000,"asks":[{"price":"95.5"}],"bids":[{"price":"95.3"}]}}
+{"t":300,"m":"usdtrub","s":200,"b":{"timestamp":1790856000,"asks":[{"price":"95.6"}],"bids":[{"price":"95.4"}]}}
+`
+	recordedAt := time.Date(2026, 10, 1, 12, 0, 0, 300*int(time.Millisecond), time.UTC)
+
+	t.Run("original pace", func(t *testing.T) {
+		replay, err := client.NewReplayClient(strings.NewReader(recording), zap.NewNop())
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = replay.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+		rate, err := replay.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+		assert.Equal(t, "95.6", rate.Ask)
+		// Times are shifted to the replay, keeping the recorded lag
+		assert.WithinDuration(t, time.Now(), rate.ReceivedAt, time.Second)
+		assert.Equal(t, 300*time.Millisecond, rate.ReceivedAt.Sub(rate.Timestamp))
+	})
+
+	t.Run("accelerated", func(t *testing.T) {
+		replay, err := client.NewReplayClient(strings.NewReader(recording), zap.NewNop(),
+			client.WithReplaySpeed(10), client.WithOriginalTimes())
+		require.NoError(t, err)
+
+		start := time.Now()
+		_, err = replay.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+		rate, err := replay.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+
+		elapsed := time.Since(start)
+		assert.GreaterOrEqual(t, elapsed, 30*time.Millisecond)
+		assert.Less(t, elapsed, 250*time.Millisecond)
+		assert.True(t, recordedAt.Equal(rate.ReceivedAt))
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		replay, err := client.NewReplayClient(strings.NewReader(recording), zap.NewNop(),
+			client.WithReplaySpeed(0), client.WithReplayLoop())
+		require.NoError(t, err)
+
+		var asks []string
+		for i := 0; i < 5; i++ {
+			rate, err := replay.GetRates(context.Background(), "usdtrub")
+			require.NoError(t, err)
+			asks = append(asks, rate.Ask)
+		}
+		assert.Equal(t, []string{"95.5", "95.6", "95.5", "95.6", "95.5"}, asks)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		replay, err := client.NewReplayClient(strings.NewReader(recording), zap.NewNop(), client.WithReplaySpeed(0.001))
+		require.NoError(t, err)
+		_, err = replay.GetRates(context.Background(), "usdtrub")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = replay.GetRates(ctx, "usdtrub")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestReplayClient_InvalidRecording(t *testing.T) {
+	for name, recording := range map[string]string{
+		"empty":           ``,
+		"unknown version": `{"version":2,"started_at":"2026-10-01T12:00:00Z"}`,
+		"no responses":    `{"version":1,"started_at":"2026-10-01T12:00:00Z"}`,
+		"broken line":     "{\"version\":1,\"started_at\":\"2026-10-01T12:00:00Z\"}\n{\"t\":",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.NewReplayClient(strings.NewReader(recording), zap.NewNop())
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestReplay_Golden replays recorded incidents through the sanity guard and
+// RatesService and compares the results with the golden files.
+// Run with -update to rewrite the golden files.
+func TestReplay_Golden(t *testing.T) {
+	recordings, err := filepath.Glob(filepath.Join("testdata", "replay", "*.jsonl"))
+	require.NoError(t, err)
+	require.NotEmpty(t, recordings)
+
+	for _, recording := range recordings {
+		name := strings.TrimSuffix(filepath.Base(recording), ".jsonl")
+		t.Run(name, func(t *testing.T) {
+			replay, err := client.OpenReplay(recording, zap.NewNop(), client.WithReplaySpeed(0))
+			require.NoError(t, err)
+
+			var saved []string
+			mockRepo := new(MockRepository)
+			mockRepo.On("SaveRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					saved = append(saved, fmt.Sprintf("%s ask=%s bid=%s", args.String(1), args.String(2), args.String(3)))
+				}).Return(nil)
+			store := new(MockQuarantineStore)
+			store.On("QuarantineRate", mock.Anything, mock.Anything).Return(nil)
+
+			guard := service.NewSanityGuard(replay, store, service.GuardConfig{
+				MaxStaleness:        5 * time.Minute,
+				JumpSigma:           6,
+				JumpWindow:          10,
+				JumpMinSamples:      5,
+				JumpMinPercent:      1,
+				MaxConsecutiveJumps: 3,
+			}, zap.NewNop())
+			svc := service.NewRatesService(guard, mockRepo, zap.NewNop())
+
+			var out strings.Builder
+			for _, market := range replay.Markets() {
+				for i := 1; ; i++ {
+					rate, err := svc.GetRates(context.Background(), market)
+					if errors.Is(err, client.ErrReplayExhausted) {
+						break
+					}
+					fmt.Fprintf(&out, "%s #%d %s\n", market, i, stableAge(replayOutcome(rate, err)))
+				}
+			}
+			out.WriteString("\nsaved:\n")
+			for _, s := range saved {
+				out.WriteString(s + "\n")
+			}
+
+			golden := filepath.Join("testdata", "replay", name+".golden")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, []byte(out.String()), 0o644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), out.String())
+		})
+	}
+}
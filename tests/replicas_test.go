@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/alik/TestForWork/internal/storage/postgres"
+)
+
+func TestReplicaSet_Check(t *testing.T) {
+	first, second := newFakeDB("replica-1"), newFakeDB("replica-2")
+	first.lag.Store(1)
+	second.lag.Store(30)
+
+	set := postgres.NewReplicaSet(10*time.Second, time.Minute, zap.NewNop(),
+		postgres.Replica{Name: "replica-1", DB: first},
+		postgres.Replica{Name: "replica-2", DB: second})
+	assert.Empty(t, set.Healthy(), "replicas are unhealthy until checked")
+
+	set.Check(context.Background())
+	assert.Equal(t, []string{"replica-1"}, set.Healthy())
+
+	first.down.Store(true)
+	second.lag.Store(2)
+	set.Check(context.Background())
+	assert.Equal(t, []string{"replica-2"}, set.Healthy())
+
+	// A replica cut off from the primary reports no lag but is stale
+	second.lag.Store(0)
+	second.disconnected.Store(true)
+	set.Check(context.Background())
+	assert.Empty(t, set.Healthy())
+
+	second.disconnected.Store(false)
+	second.lag.Store(2)
+	set.SetMaxLag(time.Second)
+	set.Check(context.Background())
+	assert.Empty(t, set.Healthy())
+}
+
+func TestRepository_ReadRouting(t *testing.T) {
+	primary, replica := newFakeDB("primary"), newFakeDB("replica")
+
+	set := postgres.NewReplicaSet(10*time.Second, time.Minute, zap.NewNop(), postgres.Replica{Name: "replica", DB: replica})
+	ctx := context.Background()
+
+	servedBy := func(ctx context.Context, repo *postgres.Repository) string {
+		rates, err := repo.GetRates(ctx, "usdtrub", 10, 0)
+		require.NoError(t, err)
+		require.Len(t, rates, 1)
+		return rates[0].Market
+	}
+	latestServedBy := func(repo *postgres.Repository) string {
+		rate, err := repo.GetLatestRate(ctx, "usdtrub")
+		require.NoError(t, err)
+		require.NotNil(t, rate)
+		return rate.Market
+	}
+
+	repo := postgres.NewRepository(primary, zap.NewNop(), postgres.WithReplicas(set), postgres.WithReadYourWrites())
+	assert.Equal(t, "primary", servedBy(ctx, repo), "falls back to the primary before the first check")
+
+	set.Check(ctx)
+	assert.Equal(t, "replica", servedBy(ctx, repo))
+	assert.Equal(t, "primary", servedBy(postgres.ReadYourWrites(ctx), repo))
+	assert.Equal(t, "primary", latestServedBy(repo))
+
+	eventual := postgres.NewRepository(primary, zap.NewNop(), postgres.WithReplicas(set))
+	assert.Equal(t, "replica", latestServedBy(eventual))
+
+	replica.lag.Store(60)
+	set.Check(ctx)
+	assert.Equal(t, "primary", servedBy(ctx, repo), "lagging replica is not used")
+}
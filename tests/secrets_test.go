@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/secrets"
+)
+
+// minimalConfigYAML passes validation without flag defaults
+const minimalConfigYAML = `
+server: {port: 8080, graceful_timeout: 30s, read_timeout: 10s, write_timeout: 10s}
+database: {host: localhost, port: 5432, user: rates, database: usdt_rates, ssl_mode: disable, max_open_conns: 5}
+grinex: {base_url: "https://grinex.io", timeout: 5s, market: usdtrub, batch_concurrency: 2, transport: http}
+catalog: {source: exchange, refresh_interval: 10m}
+logging: {level: info, format: json}
+`
+
+// loadConfigFile reads the config through the global viper used by Load
+func loadConfigFile(t *testing.T, yaml string) (*config.Config, error) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+
+	viper.SetConfigFile(path)
+	t.Cleanup(viper.Reset)
+
+	return config.Reload()
+}
+
+func writeSecret(t *testing.T, value string) string {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte(value), 0o600))
+	return path
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("RATES_TEST_SECRET", "from-env")
+	path := writeSecret(t, "from-file\n")
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "${not closed", want: "${not closed"},
+		{value: "${env:RATES_TEST_SECRET}", want: "from-env"},
+		{value: "${file:" + path + "}", want: "from-file"},
+	}
+	for _, tt := range tests {
+		got, err := secrets.Default.Resolve(ctx, tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+
+	_, err := secrets.Default.Resolve(ctx, "${vault:db/password}")
+	assert.EqualError(t, err, `unknown secret provider "vault"`)
+	_, err = secrets.Default.Resolve(ctx, "${env:RATES_TEST_MISSING}")
+	assert.Error(t, err)
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Scheme() string { return "static" }
+
+func (p staticProvider) Get(_ context.Context, ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestResolver_Register(t *testing.T) {
+	resolver := secrets.NewResolver()
+	resolver.Register(staticProvider{"db": "s3cr3t"})
+
+	got, err := resolver.Resolve(context.Background(), "${static:db}")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t", got)
+}
+
+func TestConfigSecrets_FileVariant(t *testing.T) {
+	path := writeSecret(t, "rotating-1\n")
+	t.Setenv("USDT_DATABASE_PASSWORD_FILE", path)
+
+	cfg, err := loadConfigFile(t, minimalConfigYAML)
+	require.NoError(t, err)
+	assert.Equal(t, "rotating-1", cfg.Database.Password)
+
+	rotated, err := cfg.RotatedSecrets(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, rotated)
+
+	require.NoError(t, os.WriteFile(path, []byte("rotating-2\n"), 0o600))
+	rotated, err = cfg.RotatedSecrets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"database.password"}, rotated)
+
+	// The rotated password is reloadable
+	next, err := config.Reload()
+	require.NoError(t, err)
+	changes := config.Diff(cfg, next)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "database.password", changes[0].Key)
+	assert.True(t, config.IsReloadable(changes[0].Key))
+}
+
+func TestConfigSecrets_Reference(t *testing.T) {
+	t.Setenv("RATES_TEST_ADMIN_TOKEN", "token-from-env")
+
+	cfg, err := loadConfigFile(t, minimalConfigYAML+"admin: {token: \"${env:RATES_TEST_ADMIN_TOKEN}\"}\n")
+	require.NoError(t, err)
+	assert.Equal(t, "token-from-env", cfg.Admin.Token)
+	assert.Equal(t, config.Redacted, config.Dump(cfg)["admin"].(map[string]interface{})["token"])
+}
+
+func TestConfigSecrets_Errors(t *testing.T) {
+	t.Setenv("USDT_DATABASE_PASSWORD", "plain")
+	t.Setenv("USDT_DATABASE_PASSWORD_FILE", "/run/secrets/db")
+
+	_, err := loadConfigFile(t, minimalConfigYAML+"quotes: {signing_key: \"${file:/nonexistent/key}\"}\n")
+
+	var invalid *config.ValidationError
+	require.True(t, errors.As(err, &invalid))
+	require.Len(t, invalid.Problems, 2)
+	assert.Equal(t, "database.password: set either USDT_DATABASE_PASSWORD or USDT_DATABASE_PASSWORD_FILE", invalid.Problems[0])
+	assert.Contains(t, invalid.Problems[1], "quotes.signing_key: failed to resolve file secret /nonexistent/key")
+}
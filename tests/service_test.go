@@ -33,8 +33,8 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) SaveRate(ctx context.Context, market, ask, bid string, timestamp time.Time) error {
-	args := m.Called(ctx, market, ask, bid, timestamp)
+func (m *MockRepository) SaveRate(ctx context.Context, market, ask, bid string, timestamp, receivedAt time.Time) error {
+	args := m.Called(ctx, market, ask, bid, timestamp, receivedAt)
 	return args.Error(0)
 }
 
@@ -79,7 +79,7 @@ func TestRatesService_GetRates(t *testing.T) {
 					Timestamp: time.Now(),
 				}
 				grinex.On("GetRates", mock.Anything, "usdtrub").Return(rateData, nil)
-				repo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything).Return(nil)
+				repo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything, mock.Anything).Return(nil)
 			},
 			market:         "usdtrub",
 			expectError:    false,
@@ -105,7 +105,7 @@ func TestRatesService_GetRates(t *testing.T) {
 					Timestamp: time.Now(),
 				}
 				grinex.On("GetRates", mock.Anything, "usdtrub").Return(rateData, nil)
-				repo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything).Return(errors.New("DB error"))
+				repo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything, mock.Anything).Return(errors.New("DB error"))
 			},
 			market:         "usdtrub",
 			expectError:    false, // Should not fail even if DB save fails
@@ -149,6 +149,51 @@ func TestRatesService_GetRates(t *testing.T) {
 	}
 }
 
+func TestRatesService_BatchGetRates(t *testing.T) {
+	// Setup mocks
+	mockGrinex := new(MockGrinexClient)
+	mockRepo := new(MockRepository)
+
+	markets := []string{"usdtrub", "btcusdt", "ethusdt", "trxusdt", "tonusdt"}
+	for _, market := range markets {
+		if market == "ethusdt" {
+			mockGrinex.On("GetRates", mock.Anything, market).Return(nil, errors.New("API error"))
+			continue
+		}
+		mockGrinex.On("GetRates", mock.Anything, market).Return(&client.RateData{
+			Ask:       "1.1",
+			Bid:       "1.0",
+			Market:    market,
+			Timestamp: time.Now(),
+		}, nil)
+		mockRepo.On("SaveRate", mock.Anything, market, "1.1", "1.0", mock.Anything, mock.Anything).Return(nil)
+	}
+
+	// Create service
+	logger := zap.NewNop()
+	s := service.NewRatesService(mockGrinex, mockRepo, logger, service.WithBatchConcurrency(2))
+
+	// Execute
+	results := s.BatchGetRates(context.Background(), markets)
+
+	// Assert
+	require.Len(t, results, len(markets))
+	for i, result := range results {
+		assert.Equal(t, markets[i], result.Market)
+		if result.Market == "ethusdt" {
+			assert.Error(t, result.Err)
+			assert.Nil(t, result.Rate)
+		} else {
+			require.NoError(t, result.Err)
+			assert.Equal(t, markets[i], result.Rate.Market)
+		}
+	}
+
+	// Verify mock expectations
+	mockGrinex.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestRatesService_GetLatestRate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -212,33 +257,32 @@ func TestRatesService_GetLatestRate(t *testing.T) {
 	}
 }
 
+// probeFunc is an UpstreamProbe backed by a function
+type probeFunc func(ctx context.Context) error
+
+func (f probeFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
 func TestRatesService_HealthCheck(t *testing.T) {
 	tests := []struct {
 		name        string
-		setupMocks  func(*MockGrinexClient, *MockRepository)
+		dbErr       error
+		probeErr    error
 		expectError bool
 	}{
 		{
-			name: "healthy service",
-			setupMocks: func(grinex *MockGrinexClient, repo *MockRepository) {
-				repo.On("Ping", mock.Anything).Return(nil)
-				grinex.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{}, nil)
-			},
+			name:        "healthy service",
 			expectError: false,
 		},
 		{
-			name: "database unhealthy",
-			setupMocks: func(grinex *MockGrinexClient, repo *MockRepository) {
-				repo.On("Ping", mock.Anything).Return(errors.New("DB error"))
-			},
+			name:        "database unhealthy",
+			dbErr:       errors.New("DB error"),
 			expectError: true,
 		},
 		{
-			name: "api unhealthy but service still healthy",
-			setupMocks: func(grinex *MockGrinexClient, repo *MockRepository) {
-				repo.On("Ping", mock.Anything).Return(nil)
-				grinex.On("GetRates", mock.Anything, "usdtrub").Return(nil, errors.New("API error"))
-			},
+			name:        "api unhealthy but service still healthy",
+			probeErr:    errors.New("API error"),
 			expectError: false, // API error should not fail health check
 		},
 	}
@@ -248,11 +292,16 @@ func TestRatesService_HealthCheck(t *testing.T) {
 			// Setup mocks
 			mockGrinex := new(MockGrinexClient)
 			mockRepo := new(MockRepository)
-			tt.setupMocks(mockGrinex, mockRepo)
+			mockRepo.On("Ping", mock.Anything).Return(tt.dbErr)
+			probed := false
+			probe := probeFunc(func(context.Context) error {
+				probed = true
+				return tt.probeErr
+			})
 
 			// Create service
 			logger := zap.NewNop()
-			s := service.NewRatesService(mockGrinex, mockRepo, logger)
+			s := service.NewRatesService(mockGrinex, mockRepo, logger, service.WithUpstreamProbe(probe))
 
 			// Execute
 			ctx := context.Background()
@@ -263,10 +312,11 @@ func TestRatesService_HealthCheck(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				assert.True(t, probed)
 			}
 
-			// Verify mock expectations
-			mockGrinex.AssertExpectations(t)
+			// No quote is fetched, so replays and the guard are not affected
+			mockGrinex.AssertNotCalled(t, "GetRates", mock.Anything, mock.Anything)
 			mockRepo.AssertExpectations(t)
 		})
 	}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/api/grpc"
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/service"
+	pb "github.com/alik/TestForWork/proto/rates"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestGrinexClient_GetRates_Timestamps(t *testing.T) {
+	exchangeTime := time.UnixMilli(1700000000123)
+
+	tests := []struct {
+		name     string
+		ts       int64
+		expected time.Time
+	}{
+		{"milliseconds keep full precision", exchangeTime.UnixMilli(), exchangeTime},
+		{"seconds", exchangeTime.Unix(), time.Unix(exchangeTime.Unix(), 0)},
+		{"missing", 0, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(client.DepthResponse{
+					Asks:      []client.OrderBook{{Price: "95.5"}},
+					Bids:      []client.OrderBook{{Price: "95.3"}},
+					Timestamp: tt.ts,
+				})
+			}))
+			defer server.Close()
+
+			c := client.NewGrinexClient(server.URL, "usdtrub", 5*time.Second, zap.NewNop())
+
+			before := time.Now()
+			rateData, err := c.GetRates(context.Background(), "usdtrub")
+			require.NoError(t, err)
+
+			assert.True(t, tt.expected.Equal(rateData.Timestamp), "got %s", rateData.Timestamp)
+			assert.False(t, rateData.ReceivedAt.Before(before))
+			if tt.expected.IsZero() {
+				assert.Equal(t, rateData.ReceivedAt, rateData.Time())
+			}
+		})
+	}
+}
+
+func TestRatesService_GetRates_MaxAge(t *testing.T) {
+	stale := &client.RateData{Market: "usdtrub", Ask: "95.5", Bid: "95.3",
+		Timestamp: time.Now().Add(-time.Minute), ReceivedAt: time.Now()}
+	fresh := &client.RateData{Market: "usdtrub", Ask: "95.6", Bid: "95.4",
+		Timestamp: time.Now(), ReceivedAt: time.Now()}
+
+	t.Run("refreshes a stale quote", func(t *testing.T) {
+		mockGrinex := new(MockGrinexClient)
+		mockRepo := new(MockRepository)
+		mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(stale, nil).Once()
+		mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(fresh, nil).Once()
+		mockRepo.On("SaveRate", mock.Anything, "usdtrub", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		svc := service.NewRatesService(mockGrinex, mockRepo, zap.NewNop())
+		rateData, err := svc.GetRates(context.Background(), "usdtrub", service.WithMaxAge(10*time.Second))
+
+		require.NoError(t, err)
+		assert.Equal(t, "95.6", rateData.Ask)
+		mockGrinex.AssertNumberOfCalls(t, "GetRates", 2)
+	})
+
+	t.Run("refuses when still stale", func(t *testing.T) {
+		mockGrinex := new(MockGrinexClient)
+		mockRepo := new(MockRepository)
+		mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(stale, nil)
+		mockRepo.On("SaveRate", mock.Anything, "usdtrub", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		svc := service.NewRatesService(mockGrinex, mockRepo, zap.NewNop())
+		rateData, err := svc.GetRates(context.Background(), "usdtrub", service.WithMaxAge(10*time.Second))
+
+		assert.Nil(t, rateData)
+		assert.ErrorIs(t, err, service.ErrStaleQuote)
+	})
+
+	t.Run("accepts any age without max age", func(t *testing.T) {
+		mockGrinex := new(MockGrinexClient)
+		mockRepo := new(MockRepository)
+		mockGrinex.On("GetRates", mock.Anything, "usdtrub").Return(stale, nil)
+		mockRepo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", stale.Timestamp, stale.ReceivedAt).Return(nil)
+
+		svc := service.NewRatesService(mockGrinex, mockRepo, zap.NewNop())
+		_, err := svc.GetRates(context.Background(), "usdtrub")
+
+		require.NoError(t, err)
+		mockGrinex.AssertNumberOfCalls(t, "GetRates", 1)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestGRPCHandler_GetRates_Freshness(t *testing.T) {
+	receivedAt := time.Now().Add(-2 * time.Second)
+	exchangeTime := receivedAt.Add(-500 * time.Millisecond)
+
+	mockService := new(MockRatesService)
+	mockService.On("GetRates", mock.Anything, "usdtrub").Return(&client.RateData{
+		Market: "usdtrub", Ask: "95.5", Bid: "95.3", Timestamp: exchangeTime, ReceivedAt: receivedAt,
+	}, nil)
+	mockService.On("GetRates", mock.Anything, "btcusdt").Return(nil, service.ErrStaleQuote)
+
+	handler := grpc.NewRatesHandler(mockService, zap.NewNop(), "test")
+
+	response, err := handler.GetRates(context.Background(), &pb.GetRatesRequest{Market: "usdtrub"})
+	require.NoError(t, err)
+	assert.True(t, exchangeTime.Equal(response.ExchangeTimestamp.AsTime()))
+	assert.True(t, receivedAt.Equal(response.ReceivedAt.AsTime()))
+	assert.GreaterOrEqual(t, response.Age.AsDuration(), 2500*time.Millisecond)
+
+	_, err = handler.GetRates(context.Background(), &pb.GetRatesRequest{Market: "btcusdt", MaxAge: durationpb.New(time.Second)})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = handler.GetRates(context.Background(), &pb.GetRatesRequest{Market: "usdtrub", MaxAge: durationpb.New(-time.Second)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRanger is a minimal market-data WebSocket server
+type fakeRanger struct {
+	server   *httptest.Server
+	requests chan map[string]interface{}
+
+	mu    sync.Mutex
+	conn  *websocket.Conn
+	conns int
+}
+
+func newFakeRanger(t *testing.T) *fakeRanger {
+	r := &fakeRanger{requests: make(chan map[string]interface{}, 100)}
+	upgrader := websocket.Upgrader{}
+
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		r.mu.Lock()
+		r.conn = conn
+		r.conns++
+		r.mu.Unlock()
+
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			r.requests <- msg
+		}
+	}))
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+func (r *fakeRanger) url() string {
+	return "ws" + strings.TrimPrefix(r.server.URL, "http")
+}
+
+func (r *fakeRanger) send(t *testing.T, msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.NoError(t, r.conn.WriteMessage(websocket.TextMessage, []byte(msg)))
+}
+
+func (r *fakeRanger) dropConnection() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.conn.Close()
+}
+
+func (r *fakeRanger) connections() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conns
+}
+
+// expectRequest waits for a subscription request with the given event
+func (r *fakeRanger) expectRequest(t *testing.T, event string) {
+	select {
+	case msg := <-r.requests:
+		require.Equal(t, event, msg["event"])
+		assert.Equal(t, []interface{}{"usdtrub.ob-inc"}, msg["streams"])
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s request received", event)
+	}
+}
+
+// waitForRates polls the client until the predicate holds
+func waitForRates(t *testing.T, c *client.StreamClient, check func(*client.RateData) bool) *client.RateData {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		rate, err := c.GetRates(context.Background(), "usdtrub")
+		if err == nil && check(rate) {
+			return rate
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("rates did not reach the expected state")
+	return nil
+}
+
+const rangerSnapshot = `{"usdtrub.ob-snap":{"asks":[["95.6","10"],["95.5","5"]],"bids":[["95.3","8"],["95.1","2"]],"sequence":10}}`
+
+func TestStreamClient_SnapshotAndDeltas(t *testing.T) {
+	ranger := newFakeRanger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.NewStreamClient(ranger.url(), 5*time.Second, zap.NewNop())
+	c.Start(ctx)
+
+	result := make(chan *client.RateData, 1)
+	go func() {
+		rate, err := c.GetRates(context.Background(), "usdtrub")
+		assert.NoError(t, err)
+		result <- rate
+	}()
+
+	ranger.expectRequest(t, "subscribe")
+	ranger.send(t, rangerSnapshot)
+
+	rate := <-result
+	assert.Equal(t, "95.5", rate.Ask)
+	assert.Equal(t, "95.3", rate.Bid)
+	assert.Equal(t, "usdtrub", rate.Market)
+	assert.False(t, rate.ReceivedAt.IsZero())
+
+	// Removing the best ask and adding a better bid
+	ranger.send(t, `{"usdtrub.ob-inc":{"asks":["95.5",""],"sequence":11}}`)
+	ranger.send(t, `{"usdtrub.ob-inc":{"bids":["95.4","1"],"sequence":12}}`)
+
+	rate = waitForRates(t, c, func(r *client.RateData) bool { return r.Bid == "95.4" })
+	assert.Equal(t, "95.6", rate.Ask)
+}
+
+func TestStreamClient_PriceFormatsAndQuoteAge(t *testing.T) {
+	ranger := newFakeRanger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.NewStreamClient(ranger.url(), 5*time.Second, zap.NewNop())
+	c.Subscribe("usdtrub")
+	c.Start(ctx)
+
+	ranger.expectRequest(t, "subscribe")
+	ranger.send(t, `{"usdtrub.ob-snap":{"asks":[["95.50","5"],["95.6","10"]],"bids":[["95.3","8"]],"sequence":10}}`)
+	first := waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "95.50" })
+
+	// A quiet book keeps the time of its last update
+	time.Sleep(20 * time.Millisecond)
+	rate, err := c.GetRates(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	assert.Equal(t, first.ReceivedAt, rate.ReceivedAt)
+
+	// The level is removed even though the price is sent in another format
+	ranger.send(t, `{"usdtrub.ob-inc":{"asks":["95.5","0"],"sequence":11}}`)
+	rate = waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "95.6" })
+	assert.True(t, rate.ReceivedAt.After(first.ReceivedAt))
+}
+
+func TestStreamClient_SequenceGapResubscribes(t *testing.T) {
+	ranger := newFakeRanger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.NewStreamClient(ranger.url(), 5*time.Second, zap.NewNop())
+	c.Subscribe("usdtrub")
+	c.Start(ctx)
+
+	ranger.expectRequest(t, "subscribe")
+	ranger.send(t, rangerSnapshot)
+	waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "95.5" })
+
+	// Sequence 11 is missing
+	ranger.send(t, `{"usdtrub.ob-inc":{"asks":["95.0","1"],"sequence":12}}`)
+
+	ranger.expectRequest(t, "unsubscribe")
+	ranger.expectRequest(t, "subscribe")
+
+	ranger.send(t, `{"usdtrub.ob-snap":{"asks":[["96.0","1"]],"bids":[["95.9","1"]],"sequence":20}}`)
+	rate := waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "96.0" })
+	assert.Equal(t, "95.9", rate.Bid)
+}
+
+func TestStreamClient_ReconnectResubscribes(t *testing.T) {
+	ranger := newFakeRanger(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := client.NewStreamClient(ranger.url(), 200*time.Millisecond, zap.NewNop())
+	c.Subscribe("usdtrub")
+	c.Start(ctx)
+
+	ranger.expectRequest(t, "subscribe")
+	ranger.send(t, rangerSnapshot)
+	waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "95.5" })
+
+	ranger.dropConnection()
+
+	// The book is invalid until the snapshot after reconnecting
+	ranger.expectRequest(t, "subscribe")
+	assert.Equal(t, 2, ranger.connections())
+	_, err := c.GetRates(context.Background(), "usdtrub")
+	assert.Error(t, err)
+
+	ranger.send(t, `{"usdtrub.ob-snap":{"asks":[["97.0","1"]],"bids":[["96.5","1"]],"sequence":1}}`)
+	rate := waitForRates(t, c, func(r *client.RateData) bool { return r.Ask == "97.0" })
+	assert.Equal(t, "96.5", rate.Bid)
+}
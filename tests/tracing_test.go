@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alik/TestForWork/internal/client"
+	"github.com/alik/TestForWork/internal/config"
+	"github.com/alik/TestForWork/internal/service"
+	"github.com/alik/TestForWork/internal/tracing"
+	"github.com/alik/TestForWork/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var (
+	spanRecorder     = tracetest.NewSpanRecorder()
+	spanRecorderOnce sync.Once
+)
+
+// startTestTrace installs the recording tracer provider and starts a root span.
+// The global provider is set once, as tracers created before keep delegating to it.
+func startTestTrace(t *testing.T) (context.Context, trace.Span) {
+	spanRecorderOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	})
+	return otel.Tracer("tests").Start(context.Background(), t.Name())
+}
+
+// traceSpans returns the ended spans of the trace by name
+func traceSpans(traceID trace.TraceID) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spanRecorder.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+	return spans
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_GetRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"asks":[{"price":"95.5"}],"bids":[{"price":"95.3"}]}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.DebugLevel)
+	log := zap.New(core)
+
+	repo := new(MockRepository)
+	repo.On("SaveRate", mock.Anything, "usdtrub", "95.5", "95.3", mock.Anything, mock.Anything).Return(errors.New("DB error"))
+
+	grinex := client.NewGrinexClient(server.URL, "usdtrub", time.Second, log)
+	svc := service.NewRatesService(grinex, repo, log)
+
+	ctx, root := startTestTrace(t)
+	_, err := svc.GetRates(ctx, "usdtrub", service.WithClientTier("vip"))
+	root.End()
+	require.NoError(t, err)
+
+	traceID := root.SpanContext().TraceID()
+	spans := traceSpans(traceID)
+	require.Contains(t, spans, "RatesService.GetRates")
+	require.Contains(t, spans, "RatesService.fetchRates")
+	require.Contains(t, spans, "GET /api/v2/depth")
+
+	getRates, fetch, depth := spans["RatesService.GetRates"], spans["RatesService.fetchRates"], spans["GET /api/v2/depth"]
+	assert.Equal(t, root.SpanContext().SpanID(), getRates.Parent().SpanID())
+	assert.Equal(t, getRates.SpanContext().SpanID(), fetch.Parent().SpanID())
+	assert.Equal(t, fetch.SpanContext().SpanID(), depth.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindClient, depth.SpanKind())
+
+	assert.Equal(t, "vip", spanAttribute(getRates, "rates.client_tier").AsString())
+	assert.False(t, spanAttribute(fetch, "rates.persisted").AsBool())
+	require.Len(t, fetch.Events(), 1)
+	assert.Equal(t, "rate not persisted", fetch.Events()[0].Name)
+
+	// Every log line of the request carries the trace
+	require.NotZero(t, logs.Len())
+	for _, entry := range logs.All() {
+		assert.Equal(t, traceID.String(), entry.ContextMap()["trace_id"], entry.Message)
+	}
+}
+
+func TestTracing_StaleQuoteAndFailure(t *testing.T) {
+	grinex := new(MockGrinexClient)
+	repo := new(MockRepository)
+	old := &client.RateData{Market: "usdtrub", Ask: "95.5", Bid: "95.3", Timestamp: time.Now().Add(-time.Minute)}
+	grinex.On("GetRates", mock.Anything, "usdtrub").Return(old, nil)
+	repo.On("SaveRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	svc := service.NewRatesService(grinex, repo, zap.NewNop())
+
+	ctx, root := startTestTrace(t)
+	_, err := svc.GetRates(ctx, "usdtrub", service.WithMaxAge(time.Second))
+	root.End()
+	require.ErrorIs(t, err, service.ErrStaleQuote)
+
+	spans := traceSpans(root.SpanContext().TraceID())
+	getRates := spans["RatesService.GetRates"]
+	require.NotNil(t, getRates)
+
+	var events []string
+	for _, event := range getRates.Events() {
+		events = append(events, event.Name)
+	}
+	assert.Equal(t, []string{"refreshing stale quote", "refusing stale quote", "exception"}, events)
+	assert.Equal(t, "Error", getRates.Status().Code.String())
+	assert.True(t, spanAttribute(spans["RatesService.fetchRates"], "rates.persisted").AsBool())
+}
+
+func TestWithTrace_NoSpan(t *testing.T) {
+	log := zap.NewNop()
+	assert.Same(t, log, logger.WithTrace(context.Background(), log))
+}
+
+func TestTracing_Sampler(t *testing.T) {
+	tp, err := tracing.NewTracerProvider(context.Background(), config.TracingConfig{
+		Exporter: tracing.ExporterNone, ServiceName: "rates", SampleRatio: 0,
+	}, "1.2.3")
+	require.NoError(t, err)
+	defer tp.Shutdown(context.Background())
+
+	// New traces follow the ratio
+	_, span := tp.Tracer("tests").Start(context.Background(), "root")
+	assert.True(t, span.SpanContext().IsValid())
+	assert.False(t, span.SpanContext().IsSampled())
+	span.End()
+
+	// Requests with a sampled parent are always sampled
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, span = tp.Tracer("tests").Start(trace.ContextWithRemoteSpanContext(context.Background(), parent), "child")
+	assert.True(t, span.SpanContext().IsSampled())
+	assert.Equal(t, parent.TraceID(), span.SpanContext().TraceID())
+	span.End()
+}
+
+func TestTracing_StdoutExporterResource(t *testing.T) {
+	var out bytes.Buffer
+	tp, err := tracing.NewTracerProvider(context.Background(), config.TracingConfig{
+		Exporter:           tracing.ExporterStdout,
+		ServiceName:        "rates",
+		SampleRatio:        1,
+		ResourceAttributes: map[string]string{"deployment.environment": "staging", "service.instance.id": "rates-1"},
+	}, "1.2.3", tracing.WithStdout(&out))
+	require.NoError(t, err)
+
+	_, span := tp.Tracer("tests").Start(context.Background(), "exported span")
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"exported span"`)
+	assert.Contains(t, out.String(), `"Key":"deployment.environment","Value":{"Type":"STRING","Value":"staging"}`)
+	assert.Contains(t, out.String(), `"Key":"service.instance.id","Value":{"Type":"STRING","Value":"rates-1"}`)
+	assert.Contains(t, out.String(), `"Key":"service.version","Value":{"Type":"STRING","Value":"1.2.3"}`)
+}
+
+func TestTracing_ConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TracingConfig
+		err  string
+	}{
+		{"unknown exporter", config.TracingConfig{Exporter: "jaeger", SampleRatio: 1}, `unknown tracing exporter "jaeger"`},
+		{"sample ratio", config.TracingConfig{Exporter: tracing.ExporterNone, SampleRatio: 1.5}, "sample ratio must be between 0 and 1"},
+		{"missing CA", config.TracingConfig{
+			Exporter: tracing.ExporterOTLPGRPC, SampleRatio: 1, TLS: config.TracingTLSConfig{CAFile: "/nonexistent/ca.pem"},
+		}, "failed to read tracing CA file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tracing.NewTracerProvider(context.Background(), tt.cfg, "1.2.3")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestTracing_Propagator(t *testing.T) {
+	carrier := propagation.MapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"baggage":     "tenant=acme",
+	}
+
+	ctx := tracing.Propagator().Extract(context.Background(), carrier)
+
+	sc := trace.SpanContextFromContext(ctx)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.True(t, sc.IsSampled())
+	assert.Equal(t, "acme", baggage.FromContext(ctx).Member("tenant").Value())
+}